@@ -0,0 +1,57 @@
+package main
+
+/*
+#cgo CFLAGS: -O0
+
+enum color { RED = -2, GREEN = -1, BLUE = 200 };
+
+struct bits {
+	unsigned a : 3;
+	int b : 5;
+	unsigned c : 20;
+	char d;
+};
+
+union num {
+	int i;
+	float f;
+};
+
+struct anon {
+	int x;
+	union {
+		int y;
+		unsigned int z;
+	};
+	struct {
+		int w;
+	};
+};
+
+struct node {
+	int val;
+	struct node *next;
+};
+
+struct bits gbits = { 5, -3, 1000, 'x' };
+union num gnum = { 0x3f800000 };
+struct anon ganon = { 1, { 2 }, { 3 } };
+enum color gcolor = GREEN;
+struct node gnode2 = { 2, 0 };
+struct node gnode1 = { 1, &gnode2 };
+void *gptr = &gnode1;
+
+int ctypesfn(struct node *p) {
+	struct bits lbits = gbits;
+	enum color c = RED;
+	lbits.b = -1;
+	return p->val + lbits.b + c;
+}
+*/
+import "C"
+
+import "fmt"
+
+func main() {
+	fmt.Println(C.ctypesfn(&C.gnode1))
+}
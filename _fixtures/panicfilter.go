@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+type MyErr struct {
+	msg string
+}
+
+func (err *MyErr) Error() string {
+	return err.msg
+}
+
+func try(fn func()) {
+	defer func() {
+		fmt.Println("recovered:", recover())
+	}()
+	fn()
+}
+
+func main() {
+	var s []int
+	try(func() { panic("string panic") })
+	try(func() { panic(errors.New("errors.New panic")) })
+	try(func() { panic(&MyErr{"custom panic"}) })
+	try(func() { fmt.Println(s[5]) })
+}
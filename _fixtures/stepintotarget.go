@@ -0,0 +1,20 @@
+package main
+
+import "fmt"
+
+func read(n int) int {
+	return n + 1
+}
+
+func parse(n int) int {
+	return n * 2
+}
+
+func process(n int) int {
+	return n - 3
+}
+
+func main() {
+	n := process(parse(read(1)))
+	fmt.Println(n)
+}
@@ -26,7 +26,7 @@ func getSuitableMethods(pkg *types.Package, typename string) []*types.Func {
 			continue
 		}
 
-		if fn.Name() == "Command" || fn.Name() == "Restart" || fn.Name() == "State" {
+		if fn.Name() == "Command" || fn.Name() == "Restart" || fn.Name() == "State" || fn.Name() == "Events" || fn.Name() == "Stacktrace" || fn.Name() == "ListGoroutines" || fn.Name() == "Eval" {
 			r = append(r, fn)
 			continue
 		}
@@ -125,8 +125,18 @@ func processServerMethods(serverMethods []*types.Func) []binding {
 			retType = "rpc2.CommandOut"
 		case "Restart":
 			retType = "rpc2.RestartOut"
+		case "ReplayProcess":
+			retType = "rpc2.ReplayProcessOut"
 		case "State":
 			retType = "rpc2.StateOut"
+		case "Events":
+			retType = "rpc2.EventsOut"
+		case "Stacktrace":
+			retType = "rpc2.StacktraceOut"
+		case "ListGoroutines":
+			retType = "rpc2.ListGoroutinesOut"
+		case "Eval":
+			retType = "rpc2.EvalOut"
 		}
 
 		bindings[i] = binding{
@@ -224,7 +234,7 @@ func genDocs(bindings []binding) []byte {
 		fmt.Fprintf(&buf, "%s(%s) | Equivalent to API call [%s](https://godoc.org/github.com/go-delve/delve/service/rpc2#RPCServer.%s)\n", binding.name, argNames, binding.fn.Name(), binding.fn.Name())
 	}
 
-	fmt.Fprintf(&buf, "dlv_command(command) | Executes the specified command as if typed at the dlv_prompt\n")
+	fmt.Fprintf(&buf, "dlv_command(command) | Executes the specified command as if typed at the dlv_prompt, returns the data printed by commands prefixed with -json\n")
 	fmt.Fprintf(&buf, "read_file(path) | Reads the file as a string\n")
 	fmt.Fprintf(&buf, "write_file(path, contents) | Writes string to a file\n")
 	fmt.Fprintf(&buf, "cur_scope() | Returns the current evaluation scope\n")
@@ -1,8 +1,11 @@
 package cmds
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
 	"os/exec"
@@ -17,6 +20,7 @@ import (
 	"github.com/go-delve/delve/pkg/gobuild"
 	"github.com/go-delve/delve/pkg/goversion"
 	"github.com/go-delve/delve/pkg/logflags"
+	"github.com/go-delve/delve/pkg/proc/gdbserial"
 	"github.com/go-delve/delve/pkg/terminal"
 	"github.com/go-delve/delve/pkg/version"
 	"github.com/go-delve/delve/service"
@@ -47,6 +51,9 @@ var (
 	addr string
 	// initFile is the path to initialization file.
 	initFile string
+	// outputFormat is the output format used by the terminal client
+	// commands, either "text" or "json".
+	outputFormat string
 	// buildFlags is the flags passed during compiler invocation.
 	buildFlags string
 	// workingDir is the working directory for running the program.
@@ -56,6 +63,24 @@ var (
 	checkLocalConnUser bool
 	// tty is used to provide an alternate TTY for the program you wish to debug.
 	tty string
+	// captureOutput is true if the output of the target should be captured
+	// and forwarded to clients.
+	captureOutput bool
+	// outputBufferSize is the number of bytes of captured output kept for
+	// clients that connect later.
+	outputBufferSize int
+	// authTokenFile is the file containing the token clients must send to
+	// the headless server.
+	authTokenFile string
+	// tlsCert and tlsKey are the certificate and key used by the headless
+	// server to accept TLS connections.
+	tlsCert string
+	tlsKey  string
+	// useTLS and tlsCA configure the TLS connection made by 'connect'.
+	useTLS bool
+	tlsCA  string
+	// allowedOrigins lists the origins allowed to open WebSocket connections.
+	allowedOrigins []string
 
 	// backend selection
 	backend string
@@ -73,6 +98,29 @@ var (
 	traceTestBinary bool
 	traceStackDepth int
 
+	// replayOnProcessPid is the pid of the recorded process to debug when
+	// replaying a trace containing more than one process.
+	replayOnProcessPid int
+	// replayListProcesses lists the processes contained in the trace instead
+	// of replaying it.
+	replayListProcesses bool
+
+	// gdbRemote is true if the address passed to connect is the one of a
+	// stub speaking the gdb remote serial protocol.
+	gdbRemote bool
+	// gdbRemoteAddr is the address of the stub to connect to.
+	gdbRemoteAddr string
+
+	// coreSysroot is the directory used to look up the files mapped by the
+	// process of a core dump.
+	coreSysroot string
+	// coreCacheDir is the directory used to store the decompressed contents
+	// of compressed core files.
+	coreCacheDir string
+	// coreTraceback is true if the core file is the output of a Go program
+	// that crashed.
+	coreTraceback bool
+
 	conf *config.Config
 )
 
@@ -107,7 +155,7 @@ func New(docCall bool) *cobra.Command {
 		Long:  dlvCommandLongDesc,
 	}
 
-	rootCommand.PersistentFlags().StringVarP(&addr, "listen", "l", "127.0.0.1:0", "Debugging server listen address.")
+	rootCommand.PersistentFlags().StringVarP(&addr, "listen", "l", "127.0.0.1:0", "Debugging server listen address, either host:port, unix:/path/to/socket or ws:host:port to accept WebSocket connections.")
 
 	rootCommand.PersistentFlags().BoolVarP(&log, "log", "", false, "Enable debugging server logging.")
 	rootCommand.PersistentFlags().StringVarP(&logOutput, "log-output", "", "", `Comma separated list of components that should produce debug output (see 'dlv help log')`)
@@ -117,11 +165,18 @@ func New(docCall bool) *cobra.Command {
 	rootCommand.PersistentFlags().BoolVarP(&acceptMulti, "accept-multiclient", "", false, "Allows a headless server to accept multiple client connections.")
 	rootCommand.PersistentFlags().IntVar(&apiVersion, "api-version", 1, "Selects API version when headless.")
 	rootCommand.PersistentFlags().StringVar(&initFile, "init", "", "Init file, executed by the terminal client.")
+	rootCommand.PersistentFlags().StringVar(&outputFormat, "output-format", "text", `Output format of the terminal client commands, either "text" or "json" (see 'help -json' in the terminal client).`)
 	rootCommand.PersistentFlags().StringVar(&buildFlags, "build-flags", buildFlagsDefault, "Build flags, to be passed to the compiler.")
 	rootCommand.PersistentFlags().StringVar(&workingDir, "wd", ".", "Working directory for running the program.")
 	rootCommand.PersistentFlags().BoolVarP(&checkGoVersion, "check-go-version", "", true, "Checks that the version of Go in use is compatible with Delve.")
 	rootCommand.PersistentFlags().BoolVarP(&checkLocalConnUser, "only-same-user", "", true, "Only connections from the same user that started this instance of Delve are allowed to connect.")
 	rootCommand.PersistentFlags().StringVar(&backend, "backend", "default", `Backend selection (see 'dlv help backend').`)
+	rootCommand.PersistentFlags().BoolVar(&captureOutput, "capture-output", false, "Capture the standard output and standard error of the target and forward them to clients.")
+	rootCommand.PersistentFlags().StringVar(&authTokenFile, "auth-token-file", "", "File containing the token that clients must send to connect to a headless server, or that 'connect' sends to the server. If not specified the token is read from the "+authTokenEnv+" environment variable.")
+	rootCommand.PersistentFlags().StringVar(&tlsCert, "tls-cert", "", "Certificate file (PEM) used by the headless server to accept TLS connections, requires --tls-key.")
+	rootCommand.PersistentFlags().StringVar(&tlsKey, "tls-key", "", "Private key file (PEM) matching --tls-cert.")
+	rootCommand.PersistentFlags().StringSliceVar(&allowedOrigins, "allowed-origins", nil, "Comma separated list of the origins of web pages allowed to connect to a ws: listen address, in addition to pages served from localhost on the listening port.")
+	rootCommand.PersistentFlags().IntVar(&outputBufferSize, "output-buffer-size", debugger.DefaultOutputBufferSize, "Number of bytes of captured output kept for clients that connect later.")
 
 	// 'attach' subcommand.
 	attachCommand := &cobra.Command{
@@ -146,9 +201,20 @@ option to let the process continue or kill it.
 
 	// 'connect' subcommand.
 	connectCommand := &cobra.Command{
-		Use:   "connect addr",
+		Use:   "connect addr [path to executable]",
 		Short: "Connect to a headless debug server.",
-		Long:  "Connect to a running headless debug server.",
+		Long: `Connect to a running headless debug server.
+
+The address is either a TCP host:port or, for servers started with
+--listen=unix:/path/to/socket, unix: followed by the path of the socket or,
+for servers started with --listen=ws:host:port, ws:host:port.
+
+With --gdb the address is the host:port of a stub speaking the gdb remote
+serial protocol, for example gdbserver, lldb-server or qemu-user started
+with -g, and Delve debugs the process the stub is attached to. The path to
+the executable is only needed if the stub can not report it. The program
+can be for a different architecture than Delve's, single stepping is emulated
+with breakpoints for stubs that don't support it.`,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
 				return errors.New("you must provide an address as the first argument")
@@ -157,6 +223,9 @@ option to let the process continue or kill it.
 		},
 		Run: connectCmd,
 	}
+	connectCommand.Flags().BoolVar(&useTLS, "tls", false, "Connect to the server using TLS.")
+	connectCommand.Flags().BoolVar(&gdbRemote, "gdb", false, "Connect to a gdb remote serial protocol stub instead of a Delve server.")
+	connectCommand.Flags().StringVar(&tlsCA, "tls-ca", "", "Certificate file (PEM) of the authority that signed the certificate of the server, implies --tls. If not specified the system's root certificates are used.")
 	rootCommand.AddCommand(connectCommand)
 
 	// 'dap' subcommand.
@@ -168,9 +237,15 @@ option to let the process continue or kill it.
 The server supports debugging of a precompiled binary akin to 'dlv exec' via a launch request.
 It does not yet support support specification of program arguments.
 It does not yet support launch requests with 'debug' and 'test' modes that require compilation.
+Launch requests with the 'replay' mode open the mozilla rr trace in 'traceDirPath', the 'record'
+mode records the program with rr first. Both modes support reverse execution (stepBack,
+reverseContinue and restartFrame). restartFrame goes back to the entry of the function of the
+frame, a checkpoint is taken there the first time so that restarting the frame again is faster.
 It does not yet support attach requests to debug a running process like with 'dlv attach'.
 It does not yet support asynchronous request-response communication.
-The server does not accept multiple client connections.`,
+The server does not accept multiple client connections.
+The output of the target is sent to the client as output events unless --capture-output=false
+is passed.`,
 		Run: dapCmd,
 	}
 	rootCommand.AddCommand(dapCommand)
@@ -274,7 +349,28 @@ The core command will open the specified core file and the associated
 executable and let you examine the state of the process when the
 core dump was taken.
 
-Currently supports linux/amd64 and linux/arm64 core files and windows/amd64 minidumps.`,
+Currently supports linux/amd64, linux/arm64, linux/386 and linux/arm core files
+and windows/amd64 minidumps. Core files can be opened on a host of a
+different architecture.
+
+Linux core files usually do not contain the memory of mapped files, such as
+the text of shared libraries, it is read from the files on disk instead. If
+the core dump was taken on a different machine use --sysroot to specify a
+directory containing a copy of its filesystem. Mapped files that do not
+match the ones used by the process are ignored, with a warning printed when
+the core file is loaded.
+
+Core files compressed with gzip, zstd or lz4 can be opened directly, they
+are decompressed on demand into a temporary file. Reading from files made
+of multiple independently compressed frames (for example produced by pzstd
+or bgzip) only decompresses the frames that are needed, a warning is printed
+when the core file is loaded if it is made of a single frame.
+
+With --traceback the second argument is the output of a Go program that
+crashed, as printed by the runtime with GOTRACEBACK=all or GOTRACEBACK=crash.
+Goroutines, stacktraces, with the arguments printed by the runtime, and
+source code can be examined but variables can not be evaluated, since the
+memory of the process is not available.`,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 2 {
 				return errors.New("you must provide a core file and an executable")
@@ -283,6 +379,9 @@ Currently supports linux/amd64 and linux/arm64 core files and windows/amd64 mini
 		},
 		Run: coreCmd,
 	}
+	coreCommand.Flags().StringVar(&coreSysroot, "sysroot", "", "Directory used as the root when looking for the files mapped by the core dump.")
+	coreCommand.Flags().StringVar(&coreCacheDir, "cache-dir", "", "Directory used to store the decompressed contents of compressed core dumps, defaults to the directory for temporary files.")
+	coreCommand.Flags().BoolVar(&coreTraceback, "traceback", false, "The core argument is the goroutine dump printed by a Go program that crashed.")
 	rootCommand.AddCommand(coreCommand)
 
 	// 'version' subcommand.
@@ -303,6 +402,10 @@ Currently supports linux/amd64 and linux/arm64 core files and windows/amd64 mini
 
 The replay command will open a trace generated by mozilla rr. Mozilla rr must be installed:
 https://github.com/mozilla/rr
+
+If the trace contains more than one process the one to debug can be selected
+with --pid, use --list to see the processes recorded in the trace. The
+process can not be changed once the replay has started.
 			`,
 			PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 				if len(args) == 0 {
@@ -311,10 +414,15 @@ https://github.com/mozilla/rr
 				return nil
 			},
 			Run: func(cmd *cobra.Command, args []string) {
+				if replayListProcesses {
+					os.Exit(listTraceProcesses(args[0]))
+				}
 				backend = "rr"
 				os.Exit(execute(0, []string{}, conf, args[0], executingOther))
 			},
 		}
+		replayCommand.Flags().IntVarP(&replayOnProcessPid, "pid", "p", 0, "Pid of the recorded process to debug.")
+		replayCommand.Flags().BoolVarP(&replayListProcesses, "list", "", false, "List the processes recorded in the trace and exit.")
 		rootCommand.AddCommand(replayCommand)
 	}
 
@@ -398,8 +506,22 @@ func dapCmd(cmd *cobra.Command, args []string) {
 		if len(targetArgs) > 0 {
 			fmt.Fprintf(os.Stderr, "Warning: program flags ignored with dap; specify via launch/attach request instead\n")
 		}
+		// DAP clients display the output of the target, it is captured
+		// unless --capture-output=false is passed.
+		captureOutput = captureOutput || !cmd.Flags().Changed("capture-output")
+
+		authToken, err := readAuthToken()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		tlsConfig, err := serverTLSConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
 
-		listener, err := net.Listen("tcp", addr)
+		listener, err := service.ListenWithConfig(addr, service.ListenConfig{AllowedOrigins: allowedOrigins})
 		if err != nil {
 			fmt.Printf("couldn't start listener: %s\n", err)
 			return 1
@@ -408,12 +530,16 @@ func dapCmd(cmd *cobra.Command, args []string) {
 		server := dap.NewServer(&service.Config{
 			Listener:       listener,
 			DisconnectChan: disconnectChan,
+			AuthToken:      authToken,
+			TLSConfig:      tlsConfig,
 			Debugger: debugger.Config{
 				Backend:              backend,
 				Foreground:           headless && tty == "",
 				DebugInfoDirectories: conf.DebugInfoDirectories,
 				CheckGoVersion:       checkGoVersion,
 				TTY:                  tty,
+				CaptureOutput:        captureOutput,
+				OutputBufferSize:     outputBufferSize,
 			},
 		})
 		defer server.Stop()
@@ -616,6 +742,14 @@ func connectCmd(cmd *cobra.Command, args []string) {
 		fmt.Fprint(os.Stderr, "An empty address was provided. You must provide an address as the first argument.\n")
 		os.Exit(1)
 	}
+	if err := checkOutputFormat(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if gdbRemote {
+		gdbRemoteAddr = addr
+		os.Exit(execute(0, args[1:], conf, "", executingOther))
+	}
 	os.Exit(connect(addr, nil, conf, executingOther))
 }
 
@@ -663,7 +797,16 @@ func connect(addr string, clientConn net.Conn, conf *config.Config, kind execute
 	if clientConn != nil {
 		client = rpc2.NewClientFromConn(clientConn)
 	} else {
-		client = rpc2.NewClient(addr)
+		dialConfig, err := clientDialConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		client, err = rpc2.NewClientWithConfig(addr, dialConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not connect to %s: %v\n", addr, err)
+			return 1
+		}
 	}
 	if client.IsMulticlient() {
 		state, _ := client.GetStateNonBlocking()
@@ -681,6 +824,7 @@ func connect(addr string, clientConn net.Conn, conf *config.Config, kind execute
 	}
 	term := terminal.New(client, conf)
 	term.InitFile = initFile
+	term.JSONOutput = outputFormat == "json"
 	status, err := term.Run()
 	if err != nil {
 		fmt.Println(err)
@@ -688,6 +832,82 @@ func connect(addr string, clientConn net.Conn, conf *config.Config, kind execute
 	return status
 }
 
+// serverTLSConfig returns the TLS configuration of the server, or nil if
+// --tls-cert and --tls-key were not specified.
+func serverTLSConfig() (*tls.Config, error) {
+	if tlsCert == "" && tlsKey == "" {
+		return nil, nil
+	}
+	if tlsCert == "" || tlsKey == "" {
+		return nil, errors.New("--tls-cert and --tls-key must be specified together")
+	}
+	cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not load TLS certificate: %v", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// authTokenEnv is the environment variable containing the authentication
+// token when --auth-token-file is not specified. The token is never passed
+// on the command line, where other users can read it.
+const authTokenEnv = "DELVE_AUTH_TOKEN"
+
+// readAuthToken returns the authentication token read from the file
+// specified by --auth-token-file or from the environment, the empty string
+// means that no authentication is required.
+// The environment variable is removed so that the target process doesn't
+// inherit it.
+func readAuthToken() (string, error) {
+	token := os.Getenv(authTokenEnv)
+	os.Unsetenv(authTokenEnv)
+	if authTokenFile == "" {
+		return token, nil
+	}
+	buf, err := ioutil.ReadFile(authTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("could not read authentication token: %v", err)
+	}
+	token = strings.TrimRight(string(buf), "\r\n")
+	if token == "" || strings.ContainsAny(token, "\r\n") {
+		return "", fmt.Errorf("%s must contain the authentication token on a single line", authTokenFile)
+	}
+	return token, nil
+}
+
+// clientDialConfig returns the configuration used by 'connect' to connect
+// to the server.
+func clientDialConfig() (service.DialConfig, error) {
+	authToken, err := readAuthToken()
+	if err != nil {
+		return service.DialConfig{}, err
+	}
+	cfg := service.DialConfig{AuthToken: authToken}
+	if !useTLS && tlsCA == "" {
+		return cfg, nil
+	}
+	cfg.TLSConfig = &tls.Config{}
+	if tlsCA != "" {
+		pem, err := ioutil.ReadFile(tlsCA)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.TLSConfig.RootCAs = x509.NewCertPool()
+		if !cfg.TLSConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return cfg, fmt.Errorf("no certificates found in %s", tlsCA)
+		}
+	}
+	return cfg, nil
+}
+
+func checkOutputFormat() error {
+	switch outputFormat {
+	case "text", "json":
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q", outputFormat)
+}
+
 type executeKind int
 
 const (
@@ -697,6 +917,20 @@ const (
 	executingOther
 )
 
+func listTraceProcesses(tracedir string) int {
+	procs, err := gdbserial.ListTraceProcesses(tracedir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	r := make([]api.TraceProcess, len(procs))
+	for i := range procs {
+		r[i] = api.TraceProcess(procs[i])
+	}
+	api.PrintTraceProcesses(os.Stdout, r)
+	return 0
+}
+
 func execute(attachPid int, processArgs []string, conf *config.Config, coreFile string, kind executeKind) int {
 	if err := logflags.Setup(log, logOutput, logDest); err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
@@ -718,6 +952,11 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 		}
 	}
 
+	if err := checkOutputFormat(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
 	if !headless && acceptMulti {
 		fmt.Fprint(os.Stderr, "Warning accept-multi: ignored\n")
 		// acceptMulti won't work in normal (non-headless) mode because we always
@@ -725,13 +964,29 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 		acceptMulti = false
 	}
 
+	authToken, err := readAuthToken()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !headless {
+		if authTokenFile != "" || tlsCert != "" || tlsKey != "" {
+			fmt.Fprint(os.Stderr, "Warning: auth-token-file, tls-cert and tls-key are ignored without --headless\n")
+		}
+		authToken, tlsCert, tlsKey = "", "", ""
+	}
+	tlsConfig, err := serverTLSConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
 	var listener net.Listener
 	var clientConn net.Conn
-	var err error
 
-	// Make a TCP listener
+	// Make a TCP or unix domain socket listener
 	if headless {
-		listener, err = net.Listen("tcp", addr)
+		listener, err = service.ListenWithConfig(addr, service.ListenConfig{AllowedOrigins: allowedOrigins})
 	} else {
 		listener, clientConn = service.ListenerPipe()
 	}
@@ -755,15 +1010,24 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 			APIVersion:         apiVersion,
 			CheckLocalConnUser: checkLocalConnUser,
 			DisconnectChan:     disconnectChan,
+			AuthToken:          authToken,
+			TLSConfig:          tlsConfig,
 			Debugger: debugger.Config{
 				AttachPid:            attachPid,
 				WorkingDir:           workingDir,
 				Backend:              backend,
 				CoreFile:             coreFile,
+				CoreSysroot:          coreSysroot,
+				CoreCacheDir:         coreCacheDir,
+				CoreTraceback:        coreTraceback,
 				Foreground:           headless && tty == "",
 				DebugInfoDirectories: conf.DebugInfoDirectories,
 				CheckGoVersion:       checkGoVersion,
 				TTY:                  tty,
+				CaptureOutput:        captureOutput,
+				OutputBufferSize:     outputBufferSize,
+				RrOnProcessPid:       replayOnProcessPid,
+				GdbRemoteAddr:        gdbRemoteAddr,
 			},
 		})
 	default:
@@ -791,7 +1055,17 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 	var status int
 	if headless {
 		if continueOnStart {
-			client := rpc2.NewClient(listener.Addr().String())
+			// We are connecting to ourselves, there is no need to verify the
+			// certificate of the server.
+			dialConfig := service.DialConfig{AuthToken: authToken}
+			if tlsConfig != nil {
+				dialConfig.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+			}
+			client, err := rpc2.NewClientWithConfig(service.ListenerAddr(listener), dialConfig)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
 			client.Disconnect(true) // true = continue after disconnect
 		}
 		waitForDisconnectSignal(disconnectChan)
@@ -803,5 +1077,5 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 		return status
 	}
 
-	return connect(listener.Addr().String(), clientConn, conf, kind)
+	return connect(service.ListenerAddr(listener), clientConn, conf, kind)
 }
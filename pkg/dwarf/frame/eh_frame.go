@@ -0,0 +1,304 @@
+package frame
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/go-delve/delve/pkg/dwarf/util"
+)
+
+// Pointer encodings used by .eh_frame, see:
+// https://refspecs.linuxfoundation.org/LSB_5.0.0/LSB-Core-generic/LSB-Core-generic/dwarfext.html
+const (
+	ehPeAbsptr  = 0x00
+	ehPeUleb128 = 0x01
+	ehPeUdata2  = 0x02
+	ehPeUdata4  = 0x03
+	ehPeUdata8  = 0x04
+	ehPeSleb128 = 0x09
+	ehPeSdata2  = 0x0a
+	ehPeSdata4  = 0x0b
+	ehPeSdata8  = 0x0c
+
+	ehPePcrel    = 0x10
+	ehPeIndirect = 0x80
+	ehPeOmit     = 0xff
+)
+
+// errIndirectPointer is returned for pointers that need to be read from
+// memory, they are not supported.
+var errIndirectPointer = errors.New("indirect pointer encoding not supported")
+
+// errShortEHPointer is returned when a pointer doesn't fit in its entry.
+var errShortEHPointer = errors.New("pointer exceeds entry")
+
+// ehEntry is an entry of the .eh_frame section.
+type ehEntry struct {
+	off    uint64 // offset of the entry in the section
+	idoff  uint64 // offset of the CIE id or CIE pointer field
+	length uint32
+	id     uint32
+	body   []byte // contents of the entry after the CIE id or CIE pointer
+}
+
+// ehCIE is a Common Information Entry read from .eh_frame.
+type ehCIE struct {
+	*CommonInformationEntry
+	ptrEncoding byte // encoding of the addresses of FDEs
+	hasAugData  bool // FDEs have augmentation data
+}
+
+// ParseEH parses the contents of a .eh_frame section, ehFrameAddr is the
+// address of the section in the file, before it is relocated by
+// staticBase.
+// The .eh_frame section uses the same format as .debug_frame except that
+// CIE pointers are relative to the pointer itself, CIEs can have
+// augmentation data and addresses can be encoded in several ways.
+func ParseEH(data []byte, order binary.ByteOrder, staticBase uint64, ptrSize int, ehFrameAddr uint64) (FrameDescriptionEntries, error) {
+	var entries []ehEntry
+	for off := uint64(0); off+4 <= uint64(len(data)); {
+		start := off
+		length := uint64(order.Uint32(data[off:]))
+		off += 4
+		if length == 0 {
+			// zero terminator
+			break
+		}
+		if length == 0xffffffff {
+			return nil, fmt.Errorf("64bit .eh_frame entry at %#x not supported", start)
+		}
+		if length < 4 || off+length > uint64(len(data)) {
+			return nil, fmt.Errorf("malformed .eh_frame entry at %#x", start)
+		}
+		entries = append(entries, ehEntry{
+			off:    start,
+			idoff:  off,
+			length: uint32(length - 4),
+			id:     order.Uint32(data[off:]),
+			body:   data[off+4 : off+length],
+		})
+		off += length
+	}
+
+	cies := make(map[uint64]*ehCIE)
+	for _, entry := range entries {
+		if entry.id != 0 {
+			continue
+		}
+		cie, err := parseEHCIE(entry, order, staticBase, ptrSize)
+		if err != nil {
+			return nil, err
+		}
+		cies[entry.off] = cie
+	}
+
+	fdes := newFrameIndex()
+	for _, entry := range entries {
+		if entry.id == 0 {
+			continue
+		}
+		cie := cies[entry.idoff-uint64(entry.id)]
+		if cie == nil {
+			return nil, fmt.Errorf("could not find CIE for .eh_frame entry at %#x", entry.off)
+		}
+		fde, err := parseEHFDE(entry, cie, order, ptrSize, ehFrameAddr)
+		if err != nil {
+			return nil, err
+		}
+		if fde == nil {
+			continue
+		}
+		fde.begin += staticBase
+		fdes = append(fdes, fde)
+	}
+	return fdes, nil
+}
+
+func parseEHCIE(entry ehEntry, order binary.ByteOrder, staticBase uint64, ptrSize int) (*ehCIE, error) {
+	buf := bytes.NewBuffer(entry.body)
+	cie := &ehCIE{
+		CommonInformationEntry: &CommonInformationEntry{Length: entry.length, staticBase: staticBase},
+		ptrEncoding:            ehPeAbsptr,
+	}
+	cie.Version, _ = buf.ReadByte()
+	aug, err := buf.ReadString(0x0)
+	if err != nil {
+		return nil, fmt.Errorf("malformed .eh_frame CIE at %#x", entry.off)
+	}
+	cie.Augmentation = aug[:len(aug)-1]
+	cie.CodeAlignmentFactor, _ = util.DecodeULEB128(buf)
+	cie.DataAlignmentFactor, _ = util.DecodeSLEB128(buf)
+	if cie.Version == 1 {
+		ra, _ := buf.ReadByte()
+		cie.ReturnAddressRegister = uint64(ra)
+	} else {
+		cie.ReturnAddressRegister, _ = util.DecodeULEB128(buf)
+	}
+
+	if len(cie.Augmentation) > 0 && cie.Augmentation[0] == 'z' {
+		cie.hasAugData = true
+		n, _ := util.DecodeULEB128(buf)
+		if n > uint64(buf.Len()) {
+			return nil, fmt.Errorf("malformed .eh_frame CIE at %#x", entry.off)
+		}
+		augdata := buf.Next(int(n))
+	augLoop:
+		for _, c := range cie.Augmentation[1:] {
+			switch c {
+			case 'R':
+				if len(augdata) < 1 {
+					break augLoop
+				}
+				cie.ptrEncoding = augdata[0]
+				augdata = augdata[1:]
+			case 'L':
+				if len(augdata) < 1 {
+					break augLoop
+				}
+				augdata = augdata[1:]
+			case 'P':
+				// personality routine, we only need to know its size to skip it
+				if len(augdata) < 1 {
+					break augLoop
+				}
+				_, n, err := decodeEHPointer(augdata[1:], augdata[0]&0x0f, 0, order, ptrSize)
+				if err != nil {
+					break augLoop
+				}
+				augdata = augdata[1+n:]
+			case 'S', 'B':
+				// signal frame, no data
+			default:
+				break augLoop
+			}
+		}
+	} else if cie.Augmentation != "" {
+		return nil, fmt.Errorf("unsupported .eh_frame augmentation %q at %#x", cie.Augmentation, entry.off)
+	}
+
+	cie.InitialInstructions = buf.Bytes()
+	return cie, nil
+}
+
+// parseEHFDE parses a FDE of .eh_frame, it returns nil if the FDE
+// describes an empty range.
+func parseEHFDE(entry ehEntry, cie *ehCIE, order binary.ByteOrder, ptrSize int, ehFrameAddr uint64) (*FrameDescriptionEntry, error) {
+	body := entry.body
+	fieldAddr := ehFrameAddr + entry.idoff + 4
+
+	begin, n, err := decodeEHPointer(body, cie.ptrEncoding, fieldAddr, order, ptrSize)
+	if err != nil {
+		return nil, fmt.Errorf(".eh_frame FDE at %#x: %v", entry.off, err)
+	}
+	body = body[n:]
+	size, n, err := decodeEHPointer(body, cie.ptrEncoding&0x0f, 0, order, ptrSize)
+	if err != nil {
+		return nil, fmt.Errorf(".eh_frame FDE at %#x: %v", entry.off, err)
+	}
+	body = body[n:]
+
+	if cie.hasAugData {
+		buf := bytes.NewBuffer(body)
+		augLen, _ := util.DecodeULEB128(buf)
+		if augLen > uint64(buf.Len()) {
+			return nil, fmt.Errorf("malformed .eh_frame FDE at %#x", entry.off)
+		}
+		buf.Next(int(augLen))
+		body = buf.Bytes()
+	}
+
+	if size == 0 {
+		return nil, nil
+	}
+
+	return &FrameDescriptionEntry{
+		Length:       entry.length,
+		CIE:          cie.CommonInformationEntry,
+		Instructions: body,
+		begin:        begin,
+		size:         size,
+		order:        order,
+	}, nil
+}
+
+// decodeEHPointer decodes a pointer with encoding enc from the start of
+// buf, fieldAddr is the address of the pointer, used for PC relative
+// encodings. Returns the value and the number of bytes read.
+func decodeEHPointer(buf []byte, enc byte, fieldAddr uint64, order binary.ByteOrder, ptrSize int) (uint64, int, error) {
+	if enc == ehPeOmit {
+		return 0, 0, nil
+	}
+	if enc&ehPeIndirect != 0 {
+		return 0, 0, errIndirectPointer
+	}
+
+	format := enc & 0x0f
+	if format == ehPeAbsptr {
+		switch ptrSize {
+		case 4:
+			format = ehPeUdata4
+		default:
+			format = ehPeUdata8
+		}
+	}
+
+	var v uint64
+	var n int
+	short := func(sz int) bool {
+		n = sz
+		return len(buf) < sz
+	}
+	switch format {
+	case ehPeUleb128, ehPeSleb128:
+		b := bytes.NewBuffer(buf)
+		if format == ehPeUleb128 {
+			x, sz := util.DecodeULEB128(b)
+			v, n = x, int(sz)
+		} else {
+			x, sz := util.DecodeSLEB128(b)
+			v, n = uint64(x), int(sz)
+		}
+	case ehPeUdata2:
+		if short(2) {
+			return 0, 0, errShortEHPointer
+		}
+		v = uint64(order.Uint16(buf))
+	case ehPeSdata2:
+		if short(2) {
+			return 0, 0, errShortEHPointer
+		}
+		v = uint64(int16(order.Uint16(buf)))
+	case ehPeUdata4:
+		if short(4) {
+			return 0, 0, errShortEHPointer
+		}
+		v = uint64(order.Uint32(buf))
+	case ehPeSdata4:
+		if short(4) {
+			return 0, 0, errShortEHPointer
+		}
+		v = uint64(int32(order.Uint32(buf)))
+	case ehPeUdata8, ehPeSdata8:
+		if short(8) {
+			return 0, 0, errShortEHPointer
+		}
+		v = order.Uint64(buf)
+	default:
+		return 0, 0, fmt.Errorf("unknown pointer encoding %#x", enc)
+	}
+
+	switch enc & 0x70 {
+	case 0:
+		// absolute
+	case ehPePcrel:
+		v += fieldAddr
+	default:
+		return 0, 0, fmt.Errorf("unsupported pointer encoding %#x", enc)
+	}
+	if ptrSize == 4 {
+		v &= 0xffffffff
+	}
+	return v, n, nil
+}
@@ -56,3 +56,81 @@ func BenchmarkParse(b *testing.B) {
 		Parse(data, binary.BigEndian, 0, ptrSizeByRuntimeArch())
 	}
 }
+
+func TestParseEH(t *testing.T) {
+	const (
+		ehFrameAddr = 0x1000
+		fnAddr      = 0x2000
+		staticBase  = 0x10000
+	)
+
+	var buf bytes.Buffer
+	entry := func(id uint32, body []byte) {
+		binary.Write(&buf, binary.LittleEndian, uint32(4+len(body)))
+		binary.Write(&buf, binary.LittleEndian, id)
+		buf.Write(body)
+	}
+
+	// CIE with augmentation "zR", FDE addresses are encoded as PC relative
+	// signed 4 byte integers.
+	entry(0, []byte{
+		1,             // version
+		'z', 'R', 0x0, // augmentation
+		1,    // code alignment factor
+		0x78, // data alignment factor (-8)
+		16,   // return address register
+		1,    // augmentation data length
+		0x1b, // DW_EH_PE_pcrel | DW_EH_PE_sdata4
+		DW_CFA_def_cfa, 7, 8,
+		DW_CFA_offset | 16, 1,
+		DW_CFA_nop,
+	})
+
+	fdeoff := uint32(buf.Len())
+	begin := make([]byte, 4)
+	binary.LittleEndian.PutUint32(begin, uint32(fnAddr-(ehFrameAddr+fdeoff+8)))
+	entry(fdeoff+4, append(begin,
+		0x10, 0, 0, 0, // size
+		0, // augmentation data length
+		DW_CFA_advance_loc|1, DW_CFA_def_cfa_offset, 16, DW_CFA_offset|6, 2,
+		DW_CFA_GNU_args_size, 16,
+		DW_CFA_remember_state,
+		DW_CFA_advance_loc|1, DW_CFA_def_cfa_offset, 8,
+		DW_CFA_advance_loc|1, DW_CFA_restore_state,
+	))
+	buf.Write([]byte{0, 0, 0, 0})
+
+	fdes, err := ParseEH(buf.Bytes(), binary.LittleEndian, staticBase, 8, ehFrameAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fdes) != 1 {
+		t.Fatalf("expected 1 FDE, got %d", len(fdes))
+	}
+	fde := fdes[0]
+	if fde.Begin() != staticBase+fnAddr || fde.End() != staticBase+fnAddr+0x10 {
+		t.Fatalf("wrong FDE range %#x-%#x", fde.Begin(), fde.End())
+	}
+
+	for _, tc := range []struct {
+		pc        uint64
+		cfaOffset int64
+		rbp       bool
+	}{
+		{fde.Begin(), 8, false},
+		{fde.Begin() + 1, 16, true},
+		{fde.Begin() + 2, 8, true},
+		{fde.Begin() + 3, 16, true},
+	} {
+		fctx := fde.EstablishFrame(tc.pc)
+		if fctx.CFA.Reg != 7 || fctx.CFA.Offset != tc.cfaOffset {
+			t.Errorf("%#x: wrong CFA rule %#v", tc.pc, fctx.CFA)
+		}
+		if ra := fctx.Regs[16]; ra.Rule != RuleOffset || ra.Offset != -8 {
+			t.Errorf("%#x: wrong return address rule %#v", tc.pc, ra)
+		}
+		if _, ok := fctx.Regs[6]; ok != tc.rbp {
+			t.Errorf("%#x: wrong rbp rule %#v", tc.pc, fctx.Regs[6])
+		}
+	}
+}
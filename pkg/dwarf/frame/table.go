@@ -22,7 +22,7 @@ type FrameContext struct {
 	CFA           DWRule
 	Regs          map[uint64]DWRule
 	initialRegs   map[uint64]DWRule
-	prevRegs      map[uint64]DWRule
+	prevRegs      []frameState
 	buf           *bytes.Buffer
 	cie           *CommonInformationEntry
 	RetAddrReg    uint64
@@ -30,6 +30,12 @@ type FrameContext struct {
 	dataAlignment int64
 }
 
+// frameState is a row of the table saved by DW_CFA_remember_state.
+type frameState struct {
+	cfa  DWRule
+	regs map[uint64]DWRule
+}
+
 // Instructions used to recreate the table from the .debug_frame data.
 const (
 	DW_CFA_nop                = 0x0        // No ops
@@ -56,6 +62,8 @@ const (
 	DW_CFA_val_offset_sf                   // op1: ULEB128, op2: SLEB128
 	DW_CFA_val_expression                  // op1: ULEB128, op2: BLOCK
 	DW_CFA_lo_user            = 0x1c       // op1: BLOCK
+	DW_CFA_GNU_args_size      = 0x2e       // op1: ULEB128 size
+	DW_CFA_GNU_neg_offset_ext = 0x2f       // op1: ULEB128 register, op2: ULEB128 offset
 	DW_CFA_hi_user            = 0x3f       // op1: ULEB128 register, op2: BLOCK
 	DW_CFA_advance_loc        = (0x1 << 6) // High 2 bits: 0x1, low 6: delta
 	DW_CFA_offset             = (0x2 << 6) // High 2 bits: 0x2, low 6: register
@@ -110,6 +118,8 @@ var fnlookup = map[byte]instruction{
 	DW_CFA_val_offset_sf:      valoffsetsf,
 	DW_CFA_val_expression:     valexpression,
 	DW_CFA_lo_user:            louser,
+	DW_CFA_GNU_args_size:      gnuargssize,
+	DW_CFA_GNU_neg_offset_ext: gnunegoffsetextended,
 	DW_CFA_hi_user:            hiuser,
 }
 
@@ -121,13 +131,15 @@ func executeCIEInstructions(cie *CommonInformationEntry) *FrameContext {
 		Regs:          make(map[uint64]DWRule),
 		RetAddrReg:    cie.ReturnAddressRegister,
 		initialRegs:   make(map[uint64]DWRule),
-		prevRegs:      make(map[uint64]DWRule),
 		codeAlignment: cie.CodeAlignmentFactor,
 		dataAlignment: cie.DataAlignmentFactor,
 		buf:           bytes.NewBuffer(initialInstructions),
 	}
 
 	frame.executeDwarfProgram()
+	for reg, rule := range frame.Regs {
+		frame.initialRegs[reg] = rule
+	}
 	return frame
 }
 
@@ -267,7 +279,7 @@ func restore(frame *FrameContext) {
 	reg := uint64(b & low_6_offset)
 	oldrule, ok := frame.initialRegs[reg]
 	if ok {
-		frame.Regs[reg] = DWRule{Offset: oldrule.Offset, Rule: RuleOffset}
+		frame.Regs[reg] = oldrule
 	} else {
 		frame.Regs[reg] = DWRule{Rule: RuleUndefined}
 	}
@@ -306,11 +318,21 @@ func register(frame *FrameContext) {
 }
 
 func rememberstate(frame *FrameContext) {
-	frame.prevRegs = frame.Regs
+	regs := make(map[uint64]DWRule, len(frame.Regs))
+	for reg, rule := range frame.Regs {
+		regs[reg] = rule
+	}
+	frame.prevRegs = append(frame.prevRegs, frameState{cfa: frame.CFA, regs: regs})
 }
 
 func restorestate(frame *FrameContext) {
-	frame.Regs = frame.prevRegs
+	if len(frame.prevRegs) == 0 {
+		return
+	}
+	state := frame.prevRegs[len(frame.prevRegs)-1]
+	frame.prevRegs = frame.prevRegs[:len(frame.prevRegs)-1]
+	frame.CFA = state.cfa
+	frame.Regs = state.regs
 }
 
 func restoreextended(frame *FrameContext) {
@@ -318,7 +340,7 @@ func restoreextended(frame *FrameContext) {
 
 	oldrule, ok := frame.initialRegs[reg]
 	if ok {
-		frame.Regs[reg] = DWRule{Offset: oldrule.Offset, Rule: RuleOffset}
+		frame.Regs[reg] = oldrule
 	} else {
 		frame.Regs[reg] = DWRule{Rule: RuleUndefined}
 	}
@@ -422,3 +444,18 @@ func louser(frame *FrameContext) {
 func hiuser(frame *FrameContext) {
 	frame.buf.Next(1)
 }
+
+func gnuargssize(frame *FrameContext) {
+	// The size of the arguments pushed on the stack is only needed to
+	// unwind exceptions, ignore it.
+	util.DecodeULEB128(frame.buf)
+}
+
+func gnunegoffsetextended(frame *FrameContext) {
+	var (
+		reg, _    = util.DecodeULEB128(frame.buf)
+		offset, _ = util.DecodeULEB128(frame.buf)
+	)
+
+	frame.Regs[reg] = DWRule{Offset: -int64(offset) * frame.dataAlignment, Rule: RuleOffset}
+}
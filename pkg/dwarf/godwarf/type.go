@@ -269,7 +269,12 @@ type StructField struct {
 	ByteSize   int64
 	BitOffset  int64 // within the ByteSize bytes at ByteOffset
 	BitSize    int64 // zero if not a bit field
-	Embedded   bool
+	// DataBitOffset is the offset of a bit field from the start of the
+	// struct, in bits, counting from the least significant bit. For DWARF 2
+	// style bit fields it is computed from ByteOffset and BitOffset assuming
+	// a little endian target.
+	DataBitOffset int64
+	Embedded      bool
 }
 
 func (t *StructType) String() string { return t.stringIntl(make(recCheck)) }
@@ -378,11 +383,12 @@ func (t *InterfaceType) stringIntl(recCheck recCheck) string {
 
 // An EnumType represents an enumerated type.
 // The only indication of its native integer type is its ByteSize
-// (inside CommonType).
+// (inside CommonType) and whether it is signed.
 type EnumType struct {
 	CommonType
 	EnumName string
 	Val      []*EnumValue
+	Signed   bool // if true, the values of the enum are signed integers
 }
 
 // An EnumValue represents a single enumeration value.
@@ -833,15 +839,31 @@ func readType(d *dwarf.Data, name string, r *dwarf.Reader, off dwarf.Offset, typ
 				}
 
 				haveBitOffset := false
+				haveDataBitOffset := false
 				f.Name, _ = kid.Val(dwarf.AttrName).(string)
 				f.ByteSize, _ = kid.Val(dwarf.AttrByteSize).(int64)
 				f.BitOffset, haveBitOffset = kid.Val(dwarf.AttrBitOffset).(int64)
 				f.BitSize, _ = kid.Val(dwarf.AttrBitSize).(int64)
+				f.DataBitOffset, haveDataBitOffset = kid.Val(dwarf.AttrDataBitOffset).(int64)
 				f.Embedded, _ = kid.Val(AttrGoEmbeddedField).(bool)
+				if f.Name == "" {
+					// anonymous struct or union member of a C struct, its fields are
+					// accessed as if they belonged to the containing struct.
+					f.Embedded = true
+				}
+				if f.BitSize > 0 && !haveDataBitOffset {
+					storageSize := f.ByteSize
+					if storageSize == 0 && f.Type != nil {
+						storageSize = f.Type.Size()
+					}
+					f.DataBitOffset = f.ByteOffset*8 + storageSize*8 - f.BitOffset - f.BitSize
+				}
 				t.Field = append(t.Field, f)
 
 				bito := f.BitOffset
-				if !haveBitOffset {
+				if haveDataBitOffset {
+					bito = f.DataBitOffset
+				} else if !haveBitOffset {
 					bito = f.ByteOffset * 8
 				}
 				if bito == lastFieldBitOffset && t.Kind != "union" {
@@ -897,12 +919,20 @@ func readType(d *dwarf.Data, name string, r *dwarf.Reader, off dwarf.Offset, typ
 		typeCache[off] = t
 		t.Name, _ = e.Val(dwarf.AttrName).(string)
 		t.EnumName, _ = e.Val(dwarf.AttrName).(string)
+		if enc, ok := e.Val(dwarf.AttrEncoding).(int64); ok {
+			t.Signed = enc == encSigned || enc == encSignedChar
+		} else if e.Val(dwarf.AttrType) != nil {
+			_, t.Signed = typeOf(e, dwarf.AttrType).(*IntType)
+		}
 		t.Val = make([]*EnumValue, 0, 8)
 		for kid := next(); kid != nil; kid = next() {
 			if kid.Tag == dwarf.TagEnumerator {
 				f := new(EnumValue)
 				f.Name, _ = kid.Val(dwarf.AttrName).(string)
 				f.Val, _ = kid.Val(dwarf.AttrConstValue).(int64)
+				if f.Val < 0 {
+					t.Signed = true
+				}
 				n := len(t.Val)
 				if n >= cap(t.Val) {
 					val := make([]*EnumValue, n, n*2)
@@ -913,6 +943,14 @@ func readType(d *dwarf.Data, name string, r *dwarf.Reader, off dwarf.Offset, typ
 				t.Val[n] = f
 			}
 		}
+		if bs, _ := e.Val(dwarf.AttrByteSize).(int64); t.Signed && bs > 0 && bs < 8 {
+			// values encoded with DW_FORM_dataN are read as unsigned integers
+			for _, v := range t.Val {
+				if v.Val >= 1<<uint(8*bs-1) {
+					v.Val -= 1 << uint(8*bs)
+				}
+			}
+		}
 
 	case dwarf.TagPointerType:
 		// Type modifier (DWARF v2 §5.2)
@@ -30,6 +30,7 @@ func AMD64Arch(goos string) *Arch {
 		breakpointInstruction:            amd64BreakInstruction,
 		breakInstrMovesPC:                true,
 		derefTLS:                         goos == "windows",
+		framePointers:                    true,
 		prologues:                        prologuesAMD64,
 		fixFrameUnwindContext:            amd64FixFrameUnwindContext,
 		switchStack:                      amd64SwitchStack,
@@ -185,10 +186,11 @@ func amd64SwitchStack(it *stackIterator, _ *op.DwarfRegisters) bool {
 		// reads the previous value of g0.sched.sp that runtime.cgocallback_gofunc saved on the stack
 		it.g0_sched_sp, _ = readUintRaw(it.mem, uintptr(it.regs.SP()), int64(it.bi.Arch.PtrSize()))
 		it.top = false
-		callFrameRegs, ret, retaddr := it.advanceRegs()
+		callFrameRegs, ret, retaddr, unwinder := it.advanceRegs()
 		frameOnSystemStack := it.newStackframe(ret, retaddr)
 		it.pc = frameOnSystemStack.Ret
 		it.regs = callFrameRegs
+		it.unwinder = unwinder
 		it.systemstack = true
 		return true
 
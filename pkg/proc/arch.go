@@ -15,6 +15,8 @@ type Arch struct {
 	breakpointInstruction []byte
 	breakInstrMovesPC     bool
 	derefTLS              bool
+	usesLR                bool // architecture uses a link register, also called RA on some architectures
+	framePointers         bool // the Go compiler maintains frame pointers on this architecture
 
 	// asmDecode decodes the assembly instruction starting at mem[0:] into asmInst.
 	// It assumes that the Loc and AtPC fields of asmInst have already been filled.
@@ -31,6 +31,8 @@ func ARM64Arch(goos string) *Arch {
 		breakpointInstruction:            arm64BreakInstruction,
 		breakInstrMovesPC:                false,
 		derefTLS:                         false,
+		usesLR:                           true,
+		framePointers:                    true,
 		prologues:                        prologuesARM64,
 		fixFrameUnwindContext:            arm64FixFrameUnwindContext,
 		switchStack:                      arm64SwitchStack,
@@ -342,7 +344,7 @@ func arm64RegistersToDwarfRegisters(staticBase uint64, regs Registers) op.DwarfR
 	dregs[arm64DwarfIPRegNum] = op.DwarfRegisterFromUint64(regs.PC())
 	dregs[arm64DwarfSPRegNum] = op.DwarfRegisterFromUint64(regs.SP())
 	dregs[arm64DwarfBPRegNum] = op.DwarfRegisterFromUint64(regs.BP())
-	if lr, err := regs.Get(int(arm64asm.X30)); err != nil {
+	if lr, err := regs.Get(int(arm64asm.X30)); err == nil {
 		dregs[arm64DwarfLRRegNum] = op.DwarfRegisterFromUint64(lr)
 	}
 
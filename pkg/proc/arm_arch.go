@@ -0,0 +1,207 @@
+package proc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/frame"
+	"github.com/go-delve/delve/pkg/dwarf/op"
+)
+
+const (
+	armDwarfIPRegNum uint64 = 15
+	armDwarfLRRegNum uint64 = 14
+	armDwarfSPRegNum uint64 = 13
+	armDwarfBPRegNum uint64 = 11
+)
+
+// armBreakInstruction is the undefined instruction used by the linux
+// kernel for ARM breakpoints.
+var armBreakInstruction = []byte{0xf0, 0x01, 0xf0, 0xe7}
+
+// ARMArch returns an initialized ARM
+// struct.
+func ARMArch(goos string) *Arch {
+	return &Arch{
+		Name:                             "arm",
+		ptrSize:                          4,
+		maxInstructionLength:             4,
+		breakpointInstruction:            armBreakInstruction,
+		breakInstrMovesPC:                false,
+		derefTLS:                         false,
+		usesLR:                           true,
+		prologues:                        prologuesARM,
+		fixFrameUnwindContext:            armFixFrameUnwindContext,
+		switchStack:                      armSwitchStack,
+		regSize:                          armRegSize,
+		RegistersToDwarfRegisters:        armRegistersToDwarfRegisters,
+		addrAndStackRegsToDwarfRegisters: armAddrAndStackRegsToDwarfRegisters,
+		DwarfRegisterToString:            armDwarfRegisterToString,
+		inhibitStepInto:                  func(*BinaryInfo, uint64) bool { return false },
+		asmDecode:                        armAsmDecode,
+	}
+}
+
+func armFixFrameUnwindContext(fctxt *frame.FrameContext, pc uint64, bi *BinaryInfo) *frame.FrameContext {
+	a := bi.Arch
+	if a.sigreturnfn == nil {
+		a.sigreturnfn = bi.LookupFunc["runtime.sigreturn"]
+	}
+
+	if fctxt == nil || (a.sigreturnfn != nil && pc >= a.sigreturnfn.Entry && pc < a.sigreturnfn.End) {
+		// Go does not maintain a frame pointer on ARM, when there's no frame
+		// descriptor entry the best we can do is assume that we are in a leaf
+		// function that hasn't touched the stack yet:
+		// - return address is LR
+		// - cfa is sp
+		// - sp is cfa
+		return &frame.FrameContext{
+			RetAddrReg: armDwarfLRRegNum,
+			Regs: map[uint64]frame.DWRule{
+				armDwarfLRRegNum: frame.DWRule{
+					Rule: frame.RuleSameVal,
+				},
+				armDwarfSPRegNum: frame.DWRule{
+					Rule:   frame.RuleValOffset,
+					Offset: 0,
+				},
+			},
+			CFA: frame.DWRule{
+				Rule:   frame.RuleCFA,
+				Reg:    armDwarfSPRegNum,
+				Offset: 0,
+			},
+		}
+	}
+
+	// Functions that haven't saved LR on the stack yet (including leaf
+	// functions) have no rule for it, the return address is still in LR.
+	if fctxt.Regs[armDwarfLRRegNum].Rule == frame.RuleUndefined {
+		fctxt.Regs[armDwarfLRRegNum] = frame.DWRule{
+			Rule: frame.RuleSameVal,
+		}
+	}
+
+	return fctxt
+}
+
+func armSwitchStack(it *stackIterator, _ *op.DwarfRegisters) bool {
+	if it.frame.Current.Fn == nil {
+		return false
+	}
+	switch it.frame.Current.Fn.Name {
+	case "runtime.asmcgocall", "runtime.cgocallback_gofunc", "runtime.sigpanic":
+		return false
+	case "runtime.goexit", "runtime.rt0_go", "runtime.mcall":
+		// Look for "top of stack" functions.
+		it.atend = true
+		return true
+
+	case "runtime.mstart":
+		// See the comment in i386SwitchStack.
+		if it.top || !it.systemstack || it.g == nil {
+			return false
+		}
+		if fn := it.bi.PCToFunc(it.g.PC); fn == nil || fn.Name != "runtime.systemstack_switch" {
+			return false
+		}
+
+		it.switchToGoroutineStack()
+		return true
+
+	default:
+		if it.systemstack && it.top && it.g != nil && strings.HasPrefix(it.frame.Current.Fn.Name, "runtime.") && it.frame.Current.Fn.Name != "runtime.fatalthrow" {
+			// The runtime switches to the system stack in multiple places, since
+			// we are only interested in printing the system stack for cgo calls
+			// we switch directly to the goroutine stack if we detect that the
+			// function at the top of the stack is a runtime function.
+			it.switchToGoroutineStack()
+			return true
+		}
+
+		return false
+	}
+}
+
+func armRegSize(regnum uint64) int {
+	// VFP registers
+	if regnum >= 256 && regnum <= 287 {
+		return 8
+	}
+	return 4
+}
+
+// The mapping between hardware registers and DWARF registers is specified
+// in the DWARF for the ARM Architecture document, section 3.1
+// https://developer.arm.com/documentation/ihi0040/latest
+
+var armDwarfToName = func() map[int]string {
+	r := make(map[int]string)
+	for i := 0; i <= 12; i++ {
+		r[i] = fmt.Sprintf("R%d", i)
+	}
+	r[13] = "SP"
+	r[14] = "LR"
+	r[15] = "PC"
+	for i := 0; i <= 31; i++ {
+		r[i+256] = fmt.Sprintf("D%d", i)
+	}
+	return r
+}()
+
+var armNameToDwarf = func() map[string]int {
+	r := make(map[string]int)
+	for regNum, regName := range armDwarfToName {
+		r[strings.ToLower(regName)] = regNum
+	}
+	return r
+}()
+
+func maxARMDwarfRegister() int {
+	max := int(armDwarfIPRegNum)
+	for i := range armDwarfToName {
+		if i > max {
+			max = i
+		}
+	}
+	return max
+}
+
+func armRegistersToDwarfRegisters(staticBase uint64, regs Registers) op.DwarfRegisters {
+	dregs := initDwarfRegistersFromSlice(maxARMDwarfRegister(), regs, armNameToDwarf)
+	dr := op.NewDwarfRegisters(staticBase, dregs, binary.LittleEndian, armDwarfIPRegNum, armDwarfSPRegNum, armDwarfBPRegNum, armDwarfLRRegNum)
+	dr.SetLoadMoreCallback(loadMoreDwarfRegistersFromSliceFunc(dr, regs, armNameToDwarf))
+	return *dr
+}
+
+func armAddrAndStackRegsToDwarfRegisters(staticBase, pc, sp, bp, lr uint64) op.DwarfRegisters {
+	dregs := make([]*op.DwarfRegister, armDwarfIPRegNum+1)
+	dregs[armDwarfIPRegNum] = op.DwarfRegisterFromUint64(pc)
+	dregs[armDwarfSPRegNum] = op.DwarfRegisterFromUint64(sp)
+	dregs[armDwarfBPRegNum] = op.DwarfRegisterFromUint64(bp)
+	dregs[armDwarfLRRegNum] = op.DwarfRegisterFromUint64(lr)
+
+	return *op.NewDwarfRegisters(staticBase, dregs, binary.LittleEndian, armDwarfIPRegNum, armDwarfSPRegNum, armDwarfBPRegNum, armDwarfLRRegNum)
+}
+
+func armDwarfRegisterToString(i int, reg *op.DwarfRegister) (name string, floatingPoint bool, repr string) {
+	name, ok := armDwarfToName[i]
+	if !ok {
+		name = fmt.Sprintf("unknown%d", i)
+	}
+
+	if reg.Bytes != nil && name[0] == 'D' {
+		var out bytes.Buffer
+		fmt.Fprintf(&out, "%#016x", reg.Uint64Val)
+		if len(reg.Bytes) >= 8 {
+			fmt.Fprintf(&out, "\tfloat={ %g }", math.Float64frombits(binary.LittleEndian.Uint64(reg.Bytes)))
+		}
+		return name, true, out.String()
+	} else if reg.Bytes == nil || (reg.Bytes != nil && len(reg.Bytes) <= 8) {
+		return name, false, fmt.Sprintf("%#08x", reg.Uint64Val)
+	}
+	return name, false, fmt.Sprintf("%#x", reg.Bytes)
+}
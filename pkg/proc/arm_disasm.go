@@ -0,0 +1,175 @@
+package proc
+
+import (
+	"encoding/binary"
+
+	"golang.org/x/arch/arm/armasm"
+)
+
+func armAsmDecode(asmInst *AsmInstruction, mem []byte, regs Registers, memrw MemoryReadWriter, bi *BinaryInfo) error {
+	asmInst.Size = 4
+	asmInst.Bytes = mem[:asmInst.Size]
+
+	inst, err := armDecode(mem)
+	if err != nil {
+		asmInst.Inst = (*armArchInst)(nil)
+		return err
+	}
+
+	asmInst.Inst = (*armArchInst)(&inst)
+	asmInst.Kind = OtherInstruction
+
+	switch inst.Op {
+	case armasm.BL, armasm.BLX:
+		asmInst.Kind = CallInstruction
+	case armasm.BX:
+		if inst.Args[0] == armasm.LR {
+			asmInst.Kind = RetInstruction
+		} else {
+			asmInst.Kind = JmpInstruction
+		}
+	case armasm.LDR, armasm.POP:
+		// Go functions with a frame return by popping the saved LR into PC
+		if armWritesPC(&inst) {
+			asmInst.Kind = RetInstruction
+		}
+	case armasm.MOV, armasm.ADD:
+		// frameless functions return with B (R14), assembled as a move to PC
+		if armWritesPC(&inst) && inst.Args[1] == armasm.LR {
+			asmInst.Kind = RetInstruction
+		}
+	case armasm.B:
+		asmInst.Kind = JmpInstruction
+	}
+
+	asmInst.DestLoc = resolveCallArgARM(&inst, asmInst.Loc.PC, asmInst.AtPC, regs, memrw, bi)
+
+	return nil
+}
+
+// armDecode decodes the ARM instruction at the start of mem.
+// The armasm decoder does not recognize STR instructions with a register
+// offset when the offset register is R8 or higher, the instruction is
+// decoded with the offset register replaced by a lower one which is then
+// put back.
+func armDecode(mem []byte) (armasm.Inst, error) {
+	inst, err := armasm.Decode(mem, armasm.ModeARM)
+	if err == nil || len(mem) < 4 {
+		return inst, err
+	}
+	const (
+		strRegMask  = 0x0e500010
+		strRegValue = 0x06000000
+	)
+	x := binary.LittleEndian.Uint32(mem)
+	if x&strRegMask != strRegValue || x&0x8 == 0 {
+		return inst, err
+	}
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], x&^0x8)
+	inst2, err2 := armasm.Decode(buf[:], armasm.ModeARM)
+	if err2 != nil {
+		return inst, err
+	}
+	arg, ok := inst2.Args[1].(armasm.Mem)
+	if !ok {
+		return inst, err
+	}
+	arg.Index = armasm.Reg(x & 0xf)
+	inst2.Args[1] = arg
+	inst2.Enc = x
+	return inst2, nil
+}
+
+// armWritesPC returns true if inst loads a value into PC.
+func armWritesPC(inst *armasm.Inst) bool {
+	switch arg := inst.Args[0].(type) {
+	case armasm.Reg:
+		return arg == armasm.PC
+	case armasm.RegList:
+		return arg&(1<<uint(armasm.PC)) != 0
+	}
+	return false
+}
+
+func resolveCallArgARM(inst *armasm.Inst, instAddr uint64, currentGoroutine bool, regs Registers, mem MemoryReadWriter, bininfo *BinaryInfo) *Location {
+	switch inst.Op {
+	case armasm.BL, armasm.BLX, armasm.B, armasm.BX:
+		//ok
+	default:
+		return nil
+	}
+
+	var pc uint64
+	var err error
+
+	switch arg := inst.Args[0].(type) {
+	case armasm.Reg:
+		if !currentGoroutine || regs == nil {
+			return nil
+		}
+		pc, err = regs.Get(int(arg))
+		if err != nil {
+			return nil
+		}
+	case armasm.PCRel:
+		// reading PC on ARM returns the address of the current instruction plus 8
+		pc = uint64(uint32(instAddr) + 8 + uint32(arg))
+	default:
+		return nil
+	}
+
+	file, line, fn := bininfo.PCToLine(pc)
+	if fn == nil {
+		return &Location{PC: pc}
+	}
+	return &Location{PC: pc, File: file, Line: line, Fn: fn}
+}
+
+// Possible stacksplit prologues are inserted by stacksplit in
+// $GOROOT/src/cmd/internal/obj/arm/obj5.go.
+var prologuesARM []opcodeSeq
+
+func init() {
+	var tinyStacksplit = opcodeSeq{uint64(armasm.CMP), uint64(armasm.B_LS)}
+	var smallStacksplit = opcodeSeq{uint64(armasm.SUB), uint64(armasm.CMP), uint64(armasm.B_LS)}
+	var bigStacksplit = opcodeSeq{uint64(armasm.MOVW), uint64(armasm.SUB_S), uint64(armasm.CMP_CS), uint64(armasm.B_LS)}
+	var oldBigStacksplit = opcodeSeq{uint64(armasm.CMP), uint64(armasm.ADD_NE), uint64(armasm.SUB_NE), uint64(armasm.MOV_NE), uint64(armasm.CMP_NE), uint64(armasm.B_LS)}
+	var unixGetG = opcodeSeq{uint64(armasm.LDR)}
+
+	prologuesARM = make([]opcodeSeq, 0, 4)
+	for _, getG := range []opcodeSeq{unixGetG} {
+		for _, stacksplit := range []opcodeSeq{tinyStacksplit, smallStacksplit, bigStacksplit, oldBigStacksplit} {
+			prologue := make(opcodeSeq, 0, len(getG)+len(stacksplit))
+			prologue = append(prologue, getG...)
+			prologue = append(prologue, stacksplit...)
+			prologuesARM = append(prologuesARM, prologue)
+		}
+	}
+}
+
+type armArchInst armasm.Inst
+
+func (inst *armArchInst) Text(flavour AssemblyFlavour, pc uint64, symLookup func(uint64) (string, uint64)) string {
+	if inst == nil {
+		return "?"
+	}
+
+	var text string
+
+	switch flavour {
+	case GNUFlavour:
+		text = armasm.GNUSyntax(armasm.Inst(*inst))
+	default:
+		text = armasm.GoSyntax(armasm.Inst(*inst), pc, symLookup, nil)
+	}
+
+	return text
+}
+
+func (inst *armArchInst) OpcodeEquals(op uint64) bool {
+	if inst == nil {
+		return false
+	}
+	return uint64(inst.Op) == op
+}
@@ -120,6 +120,7 @@ var (
 		elf.EM_X86_64:  true,
 		elf.EM_AARCH64: true,
 		elf.EM_386:     true,
+		elf.EM_ARM:     true,
 	}
 
 	supportedWindowsArch = map[PEMachine]bool{
@@ -385,6 +386,12 @@ func (fn *Function) privateRuntime() bool {
 	return len(name) > n && name[:n] == "runtime." && !('A' <= name[n] && name[n] <= 'Z')
 }
 
+// userFunction reports whether the function is not part of the runtime,
+// exported runtime functions are considered user functions.
+func (fn *Function) userFunction() bool {
+	return strings.Contains(fn.Name, ".") && (!strings.HasPrefix(fn.Name, "runtime.") || fn.exportedRuntime())
+}
+
 type constantsMap map[dwarfRef]*constantType
 
 type constantType struct {
@@ -433,6 +440,8 @@ func NewBinaryInfo(goos, goarch string) *BinaryInfo {
 		r.Arch = AMD64Arch(goos)
 	case "arm64":
 		r.Arch = ARM64Arch(goos)
+	case "arm":
+		r.Arch = ARMArch(goos)
 	}
 	return r
 }
@@ -489,6 +498,16 @@ func (bi *BinaryInfo) Types() ([]string, error) {
 	return types, nil
 }
 
+// PackageVarNames returns the list of names of the package variables
+// present in the debugged program.
+func (bi *BinaryInfo) PackageVarNames() []string {
+	names := make([]string, 0, len(bi.packageVars))
+	for _, v := range bi.packageVars {
+		names = append(names, v.name)
+	}
+	return names
+}
+
 // PCToLine converts an instruction address to a file/line/function.
 func (bi *BinaryInfo) PCToLine(pc uint64) (string, int, *Function) {
 	fn := bi.PCToFunc(pc)
@@ -652,6 +671,7 @@ type Image struct {
 	typeCache map[dwarf.Offset]godwarf.Type
 
 	compileUnits []*compileUnit // compileUnits is sorted by increasing DWARF offset
+	sources      []string       // sorted list of the source files of compileUnits
 
 	dwarfTreeCache *simplelru.LRU
 
@@ -777,6 +797,11 @@ func (image *Image) LoadError() error {
 	return image.loadErr
 }
 
+// Sources returns the sorted list of source files of the image.
+func (image *Image) Sources() []string {
+	return image.sources
+}
+
 func (image *Image) getDwarfTree(off dwarf.Offset) (*godwarf.Tree, error) {
 	if r, ok := image.dwarfTreeCache.Get(off); ok {
 		return r.(*godwarf.Tree), nil
@@ -1112,6 +1137,10 @@ func loadBinaryInfoElf(bi *BinaryInfo, image *Image, path string, addr uint64, w
 		image.StaticBase = addr
 	}
 
+	// .eh_frame is usually available even for shared libraries without debug
+	// symbols, it lets us unwind through C frames.
+	ehFrameEntries := bi.parseEhFrameElf(image, elfFile)
+
 	dwarfFile := elfFile
 
 	image.dwarf, err = elfFile.DWARF()
@@ -1120,11 +1149,13 @@ func loadBinaryInfoElf(bi *BinaryInfo, image *Image, path string, addr uint64, w
 		var serr error
 		sepFile, dwarfFile, serr = bi.openSeparateDebugInfo(image, elfFile, bi.debugInfoDirectories)
 		if serr != nil {
+			bi.frameEntries = bi.frameEntries.Append(ehFrameEntries)
 			return serr
 		}
 		image.sepDebugCloser = sepFile
 		image.dwarf, err = dwarfFile.DWARF()
 		if err != nil {
+			bi.frameEntries = bi.frameEntries.Append(ehFrameEntries)
 			return err
 		}
 	}
@@ -1139,7 +1170,7 @@ func loadBinaryInfoElf(bi *BinaryInfo, image *Image, path string, addr uint64, w
 	image.loclist = loclist.New(debugLocBytes, bi.Arch.PtrSize())
 
 	wg.Add(3)
-	go bi.parseDebugFrameElf(image, dwarfFile, wg)
+	go bi.parseDebugFrameElf(image, dwarfFile, ehFrameEntries, wg)
 	go bi.loadDebugInfoMaps(image, debugLineBytes, wg, nil)
 	go bi.loadSymbolName(image, elfFile, wg)
 	if image.index == 0 {
@@ -1169,12 +1200,17 @@ func (bi *BinaryInfo) loadSymbolName(image *Image, file *elf.File, wg *sync.Wait
 	}
 }
 
-func (bi *BinaryInfo) parseDebugFrameElf(image *Image, exe *elf.File, wg *sync.WaitGroup) {
+// parseDebugFrameElf loads the .debug_frame section of exe, the entries of
+// ehFrameEntries are used for the functions that it doesn't describe.
+func (bi *BinaryInfo) parseDebugFrameElf(image *Image, exe *elf.File, ehFrameEntries frame.FrameDescriptionEntries, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	debugFrameData, err := godwarf.GetDebugSectionElf(exe, "frame")
 	if err != nil {
-		image.setLoadError("could not get .debug_frame section: %v", err)
+		if len(ehFrameEntries) == 0 {
+			image.setLoadError("could not get .debug_frame section: %v", err)
+		}
+		bi.frameEntries = bi.frameEntries.Append(ehFrameEntries)
 		return
 	}
 	debugInfoData, err := godwarf.GetDebugSectionElf(exe, "info")
@@ -1183,7 +1219,36 @@ func (bi *BinaryInfo) parseDebugFrameElf(image *Image, exe *elf.File, wg *sync.W
 		return
 	}
 
-	bi.frameEntries = bi.frameEntries.Append(frame.Parse(debugFrameData, frame.DwarfEndian(debugInfoData), image.StaticBase, bi.Arch.PtrSize()))
+	fdes := frame.FrameDescriptionEntries(nil).Append(frame.Parse(debugFrameData, frame.DwarfEndian(debugInfoData), image.StaticBase, bi.Arch.PtrSize()))
+	// fdes must stay sorted while it is searched, entries of .eh_frame that
+	// are not covered by .debug_frame are appended afterwards.
+	var ehOnly frame.FrameDescriptionEntries
+	for _, fde := range ehFrameEntries {
+		if _, err := fdes.FDEForPC(fde.Begin()); err != nil {
+			ehOnly = append(ehOnly, fde)
+		}
+	}
+
+	bi.frameEntries = bi.frameEntries.Append(fdes.Append(ehOnly))
+}
+
+// parseEhFrameElf loads the .eh_frame section of exe, errors are ignored
+// since .eh_frame is only used when .debug_frame is missing.
+func (bi *BinaryInfo) parseEhFrameElf(image *Image, exe *elf.File) frame.FrameDescriptionEntries {
+	sec := exe.Section(".eh_frame")
+	if sec == nil || sec.Type == elf.SHT_NOBITS {
+		return nil
+	}
+	data, err := sec.Data()
+	if err != nil {
+		return nil
+	}
+	fdes, err := frame.ParseEH(data, exe.ByteOrder, image.StaticBase, bi.Arch.PtrSize(), sec.Addr)
+	if err != nil {
+		bi.logger.Debugf("could not parse .eh_frame of %s: %v", image.Path, err)
+		return nil
+	}
+	return fdes
 }
 
 func (bi *BinaryInfo) setGStructOffsetElf(image *Image, exe *elf.File, wg *sync.WaitGroup) {
@@ -1599,14 +1664,18 @@ func (bi *BinaryInfo) loadDebugInfoMaps(image *Image, debugLineBytes []byte, wg
 		bi.LookupFunc[bi.Functions[i].Name] = &bi.Functions[i]
 	}
 
-	bi.Sources = []string{}
+	image.sources = []string{}
 	for _, cu := range image.compileUnits {
 		if cu.lineInfo != nil {
 			for _, fileEntry := range cu.lineInfo.FileNames {
-				bi.Sources = append(bi.Sources, fileEntry.Path)
+				image.sources = append(image.sources, fileEntry.Path)
 			}
 		}
 	}
+	sort.Strings(image.sources)
+	image.sources = uniq(image.sources)
+
+	bi.Sources = append(bi.Sources, image.sources...)
 	sort.Strings(bi.Sources)
 	bi.Sources = uniq(bi.Sources)
 
@@ -1615,6 +1684,20 @@ func (bi *BinaryInfo) loadDebugInfoMaps(image *Image, debugLineBytes []byte, wg
 	}
 }
 
+// cTypeTag returns the C keyword used to refer to types with the given
+// tag, or the empty string for types that are referred to by name only.
+func cTypeTag(tag dwarf.Tag) string {
+	switch tag {
+	case dwarf.TagStructType:
+		return "struct"
+	case dwarf.TagUnionType:
+		return "union"
+	case dwarf.TagEnumerationType:
+		return "enum"
+	}
+	return ""
+}
+
 // loadDebugInfoMapsCompileUnit loads entry from a single compile unit.
 func (bi *BinaryInfo) loadDebugInfoMapsCompileUnit(ctxt *loadDebugInfoMapsContext, image *Image, reader *reader.Reader, cu *compileUnit) {
 	hasAttrGoPkgName := goversion.ProducerAfterOrEqual(cu.producer, 1, 13)
@@ -1634,6 +1717,13 @@ func (bi *BinaryInfo) loadDebugInfoMapsCompileUnit(ctxt *loadDebugInfoMapsContex
 		case dwarf.TagArrayType, dwarf.TagBaseType, dwarf.TagClassType, dwarf.TagStructType, dwarf.TagUnionType, dwarf.TagConstType, dwarf.TagVolatileType, dwarf.TagRestrictType, dwarf.TagEnumerationType, dwarf.TagPointerType, dwarf.TagSubroutineType, dwarf.TagTypedef, dwarf.TagUnspecifiedType:
 			if name, ok := entry.Val(dwarf.AttrName).(string); ok {
 				if !cu.isgo {
+					if tag := cTypeTag(entry.Tag); tag != "" {
+						// also register C.struct_name, the name used by cgo, so
+						// that it doesn't collide with a typedef of the same name.
+						if _, exists := bi.types["C."+tag+"_"+name]; !exists {
+							bi.types["C."+tag+"_"+name] = dwarfRef{image.index, entry.Offset}
+						}
+					}
 					name = "C." + name
 				}
 				if _, exists := bi.types[name]; !exists {
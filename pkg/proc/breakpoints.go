@@ -6,6 +6,9 @@ import (
 	"go/ast"
 	"go/constant"
 	"reflect"
+	"regexp"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
 )
 
 const (
@@ -64,6 +67,18 @@ type Breakpoint struct {
 	DeferReturns []uint64
 	// Cond: if not nil the breakpoint will be triggered only if evaluating Cond returns true
 	Cond ast.Expr
+	// Panic: if not nil the breakpoint is set on runtime.gopanic and will be
+	// triggered only by the panics matching the filter.
+	Panic *PanicFilter
+	// GoroutineFilter: if not nil the breakpoint will be triggered only by
+	// the goroutines matching the filter.
+	GoroutineFilter *GoroutineFilter
+	// Shared contains the other logical breakpoints set on the same address
+	// as this one. Only panic breakpoints, which are all set on
+	// runtime.gopanic, share their physical breakpoint. The breakpoints in
+	// Shared are never written to the target, they only hold the ID, name,
+	// conditions and hit counts of their logical breakpoint.
+	Shared []*Breakpoint
 	// internalCond is the same as Cond but used for the condition of internal breakpoints
 	internalCond ast.Expr
 
@@ -91,8 +106,17 @@ const (
 	// Continue will set a new breakpoint (of NextBreakpoint kind) on the
 	// destination of CALL, delete this breakpoint and then continue again
 	StepBreakpoint
+	// SharedLibraryBreakpoint is a breakpoint set on the function that the
+	// dynamic linker calls when it loads or unloads a shared library,
+	// Continue never stops on it but uses it to detect new images.
+	// Breakpoints of this kind are not removed by ClearInternalBreakpoints.
+	SharedLibraryBreakpoint
 )
 
+// internalBreakpointKinds are the kinds of breakpoints set by next, step
+// and stepout.
+const internalBreakpointKinds = NextBreakpoint | NextDeferBreakpoint | StepBreakpoint
+
 func (bp *Breakpoint) String() string {
 	return fmt.Sprintf("Breakpoint %d at %#v %s:%d (%d)", bp.LogicalID, bp.Addr, bp.File, bp.Line, bp.TotalHitCount)
 }
@@ -129,7 +153,10 @@ type returnBreakpointInfo struct {
 // CheckCondition evaluates bp's condition on thread.
 func (bp *Breakpoint) CheckCondition(thread Thread) BreakpointState {
 	bpstate := BreakpointState{Breakpoint: bp, Active: false, Internal: false, CondError: nil}
-	if bp.Cond == nil && bp.internalCond == nil {
+	if bp.Kind == SharedLibraryBreakpoint {
+		return bpstate
+	}
+	if bp.Cond == nil && bp.internalCond == nil && bp.Panic == nil && bp.GoroutineFilter == nil {
 		bpstate.Active = true
 		bpstate.Internal = bp.IsInternal()
 		return bpstate
@@ -156,11 +183,42 @@ func (bp *Breakpoint) CheckCondition(thread Thread) BreakpointState {
 	}
 	if bp.IsUser() {
 		// Check normal condition if this is also a user breakpoint
-		bpstate.Active, bpstate.CondError = evalBreakpointCondition(thread, bp.Cond)
+		bpstate.Active, bpstate.CondError = bp.checkUserCondition(thread)
+		// The state of a shared breakpoint is the state of the first logical
+		// breakpoint that is either triggered or has a condition error.
+		for _, shared := range bp.Shared {
+			if bpstate.Active || bpstate.CondError != nil {
+				break
+			}
+			bpstate.Breakpoint = shared
+			bpstate.Active, bpstate.CondError = shared.checkUserCondition(thread)
+		}
+		if !bpstate.Active && bpstate.CondError == nil {
+			bpstate.Breakpoint = bp
+		}
 	}
 	return bpstate
 }
 
+// checkUserCondition evaluates the goroutine filter, the condition and the
+// panic filter of a user breakpoint.
+func (bp *Breakpoint) checkUserCondition(thread Thread) (bool, error) {
+	if bp.GoroutineFilter != nil {
+		active, err := bp.GoroutineFilter.matches(thread)
+		if !active || err != nil {
+			return active, err
+		}
+	}
+	active, err := evalBreakpointCondition(thread, bp.Cond)
+	if !active || err != nil {
+		return active, err
+	}
+	if bp.Panic != nil {
+		return bp.Panic.matches(thread)
+	}
+	return true, nil
+}
+
 func isPanicCall(frames []Stackframe) bool {
 	return len(frames) >= 3 && frames[2].Current.Fn != nil && frames[2].Current.Fn.Name == "runtime.gopanic"
 }
@@ -180,7 +238,7 @@ func isDeferReturnCall(frames []Stackframe, deferReturns []uint64) (bool, uint64
 // User-set breakpoints can overlap with internal breakpoints, in that case
 // both IsUser and IsInternal will be true.
 func (bp *Breakpoint) IsInternal() bool {
-	return bp.Kind != UserBreakpoint
+	return bp.Kind&internalBreakpointKinds != 0
 }
 
 // IsUser returns true if bp is a user-set breakpoint.
@@ -212,6 +270,135 @@ func evalBreakpointCondition(thread Thread, cond ast.Expr) (bool, error) {
 	return constant.BoolVal(v.Value), nil
 }
 
+// GoroutineFilter selects the goroutines that trigger a breakpoint.
+type GoroutineFilter struct {
+	// ID, if not zero, is the ID of the goroutine.
+	ID int
+	// Labels, if not empty, are pprof labels that the goroutine must have.
+	Labels map[string]string
+}
+
+// matches returns true if the goroutine running on thread matches gf.
+// Unlike a condition on runtime.curg.goid this doesn't need to evaluate an
+// expression, only the G struct of the thread (and its labels, if
+// required) is read.
+func (gf *GoroutineFilter) matches(thread Thread) (bool, error) {
+	g, err := GetG(thread)
+	if err != nil {
+		return true, err
+	}
+	if g == nil {
+		return false, nil
+	}
+	if gf.ID != 0 && g.ID != gf.ID {
+		return false, nil
+	}
+	if len(gf.Labels) > 0 {
+		labels := g.Labels()
+		for k, v := range gf.Labels {
+			if gv, ok := labels[k]; !ok || gv != v {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// PanicFilter selects the panics that trigger a breakpoint set on
+// runtime.gopanic.
+type PanicFilter struct {
+	// Types is a list of type names, if it is not empty the panic value must
+	// have one of the listed types or implement one of the listed interfaces.
+	Types []string
+	// Message, if not nil, must match one of the strings contained in the
+	// panic value: the value itself if it is a string, the string fields of
+	// the value (and of the values it points to) otherwise.
+	Message *regexp.Regexp
+}
+
+// panicFilterLoadConfig is the configuration used to load the panic value.
+var panicFilterLoadConfig = LoadConfig{FollowPointers: true, MaxVariableRecurse: 2, MaxStringLen: 1024, MaxArrayValues: 0, MaxStructFields: -1}
+
+// matches returns true if the argument of runtime.gopanic, for the
+// goroutine stopped on thread, matches pf.
+func (pf *PanicFilter) matches(thread Thread) (bool, error) {
+	scope, err := GoroutineScope(thread)
+	if err != nil {
+		return true, err
+	}
+	v, err := scope.EvalExpression("e", panicFilterLoadConfig)
+	if err != nil {
+		return true, fmt.Errorf("error reading panic value: %v", err)
+	}
+	if v.Unreadable != nil {
+		return true, fmt.Errorf("panic value unreadable: %v", v.Unreadable)
+	}
+	if v.Kind != reflect.Interface || len(v.Children) == 0 || v.Children[0].Addr == 0 {
+		// panic(nil)
+		return len(pf.Types) == 0 && pf.Message == nil, nil
+	}
+	if len(pf.Types) > 0 {
+		ok, err := pf.matchesType(scope, v)
+		if !ok || err != nil {
+			return ok, err
+		}
+	}
+	if pf.Message != nil {
+		return matchesStrings(&v.Children[0], pf.Message, 0), nil
+	}
+	return true, nil
+}
+
+func (pf *PanicFilter) matchesType(scope *EvalScope, v *Variable) (bool, error) {
+	typename := v.Children[0].DwarfType.String()
+	for _, name := range pf.Types {
+		if name == typename {
+			return true, nil
+		}
+	}
+
+	_type, _, isnil := v.readInterface()
+	if isnil || _type == nil {
+		return false, nil
+	}
+	_type = _type.maybeDereference()
+	for _, name := range pf.Types {
+		typ, err := scope.BinInfo.findType(name)
+		if err != nil {
+			continue
+		}
+		ityp, isiface := resolveTypedef(typ).(*godwarf.InterfaceType)
+		if !isiface {
+			continue
+		}
+		ok, err := runtimeTypeImplements(scope.BinInfo, scope.Mem, _type, ityp)
+		if ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// matchesStrings returns true if re matches v, if v is a string, or any of
+// the strings reachable from v.
+func matchesStrings(v *Variable, re *regexp.Regexp, depth int) bool {
+	const maxDepth = 3
+	if v.Unreadable != nil || depth > maxDepth {
+		return false
+	}
+	switch v.Kind {
+	case reflect.String:
+		return re.MatchString(constant.StringVal(v.Value))
+	case reflect.Struct, reflect.Ptr, reflect.Interface:
+		for i := range v.Children {
+			if matchesStrings(&v.Children[i], re, depth+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // NoBreakpointError is returned when trying to
 // clear a breakpoint that does not exist.
 type NoBreakpointError struct {
@@ -256,14 +443,15 @@ func (bpmap *BreakpointMap) Set(addr uint64, kind BreakpointKind, cond ast.Expr,
 		// We can overlap one internal breakpoint with one user breakpoint, we
 		// need to support this otherwise a conditional breakpoint can mask a
 		// breakpoint set by next or step.
-		if (kind != UserBreakpoint && bp.Kind != UserBreakpoint) || (kind == UserBreakpoint && bp.IsUser()) {
+		if bp.Kind&kind != 0 || (kind&internalBreakpointKinds != 0 && bp.IsInternal()) {
 			return bp, BreakpointExistsError{bp.File, bp.Line, bp.Addr}
 		}
 		bp.Kind |= kind
-		if kind != UserBreakpoint {
-			bp.internalCond = cond
-		} else {
+		switch {
+		case kind == UserBreakpoint:
 			bp.Cond = cond
+		case kind&internalBreakpointKinds != 0:
+			bp.internalCond = cond
 		}
 		return bp, nil
 	}
@@ -313,6 +501,114 @@ func (bpmap *BreakpointMap) SetWithID(id int, addr uint64, writeBreakpoint Write
 	return bp, err
 }
 
+// NewLogicalID returns a new ID for a logical breakpoint that doesn't have
+// any physical breakpoint yet, for example because its location is in a
+// shared library that hasn't been loaded.
+func (bpmap *BreakpointMap) NewLogicalID() int {
+	bpmap.breakpointIDCounter++
+	return bpmap.breakpointIDCounter
+}
+
+// ReserveLogicalID makes sure that id is never returned by NewLogicalID
+// or assigned to a new breakpoint.
+func (bpmap *BreakpointMap) ReserveLogicalID(id int) {
+	if id > bpmap.breakpointIDCounter {
+		bpmap.breakpointIDCounter = id
+	}
+}
+
+// ChangeLogicalID assigns the physical breakpoints of the logical
+// breakpoint oldID to the logical breakpoint newID, which must have been
+// returned by NewLogicalID or reserved with ReserveLogicalID.
+func (bpmap *BreakpointMap) ChangeLogicalID(oldID, newID int) {
+	if oldID == newID {
+		return
+	}
+	for _, bp := range bpmap.M {
+		if !bp.IsUser() {
+			continue
+		}
+		if bp.LogicalID == oldID {
+			bp.LogicalID = newID
+		}
+		for _, shared := range bp.Shared {
+			if shared.LogicalID == oldID {
+				shared.LogicalID = newID
+			}
+		}
+	}
+	if oldID == bpmap.breakpointIDCounter {
+		bpmap.breakpointIDCounter--
+	}
+}
+
+// SetShared creates a new logical breakpoint sharing the user breakpoint
+// at addr, see Breakpoint.Shared.
+func (bpmap *BreakpointMap) SetShared(addr uint64) (*Breakpoint, error) {
+	bp, ok := bpmap.M[addr]
+	if !ok || !bp.IsUser() {
+		return nil, NoBreakpointError{Addr: addr}
+	}
+	bpmap.breakpointIDCounter++
+	shared := &Breakpoint{
+		FunctionName: bp.FunctionName,
+		File:         bp.File,
+		Line:         bp.Line,
+		Addr:         addr,
+		Kind:         UserBreakpoint,
+		LogicalID:    bpmap.breakpointIDCounter,
+		HitCount:     map[int]uint64{},
+	}
+	bp.Shared = append(bp.Shared, shared)
+	return shared, nil
+}
+
+// ClearShared removes the logical breakpoint id from the user breakpoint
+// at addr, if the breakpoint is shared with other logical breakpoints. If
+// id is the logical breakpoint stored in the physical breakpoint itself
+// the first shared logical breakpoint takes its place.
+// Returns the removed logical breakpoint, or false if the breakpoint at
+// addr isn't shared and must be removed with Clear.
+func (bpmap *BreakpointMap) ClearShared(addr uint64, id int) (*Breakpoint, bool) {
+	bp, ok := bpmap.M[addr]
+	if !ok || !bp.IsUser() || len(bp.Shared) == 0 {
+		return nil, false
+	}
+	for i, shared := range bp.Shared {
+		if shared.LogicalID == id {
+			bp.Shared = append(bp.Shared[:i], bp.Shared[i+1:]...)
+			return shared, true
+		}
+	}
+	if bp.LogicalID != id {
+		return nil, false
+	}
+	removed := &Breakpoint{FunctionName: bp.FunctionName, File: bp.File, Line: bp.Line, Addr: addr, Kind: UserBreakpoint}
+	removed.copyUserInfo(bp)
+	bp.copyUserInfo(bp.Shared[0])
+	bp.Shared = bp.Shared[1:]
+	return removed, true
+}
+
+// copyUserInfo copies the fields describing the logical breakpoint of src
+// into bp.
+func (bp *Breakpoint) copyUserInfo(src *Breakpoint) {
+	bp.LogicalID = src.LogicalID
+	bp.Name = src.Name
+	bp.Tracepoint = src.Tracepoint
+	bp.TraceReturn = src.TraceReturn
+	bp.Goroutine = src.Goroutine
+	bp.Stacktrace = src.Stacktrace
+	bp.Variables = src.Variables
+	bp.LoadArgs = src.LoadArgs
+	bp.LoadLocals = src.LoadLocals
+	bp.HitCount = src.HitCount
+	bp.TotalHitCount = src.TotalHitCount
+	bp.Cond = src.Cond
+	bp.Panic = src.Panic
+	bp.GoroutineFilter = src.GoroutineFilter
+}
+
 // Clear clears the breakpoint at addr.
 // Do not call this function call proc.Process.ClearBreakpoint instead.
 func (bpmap *BreakpointMap) Clear(addr uint64, clearBreakpoint clearBreakpointFn) (*Breakpoint, error) {
@@ -323,6 +619,7 @@ func (bpmap *BreakpointMap) Clear(addr uint64, clearBreakpoint clearBreakpointFn
 
 	bp.Kind &= ^UserBreakpoint
 	bp.Cond = nil
+	bp.Shared = nil
 	if bp.Kind != 0 {
 		return bp, nil
 	}
@@ -342,7 +639,7 @@ func (bpmap *BreakpointMap) Clear(addr uint64, clearBreakpoint clearBreakpointFn
 // instead, this function is used to implement that.
 func (bpmap *BreakpointMap) ClearInternalBreakpoints(clearBreakpoint clearBreakpointFn) error {
 	for addr, bp := range bpmap.M {
-		bp.Kind = bp.Kind & UserBreakpoint
+		bp.Kind = bp.Kind & (UserBreakpoint | SharedLibraryBreakpoint)
 		bp.internalCond = nil
 		bp.returnInfo = nil
 		if bp.Kind != 0 {
@@ -0,0 +1,352 @@
+package proc
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// parseExpr parses expr, if it isn't a valid Go expression it is parsed
+// again after converting the C syntax it contains (the '->' operator and C
+// style casts) to Go syntax, so that expressions copied from C code can be
+// evaluated. Returns the source that was parsed.
+func parseExpr(expr string) (string, ast.Expr, error) {
+	t, err := parser.ParseExpr(expr)
+	if err == nil {
+		return expr, t, nil
+	}
+	gexpr, ok := cExprToGo(expr)
+	if !ok {
+		return expr, t, err
+	}
+	t2, err2 := parser.ParseExpr(gexpr)
+	if err2 != nil {
+		if _, isAs := isAssignment(err2); !isAs {
+			return expr, t, err
+		}
+	}
+	return gexpr, t2, err2
+}
+
+type cToken struct {
+	pos, end int
+	tok      token.Token
+	lit      string
+}
+
+// cExprToGo converts the C syntax in expr to Go syntax:
+//
+//	p->field			becomes p.field
+//	(struct foo *)ptr	becomes (*C.struct_foo)(ptr)
+//	(unsigned int)x		becomes ("C.unsigned int")(x)
+//
+// Returns false if expr doesn't contain any C syntax.
+func cExprToGo(expr string) (string, bool) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(expr))
+	var s scanner.Scanner
+	s.Init(file, []byte(expr), nil, 0)
+
+	var toks []cToken
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			// automatically inserted
+			continue
+		}
+		off := file.Offset(pos)
+		text := lit
+		if text == "" {
+			text = tok.String()
+		}
+		toks = append(toks, cToken{off, off + len(text), tok, lit})
+	}
+
+	c := &cExprConverter{src: expr, toks: toks}
+	r := c.convert(0, len(toks))
+	return r, c.changed
+}
+
+type cExprConverter struct {
+	src     string
+	toks    []cToken
+	changed bool
+}
+
+// convert returns the converted source of tokens from i to j (excluded).
+func (c *cExprConverter) convert(i, j int) string {
+	var buf strings.Builder
+	for i < j {
+		if c.isArrow(i) {
+			buf.WriteString(".")
+			c.changed = true
+			i += 2
+			buf.WriteString(c.space(i-1, j))
+			continue
+		}
+		if typ, k, ok := c.cast(i, j); ok {
+			if end := c.unary(k, j); end > k {
+				c.changed = true
+				buf.WriteString("(" + typ + ")(")
+				buf.WriteString(strings.TrimSpace(c.convert(k, end)))
+				buf.WriteString(")")
+				buf.WriteString(c.space(end-1, j))
+				i = end
+				continue
+			}
+		}
+		buf.WriteString(c.src[c.toks[i].pos:c.toks[i].end])
+		buf.WriteString(c.space(i, j))
+		i++
+	}
+	return buf.String()
+}
+
+// space returns the white space following token i.
+func (c *cExprConverter) space(i, j int) string {
+	if i+1 >= j {
+		return ""
+	}
+	return c.src[c.toks[i].end:c.toks[i+1].pos]
+}
+
+func (c *cExprConverter) isArrow(i int) bool {
+	return i+1 < len(c.toks) && c.toks[i].tok == token.SUB && c.toks[i+1].tok == token.GTR && c.toks[i+1].pos == c.toks[i].end
+}
+
+// cast checks if a C style cast starts at token i, if it does it returns
+// the equivalent Go type and the index of the token following the cast.
+func (c *cExprConverter) cast(i, j int) (string, int, bool) {
+	if c.toks[i].tok != token.LPAREN {
+		return "", 0, false
+	}
+	var words []string
+	tag := ""
+	stars := 0
+	k := i + 1
+loop:
+	for ; k < j; k++ {
+		t := c.toks[k]
+		if stars > 0 && t.tok != token.MUL {
+			break
+		}
+		switch t.tok {
+		case token.STRUCT:
+			if tag != "" || len(words) > 0 {
+				return "", 0, false
+			}
+			tag = "struct"
+		case token.CONST:
+			// qualifiers are ignored
+		case token.IDENT:
+			switch {
+			case t.lit == "volatile" || t.lit == "restrict":
+				// qualifiers are ignored
+			case (t.lit == "union" || t.lit == "enum") && tag == "" && len(words) == 0:
+				tag = t.lit
+			default:
+				words = append(words, t.lit)
+			}
+		case token.MUL:
+			stars++
+		default:
+			break loop
+		}
+	}
+	if k >= j || c.toks[k].tok != token.RPAREN || len(words) == 0 || k+1 >= j {
+		return "", 0, false
+	}
+	if tag != "" && len(words) != 1 {
+		return "", 0, false
+	}
+	if tag == "" && stars == 0 && len(words) == 1 {
+		// '(T)(x)' and '(T) - x' are valid Go expressions, only accept
+		// operands that can't follow a parenthesized expression in Go.
+		switch c.toks[k+1].tok {
+		case token.IDENT, token.INT, token.FLOAT, token.CHAR, token.STRING:
+		default:
+			return "", 0, false
+		}
+	}
+
+	var typ string
+	switch {
+	case tag != "":
+		typ = "C." + tag + "_" + words[0]
+	case len(words) == 1 && words[0] == "void" && stars > 0:
+		typ = "unsafe.Pointer"
+		stars--
+	default:
+		name, ok := cBaseTypeName(words)
+		if !ok {
+			return "", 0, false
+		}
+		if strings.Contains(name, " ") {
+			typ = strconv.Quote("C." + name)
+		} else {
+			typ = "C." + name
+		}
+	}
+	return strings.Repeat("*", stars) + typ, k + 1, true
+}
+
+// unary returns the index of the token following the C unary expression
+// starting at token i, or i if there isn't one.
+func (c *cExprConverter) unary(i, j int) int {
+	if i >= j {
+		return i
+	}
+	switch c.toks[i].tok {
+	case token.SUB, token.ADD, token.NOT, token.XOR, token.MUL, token.AND:
+		if end := c.unary(i+1, j); end > i+1 {
+			return end
+		}
+		return i
+	case token.LPAREN:
+		if _, k, ok := c.cast(i, j); ok {
+			if end := c.unary(k, j); end > k {
+				return end
+			}
+			return i
+		}
+	}
+
+	// primary expression
+	k := i
+	switch c.toks[k].tok {
+	case token.IDENT, token.INT, token.FLOAT, token.IMAG, token.CHAR, token.STRING:
+		k++
+	case token.LPAREN:
+		k = c.closing(k, j)
+		if k < 0 {
+			return i
+		}
+	default:
+		return i
+	}
+
+	// selectors, index expressions and function calls
+	for k < j {
+		switch {
+		case c.toks[k].tok == token.PERIOD && k+1 < j && c.toks[k+1].tok == token.IDENT:
+			k += 2
+		case c.isArrow(k) && k+2 < j && c.toks[k+2].tok == token.IDENT:
+			k += 3
+		case c.toks[k].tok == token.LBRACK || c.toks[k].tok == token.LPAREN:
+			end := c.closing(k, j)
+			if end < 0 {
+				return k
+			}
+			k = end
+		default:
+			return k
+		}
+	}
+	return k
+}
+
+// closing returns the index of the token following the parenthesis or
+// bracket closing the one at token i, -1 if it isn't closed.
+func (c *cExprConverter) closing(i, j int) int {
+	depth := 0
+	for k := i; k < j; k++ {
+		switch c.toks[k].tok {
+		case token.LPAREN, token.LBRACK, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+			depth--
+			if depth == 0 {
+				return k + 1
+			}
+		}
+	}
+	return -1
+}
+
+// cBaseTypeName returns the name that C compilers use in DWARF for the
+// type specified by words, for example "long unsigned int" for
+// "unsigned long".
+func cBaseTypeName(words []string) (string, bool) {
+	if len(words) == 1 {
+		switch words[0] {
+		case "signed":
+			return "int", true
+		case "unsigned":
+			return "unsigned int", true
+		case "long":
+			return "long int", true
+		case "short":
+			return "short int", true
+		}
+		// typedef or builtin type
+		return words[0], true
+	}
+
+	var signed, unsigned bool
+	var long, short int
+	base := ""
+	for _, w := range words {
+		switch w {
+		case "signed":
+			signed = true
+		case "unsigned":
+			unsigned = true
+		case "long":
+			long++
+		case "short":
+			short++
+		case "int", "char", "double":
+			if base != "" {
+				return "", false
+			}
+			base = w
+		default:
+			return "", false
+		}
+	}
+	if signed && unsigned {
+		return "", false
+	}
+
+	switch {
+	case base == "char":
+		if long > 0 || short > 0 {
+			return "", false
+		}
+		switch {
+		case signed:
+			return "signed char", true
+		case unsigned:
+			return "unsigned char", true
+		}
+		return "char", true
+	case base == "double":
+		if long != 1 || short > 0 || signed || unsigned {
+			return "", false
+		}
+		return "long double", true
+	}
+
+	var name string
+	switch {
+	case short == 1 && long == 0:
+		name = "short "
+	case long == 1 && short == 0:
+		name = "long "
+	case long == 2 && short == 0:
+		name = "long long "
+	case long == 0 && short == 0:
+	default:
+		return "", false
+	}
+	if unsigned {
+		name += "unsigned "
+	}
+	return name + "int", true
+}
@@ -0,0 +1,513 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/go-delve/delve/pkg/proc/core/lz4"
+	"github.com/go-delve/delve/pkg/proc/core/zstd"
+)
+
+// coreReader is the contents of a core file.
+type coreReader interface {
+	io.ReaderAt
+	io.Closer
+}
+
+type compressionFormat uint8
+
+const (
+	notCompressed compressionFormat = iota
+	gzipCompressed
+	zstdCompressed
+	lz4Compressed
+)
+
+func (format compressionFormat) String() string {
+	switch format {
+	case gzipCompressed:
+		return "gzip"
+	case zstdCompressed:
+		return "zstd"
+	case lz4Compressed:
+		return "lz4"
+	default:
+		return "uncompressed"
+	}
+}
+
+var compressionMagics = []struct {
+	magic  []byte
+	format compressionFormat
+}{
+	{[]byte{0x1f, 0x8b}, gzipCompressed},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, zstdCompressed},
+	{[]byte{0x04, 0x22, 0x4d, 0x18}, lz4Compressed},
+	{[]byte{0x02, 0x21, 0x4c, 0x18}, lz4Compressed}, // legacy lz4 format
+}
+
+const (
+	zstdFrameMagic        = 0xfd2fb528
+	lz4FrameMagic         = 0x184d2204
+	lz4LegacyFrameMagic   = 0x184c2102
+	skippableMagicMask    = 0xfffffff0
+	skippableMagic        = 0x184d2a50 // shared by zstd and lz4
+	compressedCacheBufLen = 1 << 20
+)
+
+// openCoreFile opens the core file at path. Core files compressed with
+// gzip, zstd or lz4 are recognized by their magic number and decompressed
+// on demand into a temporary file created in cacheDir, or in the default
+// directory for temporary files if cacheDir is empty.
+// The returned warnings describe how the file could be compressed to be
+// read faster.
+func openCoreFile(path, cacheDir string) (coreReader, []string, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var magic [4]byte
+	n, _ := fh.ReadAt(magic[:], 0)
+	format := notCompressed
+	for _, cm := range compressionMagics {
+		if bytes.HasPrefix(magic[:n], cm.magic) {
+			format = cm.format
+			break
+		}
+	}
+	if format == notCompressed {
+		return fh, nil, nil
+	}
+	cf, err := newCompressedFile(fh, format, cacheDir)
+	if err != nil {
+		fh.Close()
+		return nil, nil, fmt.Errorf("could not read %s compressed core file: %v", format, err)
+	}
+	var warnings []string
+	if len(cf.chunks) == 1 {
+		// Only the data that is read is decompressed, but what comes before
+		// it in the chunk must be decompressed too.
+		warnings = append(warnings, fmt.Sprintf("%s can not be split into independently decompressible %s frames, reading data at the end of it requires decompressing all of it into a temporary file in %s (use --cache-dir to change it); compress it with bgzip or pzstd for faster access", path, format, filepath.Dir(cf.cache.Name())))
+	}
+	return cf, warnings, nil
+}
+
+// compressedFile is a ReaderAt returning the decompressed contents of a
+// compressed file.
+//
+// The compressed file is split into chunks that can be decompressed
+// independently from each other: zstd and lz4 frames or BGZF blocks.
+// Decompressed data is written to a temporary file, used as a cache, so
+// that every chunk is decompressed at most once and only if some of its
+// data is read.
+// Inside a chunk data must be decompressed sequentially, reading data
+// at the end of a big chunk means decompressing all of the chunk before
+// it. Files produced by the gzip, zstd and lz4 command line tools are made
+// of a single chunk, files compressed in multiple independent frames (for
+// example by pzstd or bgzip) are made of many.
+type compressedFile struct {
+	mu     sync.Mutex
+	fh     *os.File
+	size   int64 // size of the compressed file
+	format compressionFormat
+	chunks []compressedChunk
+	cache  *os.File
+	buf    []byte
+
+	// decoder of the last chunk that was read from, kept to continue
+	// sequential reads.
+	cur struct {
+		idx int
+		r   io.Reader
+	}
+}
+
+// compressedChunk is a part of a compressed file that can be decompressed
+// independently.
+type compressedChunk struct {
+	coff int64 // offset of the chunk in the compressed file
+	clen int64 // size of the chunk in the compressed file
+	off  int64 // offset of the decompressed chunk
+	size int64 // size of the decompressed chunk, -1 if unknown
+	done int64 // number of bytes of the decompressed chunk written to the cache
+}
+
+func newCompressedFile(fh *os.File, format compressionFormat, cacheDir string) (*compressedFile, error) {
+	fi, err := fh.Stat()
+	if err != nil {
+		return nil, err
+	}
+	cf := &compressedFile{fh: fh, size: fi.Size(), format: format}
+	cf.cur.idx = -1
+
+	switch format {
+	case gzipCompressed:
+		err = cf.indexGzip()
+	case zstdCompressed, lz4Compressed:
+		err = cf.indexFrames()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cf.cache, err = ioutil.TempFile(cacheDir, "dlv-core-")
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" {
+		// Remove the cache right away so that it doesn't outlive us.
+		os.Remove(cf.cache.Name())
+	}
+	return cf, nil
+}
+
+// indexGzip splits a gzip file into chunks. Only BGZF files, where the
+// compressed size of every member is stored in its header, can be split
+// without decompressing them, other gzip files become a single chunk.
+func (cf *compressedFile) indexGzip() error {
+	var off int64
+	for coff := int64(0); coff < cf.size; {
+		bsize, ok := bgzfBlockSize(cf.fh, coff)
+		if !ok || coff+bsize > cf.size {
+			cf.chunks = []compressedChunk{{coff: 0, clen: cf.size, size: -1}}
+			return nil
+		}
+		var isize [4]byte
+		if _, err := cf.fh.ReadAt(isize[:], coff+bsize-4); err != nil {
+			return err
+		}
+		size := int64(binary.LittleEndian.Uint32(isize[:]))
+		cf.chunks = append(cf.chunks, compressedChunk{coff: coff, clen: bsize, off: off, size: size})
+		off += size
+		coff += bsize
+	}
+	return nil
+}
+
+// bgzfBlockSize returns the size of the BGZF block at coff, as specified
+// in the "BC" extra subfield of its gzip header. See section 4.1 of
+// https://samtools.github.io/hts-specs/SAMv1.pdf
+func bgzfBlockSize(r io.ReaderAt, coff int64) (int64, bool) {
+	const fextra = 1 << 2
+	var hdr [18]byte
+	if _, err := r.ReadAt(hdr[:], coff); err != nil {
+		return 0, false
+	}
+	if hdr[0] != 0x1f || hdr[1] != 0x8b || hdr[3]&fextra == 0 {
+		return 0, false
+	}
+	xlen := binary.LittleEndian.Uint16(hdr[10:])
+	if xlen != 6 || hdr[12] != 'B' || hdr[13] != 'C' || binary.LittleEndian.Uint16(hdr[14:]) != 2 {
+		return 0, false
+	}
+	return int64(binary.LittleEndian.Uint16(hdr[16:])) + 1, true
+}
+
+// indexFrames splits a zstd or lz4 file into chunks, one for each frame,
+// by walking the headers of frames and blocks. The decompressed size of
+// frames whose header doesn't contain it is determined by decompressing
+// them, except for the last frame.
+func (cf *compressedFile) indexFrames() error {
+	var off int64
+	for coff := int64(0); coff < cf.size; {
+		magic, err := cf.readUint32(coff)
+		if err != nil {
+			return err
+		}
+		var clen, size int64
+		switch {
+		case magic&skippableMagicMask == skippableMagic:
+			n, err := cf.readUint32(coff + 4)
+			if err != nil {
+				return err
+			}
+			coff += 8 + int64(n)
+			continue
+		case cf.format == zstdCompressed && magic == zstdFrameMagic:
+			clen, size, err = cf.zstdFrameSize(coff)
+		case cf.format == lz4Compressed && magic == lz4FrameMagic:
+			clen, size, err = cf.lz4FrameSize(coff)
+		case cf.format == lz4Compressed && magic == lz4LegacyFrameMagic:
+			// Frames in the legacy lz4 format don't have an end mark and are
+			// read as a single chunk.
+			clen, size = cf.size-coff, -1
+		default:
+			return fmt.Errorf("unknown frame magic number %#x at offset %#x", magic, coff)
+		}
+		if err != nil {
+			return err
+		}
+		if coff+clen > cf.size {
+			return fmt.Errorf("truncated frame at offset %#x", coff)
+		}
+		cf.chunks = append(cf.chunks, compressedChunk{coff: coff, clen: clen, off: off, size: size})
+		coff += clen
+		if size < 0 {
+			if coff >= cf.size {
+				break
+			}
+			ch := &cf.chunks[len(cf.chunks)-1]
+			size, err = io.Copy(ioutil.Discard, cf.newDecoder(ch))
+			if err != nil {
+				return err
+			}
+			ch.size = size
+		}
+		off += size
+	}
+	if len(cf.chunks) == 0 {
+		return errors.New("no frames")
+	}
+	return nil
+}
+
+// zstdFrameSize returns the compressed and decompressed size of the zstd
+// frame at coff, the decompressed size is -1 if it isn't specified in
+// the frame header. See RFC 8878, section 3.1.1.
+func (cf *compressedFile) zstdFrameSize(coff int64) (clen, size int64, err error) {
+	var hdr [14]byte
+	if _, err := cf.fh.ReadAt(hdr[:], coff); err != nil && err != io.EOF {
+		return 0, 0, err
+	}
+	fhd := hdr[4]
+	singleSegment := fhd&(1<<5) != 0
+	hasChecksum := fhd&(1<<2) != 0
+	pos := 5
+	if !singleSegment {
+		pos++ // window descriptor
+	}
+	pos += []int{0, 1, 2, 4}[fhd&3] // dictionary ID
+	size = -1
+	switch fhd >> 6 {
+	case 0:
+		if singleSegment {
+			size = int64(hdr[pos])
+			pos++
+		}
+	case 1:
+		size = int64(binary.LittleEndian.Uint16(hdr[pos:])) + 256
+		pos += 2
+	case 2:
+		size = int64(binary.LittleEndian.Uint32(hdr[pos:]))
+		pos += 4
+	case 3:
+		size = int64(binary.LittleEndian.Uint64(hdr[pos:]))
+		pos += 8
+	}
+
+	clen = int64(pos)
+	for {
+		var bhdr [4]byte
+		if _, err := cf.fh.ReadAt(bhdr[:3], coff+clen); err != nil {
+			return 0, 0, fmt.Errorf("truncated frame at offset %#x", coff)
+		}
+		b := binary.LittleEndian.Uint32(bhdr[:])
+		last := b&1 != 0
+		bsize := int64(b >> 3)
+		switch (b >> 1) & 3 {
+		case 1:
+			// RLE block, the content is a single byte
+			bsize = 1
+		case 3:
+			return 0, 0, fmt.Errorf("reserved block type in frame at offset %#x", coff)
+		}
+		clen += 3 + bsize
+		if last {
+			break
+		}
+	}
+	if hasChecksum {
+		clen += 4
+	}
+	return clen, size, nil
+}
+
+// lz4FrameSize returns the compressed and decompressed size of the lz4
+// frame at coff, the decompressed size is -1 if it isn't specified in the
+// frame header.
+// See https://github.com/lz4/lz4/blob/dev/doc/lz4_Frame_format.md
+func (cf *compressedFile) lz4FrameSize(coff int64) (clen, size int64, err error) {
+	var hdr [14]byte
+	if _, err := cf.fh.ReadAt(hdr[:], coff); err != nil && err != io.EOF {
+		return 0, 0, err
+	}
+	flg := hdr[4]
+	blockChecksum := flg&(1<<4) != 0
+	contentChecksum := flg&(1<<2) != 0
+	clen = 4 + 2 + 1 // magic, FLG, BD and HC
+	size = -1
+	if flg&(1<<3) != 0 {
+		size = int64(binary.LittleEndian.Uint64(hdr[6:]))
+		clen += 8
+	}
+	if flg&1 != 0 {
+		clen += 4 // dictionary ID
+	}
+	for {
+		bsize, err := cf.readUint32(coff + clen)
+		if err != nil {
+			return 0, 0, fmt.Errorf("truncated frame at offset %#x", coff)
+		}
+		clen += 4
+		if bsize == 0 {
+			// EndMark
+			break
+		}
+		clen += int64(bsize & 0x7fffffff)
+		if blockChecksum {
+			clen += 4
+		}
+	}
+	if contentChecksum {
+		clen += 4
+	}
+	return clen, size, nil
+}
+
+func (cf *compressedFile) readUint32(off int64) (uint32, error) {
+	var buf [4]byte
+	if _, err := cf.fh.ReadAt(buf[:], off); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(buf[:]), nil
+}
+
+// newDecoder returns a reader decompressing chunk ch.
+func (cf *compressedFile) newDecoder(ch *compressedChunk) io.Reader {
+	r := bufio.NewReaderSize(io.NewSectionReader(cf.fh, ch.coff, ch.clen), compressedCacheBufLen)
+	switch cf.format {
+	case gzipCompressed:
+		return &lazyGzipReader{r: r}
+	case zstdCompressed:
+		return zstd.NewReader(r)
+	case lz4Compressed:
+		return lz4.NewReader(r)
+	}
+	panic("unknown compression format")
+}
+
+// lazyGzipReader is a gzip reader that reads the gzip header on the first
+// call to Read, so that newDecoder doesn't need to return an error.
+type lazyGzipReader struct {
+	r  io.Reader
+	zr *gzip.Reader
+}
+
+func (r *lazyGzipReader) Read(p []byte) (int, error) {
+	if r.zr == nil {
+		zr, err := gzip.NewReader(r.r)
+		if err != nil {
+			return 0, err
+		}
+		r.zr = zr
+	}
+	return r.zr.Read(p)
+}
+
+// ReadAt reads len(p) bytes of decompressed data starting at offset off.
+func (cf *compressedFile) ReadAt(p []byte, off int64) (n int, err error) {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+	for len(p) > 0 {
+		idx := sort.Search(len(cf.chunks), func(i int) bool { return cf.chunks[i].off > off }) - 1
+		if idx < 0 {
+			return n, io.EOF
+		}
+		ch := &cf.chunks[idx]
+		if err := cf.decompress(idx, off-ch.off+int64(len(p))); err != nil {
+			return n, err
+		}
+		avail := ch.done - (off - ch.off)
+		if avail <= 0 {
+			return n, io.EOF
+		}
+		m := len(p)
+		if int64(m) > avail {
+			m = int(avail)
+		}
+		rn, err := cf.cache.ReadAt(p[:m], off)
+		n += rn
+		if err != nil {
+			return n, err
+		}
+		p = p[m:]
+		off += int64(m)
+	}
+	return n, nil
+}
+
+// decompress writes to the cache the first end bytes of the chunk with
+// index idx, or all of it if it is shorter.
+func (cf *compressedFile) decompress(idx int, end int64) error {
+	ch := &cf.chunks[idx]
+	if ch.size >= 0 && end > ch.size {
+		end = ch.size
+	}
+	if ch.done >= end {
+		return nil
+	}
+
+	if cf.cur.idx != idx {
+		// Start decompressing the chunk from the beginning, skipping the data
+		// that is already in the cache.
+		cf.cur.idx = idx
+		cf.cur.r = cf.newDecoder(ch)
+		if _, err := io.CopyN(ioutil.Discard, cf.cur.r, ch.done); err != nil {
+			cf.cur.idx = -1
+			return err
+		}
+	}
+
+	if cf.buf == nil {
+		cf.buf = make([]byte, compressedCacheBufLen)
+	}
+	for ch.done < end {
+		n, err := io.ReadFull(cf.cur.r, cf.buf)
+		if n > 0 {
+			if _, err := cf.cache.WriteAt(cf.buf[:n], ch.off+ch.done); err != nil {
+				return err
+			}
+			ch.done += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			if ch.size < 0 {
+				ch.size = ch.done
+			}
+			if ch.done != ch.size {
+				return fmt.Errorf("decompressed size of frame at offset %#x does not match its header", ch.coff)
+			}
+			cf.cur.idx = -1
+			return nil
+		}
+		if err != nil {
+			cf.cur.idx = -1
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the compressed file and removes the cache.
+func (cf *compressedFile) Close() error {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+	cf.cache.Close()
+	if runtime.GOOS == "windows" {
+		os.Remove(cf.cache.Name())
+	}
+	return cf.fh.Close()
+}
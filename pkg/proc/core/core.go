@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go/ast"
 	"io"
+	"os"
 
 	"github.com/go-delve/delve/pkg/proc"
 )
@@ -96,7 +97,7 @@ func (r *splicedMemory) Add(reader proc.MemoryReader, off, length uintptr) {
 func (r *splicedMemory) ReadMemory(buf []byte, addr uintptr) (n int, err error) {
 	started := false
 	for _, entry := range r.readers {
-		if entry.offset+entry.length < addr {
+		if entry.offset+entry.length <= addr {
 			if !started {
 				continue
 			}
@@ -149,12 +150,18 @@ func (r *offsetReaderAt) ReadMemory(buf []byte, addr uintptr) (n int, err error)
 
 // process represents a core file.
 type process struct {
+	core    coreReader
 	mem     proc.MemoryReader
+	files   []*os.File // files, other than core, read by mem
 	Threads map[int]*thread
 	pid     int
 
 	entryPoint uint64
 
+	// warnings are the problems found while reading the core file, see
+	// proc.Target.Warnings.
+	warnings []string
+
 	bi            *proc.BinaryInfo
 	breakpoints   proc.BreakpointMap
 	currentThread *thread
@@ -189,7 +196,7 @@ var (
 	ErrChangeRegisterCore = errors.New("can not change register values of core process")
 )
 
-type openFn func(string, string) (*process, error)
+type openFn func(core coreReader, exePath, sysroot string) (*process, error)
 
 var openFns = []openFn{readLinuxCore, readAMD64Minidump}
 
@@ -200,25 +207,36 @@ var ErrUnrecognizedFormat = errors.New("unrecognized core format")
 // OpenCore will open the core file and return a Process struct.
 // If the DWARF information cannot be found in the binary, Delve will look
 // for external debug files in the directories passed in.
-func OpenCore(corePath, exePath string, debugInfoDirs []string) (*proc.Target, error) {
+// Memory of file backed mappings that is missing from the core file is
+// read from the mapped files, looked up inside sysroot if it isn't empty.
+// Core files compressed with gzip, zstd or lz4 are decompressed on demand,
+// the decompressed data is stored in a temporary file in cacheDir or, if it
+// is empty, in the default directory for temporary files.
+func OpenCore(corePath, exePath string, debugInfoDirs []string, sysroot, cacheDir string) (*proc.Target, error) {
+	core, warnings, err := openCoreFile(corePath, cacheDir)
+	if err != nil {
+		return nil, err
+	}
 	var p *process
-	var err error
 	for _, openFn := range openFns {
-		p, err = openFn(corePath, exePath)
+		p, err = openFn(core, exePath, sysroot)
 		if err != ErrUnrecognizedFormat {
 			break
 		}
 	}
 	if err != nil {
+		core.Close()
 		return nil, err
 	}
+	p.core = core
 
 	return proc.NewTarget(p, proc.NewTargetConfig{
 		Path:                exePath,
 		DebugInfoDirs:       debugInfoDirs,
 		WriteBreakpoint:     p.writeBreakpoint,
 		DisableAsyncPreempt: false,
-		StopReason:          proc.StopAttached})
+		StopReason:          proc.StopAttached,
+		Warnings:            append(warnings, p.warnings...)})
 }
 
 // BinInfo will return the binary info.
@@ -252,6 +270,12 @@ func (p *process) GetDirection() proc.Direction { return proc.Forward }
 // When does not apply to core files, it is to support the Mozilla 'rr' backend.
 func (p *process) When() (string, error) { return "", nil }
 
+// WhenTicks does not apply to core files, it is to support the Mozilla 'rr' backend.
+func (p *process) WhenTicks() (uint64, error) { return 0, nil }
+
+// SeekTicks will only return an error for core files, as they are not executing.
+func (p *process) SeekTicks(uint64) error { return ErrContinueCore }
+
 // Checkpoint for core files returns an error, there is no execution of a core file.
 func (p *process) Checkpoint(string) (int, error) { return -1, ErrContinueCore }
 
@@ -407,11 +431,18 @@ func (p *process) CurrentThread() proc.Thread {
 	return p.currentThread
 }
 
-// Detach will always return nil and have no
-// effect as you cannot detach from a core file
-// and have it continue execution or exit.
+// Detach closes the core file and the other files memory is read from, it
+// has no other effect as you cannot detach from a core file and have it
+// continue execution or exit.
 func (p *process) Detach(bool) error {
-	return nil
+	for _, fh := range p.files {
+		fh.Close()
+	}
+	p.files = nil
+	if p.core == nil {
+		return nil
+	}
+	return p.core.Close()
 }
 
 // Valid returns whether the process is active. Always returns true
@@ -2,9 +2,12 @@ package core
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/binary"
 	"flag"
 	"fmt"
 	"go/constant"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -47,6 +50,7 @@ func TestSplicedReader(t *testing.T) {
 		data = append(data, byte(i))
 		data2 = append(data2, byte(i+100))
 	}
+	data12 := append(append([]byte{}, data...), data2...)
 
 	type region struct {
 		data   []byte
@@ -131,6 +135,16 @@ func TestSplicedReader(t *testing.T) {
 			8,
 			[]byte{10, 11, 112, 113, 114, 115, 16, 17},
 		},
+		{
+			"Read after adjacent region",
+			[]region{
+				{data, 90, 10},
+				{data12, 100, 5},
+			},
+			100,
+			5,
+			[]byte{100, 101, 102, 103, 104},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -148,6 +162,212 @@ func TestSplicedReader(t *testing.T) {
 	}
 }
 
+func TestELFBuildID(t *testing.T) {
+	// The Go linker does not put .note.gnu.build-id in a PT_NOTE segment, use
+	// a C program like the shared libraries found in NT_FILE.
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not available")
+	}
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.PathsToRemove = append(test.PathsToRemove, tempDir)
+	srcPath := filepath.Join(tempDir, "buildid.c")
+	exePath := filepath.Join(tempDir, "buildid")
+	if err := ioutil.WriteFile(srcPath, []byte("int main(void) { return 0; }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("gcc", "-Wl,--build-id=0x0123456789abcdef", "-o", exePath, srcPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("could not build test program: %v\n%s", err, out)
+	}
+	fh, err := os.Open(exePath)
+	assertNoError(err, t, "Open")
+	defer fh.Close()
+	id, err := elfBuildID(fh)
+	assertNoError(err, t, "elfBuildID")
+	if want := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}; !bytes.Equal(id, want) {
+		t.Errorf("wrong build ID %x, expected %x", id, want)
+	}
+
+	// A mapped file with a different build ID than the one dumped in the
+	// core file is ignored and reported.
+	otherPath := filepath.Join(tempDir, "other")
+	cmd = exec.Command("gcc", "-Wl,--build-id=0xfedcba9876543210", "-o", otherPath, srcPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("could not build test program: %v\n%s", err, out)
+	}
+	other, err := os.Open(otherPath)
+	assertNoError(err, t, "Open")
+	defer other.Close()
+	const start = 0x400000
+	fileNote := &linuxNTFile{
+		linuxNTFileHdr: linuxNTFileHdr{Count: 1, PageSize: 0x1000},
+		entries:        []*linuxNTFileEntry{{Start: start, End: start + 0x1000, Name: exePath}},
+	}
+	for _, tc := range []struct {
+		dumped   *os.File
+		mismatch bool
+	}{
+		{fh, false},
+		{other, true},
+	} {
+		coreMemory := &splicedMemory{}
+		coreMemory.Add(&offsetReaderAt{reader: tc.dumped, offset: start}, start, 0x1000)
+		mapped, err := openMappedFile(fileNote, exePath, "", coreMemory)
+		if mapped != nil {
+			mapped.Close()
+		}
+		switch {
+		case tc.mismatch && (mapped != nil || err == nil || !strings.Contains(err.Error(), "build ID")):
+			t.Errorf("mapped file with a different build ID: got %v, %v", mapped, err)
+		case !tc.mismatch && (mapped == nil || err != nil):
+			t.Errorf("mapped file with the same build ID: got %v, %v", mapped, err)
+		}
+	}
+}
+
+func TestOpenMappedFileTooSmall(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.PathsToRemove = append(test.PathsToRemove, tempDir)
+	path := filepath.Join(tempDir, "lib.so")
+	assertNoError(ioutil.WriteFile(path, make([]byte, 0x1000), 0644), t, "WriteFile")
+
+	fileNote := &linuxNTFile{
+		linuxNTFileHdr: linuxNTFileHdr{Count: 1, PageSize: 0x1000},
+		entries:        []*linuxNTFileEntry{{Start: 0x400000, End: 0x401000, FileOfs: 2, Name: "/lib.so"}},
+	}
+	mapped, err := openMappedFile(fileNote, "/lib.so", tempDir, &splicedMemory{})
+	if mapped != nil {
+		mapped.Close()
+		t.Errorf("file smaller than its mapping was opened")
+	}
+	if err == nil || !strings.Contains(err.Error(), "smaller than its mapping") {
+		t.Errorf("wrong error for a file smaller than its mapping: %v", err)
+	}
+
+	mapped, err = openMappedFile(fileNote, "/missing.so", tempDir, &splicedMemory{})
+	if mapped != nil || err != nil {
+		t.Errorf("missing file: got %v, %v", mapped, err)
+	}
+}
+
+func TestCompressedFile(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.PathsToRemove = append(test.PathsToRemove, tempDir)
+
+	// compressible data, big enough to span multiple cache buffers
+	data := make([]byte, 0, 3*compressedCacheBufLen+1234)
+	for i := 0; len(data) < cap(data); i++ {
+		data = append(data, fmt.Sprintf("%08x ", i*i)...)
+	}
+	data = data[:cap(data)]
+	rawPath := filepath.Join(tempDir, "data")
+	assertNoError(ioutil.WriteFile(rawPath, data, 0644), t, "WriteFile")
+
+	gzipData := func(data []byte, extra []byte) []byte {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Header.Extra = extra
+		w.Write(data)
+		w.Close()
+		return buf.Bytes()
+	}
+	// BGZF files are a sequence of gzip members of at most 64kB, each with
+	// its compressed size in the header.
+	bgzfData := func(data []byte) []byte {
+		var out []byte
+		for len(data) > 0 {
+			n := 0xff00
+			if n > len(data) {
+				n = len(data)
+			}
+			member := gzipData(data[:n], []byte{'B', 'C', 2, 0, 0, 0})
+			binary.LittleEndian.PutUint16(member[16:], uint16(len(member)-1))
+			out = append(out, member...)
+			data = data[n:]
+		}
+		return out
+	}
+
+	files := map[string][]byte{
+		"gzip":       gzipData(data, nil),
+		"gzip-multi": append(gzipData(data[:1000000], nil), gzipData(data[1000000:], nil)...),
+		"bgzf":       bgzfData(data),
+	}
+	for name, buf := range files {
+		assertNoError(ioutil.WriteFile(filepath.Join(tempDir, name), buf, 0644), t, "WriteFile")
+	}
+	compressCmd := func(name, tool string, args ...string) {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Logf("%s not found, skipping %s", tool, name)
+			return
+		}
+		out, err := exec.Command(tool, append(args, "-c", rawPath)...).Output()
+		if err != nil {
+			t.Fatalf("%s: %v", tool, err)
+		}
+		files[name] = out
+		assertNoError(ioutil.WriteFile(filepath.Join(tempDir, name), out, 0644), t, "WriteFile")
+	}
+	compressCmd("zstd", "zstd", "-q")
+	compressCmd("lz4", "lz4", "-q")
+	compressCmd("lz4-linked", "lz4", "-q", "-BD", "-B4")
+	compressCmd("lz4-legacy", "lz4", "-q", "-l")
+
+	cacheDir := filepath.Join(tempDir, "cache")
+	assertNoError(os.Mkdir(cacheDir, 0755), t, "Mkdir")
+
+	for name := range files {
+		r, warnings, err := openCoreFile(filepath.Join(tempDir, name), cacheDir)
+		assertNoError(err, t, name)
+		cf, ok := r.(*compressedFile)
+		if !ok {
+			t.Fatalf("%s: not recognized as a compressed file", name)
+		}
+		if dir := filepath.Dir(cf.cache.Name()); dir != cacheDir {
+			t.Errorf("%s: cache created in %s instead of %s", name, dir, cacheDir)
+		}
+		if name == "bgzf" && len(cf.chunks) < 2 {
+			t.Errorf("%s: file was not split into chunks", name)
+		}
+		if singleChunk := len(cf.chunks) == 1; singleChunk != (len(warnings) == 1) {
+			t.Errorf("%s: %d chunks, warnings %q", name, len(cf.chunks), warnings)
+		}
+		for _, tc := range []struct {
+			off int64
+			n   int
+		}{
+			{int64(len(data)) - 100, 100},
+			{0, 100},
+			{compressedCacheBufLen - 10, 20},
+			{int64(len(data)) / 2, compressedCacheBufLen + 1},
+			{int64(len(data)) - 10, 20},
+		} {
+			buf := make([]byte, tc.n)
+			n, err := r.ReadAt(buf, tc.off)
+			want := data[tc.off:]
+			if len(want) > tc.n {
+				want = want[:tc.n]
+			}
+			if n != len(want) || !bytes.Equal(buf[:n], want) {
+				t.Errorf("%s: ReadAt(%d, %#x) returned wrong data (%d bytes, %v)", name, tc.n, tc.off, n, err)
+			}
+			if n < tc.n && err != io.EOF {
+				t.Errorf("%s: ReadAt(%d, %#x) short read with error %v", name, tc.n, tc.off, err)
+			}
+		}
+		assertNoError(r.Close(), t, "Close")
+	}
+}
+
 func withCoreFile(t *testing.T, name, args string) *proc.Target {
 	// This is all very fragile and won't work on hosts with non-default core patterns.
 	// Might be better to check in the binary and core?
@@ -173,7 +393,7 @@ func withCoreFile(t *testing.T, name, args string) *proc.Target {
 	}
 	corePath := cores[0]
 
-	p, err := OpenCore(corePath, fix.Path, []string{})
+	p, err := OpenCore(corePath, fix.Path, []string{}, "", "")
 	if err != nil {
 		t.Errorf("OpenCore(%q) failed: %v", corePath, err)
 		pat, err := ioutil.ReadFile("/proc/sys/kernel/core_pattern")
@@ -198,6 +418,64 @@ func logRegisters(t *testing.T, regs proc.Registers, arch *proc.Arch) {
 	}
 }
 
+func TestParseTraceback(t *testing.T) {
+	const traceback = `panic: boom
+
+goroutine 1 gp=0xc000002380 m=0 mp=0x52e4e0 [running]:
+panic({0x519590?, 0x495eb8?})
+	/usr/local/go/src/runtime/panic.go:878 +0x16f fp=0xc00007cd88 sp=0xc00007cce0 pc=0x48132f
+main.g(...)
+	/tmp/tb/main.go:9
+main.f(0x3, {0x48d19d, 0x5}, {0x1, 0x2})
+	/tmp/tb/main.go:13 +0x50 fp=0xc00007cdc0 sp=0xc00007cd88 pc=0x48c870
+main.main()
+	/tmp/tb/main.go:32 +0x171 fp=0xc00007cee0 sp=0xc00007ce68 pc=0x48ca91
+
+goroutine 5 [chan receive, 2 minutes]:
+main.waiter(0xc000020070, ...)
+	/tmp/tb/main.go:20 +0x30
+...additional frames elided...
+created by main.main in goroutine 1
+	/tmp/tb/main.go:28 +0x4f
+
+goroutine 1 [running]:
+main.main()
+	/tmp/tb/main.go:32 +0x171
+`
+
+	gs, err := parseTraceback(strings.NewReader(traceback))
+	assertNoError(err, t, "parseTraceback")
+
+	tgt := []*proc.DumpedGoroutine{
+		{
+			ID:     1,
+			Status: "running",
+			Frames: []proc.DumpedFrame{
+				{Function: "panic", File: "/usr/local/go/src/runtime/panic.go", Line: 878, Offset: 0x16f, SP: 0xc00007cce0, FP: 0xc00007cd88, RawArgs: "({0x519590?, 0x495eb8?})"},
+				{Function: "main.g", File: "/tmp/tb/main.go", Line: 9, Offset: -1, RawArgs: "(...)"},
+				{Function: "main.f", File: "/tmp/tb/main.go", Line: 13, Offset: 0x50, SP: 0xc00007cd88, FP: 0xc00007cdc0, RawArgs: "(0x3, {0x48d19d, 0x5}, {0x1, 0x2})"},
+				{Function: "main.main", File: "/tmp/tb/main.go", Line: 32, Offset: 0x171, SP: 0xc00007ce68, FP: 0xc00007cee0, RawArgs: "()"},
+			},
+		},
+		{
+			ID:     5,
+			Status: "chan receive",
+			Frames: []proc.DumpedFrame{
+				{Function: "main.waiter", File: "/tmp/tb/main.go", Line: 20, Offset: 0x30, RawArgs: "(0xc000020070, ...)"},
+			},
+			Elided:    true,
+			CreatedBy: &proc.DumpedFrame{Function: "main.main", File: "/tmp/tb/main.go", Line: 28, Offset: 0x4f},
+		},
+	}
+
+	if !reflect.DeepEqual(gs, tgt) {
+		t.Errorf("parseTraceback mismatch")
+		for _, g := range gs {
+			t.Logf("%#v", *g)
+		}
+	}
+}
+
 func TestCore(t *testing.T) {
 	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
 		return
@@ -383,6 +661,112 @@ mainSearch:
 	t.Logf("s = %#v\n", v2)
 }
 
+func TestCoreCrossArch(t *testing.T) {
+	// Builds the panic fixture for a different architecture and runs it,
+	// under qemu user emulation if necessary, to produce a core file, then
+	// checks that it can be opened and examined on the host.
+	if runtime.GOOS != "linux" {
+		t.Skip("cross architecture core files are only tested on linux")
+	}
+	type crossArch struct {
+		goarch, qemu, pcname string
+	}
+	var archs []crossArch
+	switch runtime.GOARCH {
+	case "amd64":
+		archs = []crossArch{{"arm64", "qemu-aarch64", "PC"}, {"arm", "qemu-arm", "PC"}, {"386", "", "Eip"}}
+	case "arm64":
+		archs = []crossArch{{"amd64", "qemu-x86_64", "Rip"}, {"arm", "", "PC"}, {"386", "qemu-i386", "Eip"}}
+	default:
+		t.Skipf("no cross architectures for %s", runtime.GOARCH)
+	}
+	for _, arch := range archs {
+		t.Run(arch.goarch, func(t *testing.T) {
+			testCoreCrossArch(t, arch.goarch, arch.qemu, arch.pcname)
+		})
+	}
+}
+
+func testCoreCrossArch(t *testing.T, goarch, qemu, pcname string) {
+	var emulator string
+	if qemu != "" {
+		var err error
+		emulator, err = exec.LookPath(qemu)
+		if err != nil {
+			t.Skipf("%s not found", qemu)
+		}
+	}
+
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.PathsToRemove = append(test.PathsToRemove, tempDir)
+	exePath := filepath.Join(tempDir, "panic")
+	cmd := exec.Command("go", "build", "-gcflags=-N -l", "-o", exePath, filepath.Join(test.FindFixturesDir(), "panic.go"))
+	cmd.Env = append(os.Environ(), "GOOS=linux", "GOARCH="+goarch, "CGO_ENABLED=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("could not build fixture for %s: %v\n%s", goarch, err, out)
+	}
+	bashCmd := fmt.Sprintf("cd %v && ulimit -c unlimited && GOTRACEBACK=crash %v %v", tempDir, emulator, exePath)
+	exec.Command("bash", "-c", bashCmd).Run()
+	// qemu writes its own core files, named qemu_<prog>_<date>_<pid>.core
+	cores, err := filepath.Glob(path.Join(tempDir, "*core*"))
+	if err != nil || len(cores) != 1 {
+		t.Skipf("core file was not produced, could not run test")
+	}
+
+	p, err := OpenCore(cores[0], exePath, []string{}, "", "")
+	assertNoError(err, t, "OpenCore")
+	if p.BinInfo().Arch.Name != goarch {
+		t.Fatalf("wrong architecture %s, expected %s", p.BinInfo().Arch.Name, goarch)
+	}
+
+	gs, _, err := proc.GoroutinesInfo(p, 0, 0)
+	assertNoError(err, t, "GoroutinesInfo")
+	var mainFrame *proc.Stackframe
+mainSearch:
+	for _, g := range gs {
+		stack, err := g.Stacktrace(10, 0)
+		assertNoError(err, t, "Stacktrace()")
+		for i := range stack {
+			if stack[i].Current.Fn != nil && stack[i].Current.Fn.Name == "main.main" {
+				mainFrame = &stack[i]
+				break mainSearch
+			}
+		}
+	}
+	if mainFrame == nil {
+		t.Fatal("could not find main.main frame")
+	}
+
+	msg, err := proc.FrameToScope(p.BinInfo(), p.CurrentThread(), nil, *mainFrame).EvalVariable("msg", proc.LoadConfig{MaxStringLen: 64})
+	assertNoError(err, t, "EvalVariable(msg)")
+	if constant.StringVal(msg.Value) != "BOOM!" {
+		t.Errorf("main.msg = %q, want %q", msg.Value, "BOOM!")
+	}
+
+	fn := mainFrame.Current.Fn
+	text, err := proc.Disassemble(p.CurrentThread(), nil, p.Breakpoints(), p.BinInfo(), fn.Entry, fn.End)
+	assertNoError(err, t, "Disassemble")
+	for _, inst := range text {
+		// instructions that can not be decoded are printed as '?'
+		if inst.Text(proc.GoFlavour, p.BinInfo()) == "?" {
+			t.Errorf("could not decode instruction at %#x", inst.Loc.PC)
+		}
+	}
+
+	regs, err := p.CurrentThread().Registers()
+	assertNoError(err, t, "Registers")
+	arch := p.BinInfo().Arch
+	logRegisters(t, regs, arch)
+	dregs := arch.RegistersToDwarfRegisters(0, regs)
+	name, _, _ := arch.DwarfRegisterToString(int(dregs.PCRegNum), dregs.Reg(dregs.PCRegNum))
+	if name != pcname {
+		t.Errorf("wrong name for PC register %q, expected %q", name, pcname)
+	}
+}
+
 func TestMinidump(t *testing.T) {
 	if runtime.GOOS != "windows" {
 		t.Skip("minidumps can only be produced on windows")
@@ -394,7 +778,7 @@ func TestMinidump(t *testing.T) {
 	fix := test.BuildFixture("sleep", buildFlags)
 	mdmpPath := procdump(t, fix.Path)
 
-	p, err := OpenCore(mdmpPath, fix.Path, []string{})
+	p, err := OpenCore(mdmpPath, fix.Path, []string{}, "", "")
 	if err != nil {
 		t.Fatalf("OpenCore: %v", err)
 	}
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"debug/elf"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/go-delve/delve/pkg/proc"
@@ -20,23 +22,40 @@ type linuxCoreTimeval struct {
 	Usec int64
 }
 
+// linuxCoreTimeval32 is the timeval struct used on 32bit systems.
+type linuxCoreTimeval32 struct {
+	Sec  int32
+	Usec int32
+}
+
 // NT_FILE is file mapping information, e.g. program text mappings. Desc is a LinuxNTFile.
 const _NT_FILE elf.NType = 0x46494c45 // "FILE".
 
 // NT_X86_XSTATE is other registers, including AVX and such.
 const _NT_X86_XSTATE elf.NType = 0x202 // Note type for notes containing X86 XSAVE area.
 
+// NT_GNU_BUILD_ID is the note type of the build ID note in ELF files.
+const _NT_GNU_BUILD_ID elf.NType = 0x3
+
 // NT_AUXV is the note type for notes containing a copy of the Auxv array
 const _NT_AUXV elf.NType = 0x6
 
 // NT_FPREGSET is the note type for floating point registers.
 const _NT_FPREGSET elf.NType = 0x2
 
+// NT_386_TLS is the note type for the TLS segment descriptors of a 386 thread.
+const _NT_386_TLS elf.NType = 0x200
+
+// NT_ARM_VFP is the note type for the VFP registers of an ARM thread.
+const _NT_ARM_VFP elf.NType = 0x400
+
 // Fetch architecture using exeELF.Machine from core file
 // Refer http://man7.org/linux/man-pages/man5/elf.5.html
 const (
 	_EM_AARCH64          = 183
 	_EM_X86_64           = 62
+	_EM_386              = 3
+	_EM_ARM              = 40
 	_ARM_FP_HEADER_START = 512
 )
 
@@ -45,23 +64,34 @@ const elfErrorBadMagicNumber = "bad magic number"
 func linuxThreadsFromNotes(p *process, notes []*note, machineType elf.Machine) {
 	var lastThreadAMD *linuxAMD64Thread
 	var lastThreadARM *linuxARM64Thread
+	var lastThread386 *linux386Thread
+	var lastThreadARM32 *linuxARMThread
+	addThread := func(pid int32, t osThread) {
+		p.Threads[int(pid)] = &thread{t, p, proc.CommonThread{}}
+		if p.currentThread == nil {
+			p.currentThread = p.Threads[int(pid)]
+		}
+	}
 	for _, note := range notes {
 		switch note.Type {
 		case elf.NT_PRSTATUS:
-			if machineType == _EM_X86_64 {
+			switch machineType {
+			case _EM_X86_64:
 				t := note.Desc.(*linuxPrStatusAMD64)
 				lastThreadAMD = &linuxAMD64Thread{linutil.AMD64Registers{Regs: &t.Reg}, t}
-				p.Threads[int(t.Pid)] = &thread{lastThreadAMD, p, proc.CommonThread{}}
-				if p.currentThread == nil {
-					p.currentThread = p.Threads[int(t.Pid)]
-				}
-			} else if machineType == _EM_AARCH64 {
+				addThread(t.Pid, lastThreadAMD)
+			case _EM_AARCH64:
 				t := note.Desc.(*linuxPrStatusARM64)
 				lastThreadARM = &linuxARM64Thread{linutil.ARM64Registers{Regs: &t.Reg}, t}
-				p.Threads[int(t.Pid)] = &thread{lastThreadARM, p, proc.CommonThread{}}
-				if p.currentThread == nil {
-					p.currentThread = p.Threads[int(t.Pid)]
-				}
+				addThread(t.Pid, lastThreadARM)
+			case _EM_386:
+				t := note.Desc.(*linuxPrStatus386)
+				lastThread386 = &linux386Thread{linutil.I386Registers{Regs: &t.Reg}, t}
+				addThread(t.Pid, lastThread386)
+			case _EM_ARM:
+				t := note.Desc.(*linuxPrStatusARM)
+				lastThreadARM32 = &linuxARMThread{linutil.ARMRegisters{Regs: &t.Reg}, t}
+				addThread(t.Pid, lastThreadARM32)
 			}
 		case _NT_FPREGSET:
 			if machineType == _EM_AARCH64 {
@@ -70,25 +100,50 @@ func linuxThreadsFromNotes(p *process, notes []*note, machineType elf.Machine) {
 				}
 			}
 		case _NT_X86_XSTATE:
-			if machineType == _EM_X86_64 {
+			switch machineType {
+			case _EM_X86_64:
 				if lastThreadAMD != nil {
 					lastThreadAMD.regs.Fpregs = note.Desc.(*linutil.AMD64Xstate).Decode()
 				}
+			case _EM_386:
+				if lastThread386 != nil {
+					lastThread386.regs.Fpregs = note.Desc.(*linutil.I386Xstate).Decode()
+				}
+			}
+		case _NT_386_TLS:
+			if lastThread386 != nil {
+				// The GS segment selector is the index of the TLS descriptor
+				// shifted left by three bits.
+				gsidx := uint32(lastThread386.t.Reg.Xgs) >> 3
+				for _, ud := range note.Desc.([]linuxUserDesc) {
+					if ud.EntryNumber == gsidx {
+						lastThread386.regs.Tls = uint64(ud.BaseAddr)
+					}
+				}
+			}
+		case _NT_ARM_VFP:
+			if lastThreadARM32 != nil {
+				lastThreadARM32.regs.Fpregs = note.Desc.(*linutil.ARMVFPRegs).Decode()
 			}
 		case elf.NT_PRPSINFO:
-			p.pid = int(note.Desc.(*linuxPrPsInfo).Pid)
+			switch desc := note.Desc.(type) {
+			case *linuxPrPsInfo:
+				p.pid = int(desc.Pid)
+			case *linuxPrPsInfo32:
+				p.pid = int(desc.Pid)
+			}
 		}
 	}
 }
 
-// readLinuxCore reads a core file from corePath corresponding to the executable at
+// readLinuxCore reads the core file core corresponding to the executable at
 // exePath. For details on the Linux ELF core format, see:
 // http://www.gabriel.urdhr.fr/2015/05/29/core-file/,
 // http://uhlo.blogspot.fr/2012/05/brief-look-into-core-dumps.html,
 // elf_core_dump in http://lxr.free-electrons.com/source/fs/binfmt_elf.c,
 // and, if absolutely desperate, readelf.c from the binutils source.
-func readLinuxCore(corePath, exePath string) (*process, error) {
-	coreFile, err := elf.Open(corePath)
+func readLinuxCore(core coreReader, exePath, sysroot string) (*process, error) {
+	coreFile, err := elf.NewFile(core)
 	if err != nil {
 		if _, isfmterr := err.(*elf.FormatError); isfmterr && (strings.Contains(err.Error(), elfErrorBadMagicNumber) || strings.Contains(err.Error(), " at offset 0x0: too short")) {
 			// Go >=1.11 and <1.11 produce different errors when reading a non-elf file.
@@ -102,38 +157,54 @@ func readLinuxCore(corePath, exePath string) (*process, error) {
 	}
 	exeELF, err := elf.NewFile(exe)
 	if err != nil {
+		exe.Close()
 		return nil, err
 	}
 
 	if coreFile.Type != elf.ET_CORE {
+		exe.Close()
 		return nil, fmt.Errorf("%v is not a core file", coreFile)
 	}
 	if exeELF.Type != elf.ET_EXEC && exeELF.Type != elf.ET_DYN {
+		exe.Close()
 		return nil, fmt.Errorf("%v is not an exe file", exeELF)
 	}
 
-	machineType := exeELF.Machine
+	// The architecture is taken from the core file, not from the host, so
+	// that cores produced on a different architecture can be opened.
+	machineType := coreFile.Machine
+	if exeELF.Machine != machineType {
+		exe.Close()
+		return nil, fmt.Errorf("executable architecture %v does not match core file architecture %v", exeELF.Machine, machineType)
+	}
 	notes, err := readNotes(coreFile, machineType)
 	if err != nil {
+		exe.Close()
 		return nil, err
 	}
-	memory := buildMemory(coreFile, exeELF, exe, notes)
 
-	// TODO support 386
 	var bi *proc.BinaryInfo
 	switch machineType {
 	case _EM_X86_64:
 		bi = proc.NewBinaryInfo("linux", "amd64")
 	case _EM_AARCH64:
 		bi = proc.NewBinaryInfo("linux", "arm64")
+	case _EM_386:
+		bi = proc.NewBinaryInfo("linux", "386")
+	case _EM_ARM:
+		bi = proc.NewBinaryInfo("linux", "arm")
 	default:
+		exe.Close()
 		return nil, fmt.Errorf("unsupported machine type")
 	}
 
 	entryPoint := findEntryPoint(notes, bi.Arch.PtrSize())
+	memory, mappedFiles, warnings := buildMemory(coreFile, exeELF, exe, notes, entryPoint, sysroot)
 
 	p := &process{
 		mem:         memory,
+		files:       append(mappedFiles, exe),
+		warnings:    warnings,
 		Threads:     map[int]*thread{},
 		entryPoint:  entryPoint,
 		bi:          bi,
@@ -154,6 +225,16 @@ type linuxARM64Thread struct {
 	t    *linuxPrStatusARM64
 }
 
+type linux386Thread struct {
+	regs linutil.I386Registers
+	t    *linuxPrStatus386
+}
+
+type linuxARMThread struct {
+	regs linutil.ARMRegisters
+	t    *linuxPrStatusARM
+}
+
 func (t *linuxAMD64Thread) registers() (proc.Registers, error) {
 	var r linutil.AMD64Registers
 	r.Regs = t.regs.Regs
@@ -168,6 +249,21 @@ func (t *linuxARM64Thread) registers() (proc.Registers, error) {
 	return &r, nil
 }
 
+func (t *linux386Thread) registers() (proc.Registers, error) {
+	var r linutil.I386Registers
+	r.Regs = t.regs.Regs
+	r.Fpregs = t.regs.Fpregs
+	r.Tls = t.regs.Tls
+	return &r, nil
+}
+
+func (t *linuxARMThread) registers() (proc.Registers, error) {
+	var r linutil.ARMRegisters
+	r.Regs = t.regs.Regs
+	r.Fpregs = t.regs.Fpregs
+	return &r, nil
+}
+
 func (t *linuxAMD64Thread) pid() int {
 	return int(t.t.Pid)
 }
@@ -176,13 +272,23 @@ func (t *linuxARM64Thread) pid() int {
 	return int(t.t.Pid)
 }
 
+func (t *linux386Thread) pid() int {
+	return int(t.t.Pid)
+}
+
+func (t *linuxARMThread) pid() int {
+	return int(t.t.Pid)
+}
+
 // Note is a note from the PT_NOTE prog.
 // Relevant types:
 // - NT_FILE: File mapping information, e.g. program text mappings. Desc is a LinuxNTFile.
 // - NT_PRPSINFO: Information about a process, including PID and signal. Desc is a LinuxPrPsInfo.
 // - NT_PRSTATUS: Information about a thread, including base registers, state, etc. Desc is a LinuxPrStatus.
-// - NT_FPREGSET (Not implemented): x87 floating point registers.
+// - NT_FPREGSET: floating point registers, only decoded for arm64.
 // - NT_X86_XSTATE: Other registers, including AVX and such.
+// - NT_386_TLS: TLS segment descriptors, used to find the TLS on 386.
+// - NT_ARM_VFP: VFP registers on 32bit ARM.
 type note struct {
 	Type elf.NType
 	Name string
@@ -243,18 +349,27 @@ func readNote(r io.ReadSeeker, machineType elf.Machine) (*note, error) {
 	descReader := bytes.NewReader(desc)
 	switch note.Type {
 	case elf.NT_PRSTATUS:
-		if machineType == _EM_X86_64 {
+		switch machineType {
+		case _EM_X86_64:
 			note.Desc = &linuxPrStatusAMD64{}
-		} else if machineType == _EM_AARCH64 {
+		case _EM_AARCH64:
 			note.Desc = &linuxPrStatusARM64{}
-		} else {
+		case _EM_386:
+			note.Desc = &linuxPrStatus386{}
+		case _EM_ARM:
+			note.Desc = &linuxPrStatusARM{}
+		default:
 			return nil, fmt.Errorf("unsupported machine type")
 		}
 		if err := binary.Read(descReader, binary.LittleEndian, note.Desc); err != nil {
 			return nil, fmt.Errorf("reading NT_PRSTATUS: %v", err)
 		}
 	case elf.NT_PRPSINFO:
-		note.Desc = &linuxPrPsInfo{}
+		if is32bit(machineType) {
+			note.Desc = &linuxPrPsInfo32{}
+		} else {
+			note.Desc = &linuxPrPsInfo{}
+		}
 		if err := binary.Read(descReader, binary.LittleEndian, note.Desc); err != nil {
 			return nil, fmt.Errorf("reading NT_PRPSINFO: %v", err)
 		}
@@ -262,27 +377,66 @@ func readNote(r io.ReadSeeker, machineType elf.Machine) (*note, error) {
 		// No good documentation reference, but the structure is
 		// simply a header, including entry count, followed by that
 		// many entries, and then the file name of each entry,
-		// null-delimited. Not reading the names here.
+		// null-delimited.
+		// All fields are word sized.
+		readWord := func(out *uint64) error {
+			if !is32bit(machineType) {
+				return binary.Read(descReader, binary.LittleEndian, out)
+			}
+			var w uint32
+			err := binary.Read(descReader, binary.LittleEndian, &w)
+			*out = uint64(w)
+			return err
+		}
 		data := &linuxNTFile{}
-		if err := binary.Read(descReader, binary.LittleEndian, &data.linuxNTFileHdr); err != nil {
-			return nil, fmt.Errorf("reading NT_FILE header: %v", err)
+		for _, out := range []*uint64{&data.Count, &data.PageSize} {
+			if err := readWord(out); err != nil {
+				return nil, fmt.Errorf("reading NT_FILE header: %v", err)
+			}
 		}
 		for i := 0; i < int(data.Count); i++ {
 			entry := &linuxNTFileEntry{}
-			if err := binary.Read(descReader, binary.LittleEndian, entry); err != nil {
-				return nil, fmt.Errorf("reading NT_FILE entry %v: %v", i, err)
+			for _, out := range []*uint64{&entry.Start, &entry.End, &entry.FileOfs} {
+				if err := readWord(out); err != nil {
+					return nil, fmt.Errorf("reading NT_FILE entry %v: %v", i, err)
+				}
 			}
 			data.entries = append(data.entries, entry)
 		}
+		names := strings.Split(string(desc[len(desc)-descReader.Len():]), "\x00")
+		for i, entry := range data.entries {
+			if i < len(names) {
+				entry.Name = names[i]
+			}
+		}
 		note.Desc = data
 	case _NT_X86_XSTATE:
-		if machineType == _EM_X86_64 {
+		switch machineType {
+		case _EM_X86_64:
 			var fpregs linutil.AMD64Xstate
 			if err := linutil.AMD64XstateRead(desc, true, &fpregs); err != nil {
 				return nil, err
 			}
 			note.Desc = &fpregs
+		case _EM_386:
+			var fpregs linutil.I386Xstate
+			if err := linutil.I386XstateRead(desc, true, &fpregs); err != nil {
+				return nil, err
+			}
+			note.Desc = &fpregs
 		}
+	case _NT_386_TLS:
+		uds := make([]linuxUserDesc, len(desc)/binary.Size(linuxUserDesc{}))
+		if err := binary.Read(descReader, binary.LittleEndian, uds); err != nil {
+			return nil, fmt.Errorf("reading NT_386_TLS: %v", err)
+		}
+		note.Desc = uds
+	case _NT_ARM_VFP:
+		fpregs, err := linutil.ARMVFPRegsRead(desc)
+		if err != nil {
+			return nil, fmt.Errorf("reading NT_ARM_VFP: %v", err)
+		}
+		note.Desc = fpregs
 	case _NT_AUXV:
 		note.Desc = desc
 	case _NT_FPREGSET:
@@ -301,6 +455,12 @@ func readNote(r io.ReadSeeker, machineType elf.Machine) (*note, error) {
 	return note, nil
 }
 
+// is32bit returns true if word sized fields of the notes are 32bit long on
+// machineType.
+func is32bit(machineType elf.Machine) bool {
+	return machineType == _EM_386 || machineType == _EM_ARM
+}
+
 // skipPadding moves r to the next multiple of pad.
 func skipPadding(r io.ReadSeeker, pad int64) error {
 	pos, err := r.Seek(0, os.SEEK_CUR)
@@ -316,16 +476,50 @@ func skipPadding(r io.ReadSeeker, pad int64) error {
 	return nil
 }
 
-func buildMemory(core, exeELF *elf.File, exe io.ReaderAt, notes []*note) proc.MemoryReader {
+// buildMemory returns the memory of the process, read from the core file,
+// the executable and, for the parts missing from the core file, the files
+// mapped by the process. The mapped files that were opened are returned
+// along with it, as well as the reasons why some of the mapped files were
+// ignored.
+func buildMemory(core, exeELF *elf.File, exe io.ReaderAt, notes []*note, entryPoint uint64, sysroot string) (proc.MemoryReader, []*os.File, []string) {
 	memory := &splicedMemory{}
+	var opened []*os.File
+	var warnings []string
+
+	// Memory dumped in the core file, used to read the ELF headers of the
+	// mapped files.
+	coreMemory := &splicedMemory{}
+	addProgs(coreMemory, core)
 
-	// For now, assume all file mappings are to the exe.
 	for _, note := range notes {
 		if note.Type == _NT_FILE {
 			fileNote := note.Desc.(*linuxNTFile)
+			exeName := mainExecutableMapping(fileNote, entryPoint)
+			files := make(map[string]io.ReaderAt)
 			for _, entry := range fileNote.entries {
+				var reader io.ReaderAt
+				if entry.Name == exeName {
+					reader = exe
+				} else {
+					var ok bool
+					reader, ok = files[entry.Name]
+					if !ok {
+						fh, err := openMappedFile(fileNote, entry.Name, sysroot, coreMemory)
+						if err != nil {
+							warnings = append(warnings, err.Error())
+						}
+						if fh != nil {
+							opened = append(opened, fh)
+							reader = fh
+						}
+						files[entry.Name] = reader
+					}
+				}
+				if reader == nil {
+					continue
+				}
 				r := &offsetReaderAt{
-					reader: exe,
+					reader: reader,
 					offset: uintptr(entry.Start - (entry.FileOfs * fileNote.PageSize)),
 				}
 				memory.Add(r, uintptr(entry.Start), uintptr(entry.End-entry.Start))
@@ -337,20 +531,175 @@ func buildMemory(core, exeELF *elf.File, exe io.ReaderAt, notes []*note) proc.Me
 	// Load memory segments from exe and then from the core file,
 	// allowing the corefile to overwrite previously loaded segments
 	for _, elfFile := range []*elf.File{exeELF, core} {
-		for _, prog := range elfFile.Progs {
-			if prog.Type == elf.PT_LOAD {
-				if prog.Filesz == 0 {
-					continue
-				}
-				r := &offsetReaderAt{
-					reader: prog.ReaderAt,
-					offset: uintptr(prog.Vaddr),
-				}
-				memory.Add(r, uintptr(prog.Vaddr), uintptr(prog.Filesz))
+		addProgs(memory, elfFile)
+	}
+	return memory, opened, warnings
+}
+
+// addProgs adds the PT_LOAD segments of elfFile to memory.
+func addProgs(memory *splicedMemory, elfFile *elf.File) {
+	for _, prog := range elfFile.Progs {
+		if prog.Type == elf.PT_LOAD {
+			if prog.Filesz == 0 {
+				continue
+			}
+			r := &offsetReaderAt{
+				reader: prog.ReaderAt,
+				offset: uintptr(prog.Vaddr),
 			}
+			memory.Add(r, uintptr(prog.Vaddr), uintptr(prog.Filesz))
+		}
+	}
+}
+
+// mainExecutableMapping returns the name of the file containing the entry
+// point of the process, i.e. the executable. If the entry point is unknown
+// the executable is assumed to be the first file mapped.
+func mainExecutableMapping(fileNote *linuxNTFile, entryPoint uint64) string {
+	for _, entry := range fileNote.entries {
+		if entryPoint >= entry.Start && entryPoint < entry.End {
+			return entry.Name
 		}
 	}
-	return memory
+	if len(fileNote.entries) > 0 {
+		return fileNote.entries[0].Name
+	}
+	return ""
+}
+
+// openMappedFile opens the file name, mapped in the address space of the
+// process, inside sysroot. Returns nil if the file can not be opened and
+// an error if it doesn't match the file that was mapped when the core file
+// was produced.
+func openMappedFile(fileNote *linuxNTFile, name, sysroot string, coreMemory proc.MemoryReader) (*os.File, error) {
+	if name == "" {
+		return nil, nil
+	}
+	path := name
+	if sysroot != "" {
+		path = filepath.Join(sysroot, name)
+	}
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, nil
+	}
+	fi, err := fh.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		fh.Close()
+		return nil, nil
+	}
+
+	for _, entry := range fileNote.entries {
+		if entry.Name != name {
+			continue
+		}
+		if fileOfs := entry.FileOfs * fileNote.PageSize; uint64(fi.Size()) <= fileOfs {
+			fh.Close()
+			return nil, fmt.Errorf("%s is smaller than its mapping in the core file (%d bytes, mapped at offset %#x), ignoring it", path, fi.Size(), fileOfs)
+		}
+		if entry.FileOfs != 0 {
+			continue
+		}
+		// The kernel dumps the first page of mapped ELF files, use it to
+		// compare build IDs.
+		coreID, err := elfBuildID(&memoryReaderAt{coreMemory, entry.Start})
+		if err != nil {
+			continue
+		}
+		fileID, err := elfBuildID(fh)
+		if err == nil && !bytes.Equal(coreID, fileID) {
+			fh.Close()
+			return nil, fmt.Errorf("build ID of %s (%x) does not match the build ID of the mapped file in the core file (%x), ignoring it", path, fileID, coreID)
+		}
+	}
+	return fh, nil
+}
+
+// memoryReaderAt reads from memory starting at base.
+type memoryReaderAt struct {
+	mem  proc.MemoryReader
+	base uint64
+}
+
+func (r *memoryReaderAt) ReadAt(buf []byte, off int64) (int, error) {
+	return r.mem.ReadMemory(buf, uintptr(r.base+uint64(off)))
+}
+
+// elfBuildID returns the contents of the build ID note of the ELF file r.
+// Only the program headers are used, so that it can be called on the
+// first page of a mapped ELF file.
+func elfBuildID(r io.ReaderAt) ([]byte, error) {
+	var ident [elf.EI_NIDENT]byte
+	if _, err := r.ReadAt(ident[:], 0); err != nil {
+		return nil, err
+	}
+	if string(ident[:4]) != elf.ELFMAG {
+		return nil, errors.New("not an ELF file")
+	}
+	if elf.Data(ident[elf.EI_DATA]) != elf.ELFDATA2LSB {
+		return nil, errors.New("unsupported byte order")
+	}
+	sr := io.NewSectionReader(r, 0, 1<<62)
+
+	type prog struct{ typ, off, filesz uint64 }
+	var progs []prog
+	switch elf.Class(ident[elf.EI_CLASS]) {
+	case elf.ELFCLASS64:
+		var hdr elf.Header64
+		if err := binary.Read(sr, binary.LittleEndian, &hdr); err != nil {
+			return nil, err
+		}
+		for i := 0; i < int(hdr.Phnum); i++ {
+			var ph elf.Prog64
+			sr.Seek(int64(hdr.Phoff)+int64(i)*int64(hdr.Phentsize), io.SeekStart)
+			if err := binary.Read(sr, binary.LittleEndian, &ph); err != nil {
+				return nil, err
+			}
+			progs = append(progs, prog{uint64(ph.Type), ph.Off, ph.Filesz})
+		}
+	case elf.ELFCLASS32:
+		var hdr elf.Header32
+		if err := binary.Read(sr, binary.LittleEndian, &hdr); err != nil {
+			return nil, err
+		}
+		for i := 0; i < int(hdr.Phnum); i++ {
+			var ph elf.Prog32
+			sr.Seek(int64(hdr.Phoff)+int64(i)*int64(hdr.Phentsize), io.SeekStart)
+			if err := binary.Read(sr, binary.LittleEndian, &ph); err != nil {
+				return nil, err
+			}
+			progs = append(progs, prog{uint64(ph.Type), uint64(ph.Off), uint64(ph.Filesz)})
+		}
+	default:
+		return nil, errors.New("unsupported ELF class")
+	}
+
+	for _, ph := range progs {
+		if elf.ProgType(ph.typ) != elf.PT_NOTE {
+			continue
+		}
+		notes := io.NewSectionReader(r, int64(ph.off), int64(ph.filesz))
+		for {
+			var hdr elfNotesHdr
+			if err := binary.Read(notes, binary.LittleEndian, &hdr); err != nil {
+				break
+			}
+			name := make([]byte, hdr.Namesz)
+			if _, err := io.ReadFull(notes, name); err != nil {
+				break
+			}
+			skipPadding(notes, 4)
+			desc := make([]byte, hdr.Descsz)
+			if _, err := io.ReadFull(notes, desc); err != nil {
+				break
+			}
+			skipPadding(notes, 4)
+			if elf.NType(hdr.Type) == _NT_GNU_BUILD_ID && string(name) == "GNU\x00" {
+				return desc, nil
+			}
+		}
+	}
+	return nil, errors.New("no build ID note")
 }
 
 func findEntryPoint(notes []*note, ptrSize int) uint64 {
@@ -379,6 +728,19 @@ type linuxPrPsInfo struct {
 	Args                 [80]uint8
 }
 
+// linuxPrPsInfo32 is the prpsinfo struct used on 32bit systems.
+type linuxPrPsInfo32 struct {
+	State                uint8
+	Sname                int8
+	Zomb                 uint8
+	Nice                 int8
+	Flag                 uint32
+	Uid, Gid             uint16
+	Pid, Ppid, Pgrp, Sid int32
+	Fname                [16]uint8
+	Args                 [80]uint8
+}
+
 // LinuxPrStatusAMD64 is a copy of the prstatus kernel struct.
 type linuxPrStatusAMD64 struct {
 	Siginfo                      linuxSiginfo
@@ -405,6 +767,41 @@ type linuxPrStatusARM64 struct {
 	Fpvalid                      int32
 }
 
+// linuxPrStatus386 is a copy of the prstatus kernel struct on 386.
+type linuxPrStatus386 struct {
+	Siginfo                      linuxSiginfo
+	Cursig                       uint16
+	_                            [2]uint8
+	Sigpend                      uint32
+	Sighold                      uint32
+	Pid, Ppid, Pgrp, Sid         int32
+	Utime, Stime, CUtime, CStime linuxCoreTimeval32
+	Reg                          linutil.I386PtraceRegs
+	Fpvalid                      int32
+}
+
+// linuxPrStatusARM is a copy of the prstatus kernel struct on 32bit ARM.
+type linuxPrStatusARM struct {
+	Siginfo                      linuxSiginfo
+	Cursig                       uint16
+	_                            [2]uint8
+	Sigpend                      uint32
+	Sighold                      uint32
+	Pid, Ppid, Pgrp, Sid         int32
+	Utime, Stime, CUtime, CStime linuxCoreTimeval32
+	Reg                          linutil.ARMPtraceRegs
+	Fpvalid                      int32
+}
+
+// linuxUserDesc is a copy of the user_desc kernel struct, describing a
+// TLS segment.
+type linuxUserDesc struct {
+	EntryNumber uint32
+	BaseAddr    uint32
+	Limit       uint32
+	Flags       uint32
+}
+
 // LinuxSiginfo is a copy of the
 // siginfo kernel struct.
 type linuxSiginfo struct {
@@ -430,6 +827,7 @@ type linuxNTFileEntry struct {
 	Start   uint64
 	End     uint64
 	FileOfs uint64
+	Name    string
 }
 
 // elfNotesHdr is the ELF Notes header.
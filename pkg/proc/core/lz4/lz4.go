@@ -0,0 +1,329 @@
+// Package lz4 implements a decompressor for the LZ4 frame format, as
+// produced by the lz4 command line tool, described in:
+// https://github.com/lz4/lz4/blob/dev/doc/lz4_Frame_format.md
+//
+// Checksums are not verified and dictionaries are not supported.
+package lz4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	frameMagic         = 0x184D2204
+	legacyFrameMagic   = 0x184C2102
+	skippableMagicMask = 0xFFFFFFF0
+	skippableMagic     = 0x184D2A50
+
+	legacyBlockSize = 8 << 20
+	maxHistory      = 64 << 10
+	minMatch        = 4
+)
+
+var (
+	// ErrCorrupt is returned when the input is not a valid LZ4 stream.
+	ErrCorrupt = errors.New("corrupt lz4 stream")
+)
+
+// Reader implements io.Reader to read a LZ4 compressed stream, the stream
+// can contain multiple frames, skippable frames and frames in the legacy
+// format.
+type Reader struct {
+	r io.Reader
+
+	// frame state
+	inFrame         bool
+	legacy          bool
+	independent     bool
+	blockChecksum   bool
+	contentChecksum bool
+	blockMax        int
+
+	buf  []byte // decompressed data, starting with history for linked blocks
+	off  int    // read offset in buf
+	cbuf []byte // compressed block
+
+	scratch [16]byte
+}
+
+// NewReader creates a new Reader that decompresses data from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Read implements io.Reader.
+func (z *Reader) Read(p []byte) (int, error) {
+	for z.off >= len(z.buf) {
+		if err := z.refill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, z.buf[z.off:])
+	z.off += n
+	return n, nil
+}
+
+// refill decompresses the next block of the stream into z.buf.
+func (z *Reader) refill() error {
+	for {
+		if !z.inFrame {
+			magic, err := z.readUint32()
+			if err != nil {
+				// A clean end of the stream is only possible between frames.
+				return err
+			}
+			if err := z.readFrameHeader(magic); err != nil {
+				return err
+			}
+			continue
+		}
+
+		size, err := z.readUint32()
+		if err != nil {
+			if z.legacy && err == io.EOF {
+				return io.EOF
+			}
+			return unexpectedEOF(err)
+		}
+
+		if z.legacy {
+			if size == legacyFrameMagic {
+				continue
+			}
+			if size == frameMagic || size&skippableMagicMask == skippableMagic {
+				z.inFrame = false
+				if err := z.readFrameHeader(size); err != nil {
+					return err
+				}
+				continue
+			}
+			return z.readBlock(int(size), false)
+		}
+
+		if size == 0 {
+			// EndMark
+			z.inFrame = false
+			if z.contentChecksum {
+				if _, err := io.ReadFull(z.r, z.scratch[:4]); err != nil {
+					return unexpectedEOF(err)
+				}
+			}
+			continue
+		}
+
+		if err := z.readBlock(int(size&0x7fffffff), size&0x80000000 != 0); err != nil {
+			return err
+		}
+		if z.blockChecksum {
+			if _, err := io.ReadFull(z.r, z.scratch[:4]); err != nil {
+				return unexpectedEOF(err)
+			}
+		}
+		return nil
+	}
+}
+
+// readFrameHeader reads the header of a frame, whose magic number has
+// already been read.
+func (z *Reader) readFrameHeader(magic uint32) error {
+	switch {
+	case magic == frameMagic:
+		// ok
+	case magic == legacyFrameMagic:
+		z.inFrame = true
+		z.legacy = true
+		z.independent = true
+		z.blockMax = legacyBlockSize
+		return nil
+	case magic&skippableMagicMask == skippableMagic:
+		size, err := z.readUint32()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if _, err := io.CopyN(ioutil.Discard, z.r, int64(size)); err != nil {
+			return unexpectedEOF(err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%v: unknown magic number %#x", ErrCorrupt, magic)
+	}
+
+	if _, err := io.ReadFull(z.r, z.scratch[:2]); err != nil {
+		return unexpectedEOF(err)
+	}
+	flg, bd := z.scratch[0], z.scratch[1]
+	if flg>>6 != 1 {
+		return fmt.Errorf("%v: unsupported frame version %d", ErrCorrupt, flg>>6)
+	}
+	if flg&0x1 != 0 {
+		return errors.New("lz4 frames with a dictionary are not supported")
+	}
+	z.independent = flg&0x20 != 0
+	z.blockChecksum = flg&0x10 != 0
+	z.contentChecksum = flg&0x04 != 0
+	switch (bd >> 4) & 0x7 {
+	case 4:
+		z.blockMax = 64 << 10
+	case 5:
+		z.blockMax = 256 << 10
+	case 6:
+		z.blockMax = 1 << 20
+	case 7:
+		z.blockMax = 4 << 20
+	default:
+		return fmt.Errorf("%v: invalid block maximum size", ErrCorrupt)
+	}
+
+	// content size, dictionary ID and header checksum
+	skip := 1
+	if flg&0x08 != 0 {
+		skip += 8
+	}
+	if _, err := io.ReadFull(z.r, z.scratch[:skip]); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	z.inFrame = true
+	z.legacy = false
+	z.buf = z.buf[:0]
+	z.off = 0
+	return nil
+}
+
+// readBlock reads a block of size bytes and decompresses it.
+func (z *Reader) readBlock(size int, uncompressed bool) error {
+	if (uncompressed && size > z.blockMax) || size > compressBound(z.blockMax) {
+		return fmt.Errorf("%v: block too big", ErrCorrupt)
+	}
+	if cap(z.cbuf) < size {
+		z.cbuf = make([]byte, size)
+	}
+	z.cbuf = z.cbuf[:size]
+	if _, err := io.ReadFull(z.r, z.cbuf); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	hist := 0
+	if !z.independent {
+		hist = len(z.buf)
+		if hist > maxHistory {
+			hist = maxHistory
+		}
+	}
+	if cap(z.buf) < hist+z.blockMax {
+		buf := make([]byte, hist, hist+z.blockMax)
+		copy(buf, z.buf[len(z.buf)-hist:])
+		z.buf = buf
+	} else {
+		copy(z.buf[:hist], z.buf[len(z.buf)-hist:])
+		z.buf = z.buf[:hist]
+	}
+	z.off = hist
+
+	if uncompressed {
+		z.buf = append(z.buf, z.cbuf...)
+		return nil
+	}
+	var err error
+	z.buf, err = decodeBlock(z.buf, z.cbuf, hist+z.blockMax)
+	return err
+}
+
+func (z *Reader) readUint32() (uint32, error) {
+	if _, err := io.ReadFull(z.r, z.scratch[:4]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(z.scratch[:4]), nil
+}
+
+// compressBound returns the maximum size of a compressed block containing
+// n bytes of data.
+func compressBound(n int) int {
+	return n + n/255 + 16
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// decodeBlock decompresses the LZ4 block src appending it to dst, matches
+// can refer to data already in dst. The length of dst can not exceed max.
+// See https://github.com/lz4/lz4/blob/dev/doc/lz4_Block_format.md
+func decodeBlock(dst, src []byte, max int) ([]byte, error) {
+	i := 0
+	for i < len(src) {
+		token := src[i]
+		i++
+
+		// literals
+		litlen := int(token >> 4)
+		if litlen == 0xf {
+			for {
+				if i >= len(src) {
+					return dst, ErrCorrupt
+				}
+				b := src[i]
+				i++
+				litlen += int(b)
+				if b != 0xff {
+					break
+				}
+			}
+		}
+		if litlen > len(src)-i || litlen > max-len(dst) {
+			return dst, ErrCorrupt
+		}
+		dst = append(dst, src[i:i+litlen]...)
+		i += litlen
+
+		if i == len(src) {
+			// the last sequence only contains literals
+			break
+		}
+
+		// match
+		if i+2 > len(src) {
+			return dst, ErrCorrupt
+		}
+		offset := int(binary.LittleEndian.Uint16(src[i:]))
+		i += 2
+		if offset == 0 || offset > len(dst) {
+			return dst, ErrCorrupt
+		}
+		mlen := int(token & 0xf)
+		if mlen == 0xf {
+			for {
+				if i >= len(src) {
+					return dst, ErrCorrupt
+				}
+				b := src[i]
+				i++
+				mlen += int(b)
+				if b != 0xff {
+					break
+				}
+			}
+		}
+		mlen += minMatch
+		if mlen > max-len(dst) {
+			return dst, ErrCorrupt
+		}
+		start := len(dst) - offset
+		if offset >= mlen {
+			dst = append(dst, dst[start:start+mlen]...)
+		} else {
+			// overlapping match, copy byte by byte
+			for j := 0; j < mlen; j++ {
+				dst = append(dst, dst[start+j])
+			}
+		}
+	}
+	return dst, nil
+}
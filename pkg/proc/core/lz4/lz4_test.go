@@ -0,0 +1,39 @@
+package lz4
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	// literals "abcd", overlapping match of 8 bytes at offset 4, last
+	// sequence with the literal "e"
+	block := []byte{0x44, 'a', 'b', 'c', 'd', 0x04, 0x00, 0x10, 'e'}
+
+	var frame []byte
+	frame = append(frame, 0x04, 0x22, 0x4d, 0x18) // magic
+	frame = append(frame, 0x60, 0x40, 0x00)       // FLG, BD, HC
+	frame = append(frame, byte(len(block)), 0, 0, 0)
+	frame = append(frame, block...)
+	frame = append(frame, 3, 0, 0, 0x80, 'x', 'y', 'z') // uncompressed block
+	frame = append(frame, 0, 0, 0, 0)                   // EndMark
+
+	var in []byte
+	in = append(in, frame...)
+	in = append(in, 0x50, 0x2a, 0x4d, 0x18, 2, 0, 0, 0, 0xff, 0xff) // skippable frame
+	in = append(in, frame...)
+
+	out, err := ioutil.ReadAll(NewReader(bytes.NewReader(in)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "abcdabcdabcdexyzabcdabcdabcdexyz"; string(out) != want {
+		t.Errorf("got %q, expected %q", out, want)
+	}
+
+	_, err = ioutil.ReadAll(NewReader(bytes.NewReader(in[:len(frame)-4])))
+	if err == nil {
+		t.Errorf("no error reading truncated frame")
+	}
+}
@@ -0,0 +1,290 @@
+package core
+
+import (
+	"bufio"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// ErrNoGoroutines is returned by OpenTraceback when the file doesn't
+// contain any goroutine.
+var ErrNoGoroutines = errors.New("no goroutines found in traceback")
+
+// errTracebackMemory is returned when reading the memory of a process
+// opened from a traceback.
+var errTracebackMemory = errors.New("memory is not available for tracebacks")
+
+// tracebackProcess is a process created from the goroutine dump that the
+// Go runtime prints when a program crashes, only stacktraces are
+// available, the memory of the process can not be read.
+type tracebackProcess struct {
+	*process
+	dumped []*proc.DumpedGoroutine
+
+	// gs and frames are the goroutines of the process and their stack
+	// frames, created from dumped once the binary info is loaded.
+	gs     []*proc.G
+	frames map[*proc.G][]proc.Stackframe
+}
+
+var _ proc.GoroutineDump = &tracebackProcess{}
+
+// Goroutines returns the goroutines read from the traceback.
+func (p *tracebackProcess) Goroutines() []*proc.G {
+	if p.gs == nil {
+		p.gs = make([]*proc.G, 0, len(p.dumped))
+		p.frames = make(map[*proc.G][]proc.Stackframe, len(p.dumped))
+		for _, dg := range p.dumped {
+			g, frames := proc.NewDumpedGoroutine(p.bi, p, dg)
+			p.gs = append(p.gs, g)
+			p.frames[g] = frames
+		}
+		if len(p.gs) > 0 {
+			p.gs[0].Thread = p.currentThread
+		}
+	}
+	return p.gs
+}
+
+// GoroutineStacktrace returns the stack frames of g read from the
+// traceback.
+func (p *tracebackProcess) GoroutineStacktrace(g *proc.G) []proc.Stackframe {
+	return p.frames[g]
+}
+
+// ReadMemory always fails, the memory of the process is not available.
+func (p *tracebackProcess) ReadMemory(buf []byte, addr uintptr) (int, error) {
+	return 0, errTracebackMemory
+}
+
+// WriteMemory always fails, the memory of the process is not available.
+func (p *tracebackProcess) WriteMemory(addr uintptr, data []byte) (int, error) {
+	return 0, errTracebackMemory
+}
+
+// OpenTraceback opens the output of a Go program that crashed, for example
+// with GOTRACEBACK=crash or GOTRACEBACK=all, as if it was a core file. The
+// goroutines and their stacktraces are read from the goroutine dump,
+// variables can not be evaluated since their memory is not available.
+func OpenTraceback(tracebackPath, exePath string, debugInfoDirs []string) (*proc.Target, error) {
+	fh, err := os.Open(tracebackPath)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	gs, err := parseTraceback(fh)
+	if err != nil {
+		return nil, err
+	}
+	if len(gs) == 0 {
+		return nil, ErrNoGoroutines
+	}
+
+	bi, entryPoint, err := tracebackBinaryInfo(exePath)
+	if err != nil {
+		return nil, err
+	}
+
+	top := gs[0].Frames
+	var sp uint64
+	if len(top) > 0 {
+		sp = top[0].SP
+	}
+	th := &tracebackThread{id: 1, regs: tracebackRegisters{sp: sp}}
+	p := &tracebackProcess{
+		process: &process{
+			Threads:     map[int]*thread{},
+			entryPoint:  entryPoint,
+			bi:          bi,
+			breakpoints: proc.NewBreakpointMap(),
+		},
+		dumped: gs,
+	}
+	p.mem = p
+	p.Threads[th.id] = &thread{th: th, p: p.process}
+	p.currentThread = p.Threads[th.id]
+
+	t, err := proc.NewTarget(p, proc.NewTargetConfig{
+		Path:                exePath,
+		DebugInfoDirs:       debugInfoDirs,
+		WriteBreakpoint:     p.writeBreakpoint,
+		DisableAsyncPreempt: false,
+		StopReason:          proc.StopAttached})
+	if err != nil {
+		return nil, err
+	}
+	// The registers of the thread can only be computed once the functions
+	// of the executable are known.
+	if g := t.SelectedGoroutine(); g != nil {
+		th.regs.pc = g.CurrentLoc.PC
+	}
+	return t, nil
+}
+
+// tracebackBinaryInfo returns a BinaryInfo object for the architecture
+// of the executable and its entry point.
+func tracebackBinaryInfo(exePath string) (*proc.BinaryInfo, uint64, error) {
+	if f, err := elf.Open(exePath); err == nil {
+		defer f.Close()
+		switch f.Machine {
+		case _EM_X86_64:
+			return proc.NewBinaryInfo("linux", "amd64"), f.Entry, nil
+		case _EM_AARCH64:
+			return proc.NewBinaryInfo("linux", "arm64"), f.Entry, nil
+		case _EM_386:
+			return proc.NewBinaryInfo("linux", "386"), f.Entry, nil
+		case _EM_ARM:
+			return proc.NewBinaryInfo("linux", "arm"), f.Entry, nil
+		}
+		return nil, 0, fmt.Errorf("unsupported machine type %v", f.Machine)
+	}
+	if f, err := pe.Open(exePath); err == nil {
+		defer f.Close()
+		if opth, ok := f.OptionalHeader.(*pe.OptionalHeader64); ok && f.Machine == pe.IMAGE_FILE_MACHINE_AMD64 {
+			return proc.NewBinaryInfo("windows", "amd64"), opth.ImageBase, nil
+		}
+		return nil, 0, fmt.Errorf("unsupported machine type %#x", f.Machine)
+	}
+	if f, err := macho.Open(exePath); err == nil {
+		defer f.Close()
+		if f.Cpu == macho.CpuAmd64 {
+			return proc.NewBinaryInfo("darwin", "amd64"), 0, nil
+		}
+		return nil, 0, fmt.Errorf("unsupported machine type %v", f.Cpu)
+	}
+	return nil, 0, fmt.Errorf("could not open %s: unrecognized executable format", exePath)
+}
+
+// tracebackThread is the thread that was running the goroutine that
+// crashed, its registers are the ones of the topmost frame.
+type tracebackThread struct {
+	id   int
+	regs tracebackRegisters
+}
+
+func (th *tracebackThread) registers() (proc.Registers, error) {
+	return &th.regs, nil
+}
+
+func (th *tracebackThread) pid() int {
+	return th.id
+}
+
+// tracebackRegisters only contains the PC and SP registers.
+type tracebackRegisters struct {
+	pc, sp uint64
+}
+
+func (r *tracebackRegisters) PC() uint64              { return r.pc }
+func (r *tracebackRegisters) SP() uint64              { return r.sp }
+func (r *tracebackRegisters) BP() uint64              { return 0 }
+func (r *tracebackRegisters) TLS() uint64             { return 0 }
+func (r *tracebackRegisters) GAddr() (uint64, bool)   { return 0, false }
+func (r *tracebackRegisters) Get(int) (uint64, error) { return 0, proc.ErrUnknownRegister }
+
+func (r *tracebackRegisters) Slice(floatingPoint bool) ([]proc.Register, error) {
+	var out []proc.Register
+	out = proc.AppendUint64Register(out, "PC", r.pc)
+	out = proc.AppendUint64Register(out, "SP", r.sp)
+	return out, nil
+}
+
+func (r *tracebackRegisters) Copy() (proc.Registers, error) {
+	r2 := *r
+	return &r2, nil
+}
+
+var (
+	tracebackGoroutineRx = regexp.MustCompile(`^goroutine (\d+)(?: gp=\S+)?(?: m=\S+)?(?: mp=\S+)? \[(.*)\]:$`)
+	tracebackPosRx       = regexp.MustCompile(`^\t(.*):(\d+)(?: \+0x([0-9a-f]+))?(?: fp=0x([0-9a-f]+) sp=0x([0-9a-f]+) pc=0x[0-9a-f]+)?$`)
+	tracebackCreatedByRx = regexp.MustCompile(`^created by (\S+)(?: in goroutine \d+)?$`)
+)
+
+// parseTraceback reads the goroutines printed by the Go runtime in r.
+// Lines that are not part of a goroutine dump are ignored, when the same
+// goroutine is printed more than once only the first one is used.
+func parseTraceback(r io.Reader) ([]*proc.DumpedGoroutine, error) {
+	var (
+		gs   []*proc.DumpedGoroutine
+		seen = map[int]bool{}
+		cur  *proc.DumpedGoroutine
+		// fn is the function line waiting for its position line, created is
+		// true if it was a 'created by' line.
+		fn      *proc.DumpedFrame
+		created bool
+	)
+
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+
+		if m := tracebackGoroutineRx.FindStringSubmatch(line); m != nil {
+			id, _ := strconv.Atoi(m[1])
+			cur, fn = nil, nil
+			if id != 0 && !seen[id] {
+				seen[id] = true
+				status := m[2]
+				if i := strings.Index(status, ","); i >= 0 {
+					status = status[:i]
+				}
+				cur = &proc.DumpedGoroutine{ID: id, Status: status}
+				gs = append(gs, cur)
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+
+		switch {
+		case line == "":
+			cur, fn = nil, nil
+		case fn != nil:
+			m := tracebackPosRx.FindStringSubmatch(line)
+			if m == nil {
+				fn = nil
+				continue
+			}
+			fn.File = m[1]
+			fn.Line, _ = strconv.Atoi(m[2])
+			fn.Offset = -1
+			if m[3] != "" {
+				fn.Offset, _ = strconv.ParseInt(m[3], 16, 64)
+			}
+			if m[4] != "" {
+				fn.FP, _ = strconv.ParseUint(m[4], 16, 64)
+				fn.SP, _ = strconv.ParseUint(m[5], 16, 64)
+			}
+			if created {
+				cur.CreatedBy = fn
+			} else {
+				cur.Frames = append(cur.Frames, *fn)
+			}
+			fn = nil
+		case strings.HasPrefix(line, "...") && strings.HasSuffix(line, "elided..."):
+			cur.Elided = true
+		case strings.HasPrefix(line, "created by "):
+			if m := tracebackCreatedByRx.FindStringSubmatch(line); m != nil {
+				fn, created = &proc.DumpedFrame{Function: m[1]}, true
+			}
+		case strings.HasSuffix(line, ")"):
+			i := strings.LastIndex(line, "(")
+			if i <= 0 {
+				continue
+			}
+			fn, created = &proc.DumpedFrame{Function: line[:i], RawArgs: line[i:]}, false
+		}
+	}
+	return gs, s.Err()
+}
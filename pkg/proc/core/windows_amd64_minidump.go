@@ -1,19 +1,27 @@
 package core
 
 import (
+	"io"
+	"io/ioutil"
+	"math"
+
 	"github.com/go-delve/delve/pkg/logflags"
 	"github.com/go-delve/delve/pkg/proc"
 	"github.com/go-delve/delve/pkg/proc/core/minidump"
 	"github.com/go-delve/delve/pkg/proc/winutil"
 )
 
-func readAMD64Minidump(minidumpPath, exePath string) (*process, error) {
+func readAMD64Minidump(core coreReader, exePath, sysroot string) (*process, error) {
 	var logfn func(string, ...interface{})
 	if logflags.Minidump() {
 		logfn = logflags.MinidumpLogger().Infof
 	}
 
-	mdmp, err := minidump.Open(minidumpPath, logfn)
+	rawbuf, err := ioutil.ReadAll(io.NewSectionReader(core, 0, math.MaxInt64))
+	if err != nil {
+		return nil, err
+	}
+	mdmp, err := minidump.Read(rawbuf, logfn)
 	if err != nil {
 		if _, isNotAMinidump := err.(minidump.ErrNotAMinidump); isNotAMinidump {
 			return nil, ErrUnrecognizedFormat
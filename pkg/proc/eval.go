@@ -2,13 +2,13 @@ package proc
 
 import (
 	"bytes"
+	"context"
 	"debug/dwarf"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"go/ast"
 	"go/constant"
-	"go/parser"
 	"go/printer"
 	"go/scanner"
 	"go/token"
@@ -51,6 +51,21 @@ type EvalScope struct {
 	// The goroutine executing the expression evaluation shall signal that the
 	// evaluation is complete by closing the continueRequest channel.
 	callCtx *callContext
+
+	// ctx, if not nil, interrupts the evaluation when it is done, see
+	// WithContext.
+	ctx context.Context
+}
+
+// WithContext returns a copy of scope whose evaluations are interrupted
+// when ctx is done: reading memory fails and the evaluation returns
+// ctx.Err(). Loading large values, for example big maps, can take a long
+// time.
+func (scope *EvalScope) WithContext(ctx context.Context) *EvalScope {
+	r := *scope
+	r.Mem = &contextMemory{MemoryReadWriter: scope.Mem, ctx: ctx}
+	r.ctx = ctx
+	return &r
 }
 
 // ConvertEvalScope returns a new EvalScope in the context of the
@@ -161,14 +176,35 @@ func GoroutineScope(thread Thread) (*EvalScope, error) {
 
 // EvalExpression returns the value of the given expression.
 func (scope *EvalScope) EvalExpression(expr string, cfg LoadConfig) (*Variable, error) {
+	return scope.evalExpression(expr, cfg, func(v *Variable) error {
+		v.loadValue(cfg)
+		return nil
+	})
+}
+
+// EvalExpressionRange returns the value of the given expression, loading
+// at most count of its children starting at index start, see
+// (*Variable).LoadChildren.
+func (scope *EvalScope) EvalExpressionRange(expr string, start, count int, cfg LoadConfig) (*Variable, error) {
+	return scope.evalExpression(expr, cfg, func(v *Variable) error {
+		err := v.LoadChildren(start, count, cfg)
+		if v.Unreadable != nil {
+			// reported in v.Unreadable, like EvalExpression does
+			return nil
+		}
+		return err
+	})
+}
+
+func (scope *EvalScope) evalExpression(expr string, cfg LoadConfig, load func(*Variable) error) (*Variable, error) {
 	if scope.callCtx != nil {
 		// makes sure that the other goroutine won't wait forever if we make a mistake
 		defer close(scope.callCtx.continueRequest)
 	}
-	t, err := parser.ParseExpr(expr)
+	src, t, err := parseExpr(expr)
 	if eqOff, isAs := isAssignment(err); scope.callCtx != nil && isAs {
-		lexpr := expr[:eqOff]
-		rexpr := expr[eqOff+1:]
+		lexpr := src[:eqOff]
+		rexpr := src[eqOff+1:]
 		err := scope.SetVariable(lexpr, rexpr)
 		scope.callCtx.doReturn(nil, err)
 		return nil, err
@@ -182,11 +218,18 @@ func (scope *EvalScope) EvalExpression(expr string, cfg LoadConfig) (*Variable,
 	if ev == nil && err == nil {
 		ev, err = scope.evalAST(t)
 	}
+	if err == nil {
+		err = load(ev)
+	}
+	if scope.ctx != nil && scope.ctx.Err() != nil {
+		// the evaluation was interrupted, the errors it returned, or stored
+		// in the value, were caused by the failed memory reads.
+		err = scope.ctx.Err()
+	}
 	if err != nil {
 		scope.callCtx.doReturn(nil, err)
 		return nil, err
 	}
-	ev.loadValue(cfg)
 	if ev.Name == "" {
 		ev.Name = expr
 	}
@@ -318,6 +361,10 @@ func (scope *EvalScope) setValue(dstv, srcv *Variable, srcExpr string) error {
 		return fmt.Errorf("Expression \"%s\" is unreadable: %v", srcExpr, srcv.Unreadable)
 	}
 
+	if dstv.bitSize > 0 {
+		return setBitField(dstv, srcv)
+	}
+
 	// Numerical types
 	switch dstv.Kind {
 	case reflect.Float32, reflect.Float64:
@@ -368,14 +415,76 @@ func (scope *EvalScope) setValue(dstv, srcv *Variable, srcExpr string) error {
 	return fmt.Errorf("can not set variables of type %s (not implemented)", dstv.Kind.String())
 }
 
+// setBitField writes the value of srcv to the bit field dstv. The value
+// must be an integer that fits in the bit field, or a boolean for boolean
+// bit fields.
+func setBitField(dstv, srcv *Variable) error {
+	converr := fmt.Errorf("can not convert %s constant to %s", srcv.Value, dstv.RealType.String())
+	if dstv.Kind == reflect.Bool {
+		if srcv.Value == nil || srcv.Value.Kind() != constant.Bool {
+			return converr
+		}
+		if constant.BoolVal(srcv.Value) {
+			return dstv.writeBitField(1)
+		}
+		return dstv.writeBitField(0)
+	}
+
+	if srcv.Value == nil || srcv.Value.Kind() != constant.Int {
+		return converr
+	}
+	var min, max constant.Value // max is excluded
+	switch dstv.Kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		max = constant.Shift(constant.MakeInt64(1), token.SHL, uint(dstv.bitSize-1))
+		min = constant.UnaryOp(token.SUB, max, 0)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		max = constant.Shift(constant.MakeInt64(1), token.SHL, uint(dstv.bitSize))
+		min = constant.MakeInt64(0)
+	default:
+		return fmt.Errorf("can not set bit fields of type %s (not implemented)", dstv.Kind.String())
+	}
+	if constant.Compare(srcv.Value, token.LSS, min) || !constant.Compare(srcv.Value, token.LSS, max) {
+		return fmt.Errorf("constant %s overflows bit field of %d bits", srcv.Value, dstv.bitSize)
+	}
+	if constant.Sign(min) < 0 {
+		n, _ := constant.Int64Val(srcv.Value)
+		return dstv.writeBitField(uint64(n))
+	}
+	n, _ := constant.Uint64Val(srcv.Value)
+	return dstv.writeBitField(n)
+}
+
 // EvalVariable returns the value of the given expression (backwards compatibility).
 func (scope *EvalScope) EvalVariable(name string, cfg LoadConfig) (*Variable, error) {
 	return scope.EvalExpression(name, cfg)
 }
 
+// VariableAt returns the variable stored at addr whose type is the DWARF
+// type at typeOffset in the image with index imageIndex, without loading
+// its value.
+// Together addr, imageIndex and typeOffset identify a variable for as long
+// as its memory isn't freed, they can be used to load the children of a
+// variable without evaluating an expression, which is not always possible
+// (for example for map values with keys that can not be written in Go
+// syntax).
+func (scope *EvalScope) VariableAt(addr uintptr, imageIndex int, typeOffset dwarf.Offset) (*Variable, error) {
+	if addr == 0 {
+		return nil, errors.New("invalid address 0")
+	}
+	if imageIndex < 0 || imageIndex >= len(scope.BinInfo.Images) {
+		return nil, fmt.Errorf("unknown image %d", imageIndex)
+	}
+	typ, err := scope.BinInfo.Images[imageIndex].Type(typeOffset)
+	if err != nil {
+		return nil, err
+	}
+	return newVariable("", addr, typ, scope.BinInfo, scope.Mem), nil
+}
+
 // SetVariable sets the value of the named variable
 func (scope *EvalScope) SetVariable(name, value string) error {
-	t, err := parser.ParseExpr(name)
+	_, t, err := parseExpr(name)
 	if err != nil {
 		return err
 	}
@@ -393,7 +502,7 @@ func (scope *EvalScope) SetVariable(name, value string) error {
 		return fmt.Errorf("Expression \"%s\" is unreadable: %v", name, xv.Unreadable)
 	}
 
-	t, err = parser.ParseExpr(value)
+	_, t, err = parseExpr(value)
 	if err != nil {
 		return err
 	}
@@ -826,21 +935,23 @@ func (scope *EvalScope) evalTypeCast(node *ast.CallExpr) (*Variable, error) {
 
 	switch ttyp := typ.(type) {
 	case *godwarf.PtrType:
+		var n int64
 		switch argv.Kind {
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			// ok
+			n, _ = constant.Int64Val(argv.Value)
 		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			// ok
+			n, _ = constant.Int64Val(argv.Value)
+		case reflect.Ptr, reflect.UnsafePointer:
+			// conversions between pointer types are allowed, like in C
+			n = int64(argv.Children[0].Addr)
 		default:
 			return nil, converr
 		}
 
-		n, _ := constant.Int64Val(argv.Value)
-
 		v.Children = []Variable{*(newVariable("", uintptr(n), ttyp.Type, scope.BinInfo, scope.Mem))}
 		return v, nil
 
-	case *godwarf.UintType:
+	case *godwarf.UintType, *godwarf.UcharType:
 		switch argv.Kind {
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 			n, _ := constant.Int64Val(argv.Value)
@@ -858,7 +969,7 @@ func (scope *EvalScope) evalTypeCast(node *ast.CallExpr) (*Variable, error) {
 			v.Value = constant.MakeUint64(uint64(argv.Children[0].Addr))
 			return v, nil
 		}
-	case *godwarf.IntType:
+	case *godwarf.IntType, *godwarf.CharType:
 		switch argv.Kind {
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 			n, _ := constant.Int64Val(argv.Value)
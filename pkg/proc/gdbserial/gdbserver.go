@@ -58,11 +58,17 @@
 // unavailable but the inferior is run in single threaded mode.
 //
 // Therefore the following code will assume lldb-server-like behavior.
+//
+// Some stubs can not single step threads (they do not list the 's' action
+// in their reply to vCont?), for those single stepping is emulated by
+// setting temporary breakpoints on the possible destinations of the current
+// instruction, see gdbserver_step.go.
 
 package gdbserial
 
 import (
 	"bytes"
+	"debug/elf"
 	"debug/macho"
 	"encoding/binary"
 	"errors"
@@ -76,6 +82,8 @@ import (
 	"strings"
 	"time"
 
+	"golang.org/x/arch/arm/armasm"
+	"golang.org/x/arch/arm64/arm64asm"
 	"golang.org/x/arch/x86/x86asm"
 
 	"github.com/go-delve/delve/pkg/logflags"
@@ -160,6 +168,7 @@ func (err *ErrBackendUnavailable) Error() string {
 type gdbRegisters struct {
 	regs     map[string]gdbRegister
 	regsInfo []gdbRegisterInfo
+	regnames *gdbRegnames
 	tls      uint64
 	gaddr    uint64
 	hasgaddr bool
@@ -179,10 +188,12 @@ func newProcess(process *os.Process) *gdbProcess {
 	logger := logflags.GdbWireLogger()
 	p := &gdbProcess{
 		conn: gdbConn{
+			stdout:              os.Stdout,
 			maxTransmitAttempts: maxTransmitAttempts,
 			inbuf:               make([]byte, 0, initialInputBufferSize),
 			direction:           proc.Forward,
 			log:                 logger,
+			regnames:            newGdbRegnames(runtime.GOARCH),
 		},
 		threads:        make(map[int]*gdbThread),
 		bi:             proc.NewBinaryInfo(runtime.GOOS, runtime.GOARCH),
@@ -250,6 +261,14 @@ func (p *gdbProcess) Dial(addr string, path string, pid int, debugInfoDirs []str
 func (p *gdbProcess) Connect(conn net.Conn, path string, pid int, debugInfoDirs []string, stopReason proc.StopReason) (*proc.Target, error) {
 	p.conn.conn = conn
 	p.conn.pid = pid
+	if path != "" {
+		// The registers described by the stub during the handshake are
+		// interpreted according to the architecture of the target.
+		if err := p.selectArch(path); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
 	err := p.conn.handshake()
 	if err != nil {
 		conn.Close()
@@ -281,9 +300,12 @@ func (p *gdbProcess) Connect(conn net.Conn, path string, pid int, debugInfoDirs
 	// store the MOV instruction.
 	// If the stub doesn't support memory allocation reloadRegisters will
 	// overwrite some existing memory to store the MOV.
-	if addr, err := p.conn.allocMemory(256); err == nil {
-		if _, err := p.conn.writeMemory(uintptr(addr), p.loadGInstr()); err == nil {
-			p.loadGInstrAddr = addr
+	// This isn't needed on architectures that keep G in a register.
+	if p.conn.regnames.G == "" {
+		if addr, err := p.conn.allocMemory(256); err == nil {
+			if _, err := p.conn.writeMemory(uintptr(addr), p.loadGInstr()); err == nil {
+				p.loadGInstrAddr = addr
+			}
 		}
 	}
 
@@ -343,7 +365,10 @@ func getLdEnvVars() []string {
 // LLDBLaunch starts an instance of lldb-server and connects to it, asking
 // it to launch the specified target program with the specified arguments
 // (cmd) on the specified directory wd.
-func LLDBLaunch(cmd []string, wd string, foreground bool, debugInfoDirs []string, tty string) (*proc.Target, error) {
+// The output of the target is written to the writers in redirect, either
+// because it is inherited from the stub or because the stub forwards it to
+// us, in the latter case standard error is merged into standard output.
+func LLDBLaunch(cmd []string, wd string, foreground bool, debugInfoDirs []string, tty string, redirect proc.OutputRedirect) (*proc.Target, error) {
 	if runtime.GOOS == "windows" {
 		return nil, ErrUnsupportedOS
 	}
@@ -402,6 +427,9 @@ func LLDBLaunch(cmd []string, wd string, foreground bool, debugInfoDirs []string
 	if logflags.LLDBServerOutput() || logflags.GdbWire() || foreground {
 		process.Stdout = os.Stdout
 		process.Stderr = os.Stderr
+	} else {
+		process.Stdout = redirect.Stdout
+		process.Stderr = redirect.Stderr
 	}
 	if foreground {
 		foregroundSignalsIgnore()
@@ -423,6 +451,9 @@ func LLDBLaunch(cmd []string, wd string, foreground bool, debugInfoDirs []string
 
 	p := newProcess(process.Process)
 	p.conn.isDebugserver = isDebugserver
+	if redirect.Stdout != nil {
+		p.conn.stdout = redirect.Stdout
+	}
 
 	var tgt *proc.Target
 	if listener != nil {
@@ -485,6 +516,29 @@ func LLDBAttach(pid int, path string, debugInfoDirs []string) (*proc.Target, err
 	return tgt, err
 }
 
+// ConnectRemote connects to a stub already debugging a process listening
+// on addr, for example gdbserver, qemu-user started with -g or lldb-server.
+// Path is the path to the executable of the target program, it is
+// optional for stubs that can report it.
+// Stubs that do not implement qThreadStopInfo, like gdbserver and
+// qemu-user, only report the stop reason of one thread: the other threads
+// are considered stopped at a breakpoint when their PC is the address of
+// one. Stubs that can not single step threads are supported by emulating
+// single step with temporary breakpoints, for all the architectures
+// supported by proc.
+func ConnectRemote(addr string, path string, debugInfoDirs []string) (*proc.Target, error) {
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	p := newProcess(nil)
+	tgt, err := p.Connect(conn, path, 0, debugInfoDirs, proc.StopAttached)
+	if err != nil && path == "" {
+		return nil, fmt.Errorf("%v (the stub may not be able to report the path of the executable, try specifying it)", err)
+	}
+	return tgt, err
+}
+
 // EntryPoint will return the process entry point address, useful for
 // debugging PIEs.
 func (p *gdbProcess) EntryPoint() (uint64, error) {
@@ -536,6 +590,15 @@ func (p *gdbProcess) initialize(path string, debugInfoDirs []string, stopReason
 		}
 	}
 
+	if err := p.selectArch(path); err != nil {
+		p.conn.conn.Close()
+		return nil, err
+	}
+	if err := p.conn.checkRegisters(); err != nil {
+		p.conn.conn.Close()
+		return nil, err
+	}
+
 	err = p.updateThreadList(&threadUpdater{p: p})
 	if err != nil {
 		p.conn.conn.Close()
@@ -562,9 +625,60 @@ func (p *gdbProcess) initialize(path string, debugInfoDirs []string, stopReason
 		p.conn.conn.Close()
 		return nil, err
 	}
+	if p.BinInfo().GOOS == "linux" {
+		if err := linutil.ElfUpdateSharedObjects(p); err != nil {
+			p.conn.conn.Close()
+			return nil, err
+		}
+	}
 	return tgt, nil
 }
 
+// selectArch replaces the BinaryInfo of p with one for the operating system
+// and architecture of the executable at path, a remote stub can be
+// debugging a program for a different operating system or architecture,
+// for example qemu-user.
+func (p *gdbProcess) selectArch(path string) error {
+	goos, goarch, ok := elfArch(path)
+	if !ok || (goos == p.bi.GOOS && goarch == p.bi.Arch.Name) {
+		return nil
+	}
+	if goos != "linux" && goarch != "amd64" {
+		return fmt.Errorf("can not debug %s: %s/%s executables are not supported", path, goos, goarch)
+	}
+	p.bi = proc.NewBinaryInfo(goos, goarch)
+	p.conn.regnames = newGdbRegnames(goarch)
+	return nil
+}
+
+// elfArch returns the operating system and architecture of the ELF
+// executable at path, ok is false if path isn't an ELF file of a supported
+// architecture.
+func elfArch(path string) (goos, goarch string, ok bool) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return "", "", false
+	}
+	defer f.Close()
+	switch f.Machine {
+	case elf.EM_X86_64:
+		goarch = "amd64"
+	case elf.EM_386:
+		goarch = "386"
+	case elf.EM_AARCH64:
+		goarch = "arm64"
+	case elf.EM_ARM:
+		goarch = "arm"
+	default:
+		return "", "", false
+	}
+	goos = "linux"
+	if f.OSABI == elf.ELFOSABI_FREEBSD {
+		goos = "freebsd"
+	}
+	return goos, goarch, true
+}
+
 func queryProcessInfo(p *gdbProcess, pid int) (int, string, error) {
 	pi, err := p.conn.queryProcessInfo(pid)
 	if err != nil {
@@ -953,6 +1067,59 @@ func (p *gdbProcess) Restart(pos string) error {
 	return p.setCurrentBreakpoints()
 }
 
+const whenTicksPrefix = "Current tick: "
+
+// WhenTicks executes the 'when-ticks' command for the Mozilla RR backend.
+// This command returns the number of ticks of the current thread, which
+// identify a position in the recording more precisely than event numbers.
+func (p *gdbProcess) WhenTicks() (uint64, error) {
+	if p.tracedir == "" {
+		return 0, proc.ErrNotRecorded
+	}
+	resp, err := p.conn.qRRCmd("when-ticks")
+	if err != nil {
+		return 0, err
+	}
+	resp = strings.TrimSpace(resp)
+	if !strings.HasPrefix(resp, whenTicksPrefix) {
+		return 0, fmt.Errorf("can not parse when-ticks response %q", resp)
+	}
+	return strconv.ParseUint(resp[len(whenTicksPrefix):], 10, 64)
+}
+
+// SeekTicks executes the 'seek-ticks' command for the Mozilla RR backend,
+// which moves the recording position to the given number of ticks of the
+// current thread.
+func (p *gdbProcess) SeekTicks(ticks uint64) error {
+	if p.tracedir == "" {
+		return proc.ErrNotRecorded
+	}
+	p.exited = false
+	for _, th := range p.threads {
+		th.clearBreakpointState()
+	}
+	if _, err := p.conn.qRRCmd("seek-ticks", strconv.FormatUint(ticks, 10)); err != nil {
+		return err
+	}
+	if err := p.updateThreadList(&threadUpdater{p: p}); err != nil {
+		return err
+	}
+	p.clearThreadSignals()
+	p.clearThreadRegisters()
+	if err := p.setCurrentBreakpoints(); err != nil {
+		return err
+	}
+	cur, err := p.WhenTicks()
+	if err != nil {
+		return err
+	}
+	if cur != ticks {
+		// older versions of rr do not support seek-ticks
+		return fmt.Errorf("could not seek to tick %d, current tick is %d", ticks, cur)
+	}
+	return nil
+}
+
 // When executes the 'when' command for the Mozilla RR backend.
 // This command will return rr's internal event number.
 func (p *gdbProcess) When() (string, error) {
@@ -1290,7 +1457,7 @@ func (t *gdbThread) Location() (*proc.Location, error) {
 	if err != nil {
 		return nil, err
 	}
-	if pcreg, ok := regs.(*gdbRegisters).regs[regnamePC]; !ok {
+	if pcreg, ok := regs.(*gdbRegisters).regs[t.p.conn.regnames.PC]; !ok {
 		t.p.conn.log.Errorf("thread %d could not find RIP register", t.ID)
 	} else if len(pcreg.value) < t.p.bi.Arch.PtrSize() {
 		t.p.conn.log.Errorf("thread %d bad length for RIP register: %d", t.ID, len(pcreg.value))
@@ -1348,7 +1515,16 @@ func (t *gdbThread) stepInstruction(tu *threadUpdater) error {
 	// Reset thread registers so the next call to
 	// Thread.Registers will not be cached.
 	t.regs.regs = nil
-	return t.p.conn.step(t.strID, tu, false)
+	return t.step(tu, false)
+}
+
+// step executes exactly one instruction of the thread, using the stub's
+// single step when it is available or emulating it otherwise.
+func (t *gdbThread) step(tu *threadUpdater, ignoreFaultSignal bool) error {
+	if !t.p.conn.swSingleStep || t.p.conn.direction != proc.Forward {
+		return t.p.conn.step(t.strID, tu, ignoreFaultSignal)
+	}
+	return t.softwareStep(tu, ignoreFaultSignal)
 }
 
 // StepInstruction will step exactly 1 CPU instruction.
@@ -1387,11 +1563,14 @@ func (t *gdbThread) Blocked() bool {
 // inferior's thread.
 func (p *gdbProcess) loadGInstr() []byte {
 	var op []byte
-	switch p.bi.GOOS {
-	case "windows", "darwin", "freebsd":
+	switch {
+	case p.bi.Arch.Name == "386":
+		// mov ecx, DWORD PTR gs:{uint32(off)}
+		op = []byte{0x65, 0x8b, 0x0d}
+	case p.bi.GOOS == "windows", p.bi.GOOS == "darwin", p.bi.GOOS == "freebsd":
 		// mov rcx, QWORD PTR gs:{uint32(off)}
 		op = []byte{0x65, 0x48, 0x8b, 0x0c, 0x25}
-	case "linux":
+	case p.bi.GOOS == "linux":
 		// mov rcx,QWORD PTR fs:{uint32(off)}
 		op = []byte{0x64, 0x48, 0x8B, 0x0C, 0x25}
 	default:
@@ -1403,9 +1582,10 @@ func (p *gdbProcess) loadGInstr() []byte {
 	return buf.Bytes()
 }
 
-func (regs *gdbRegisters) init(regsInfo []gdbRegisterInfo) {
+func (regs *gdbRegisters) init(regsInfo []gdbRegisterInfo, regnames *gdbRegnames) {
 	regs.regs = make(map[string]gdbRegister)
 	regs.regsInfo = regsInfo
+	regs.regnames = regnames
 
 	regsz := 0
 	for _, reginfo := range regsInfo {
@@ -1425,29 +1605,23 @@ func (regs *gdbRegisters) init(regsInfo []gdbRegisterInfo) {
 // the stub can allocate memory, or reloadGAtPC, if the stub can't.
 func (t *gdbThread) reloadRegisters() error {
 	if t.regs.regs == nil {
-		t.regs.init(t.p.conn.regsInfo)
+		t.regs.init(t.p.conn.regsInfo, t.p.conn.regnames)
 	}
 
-	if t.p.gcmdok {
-		if err := t.p.conn.readRegisters(t.strID, t.regs.buf); err != nil {
-			if isProtocolErrorUnsupported(err) {
-				t.p.gcmdok = false
-			} else {
-				return err
-			}
-		}
+	if err := t.readRegistersInto(&t.regs); err != nil {
+		return err
 	}
-	if !t.p.gcmdok {
-		for _, reginfo := range t.p.conn.regsInfo {
-			if err := t.p.conn.readRegister(t.strID, reginfo.Regnum, t.regs.regs[reginfo.Name].value); err != nil {
-				return err
-			}
-		}
+
+	if regname := t.p.conn.regnames.G; regname != "" {
+		t.regs.tls = 0
+		t.regs.gaddr = t.regs.byName(regname)
+		t.regs.hasgaddr = true
+		return nil
 	}
 
 	switch t.p.bi.GOOS {
 	case "linux":
-		if reg, hasFsBase := t.regs.regs[regnameFsBase]; hasFsBase {
+		if reg, hasFsBase := t.regs.regs[t.p.conn.regnames.FsBase]; hasFsBase {
 			t.regs.gaddr = 0
 			t.regs.tls = binary.LittleEndian.Uint64(reg.value)
 			t.regs.hasgaddr = false
@@ -1461,6 +1635,28 @@ func (t *gdbThread) reloadRegisters() error {
 	return t.reloadGAtPC()
 }
 
+// readRegistersInto reads the value of all the registers of the thread
+// into regs, which must have been initialized.
+func (t *gdbThread) readRegistersInto(regs *gdbRegisters) error {
+	if t.p.gcmdok {
+		if err := t.p.conn.readRegisters(t.strID, regs.buf); err != nil {
+			if isProtocolErrorUnsupported(err) {
+				t.p.gcmdok = false
+			} else {
+				return err
+			}
+		}
+	}
+	if !t.p.gcmdok {
+		for _, reginfo := range t.p.conn.regsInfo {
+			if err := t.p.conn.readRegister(t.strID, reginfo.Regnum, regs.regs[reginfo.Name].value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (t *gdbThread) writeSomeRegisters(regNames ...string) error {
 	if t.p.gcmdok {
 		return t.p.conn.writeRegisters(t.strID, t.regs.buf)
@@ -1549,13 +1745,13 @@ func (t *gdbThread) reloadGAtPC() error {
 		}
 		t.regs.setPC(pc)
 		t.regs.setCX(cx)
-		err1 := t.writeSomeRegisters(regnamePC, regnameCX)
+		err1 := t.writeSomeRegisters(t.p.conn.regnames.PC, t.p.conn.regnames.CX)
 		if err == nil {
 			err = err1
 		}
 	}()
 
-	err = t.p.conn.step(t.strID, nil, true)
+	err = t.step(nil, true)
 	if err != nil {
 		if err == threadBlockedError {
 			t.regs.tls = 0
@@ -1566,7 +1762,7 @@ func (t *gdbThread) reloadGAtPC() error {
 		return err
 	}
 
-	if err := t.readSomeRegisters(regnamePC, regnameCX); err != nil {
+	if err := t.readSomeRegisters(t.p.conn.regnames.PC, t.p.conn.regnames.CX); err != nil {
 		return err
 	}
 
@@ -1593,7 +1789,7 @@ func (t *gdbThread) reloadGAlloc() error {
 	pc := t.regs.PC()
 
 	t.regs.setPC(t.p.loadGInstrAddr)
-	if err := t.writeSomeRegisters(regnamePC); err != nil {
+	if err := t.writeSomeRegisters(t.p.conn.regnames.PC); err != nil {
 		return err
 	}
 
@@ -1602,13 +1798,13 @@ func (t *gdbThread) reloadGAlloc() error {
 	defer func() {
 		t.regs.setPC(pc)
 		t.regs.setCX(cx)
-		err1 := t.writeSomeRegisters(regnamePC, regnameCX)
+		err1 := t.writeSomeRegisters(t.p.conn.regnames.PC, t.p.conn.regnames.CX)
 		if err == nil {
 			err = err1
 		}
 	}()
 
-	err = t.p.conn.step(t.strID, nil, true)
+	err = t.step(nil, true)
 	if err != nil {
 		if err == threadBlockedError {
 			t.regs.tls = 0
@@ -1619,7 +1815,7 @@ func (t *gdbThread) reloadGAlloc() error {
 		return err
 	}
 
-	if err := t.readSomeRegisters(regnameCX); err != nil {
+	if err := t.readSomeRegisters(t.p.conn.regnames.CX); err != nil {
 		return err
 	}
 
@@ -1662,34 +1858,34 @@ func (t *gdbThread) SetCurrentBreakpoint(adjustPC bool) error {
 }
 
 func (regs *gdbRegisters) PC() uint64 {
-	return binary.LittleEndian.Uint64(regs.regs[regnamePC].value)
+	return regs.byName(regs.regnames.PC)
 }
 
 func (regs *gdbRegisters) setPC(value uint64) {
-	binary.LittleEndian.PutUint64(regs.regs[regnamePC].value, value)
+	regs.setByName(regs.regnames.PC, value)
 }
 
 func (regs *gdbRegisters) SP() uint64 {
-	return binary.LittleEndian.Uint64(regs.regs[regnameSP].value)
+	return regs.byName(regs.regnames.SP)
 }
 func (regs *gdbRegisters) setSP(value uint64) {
-	binary.LittleEndian.PutUint64(regs.regs[regnameSP].value, value)
+	regs.setByName(regs.regnames.SP, value)
 }
 
 func (regs *gdbRegisters) setDX(value uint64) {
-	binary.LittleEndian.PutUint64(regs.regs[regnameDX].value, value)
+	regs.setByName(regs.regnames.DX, value)
 }
 
 func (regs *gdbRegisters) BP() uint64 {
-	return binary.LittleEndian.Uint64(regs.regs[regnameBP].value)
+	return regs.byName(regs.regnames.BP)
 }
 
 func (regs *gdbRegisters) CX() uint64 {
-	return binary.LittleEndian.Uint64(regs.regs[regnameCX].value)
+	return regs.byName(regs.regnames.CX)
 }
 
 func (regs *gdbRegisters) setCX(value uint64) {
-	binary.LittleEndian.PutUint64(regs.regs[regnameCX].value, value)
+	regs.setByName(regs.regnames.CX, value)
 }
 
 func (regs *gdbRegisters) TLS() uint64 {
@@ -1700,166 +1896,213 @@ func (regs *gdbRegisters) GAddr() (uint64, bool) {
 	return regs.gaddr, regs.hasgaddr
 }
 
+// byName returns the value of the register called name, registers smaller
+// than 64 bits are zero extended.
 func (regs *gdbRegisters) byName(name string) uint64 {
 	reg, ok := regs.regs[name]
 	if !ok {
 		return 0
 	}
-	return binary.LittleEndian.Uint64(reg.value)
+	var buf [8]byte
+	copy(buf[:], reg.value)
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+// setByName sets the value of the register called name, truncating value
+// for registers smaller than 64 bits.
+func (regs *gdbRegisters) setByName(name string, value uint64) {
+	reg, ok := regs.regs[name]
+	if !ok {
+		return
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], value)
+	copy(reg.value, buf[:])
+}
+
+// x86Reg returns the value of the register called name on amd64, or of
+// the 32 bit register it extends on 386.
+func (regs *gdbRegisters) x86Reg(name string) uint64 {
+	if regs.regnames.arch == "386" {
+		name = "e" + name[1:]
+	}
+	return regs.byName(name)
 }
 
 func (regs *gdbRegisters) Get(n int) (uint64, error) {
+	switch regs.regnames.arch {
+	case "arm64":
+		reg := arm64asm.Reg(n)
+		if reg >= arm64asm.X0 && reg <= arm64asm.X30 {
+			return regs.byName(fmt.Sprintf("x%d", reg-arm64asm.X0)), nil
+		}
+		return 0, proc.ErrUnknownRegister
+	case "arm":
+		reg := armasm.Reg(n)
+		switch {
+		case reg == armasm.SP:
+			return regs.byName("sp"), nil
+		case reg == armasm.LR:
+			return regs.byName("lr"), nil
+		case reg == armasm.PC:
+			return regs.byName("pc"), nil
+		case reg >= armasm.R0 && reg <= armasm.R12:
+			return regs.byName(fmt.Sprintf("r%d", reg-armasm.R0)), nil
+		}
+		return 0, proc.ErrUnknownRegister
+	}
+
 	reg := x86asm.Reg(n)
 	const (
-		mask8  = 0x000f
-		mask16 = 0x00ff
-		mask32 = 0xffff
+		mask8  = 0xff
+		mask16 = 0xffff
+		mask32 = 0xffffffff
 	)
 
 	switch reg {
 	// 8-bit
 	case x86asm.AL:
-		return regs.byName("rax") & mask8, nil
+		return regs.x86Reg("rax") & mask8, nil
 	case x86asm.CL:
-		return regs.byName("rcx") & mask8, nil
+		return regs.x86Reg("rcx") & mask8, nil
 	case x86asm.DL:
-		return regs.byName("rdx") & mask8, nil
+		return regs.x86Reg("rdx") & mask8, nil
 	case x86asm.BL:
-		return regs.byName("rbx") & mask8, nil
+		return regs.x86Reg("rbx") & mask8, nil
 	case x86asm.AH:
-		return (regs.byName("rax") >> 8) & mask8, nil
+		return (regs.x86Reg("rax") >> 8) & mask8, nil
 	case x86asm.CH:
-		return (regs.byName("rcx") >> 8) & mask8, nil
+		return (regs.x86Reg("rcx") >> 8) & mask8, nil
 	case x86asm.DH:
-		return (regs.byName("rdx") >> 8) & mask8, nil
+		return (regs.x86Reg("rdx") >> 8) & mask8, nil
 	case x86asm.BH:
-		return (regs.byName("rbx") >> 8) & mask8, nil
+		return (regs.x86Reg("rbx") >> 8) & mask8, nil
 	case x86asm.SPB:
-		return regs.byName("rsp") & mask8, nil
+		return regs.x86Reg("rsp") & mask8, nil
 	case x86asm.BPB:
-		return regs.byName("rbp") & mask8, nil
+		return regs.x86Reg("rbp") & mask8, nil
 	case x86asm.SIB:
-		return regs.byName("rsi") & mask8, nil
+		return regs.x86Reg("rsi") & mask8, nil
 	case x86asm.DIB:
-		return regs.byName("rdi") & mask8, nil
+		return regs.x86Reg("rdi") & mask8, nil
 	case x86asm.R8B:
-		return regs.byName("r8") & mask8, nil
+		return regs.x86Reg("r8") & mask8, nil
 	case x86asm.R9B:
-		return regs.byName("r9") & mask8, nil
+		return regs.x86Reg("r9") & mask8, nil
 	case x86asm.R10B:
-		return regs.byName("r10") & mask8, nil
+		return regs.x86Reg("r10") & mask8, nil
 	case x86asm.R11B:
-		return regs.byName("r11") & mask8, nil
+		return regs.x86Reg("r11") & mask8, nil
 	case x86asm.R12B:
-		return regs.byName("r12") & mask8, nil
+		return regs.x86Reg("r12") & mask8, nil
 	case x86asm.R13B:
-		return regs.byName("r13") & mask8, nil
+		return regs.x86Reg("r13") & mask8, nil
 	case x86asm.R14B:
-		return regs.byName("r14") & mask8, nil
+		return regs.x86Reg("r14") & mask8, nil
 	case x86asm.R15B:
-		return regs.byName("r15") & mask8, nil
+		return regs.x86Reg("r15") & mask8, nil
 
 	// 16-bit
 	case x86asm.AX:
-		return regs.byName("rax") & mask16, nil
+		return regs.x86Reg("rax") & mask16, nil
 	case x86asm.CX:
-		return regs.byName("rcx") & mask16, nil
+		return regs.x86Reg("rcx") & mask16, nil
 	case x86asm.DX:
-		return regs.byName("rdx") & mask16, nil
+		return regs.x86Reg("rdx") & mask16, nil
 	case x86asm.BX:
-		return regs.byName("rbx") & mask16, nil
+		return regs.x86Reg("rbx") & mask16, nil
 	case x86asm.SP:
-		return regs.byName("rsp") & mask16, nil
+		return regs.x86Reg("rsp") & mask16, nil
 	case x86asm.BP:
-		return regs.byName("rbp") & mask16, nil
+		return regs.x86Reg("rbp") & mask16, nil
 	case x86asm.SI:
-		return regs.byName("rsi") & mask16, nil
+		return regs.x86Reg("rsi") & mask16, nil
 	case x86asm.DI:
-		return regs.byName("rdi") & mask16, nil
+		return regs.x86Reg("rdi") & mask16, nil
 	case x86asm.R8W:
-		return regs.byName("r8") & mask16, nil
+		return regs.x86Reg("r8") & mask16, nil
 	case x86asm.R9W:
-		return regs.byName("r9") & mask16, nil
+		return regs.x86Reg("r9") & mask16, nil
 	case x86asm.R10W:
-		return regs.byName("r10") & mask16, nil
+		return regs.x86Reg("r10") & mask16, nil
 	case x86asm.R11W:
-		return regs.byName("r11") & mask16, nil
+		return regs.x86Reg("r11") & mask16, nil
 	case x86asm.R12W:
-		return regs.byName("r12") & mask16, nil
+		return regs.x86Reg("r12") & mask16, nil
 	case x86asm.R13W:
-		return regs.byName("r13") & mask16, nil
+		return regs.x86Reg("r13") & mask16, nil
 	case x86asm.R14W:
-		return regs.byName("r14") & mask16, nil
+		return regs.x86Reg("r14") & mask16, nil
 	case x86asm.R15W:
-		return regs.byName("r15") & mask16, nil
+		return regs.x86Reg("r15") & mask16, nil
 
 	// 32-bit
 	case x86asm.EAX:
-		return regs.byName("rax") & mask32, nil
+		return regs.x86Reg("rax") & mask32, nil
 	case x86asm.ECX:
-		return regs.byName("rcx") & mask32, nil
+		return regs.x86Reg("rcx") & mask32, nil
 	case x86asm.EDX:
-		return regs.byName("rdx") & mask32, nil
+		return regs.x86Reg("rdx") & mask32, nil
 	case x86asm.EBX:
-		return regs.byName("rbx") & mask32, nil
+		return regs.x86Reg("rbx") & mask32, nil
 	case x86asm.ESP:
-		return regs.byName("rsp") & mask32, nil
+		return regs.x86Reg("rsp") & mask32, nil
 	case x86asm.EBP:
-		return regs.byName("rbp") & mask32, nil
+		return regs.x86Reg("rbp") & mask32, nil
 	case x86asm.ESI:
-		return regs.byName("rsi") & mask32, nil
+		return regs.x86Reg("rsi") & mask32, nil
 	case x86asm.EDI:
-		return regs.byName("rdi") & mask32, nil
+		return regs.x86Reg("rdi") & mask32, nil
 	case x86asm.R8L:
-		return regs.byName("r8") & mask32, nil
+		return regs.x86Reg("r8") & mask32, nil
 	case x86asm.R9L:
-		return regs.byName("r9") & mask32, nil
+		return regs.x86Reg("r9") & mask32, nil
 	case x86asm.R10L:
-		return regs.byName("r10") & mask32, nil
+		return regs.x86Reg("r10") & mask32, nil
 	case x86asm.R11L:
-		return regs.byName("r11") & mask32, nil
+		return regs.x86Reg("r11") & mask32, nil
 	case x86asm.R12L:
-		return regs.byName("r12") & mask32, nil
+		return regs.x86Reg("r12") & mask32, nil
 	case x86asm.R13L:
-		return regs.byName("r13") & mask32, nil
+		return regs.x86Reg("r13") & mask32, nil
 	case x86asm.R14L:
-		return regs.byName("r14") & mask32, nil
+		return regs.x86Reg("r14") & mask32, nil
 	case x86asm.R15L:
-		return regs.byName("r15") & mask32, nil
+		return regs.x86Reg("r15") & mask32, nil
 
 	// 64-bit
 	case x86asm.RAX:
-		return regs.byName("rax"), nil
+		return regs.x86Reg("rax"), nil
 	case x86asm.RCX:
-		return regs.byName("rcx"), nil
+		return regs.x86Reg("rcx"), nil
 	case x86asm.RDX:
-		return regs.byName("rdx"), nil
+		return regs.x86Reg("rdx"), nil
 	case x86asm.RBX:
-		return regs.byName("rbx"), nil
+		return regs.x86Reg("rbx"), nil
 	case x86asm.RSP:
-		return regs.byName("rsp"), nil
+		return regs.x86Reg("rsp"), nil
 	case x86asm.RBP:
-		return regs.byName("rbp"), nil
+		return regs.x86Reg("rbp"), nil
 	case x86asm.RSI:
-		return regs.byName("rsi"), nil
+		return regs.x86Reg("rsi"), nil
 	case x86asm.RDI:
-		return regs.byName("rdi"), nil
+		return regs.x86Reg("rdi"), nil
 	case x86asm.R8:
-		return regs.byName("r8"), nil
+		return regs.x86Reg("r8"), nil
 	case x86asm.R9:
-		return regs.byName("r9"), nil
+		return regs.x86Reg("r9"), nil
 	case x86asm.R10:
-		return regs.byName("r10"), nil
+		return regs.x86Reg("r10"), nil
 	case x86asm.R11:
-		return regs.byName("r11"), nil
+		return regs.x86Reg("r11"), nil
 	case x86asm.R12:
-		return regs.byName("r12"), nil
+		return regs.x86Reg("r12"), nil
 	case x86asm.R13:
-		return regs.byName("r13"), nil
+		return regs.x86Reg("r13"), nil
 	case x86asm.R14:
-		return regs.byName("r14"), nil
+		return regs.x86Reg("r14"), nil
 	case x86asm.R15:
-		return regs.byName("r15"), nil
+		return regs.x86Reg("r15"), nil
 	}
 
 	return 0, proc.ErrUnknownRegister
@@ -1875,7 +2118,7 @@ func (t *gdbThread) SetPC(pc uint64) error {
 	if t.p.gcmdok {
 		return t.p.conn.writeRegisters(t.strID, t.regs.buf)
 	}
-	reg := t.regs.regs[regnamePC]
+	reg := t.regs.regs[t.p.conn.regnames.PC]
 	return t.p.conn.writeRegister(t.strID, reg.regnum, reg.value)
 }
 
@@ -1885,17 +2128,20 @@ func (t *gdbThread) SetSP(sp uint64) error {
 	if t.p.gcmdok {
 		return t.p.conn.writeRegisters(t.strID, t.regs.buf)
 	}
-	reg := t.regs.regs[regnameSP]
+	reg := t.regs.regs[t.p.conn.regnames.SP]
 	return t.p.conn.writeRegister(t.strID, reg.regnum, reg.value)
 }
 
 // SetDX will set the value of the DX register to the given value.
 func (t *gdbThread) SetDX(dx uint64) error {
+	if t.p.conn.regnames.DX == "" {
+		return proc.ErrUnknownRegister
+	}
 	t.regs.setDX(dx)
 	if t.p.gcmdok {
 		return t.p.conn.writeRegisters(t.strID, t.regs.buf)
 	}
-	reg := t.regs.regs[regnameDX]
+	reg := t.regs.regs[t.p.conn.regnames.DX]
 	return t.p.conn.writeRegister(t.strID, reg.regnum, reg.value)
 }
 
@@ -1906,7 +2152,7 @@ func (regs *gdbRegisters) Slice(floatingPoint bool) ([]proc.Register, error) {
 			continue
 		}
 		switch {
-		case reginfo.Name == "eflags":
+		case reginfo.Name == "eflags" && regs.regnames.arch == "amd64":
 			r = proc.AppendBytesRegister(r, "Rflags", regs.regs[reginfo.Name].value)
 		case reginfo.Name == "mxcsr":
 			r = proc.AppendBytesRegister(r, reginfo.Name, regs.regs[reginfo.Name].value)
@@ -1950,7 +2196,7 @@ func (regs *gdbRegisters) Slice(floatingPoint bool) ([]proc.Register, error) {
 
 func (regs *gdbRegisters) Copy() (proc.Registers, error) {
 	savedRegs := &gdbRegisters{}
-	savedRegs.init(regs.regsInfo)
+	savedRegs.init(regs.regsInfo, regs.regnames)
 	copy(savedRegs.buf, regs.buf)
 	return savedRegs, nil
 }
@@ -10,7 +10,6 @@ import (
 	"fmt"
 	"io"
 	"net"
-	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -36,6 +35,7 @@ type gdbConn struct {
 
 	packetSize int               // maximum packet size supported by stub
 	regsInfo   []gdbRegisterInfo // list of registers
+	regnames   *gdbRegnames      // names of the registers used by the backend on the architecture of the target
 
 	pid int // cache process id
 
@@ -44,19 +44,41 @@ type gdbConn struct {
 	maxTransmitAttempts   int  // maximum number of transmit or receive attempts when bad checksums are read
 	threadSuffixSupported bool // thread suffix supported by stub
 	isDebugserver         bool // true if the stub is debugserver
+	swSingleStep          bool // the stub can not single step threads, single step is emulated with breakpoints
+
+	stdout io.Writer // destination of the output of the target forwarded by the stub
 
 	log *logrus.Entry
 }
 
-const (
-	regnamePC     = "rip"
-	regnameCX     = "rcx"
-	regnameSP     = "rsp"
-	regnameDX     = "rdx"
-	regnameBP     = "rbp"
-	regnameFsBase = "fs_base"
-	regnameGsBase = "gs_base"
-)
+// gdbRegnames are the names the stub uses for the registers that the
+// backend accesses directly on an architecture.
+type gdbRegnames struct {
+	arch string
+
+	PC, SP, BP string
+	// CX is the register loaded with the address of the current G by the
+	// instruction returned by loadGInstr, DX is the closure context
+	// register used by function calls. Both are empty on architectures
+	// that keep the address of the current G in register G.
+	CX, DX string
+	G      string
+	FsBase string
+}
+
+// newGdbRegnames returns the register names used on arch, which must be
+// one of the architectures supported by proc.
+func newGdbRegnames(arch string) *gdbRegnames {
+	switch arch {
+	case "386":
+		return &gdbRegnames{arch: arch, PC: "eip", SP: "esp", BP: "ebp", CX: "ecx", DX: "edx"}
+	case "arm64":
+		return &gdbRegnames{arch: arch, PC: "pc", SP: "sp", BP: "x29", G: "x28"}
+	case "arm":
+		return &gdbRegnames{arch: arch, PC: "pc", SP: "sp", BP: "r11", G: "r10"}
+	}
+	return &gdbRegnames{arch: "amd64", PC: "rip", SP: "rsp", BP: "rbp", CX: "rcx", DX: "rdx", FsBase: "fs_base"}
+}
 
 var ErrTooManyAttempts = errors.New("too many transmit attempts")
 
@@ -173,9 +195,31 @@ func (conn *gdbConn) handshake() error {
 		}
 	}
 
+	// Stubs for targets without hardware single step (for example gdbserver
+	// on some ARM systems) do not list the 's' action in their reply to
+	// vCont?. Stubs that don't support vCont? are assumed to support it.
+	if resp, err := conn.exec([]byte("$vCont?"), "init"); err == nil {
+		conn.swSingleStep = !vContActionSupported(string(resp), "s")
+	}
+
 	return nil
 }
 
+// vContActionSupported returns true if action is listed in resp, the
+// response to vCont?.
+func vContActionSupported(resp, action string) bool {
+	v := strings.Split(resp, ";")
+	if v[0] != "vCont" {
+		return false
+	}
+	for _, a := range v[1:] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
 // qSupported interprets qSupported responses.
 func (conn *gdbConn) qSupported(multiprocess bool) (features map[string]bool, err error) {
 	q := qSupportedSimple
@@ -241,7 +285,6 @@ func (conn *gdbConn) readTargetXml() (err error) {
 		return err
 	}
 	var offset int
-	var pcFound, cxFound, spFound bool
 	regnum := 0
 	for i := range conn.regsInfo {
 		if conn.regsInfo[i].Regnum == 0 {
@@ -251,25 +294,8 @@ func (conn *gdbConn) readTargetXml() (err error) {
 		}
 		conn.regsInfo[i].Offset = offset
 		offset += conn.regsInfo[i].Bitsize / 8
-		switch conn.regsInfo[i].Name {
-		case regnamePC:
-			pcFound = true
-		case regnameCX:
-			cxFound = true
-		case regnameSP:
-			spFound = true
-		}
 		regnum++
 	}
-	if !pcFound {
-		return errors.New("could not find RIP register")
-	}
-	if !spFound {
-		return errors.New("could not find RSP register")
-	}
-	if !cxFound {
-		return errors.New("could not find RCX register")
-	}
 	return nil
 }
 
@@ -277,7 +303,6 @@ func (conn *gdbConn) readTargetXml() (err error) {
 // when qXfer:feature:read is not supported).
 func (conn *gdbConn) readRegisterInfo() (err error) {
 	regnum := 0
-	var pcFound, cxFound, spFound bool
 	for {
 		conn.outbuf.Reset()
 		fmt.Fprintf(&conn.outbuf, "$qRegisterInfo%x", regnum)
@@ -330,30 +355,33 @@ func (conn *gdbConn) readRegisterInfo() (err error) {
 			continue
 		}
 
-		switch regname {
-		case regnamePC:
-			pcFound = true
-		case regnameCX:
-			cxFound = true
-		case regnameSP:
-			spFound = true
-		}
-
 		conn.regsInfo = append(conn.regsInfo, gdbRegisterInfo{Regnum: regnum, Name: regname, Bitsize: bitsize, Offset: offset})
 
 		regnum++
 	}
 
-	if !pcFound {
-		return errors.New("could not find RIP register")
-	}
-	if !spFound {
-		return errors.New("could not find RSP register")
-	}
-	if !cxFound {
-		return errors.New("could not find RCX register")
-	}
+	return nil
+}
 
+// checkRegisters returns an error if one of the registers used by the
+// backend on the architecture of the target is not described by the stub.
+func (conn *gdbConn) checkRegisters() error {
+	names := conn.regnames
+	for _, name := range []string{names.PC, names.SP, names.CX, names.G} {
+		if name == "" {
+			continue
+		}
+		found := false
+		for i := range conn.regsInfo {
+			if conn.regsInfo[i].Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("could not find %s register", strings.ToUpper(name))
+		}
+	}
 	return nil
 }
 
@@ -412,22 +440,33 @@ func (conn *gdbConn) qXfer(kind, annex string, binary bool) ([]byte, error) {
 	return out, nil
 }
 
-// setBreakpoint executes a 'Z' (insert breakpoint) command of type '0' and kind '1'
+// setBreakpoint executes a 'Z' (insert breakpoint) command of type '0', the
+// kind is the size of the breakpoint instruction of the target.
 func (conn *gdbConn) setBreakpoint(addr uint64) error {
 	conn.outbuf.Reset()
-	fmt.Fprintf(&conn.outbuf, "$Z0,%x,1", addr)
+	fmt.Fprintf(&conn.outbuf, "$Z0,%x,%d", addr, conn.breakpointKind())
 	_, err := conn.exec(conn.outbuf.Bytes(), "set breakpoint")
 	return err
 }
 
-// clearBreakpoint executes a 'z' (remove breakpoint) command of type '0' and kind '1'
+// clearBreakpoint executes a 'z' (remove breakpoint) command of type '0'.
 func (conn *gdbConn) clearBreakpoint(addr uint64) error {
 	conn.outbuf.Reset()
-	fmt.Fprintf(&conn.outbuf, "$z0,%x,1", addr)
+	fmt.Fprintf(&conn.outbuf, "$z0,%x,%d", addr, conn.breakpointKind())
 	_, err := conn.exec(conn.outbuf.Bytes(), "clear breakpoint")
 	return err
 }
 
+// breakpointKind returns the kind of the software breakpoints inserted
+// with 'Z0', the size of the breakpoint instruction of the architecture.
+func (conn *gdbConn) breakpointKind() int {
+	switch conn.regnames.arch {
+	case "arm64", "arm":
+		return 4
+	}
+	return 1
+}
+
 // kill executes a 'k' (kill) command.
 func (conn *gdbConn) kill() error {
 	resp, err := conn.exec([]byte{'$', 'k'}, "kill")
@@ -614,24 +653,54 @@ func (conn *gdbConn) step(threadID string, tu *threadUpdater, ignoreFaultSignal
 		if err != nil {
 			return err
 		}
-		switch sig {
-		case faultSignal:
-			if ignoreFaultSignal { // we attempting to read the TLS, a fault here should be ignored
-				return nil
-			}
-		case interruptSignal, breakpointSignal, stopSignal:
-			return nil
-		case childSignal: // stop on debugserver but SIGCHLD on lldb-server/linux
-			if conn.isDebugserver {
-				return nil
-			}
-		case debugServerTargetExcBadAccess, debugServerTargetExcBadInstruction, debugServerTargetExcArithmetic, debugServerTargetExcEmulation, debugServerTargetExcSoftware, debugServerTargetExcBreakpoint:
+		if conn.stepDone(sig, ignoreFaultSignal) {
 			return nil
 		}
 		// any other signal is propagated to the inferior
 	}
 }
 
+// stepDone returns true if a thread that was single stepped and stopped
+// with signal sig completed its step, false if sig should be propagated to
+// the inferior and the step repeated.
+func (conn *gdbConn) stepDone(sig uint8, ignoreFaultSignal bool) bool {
+	switch sig {
+	case faultSignal:
+		if ignoreFaultSignal { // we attempting to read the TLS, a fault here should be ignored
+			return true
+		}
+	case interruptSignal, breakpointSignal, stopSignal:
+		return true
+	case childSignal: // stop on debugserver but SIGCHLD on lldb-server/linux
+		if conn.isDebugserver {
+			return true
+		}
+	case debugServerTargetExcBadAccess, debugServerTargetExcBadInstruction, debugServerTargetExcArithmetic, debugServerTargetExcEmulation, debugServerTargetExcSoftware, debugServerTargetExcBreakpoint:
+		return true
+	}
+	return false
+}
+
+// resumeThread executes a 'vCont' command with the 'c' action (or 'C' if
+// sig isn't zero) on the specified thread only, other threads are left
+// stopped. It is used to emulate single stepping.
+func (conn *gdbConn) resumeThread(threadID string, sig uint8, tu *threadUpdater) (uint8, error) {
+	conn.outbuf.Reset()
+	if sig == 0 {
+		fmt.Fprintf(&conn.outbuf, "$vCont;c:%s", threadID)
+	} else {
+		fmt.Fprintf(&conn.outbuf, "$vCont;C%02x:%s", sig, threadID)
+	}
+	if err := conn.send(conn.outbuf.Bytes()); err != nil {
+		return 0, err
+	}
+	if tu != nil {
+		tu.Reset()
+	}
+	_, sig, err := conn.waitForvContStop("singlestep", threadID, tu)
+	return sig, err
+}
+
 var threadBlockedError = errors.New("thread blocked")
 
 func (conn *gdbConn) waitForvContStop(context string, threadID string, tu *threadUpdater) (string, uint8, error) {
@@ -747,7 +816,7 @@ func (conn *gdbConn) parseStopPacket(resp []byte, threadID string, tu *threadUpd
 			n, _ := strconv.ParseUint(string(resp[i:i+2]), 16, 8)
 			data = append(data, uint8(n))
 		}
-		os.Stdout.Write(data)
+		conn.stdout.Write(data)
 		return true, sp, nil
 
 	default:
@@ -0,0 +1,301 @@
+package gdbserial
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/arch/arm/armasm"
+	"golang.org/x/arch/arm64/arm64asm"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// softwareStep emulates single stepping the thread, for stubs that do not
+// support the 's' action of vCont: it decodes the instruction at the
+// current PC, sets a temporary breakpoint on every address where the
+// thread could be after executing it and resumes only this thread.
+func (t *gdbThread) softwareStep(tu *threadUpdater, ignoreFaultSignal bool) error {
+	var regs gdbRegisters
+	regs.init(t.p.conn.regsInfo, t.p.conn.regnames)
+	if err := t.readRegistersInto(&regs); err != nil {
+		return err
+	}
+	pcs, err := nextPCs(&regs, t.p.conn.readMemory)
+	if err != nil {
+		return fmt.Errorf("could not single step thread %s: %v", t.strID, err)
+	}
+
+	// The breakpoint at the current PC, if any, would stop the thread
+	// before it executes the instruction.
+	pc := regs.PC()
+	if _, isbp := t.p.breakpoints.M[pc]; isbp {
+		if err := t.p.conn.clearBreakpoint(pc); err == nil {
+			defer t.p.conn.setBreakpoint(pc)
+		}
+	}
+	for _, next := range pcs {
+		if _, isbp := t.p.breakpoints.M[next]; isbp && next != pc {
+			continue
+		}
+		if err := t.p.conn.setBreakpoint(next); err != nil {
+			return err
+		}
+		defer t.p.conn.clearBreakpoint(next)
+	}
+
+	var sig uint8
+	for {
+		sig, err = t.p.conn.resumeThread(t.strID, sig, tu)
+		if err != nil {
+			return err
+		}
+		if t.p.conn.stepDone(sig, ignoreFaultSignal) {
+			return nil
+		}
+		// any other signal is propagated to the inferior
+	}
+}
+
+// nextPCs returns the addresses where a thread with registers regs could
+// be after executing the instruction at its PC, mem is used to read the
+// instruction and the memory operands of indirect branches.
+func nextPCs(regs *gdbRegisters, mem func([]byte, uintptr) error) ([]uint64, error) {
+	switch arch := regs.regnames.arch; arch {
+	case "amd64":
+		return x86NextPCs(regs, mem, 64)
+	case "386":
+		return x86NextPCs(regs, mem, 32)
+	case "arm64":
+		return arm64NextPCs(regs, mem)
+	case "arm":
+		return armNextPCs(regs, mem)
+	default:
+		return nil, fmt.Errorf("unsupported architecture %s", arch)
+	}
+}
+
+// x86MaxInstructionLength is the maximum length of an x86 instruction.
+const x86MaxInstructionLength = 15
+
+func x86NextPCs(regs *gdbRegisters, mem func([]byte, uintptr) error, mode int) ([]uint64, error) {
+	pc := regs.PC()
+	buf := make([]byte, x86MaxInstructionLength)
+	if err := mem(buf, uintptr(pc)); err != nil {
+		return nil, err
+	}
+	inst, err := x86asm.Decode(buf, mode)
+	if err != nil {
+		return nil, err
+	}
+	next := pc + uint64(inst.Len)
+	ptrSize := mode / 8
+
+	switch inst.Op {
+	case x86asm.RET:
+		retaddr, err := readPtr(mem, regs.SP(), ptrSize)
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{retaddr}, nil
+	case x86asm.JMP, x86asm.CALL:
+		dst, err := x86BranchTarget(&inst, next, regs, mem, ptrSize)
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{dst}, nil
+	case x86asm.JA, x86asm.JAE, x86asm.JB, x86asm.JBE, x86asm.JCXZ, x86asm.JE, x86asm.JECXZ, x86asm.JG, x86asm.JGE, x86asm.JL, x86asm.JLE, x86asm.JNE, x86asm.JNO, x86asm.JNP, x86asm.JNS, x86asm.JO, x86asm.JP, x86asm.JRCXZ, x86asm.JS, x86asm.LOOP, x86asm.LOOPE, x86asm.LOOPNE:
+		dst, err := x86BranchTarget(&inst, next, regs, mem, ptrSize)
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{next, dst}, nil
+	case x86asm.LCALL, x86asm.LJMP, x86asm.LRET, x86asm.IRET, x86asm.IRETD, x86asm.IRETQ:
+		return nil, fmt.Errorf("can not single step %v instruction", inst.Op)
+	}
+	return []uint64{next}, nil
+}
+
+// x86BranchTarget returns the destination of the jump or call instruction
+// inst, next is the address of the following instruction.
+func x86BranchTarget(inst *x86asm.Inst, next uint64, regs *gdbRegisters, mem func([]byte, uintptr) error, ptrSize int) (uint64, error) {
+	mask := ^uint64(0) >> uint(64-8*ptrSize)
+	switch arg := inst.Args[0].(type) {
+	case x86asm.Rel:
+		return (next + uint64(int64(arg))) & mask, nil
+	case x86asm.Reg:
+		return regs.Get(int(arg))
+	case x86asm.Mem:
+		if arg.Segment != 0 {
+			return 0, errors.New("segment relative branch target")
+		}
+		addr := uint64(arg.Disp)
+		if arg.Base != 0 {
+			base, err := x86MemOperandReg(arg.Base, next, regs)
+			if err != nil {
+				return 0, err
+			}
+			addr += base
+		}
+		if arg.Index != 0 {
+			index, err := x86MemOperandReg(arg.Index, next, regs)
+			if err != nil {
+				return 0, err
+			}
+			addr += index * uint64(arg.Scale)
+		}
+		return readPtr(mem, addr&mask, ptrSize)
+	}
+	return 0, fmt.Errorf("unknown branch target %v", inst.Args[0])
+}
+
+// x86MemOperandReg returns the value of register reg used in a memory
+// operand, for RIP relative operands it is the address of the next
+// instruction.
+func x86MemOperandReg(reg x86asm.Reg, next uint64, regs *gdbRegisters) (uint64, error) {
+	if reg == x86asm.RIP {
+		return next, nil
+	}
+	return regs.Get(int(reg))
+}
+
+func arm64NextPCs(regs *gdbRegisters, mem func([]byte, uintptr) error) ([]uint64, error) {
+	pc := regs.PC()
+	buf := make([]byte, 4)
+	if err := mem(buf, uintptr(pc)); err != nil {
+		return nil, err
+	}
+	inst, err := arm64asm.Decode(buf)
+	if err != nil {
+		return nil, err
+	}
+	next := pc + 4
+
+	// relTarget returns the destination of a PC relative branch, its label
+	// is the last argument of the instruction.
+	relTarget := func() (uint64, error) {
+		for i := len(inst.Args) - 1; i >= 0; i-- {
+			if rel, ok := inst.Args[i].(arm64asm.PCRel); ok {
+				return pc + uint64(int64(rel)), nil
+			}
+		}
+		return 0, fmt.Errorf("unknown branch target of %v", inst)
+	}
+
+	switch inst.Op {
+	case arm64asm.B:
+		dst, err := relTarget()
+		if err != nil {
+			return nil, err
+		}
+		if _, conditional := inst.Args[0].(arm64asm.Cond); conditional {
+			return []uint64{next, dst}, nil
+		}
+		return []uint64{dst}, nil
+	case arm64asm.BL:
+		dst, err := relTarget()
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{dst}, nil
+	case arm64asm.CBZ, arm64asm.CBNZ, arm64asm.TBZ, arm64asm.TBNZ:
+		dst, err := relTarget()
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{next, dst}, nil
+	case arm64asm.BR, arm64asm.BLR, arm64asm.RET:
+		reg, ok := inst.Args[0].(arm64asm.Reg)
+		if !ok {
+			return nil, fmt.Errorf("unknown branch target of %v", inst)
+		}
+		dst, err := regs.Get(int(reg))
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{dst}, nil
+	case arm64asm.ERET:
+		return nil, fmt.Errorf("can not single step %v instruction", inst.Op)
+	}
+	return []uint64{next}, nil
+}
+
+func armNextPCs(regs *gdbRegisters, mem func([]byte, uintptr) error) ([]uint64, error) {
+	pc := regs.PC()
+	buf := make([]byte, 4)
+	if err := mem(buf, uintptr(pc)); err != nil {
+		return nil, err
+	}
+	inst, err := armasm.Decode(buf, armasm.ModeARM)
+	if err != nil {
+		return nil, err
+	}
+	next := pc + 4
+
+	// The condition is encoded in the low 4 bits of the opcode, 14 is
+	// 'always' and 15 is the unconditional space.
+	var pcs []uint64
+	if inst.Op&15 < 14 {
+		pcs = append(pcs, next)
+	}
+	switch inst.Op &^ 15 {
+	case armasm.B_EQ, armasm.BL_EQ, armasm.BX_EQ, armasm.BLX_EQ:
+		var dst uint64
+		switch arg := inst.Args[0].(type) {
+		case armasm.PCRel:
+			// reading PC on ARM returns the address of the current
+			// instruction plus 8
+			dst = uint64(uint32(pc) + 8 + uint32(arg))
+		case armasm.Reg:
+			dst, err = regs.Get(int(arg))
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown branch target of %v", inst)
+		}
+		if dst&1 != 0 {
+			return nil, errors.New("can not single step into thumb code")
+		}
+		return append(pcs, dst), nil
+	}
+	if armWritesPC(&inst) {
+		return nil, fmt.Errorf("can not single step %v instruction", inst)
+	}
+	if len(pcs) == 0 {
+		pcs = append(pcs, next)
+	}
+	return pcs, nil
+}
+
+// armWritesPC returns true if inst loads a value into PC.
+func armWritesPC(inst *armasm.Inst) bool {
+	op := inst.Op.String()
+	for _, prefix := range []string{"ST", "PUSH", "CMP", "CMN", "TST", "TEQ"} {
+		if strings.HasPrefix(op, prefix) {
+			// PC is a source operand
+			return false
+		}
+	}
+	if reg, ok := inst.Args[0].(armasm.Reg); ok && reg == armasm.PC {
+		return true
+	}
+	for _, arg := range inst.Args {
+		if regs, ok := arg.(armasm.RegList); ok && regs&(1<<uint(armasm.PC)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// readPtr reads a pointer of ptrSize bytes at addr.
+func readPtr(mem func([]byte, uintptr) error, addr uint64, ptrSize int) (uint64, error) {
+	buf := make([]byte, ptrSize)
+	if err := mem(buf, uintptr(addr)); err != nil {
+		return 0, err
+	}
+	if ptrSize == 4 {
+		return uint64(binary.LittleEndian.Uint32(buf)), nil
+	}
+	return binary.LittleEndian.Uint64(buf), nil
+}
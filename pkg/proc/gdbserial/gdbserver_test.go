@@ -0,0 +1,69 @@
+package gdbserial_test
+
+import (
+	"bufio"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"testing"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/pkg/proc/gdbserial"
+	protest "github.com/go-delve/delve/pkg/proc/test"
+)
+
+func TestConnectRemote(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("test skipped, gdbserver is only tested on linux")
+	}
+	gdbserver, _ := exec.LookPath("gdbserver")
+	if gdbserver == "" {
+		t.Skip("test skipped, gdbserver not found")
+	}
+	fixture := protest.BuildFixture("continuetestprog", 0)
+
+	cmd := exec.Command(gdbserver, "--once", "127.0.0.1:0", fixture.Path)
+	stderr, err := cmd.StderrPipe()
+	assertNoError(err, t, "StderrPipe")
+	assertNoError(cmd.Start(), t, "starting gdbserver")
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	listeningRx := regexp.MustCompile(`Listening on port (\d+)`)
+	var port string
+	scan := bufio.NewScanner(stderr)
+	for port == "" && scan.Scan() {
+		if m := listeningRx.FindStringSubmatch(scan.Text()); m != nil {
+			port = m[1]
+		}
+	}
+	if port == "" {
+		t.Fatal("could not read the port of gdbserver")
+	}
+	go func() {
+		for scan.Scan() {
+		}
+	}()
+
+	p, err := gdbserial.ConnectRemote("127.0.0.1:"+port, fixture.Path, nil)
+	assertNoError(err, t, "ConnectRemote")
+	defer p.Detach(true)
+
+	if arch := p.BinInfo().Arch.Name; arch != runtime.GOARCH {
+		t.Errorf("wrong architecture %s, expected %s", arch, runtime.GOARCH)
+	}
+
+	setFunctionBreakpoint(p, t, "main.main")
+	assertNoError(p.Continue(), t, "Continue")
+	loc, err := p.CurrentThread().Location()
+	assertNoError(err, t, "CurrentThread().Location()")
+	if loc.Fn == nil || loc.Fn.Name != "main.main" {
+		t.Fatalf("wrong location %s:%d, expected main.main", loc.File, loc.Line)
+	}
+	assertNoError(p.Next(), t, "Next")
+
+	err = p.Continue()
+	if _, isexited := err.(proc.ErrProcessExited); !isexited {
+		t.Fatalf("program did not exit: %v", err)
+	}
+}
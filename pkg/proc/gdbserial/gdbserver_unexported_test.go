@@ -0,0 +1,372 @@
+package gdbserial
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-delve/delve/pkg/proc"
+	protest "github.com/go-delve/delve/pkg/proc/test"
+)
+
+func TestVContActionSupported(t *testing.T) {
+	for _, tc := range []struct {
+		resp   string
+		action string
+		tgt    bool
+	}{
+		{"vCont;c;C;s;S", "s", true},
+		{"vCont;c;C;t", "s", false},
+		{"vCont;c;C;S", "s", false},
+		{"", "s", false},
+	} {
+		if out := vContActionSupported(tc.resp, tc.action); out != tc.tgt {
+			t.Errorf("vContActionSupported(%q, %q) = %v, expected %v", tc.resp, tc.action, out, tc.tgt)
+		}
+	}
+}
+
+// memoryReader returns a function that reads the memory described by mem,
+// with code at address pc.
+func memoryReader(mem map[uint64][]byte, pc uint64, code []byte) func([]byte, uintptr) error {
+	return func(data []byte, addr uintptr) error {
+		if uint64(addr) == pc {
+			for i := range data {
+				data[i] = 0
+			}
+			copy(data, code)
+			return nil
+		}
+		buf, ok := mem[uint64(addr)]
+		if !ok || len(buf) < len(data) {
+			return fmt.Errorf("unreadable address %#x", addr)
+		}
+		copy(data, buf)
+		return nil
+	}
+}
+
+func TestNextPCs(t *testing.T) {
+	const (
+		pc = 0x1000
+		sp = 0x8000
+	)
+	type testCase struct {
+		name string
+		code []byte
+		tgt  []uint64
+	}
+	for _, arch := range []struct {
+		name  string
+		regs  []gdbRegisterInfo
+		setup func(regs *gdbRegisters)
+		mem   map[uint64][]byte
+		tests []testCase
+	}{
+		{
+			"amd64",
+			[]gdbRegisterInfo{
+				{Name: "rip", Bitsize: 64, Offset: 0},
+				{Name: "rsp", Bitsize: 64, Offset: 8},
+				{Name: "rax", Bitsize: 64, Offset: 16},
+			},
+			func(regs *gdbRegisters) { regs.setByName("rax", 0x3000) },
+			map[uint64][]byte{
+				sp:     {0x00, 0x20, 0, 0, 0, 0, 0, 0}, // return address 0x2000
+				0x3000: {0x00, 0x40, 0, 0, 0, 0, 0, 0}, // pointer to 0x4000
+				0x1010: {0x00, 0x50, 0, 0, 0, 0, 0, 0}, // pointer to 0x5000
+			},
+			[]testCase{
+				{"nop", []byte{0x90}, []uint64{pc + 1}},
+				{"mov", []byte{0x48, 0x89, 0xc3}, []uint64{pc + 3}},
+				{"ret", []byte{0xc3}, []uint64{0x2000}},
+				{"call rel", []byte{0xe8, 0x0b, 0x00, 0x00, 0x00}, []uint64{pc + 5 + 0x0b}},
+				{"jmp rel8", []byte{0xeb, 0xfe}, []uint64{pc}},
+				{"je rel8", []byte{0x74, 0x10}, []uint64{pc + 2, pc + 2 + 0x10}},
+				{"call rax", []byte{0xff, 0xd0}, []uint64{0x3000}},
+				{"jmp [rax]", []byte{0xff, 0x20}, []uint64{0x4000}},
+				{"call [rip+0xa]", []byte{0xff, 0x15, 0x0a, 0x00, 0x00, 0x00}, []uint64{0x5000}},
+			},
+		},
+		{
+			"386",
+			[]gdbRegisterInfo{
+				{Name: "eip", Bitsize: 32, Offset: 0},
+				{Name: "esp", Bitsize: 32, Offset: 4},
+				{Name: "eax", Bitsize: 32, Offset: 8},
+			},
+			func(regs *gdbRegisters) { regs.setByName("eax", 0x3000) },
+			map[uint64][]byte{
+				sp:     {0x00, 0x20, 0, 0}, // return address 0x2000
+				0x3000: {0x00, 0x40, 0, 0}, // pointer to 0x4000
+			},
+			[]testCase{
+				{"nop", []byte{0x90}, []uint64{pc + 1}},
+				{"ret", []byte{0xc3}, []uint64{0x2000}},
+				{"call rel", []byte{0xe8, 0x0b, 0x00, 0x00, 0x00}, []uint64{pc + 5 + 0x0b}},
+				{"jne rel8", []byte{0x75, 0xf0}, []uint64{pc + 2, pc + 2 - 0x10}},
+				{"call eax", []byte{0xff, 0xd0}, []uint64{0x3000}},
+				{"jmp [eax]", []byte{0xff, 0x20}, []uint64{0x4000}},
+			},
+		},
+		{
+			"arm64",
+			[]gdbRegisterInfo{
+				{Name: "pc", Bitsize: 64, Offset: 0},
+				{Name: "sp", Bitsize: 64, Offset: 8},
+				{Name: "x1", Bitsize: 64, Offset: 16},
+				{Name: "x30", Bitsize: 64, Offset: 24},
+			},
+			func(regs *gdbRegisters) {
+				regs.setByName("x1", 0x3000)
+				regs.setByName("x30", 0x2000)
+			},
+			nil,
+			[]testCase{
+				{"nop", []byte{0x1f, 0x20, 0x03, 0xd5}, []uint64{pc + 4}},
+				{"ret", []byte{0xc0, 0x03, 0x5f, 0xd6}, []uint64{0x2000}},
+				{"br x1", []byte{0x20, 0x00, 0x1f, 0xd6}, []uint64{0x3000}},
+				{"b", []byte{0x04, 0x00, 0x00, 0x14}, []uint64{pc + 0x10}},
+				{"bl", []byte{0xfc, 0xff, 0xff, 0x97}, []uint64{pc - 0x10}},
+				{"b.eq", []byte{0x80, 0x00, 0x00, 0x54}, []uint64{pc + 4, pc + 0x10}},
+				{"cbz x1", []byte{0x81, 0x00, 0x00, 0xb4}, []uint64{pc + 4, pc + 0x10}},
+				{"tbnz x1, #3", []byte{0x81, 0x00, 0x18, 0x37}, []uint64{pc + 4, pc + 0x10}},
+			},
+		},
+		{
+			"arm",
+			[]gdbRegisterInfo{
+				{Name: "pc", Bitsize: 32, Offset: 0},
+				{Name: "sp", Bitsize: 32, Offset: 4},
+				{Name: "r1", Bitsize: 32, Offset: 8},
+				{Name: "lr", Bitsize: 32, Offset: 12},
+			},
+			func(regs *gdbRegisters) {
+				regs.setByName("r1", 0x3000)
+				regs.setByName("lr", 0x2000)
+			},
+			nil,
+			[]testCase{
+				{"mov", []byte{0x01, 0x00, 0xa0, 0xe1}, []uint64{pc + 4}},
+				{"b", []byte{0x02, 0x00, 0x00, 0xea}, []uint64{pc + 8 + 8}},
+				{"bl", []byte{0xfc, 0xff, 0xff, 0xeb}, []uint64{pc + 8 - 0x10}},
+				{"beq", []byte{0x02, 0x00, 0x00, 0x0a}, []uint64{pc + 4, pc + 8 + 8}},
+				{"bx lr", []byte{0x1e, 0xff, 0x2f, 0xe1}, []uint64{0x2000}},
+				{"blx r1", []byte{0x31, 0xff, 0x2f, 0xe1}, []uint64{0x3000}},
+			},
+		},
+	} {
+		var regs gdbRegisters
+		regs.init(arch.regs, newGdbRegnames(arch.name))
+		regs.setPC(pc)
+		regs.setSP(sp)
+		arch.setup(&regs)
+
+		for _, tc := range arch.tests {
+			out, err := nextPCs(&regs, memoryReader(arch.mem, pc, tc.code))
+			if err != nil {
+				t.Errorf("%s %s: %v", arch.name, tc.name, err)
+				continue
+			}
+			if !reflect.DeepEqual(out, tc.tgt) {
+				t.Errorf("%s %s: got %#x expected %#x", arch.name, tc.name, out, tc.tgt)
+			}
+		}
+	}
+
+	var regs gdbRegisters
+	regs.init([]gdbRegisterInfo{{Name: "pc", Bitsize: 32, Offset: 0}}, newGdbRegnames("arm"))
+	regs.setPC(pc)
+	for _, code := range [][]byte{
+		{0x04, 0xf0, 0x9d, 0xe4}, // pop {pc}
+		{0x00, 0x80, 0xbd, 0xe8}, // pop {r15}, as ldm
+	} {
+		if out, err := nextPCs(&regs, memoryReader(nil, pc, code)); err == nil {
+			t.Errorf("arm %x: got %#x expected an error for an instruction loading PC", code, out)
+		}
+	}
+}
+
+// fakeStub is a gdb remote stub that serves a single stopped thread,
+// without acknowledgments, and records the packets it receives.
+type fakeStub struct {
+	regs    []byte
+	mem     func([]byte, uintptr) error
+	packets []string
+}
+
+func (stub *fakeStub) serve(conn net.Conn) {
+	rdr := bufio.NewReader(conn)
+	for {
+		packet, err := rdr.ReadString('#')
+		if err != nil {
+			return
+		}
+		if _, err := rdr.Discard(2); err != nil {
+			return
+		}
+		packet = strings.TrimSuffix(strings.TrimPrefix(packet, "$"), "#")
+		stub.packets = append(stub.packets, packet)
+
+		var resp bytes.Buffer
+		switch {
+		case strings.HasPrefix(packet, "g"):
+			writeAsciiBytes(&resp, stub.regs)
+		case strings.HasPrefix(packet, "m"):
+			args := strings.Split(packet[1:], ",")
+			addr, _ := strconv.ParseUint(args[0], 16, 64)
+			sz, _ := strconv.ParseUint(args[1], 16, 64)
+			buf := make([]byte, sz)
+			if err := stub.mem(buf, uintptr(addr)); err != nil {
+				resp.WriteString("E01")
+				break
+			}
+			writeAsciiBytes(&resp, buf)
+		case strings.HasPrefix(packet, "Z0,"), strings.HasPrefix(packet, "z0,"):
+			resp.WriteString("OK")
+		case strings.HasPrefix(packet, "vCont;c:"):
+			fmt.Fprintf(&resp, "T05thread:%s;", packet[len("vCont;c:"):])
+		}
+		sum := checksum(append([]byte{'$'}, resp.Bytes()...))
+		if _, err := fmt.Fprintf(conn, "$%s#%02x", resp.Bytes(), sum); err != nil {
+			return
+		}
+	}
+}
+
+func TestSoftwareStep(t *testing.T) {
+	// Single step emulation only needs the stub to read registers and
+	// memory, set breakpoints and resume one thread, this is checked against
+	// a fake stub so that no gdbserver needs to be installed.
+	const pc = 0x1000
+	p := newProcess(nil)
+	p.conn.regnames = newGdbRegnames("amd64")
+	p.conn.regsInfo = []gdbRegisterInfo{
+		{Name: "rip", Bitsize: 64, Offset: 0},
+		{Name: "rsp", Bitsize: 64, Offset: 8},
+		{Name: "rcx", Bitsize: 64, Offset: 16},
+	}
+	p.conn.packetSize = 256
+	p.conn.threadSuffixSupported = true
+	p.conn.swSingleStep = true
+	if err := p.conn.checkRegisters(); err != nil {
+		t.Fatal(err)
+	}
+	p.breakpoints.M[pc] = &proc.Breakpoint{Addr: pc}
+
+	stub := &fakeStub{
+		regs: make([]byte, 24),
+		mem:  memoryReader(nil, pc, []byte{0x74, 0x10}), // je pc+0x12
+	}
+	binary.LittleEndian.PutUint64(stub.regs, pc)
+
+	client, server := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		stub.serve(server)
+		close(done)
+	}()
+	p.conn.conn = client
+	p.conn.rdr = bufio.NewReader(client)
+
+	th := &gdbThread{ID: 1, strID: "1", p: p}
+	err := th.step(nil, false)
+	client.Close()
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tgt := []string{
+		"g;thread:1;",
+		fmt.Sprintf("m%x,%x", pc, x86MaxInstructionLength),
+		fmt.Sprintf("z0,%x,1", pc),
+		fmt.Sprintf("Z0,%x,1", pc+2),
+		fmt.Sprintf("Z0,%x,1", pc+2+0x10),
+		"vCont;c:1",
+		fmt.Sprintf("z0,%x,1", pc+2+0x10),
+		fmt.Sprintf("z0,%x,1", pc+2),
+		fmt.Sprintf("Z0,%x,1", pc),
+	}
+	if !reflect.DeepEqual(stub.packets, tgt) {
+		t.Errorf("packets sent to the stub:\n%s\nexpected:\n%s", strings.Join(stub.packets, "\n"), strings.Join(tgt, "\n"))
+	}
+}
+
+func TestCheckRegisters(t *testing.T) {
+	for _, tc := range []struct {
+		arch string
+		regs []string
+		tgt  string
+		kind int
+	}{
+		{"amd64", []string{"rip", "rsp", "rbp", "rcx", "rdx"}, "", 1},
+		{"amd64", []string{"rip", "rsp", "rbp"}, "could not find RCX register", 1},
+		{"386", []string{"eip", "esp", "ebp", "ecx", "edx"}, "", 1},
+		{"arm64", []string{"pc", "sp", "x28", "x29"}, "", 4},
+		{"arm64", []string{"pc", "sp", "x29"}, "could not find X28 register", 4},
+		{"arm", []string{"pc", "sp", "r10", "r11"}, "", 4},
+	} {
+		conn := gdbConn{regnames: newGdbRegnames(tc.arch)}
+		for i, name := range tc.regs {
+			conn.regsInfo = append(conn.regsInfo, gdbRegisterInfo{Name: name, Bitsize: 64, Offset: i * 8})
+		}
+		errstr := ""
+		if err := conn.checkRegisters(); err != nil {
+			errstr = err.Error()
+		}
+		if errstr != tc.tgt {
+			t.Errorf("%s %v: got error %q expected %q", tc.arch, tc.regs, errstr, tc.tgt)
+		}
+		if kind := conn.breakpointKind(); kind != tc.kind {
+			t.Errorf("%s: breakpoint kind %d expected %d", tc.arch, kind, tc.kind)
+		}
+	}
+}
+
+func TestElfArch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "elfarch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(protest.FindFixturesDir(), "continuetestprog.go")
+	for _, tc := range []struct{ goos, goarch string }{
+		{"linux", "amd64"},
+		{"linux", "arm64"},
+		{"freebsd", "amd64"},
+	} {
+		path := filepath.Join(dir, tc.goos+"_"+tc.goarch)
+		cmd := exec.Command("go", "build", "-o", path, src)
+		cmd.Env = append(os.Environ(), "GOOS="+tc.goos, "GOARCH="+tc.goarch, "CGO_ENABLED=0")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("could not build for %s/%s: %v\n%s", tc.goos, tc.goarch, err, out)
+		}
+		goos, goarch, ok := elfArch(path)
+		if !ok || goos != tc.goos || goarch != tc.goarch {
+			t.Errorf("elfArch(%s) = %q, %q, %v expected %q, %q", path, goos, goarch, ok, tc.goos, tc.goarch)
+		}
+		p := newProcess(nil)
+		switch err := p.selectArch(path); {
+		case err != nil:
+			t.Errorf("selectArch(%s): %v", path, err)
+		case p.bi.GOOS != tc.goos || p.bi.Arch.Name != tc.goarch:
+			t.Errorf("selectArch(%s) selected %s/%s", path, p.bi.GOOS, p.bi.Arch.Name)
+		case p.conn.regnames.arch != tc.goarch:
+			t.Errorf("selectArch(%s) selected the registers of %s", path, p.conn.regnames.arch)
+		}
+	}
+	if _, _, ok := elfArch(src); ok {
+		t.Errorf("elfArch(%s) succeeded on a source file", src)
+	}
+}
@@ -77,12 +77,20 @@ func Record(cmd []string, wd string, quiet bool) (tracedir string, err error) {
 
 // Replay starts an instance of rr in replay mode, with the specified trace
 // directory, and connects to it.
-func Replay(tracedir string, quiet, deleteOnDetach bool, debugInfoDirs []string) (*proc.Target, error) {
+// If rrOnProcessPid is not zero the process with that pid, which must be
+// one of the processes returned by ListTraceProcesses, is replayed instead
+// of the first process of the trace.
+func Replay(tracedir string, quiet, deleteOnDetach bool, debugInfoDirs []string, rrOnProcessPid int) (*proc.Target, error) {
 	if err := checkRRAvailabe(); err != nil {
 		return nil, err
 	}
 
-	rrcmd := exec.Command("rr", "replay", "--dbgport=0", tracedir)
+	args := []string{"replay", "--dbgport=0"}
+	if rrOnProcessPid != 0 {
+		args = append(args, fmt.Sprintf("--onprocess=%d", rrOnProcessPid))
+	}
+	args = append(args, tracedir)
+	rrcmd := exec.Command("rr", args...)
 	rrcmd.Stdout = os.Stdout
 	stderr, err := rrcmd.StderrPipe()
 	if err != nil {
@@ -120,6 +128,83 @@ func Replay(tracedir string, quiet, deleteOnDetach bool, debugInfoDirs []string)
 	return tgt, nil
 }
 
+// ReplayProcess starts a new replay of the trace replayed by t, debugging
+// the process with the given pid instead. If the trace directory was to be
+// deleted when detaching from t it will be deleted when detaching from the
+// new target instead.
+func ReplayProcess(t *proc.Target, pid int, quiet bool, debugInfoDirs []string) (*proc.Target, error) {
+	p, ok := t.Process.(*gdbProcess)
+	if !ok || p.tracedir == "" {
+		return nil, proc.ErrNotRecorded
+	}
+	procs, err := ListTraceProcesses(p.tracedir)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, tp := range procs {
+		if tp.Pid == pid {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no process with pid %d in the trace", pid)
+	}
+	tgt, err := Replay(p.tracedir, quiet, p.onDetach != nil, debugInfoDirs, pid)
+	if err != nil {
+		return nil, err
+	}
+	p.onDetach = nil
+	return tgt, nil
+}
+
+// TraceProcess describes a process recorded in a trace.
+type TraceProcess struct {
+	Pid  int
+	Ppid int // 0 for the first process of the trace
+	// Exit is the exit status of the process as printed by rr, either an
+	// exit code, a negative signal number or "none".
+	Exit string
+	Cmd  string
+}
+
+// ListTraceProcesses returns the list of processes recorded in tracedir.
+func ListTraceProcesses(tracedir string) ([]TraceProcess, error) {
+	if err := checkRRAvailabe(); err != nil {
+		return nil, err
+	}
+	out, err := exec.Command("rr", "ps", tracedir).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("rr ps: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return parseRRPs(string(out))
+}
+
+// parseRRPs parses the output of 'rr ps', a header line followed by one
+// line for each process with tab separated pid, parent pid, exit status and
+// command line.
+func parseRRPs(out string) ([]TraceProcess, error) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	r := make([]TraceProcess, 0, len(lines))
+	for _, line := range lines[1:] {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("can not parse rr ps line %q", line)
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("can not parse rr ps line %q", line)
+		}
+		ppid, _ := strconv.Atoi(fields[1])
+		r = append(r, TraceProcess{Pid: pid, Ppid: ppid, Exit: fields[2], Cmd: fields[3]})
+	}
+	return r, nil
+}
+
 // ErrPerfEventParanoid is the error returned by Reply and Record if
 // /proc/sys/kernel/perf_event_paranoid is greater than 1.
 type ErrPerfEventParanoid struct {
@@ -291,7 +376,7 @@ func RecordAndReplay(cmd []string, wd string, quiet bool, debugInfoDirs []string
 	if tracedir == "" {
 		return nil, "", err
 	}
-	t, err := Replay(tracedir, quiet, true, debugInfoDirs)
+	t, err := Replay(tracedir, quiet, true, debugInfoDirs, 0)
 	return t, tracedir, err
 }
 
@@ -0,0 +1,24 @@
+package gdbserial
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRRPs(t *testing.T) {
+	const out = "PID\tPPID\tEXIT\tCMD\n123\t--\t0\t/bin/prog a\n130\t123\t-9\tsh -c x\n"
+	procs, err := parseRRPs(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tgt := []TraceProcess{
+		{Pid: 123, Ppid: 0, Exit: "0", Cmd: "/bin/prog a"},
+		{Pid: 130, Ppid: 123, Exit: "-9", Cmd: "sh -c x"},
+	}
+	if !reflect.DeepEqual(procs, tgt) {
+		t.Fatalf("mismatch:\ngot:      %#v\nexpected: %#v", procs, tgt)
+	}
+	if _, err := parseRRPs("PID\tPPID\tEXIT\tCMD\nbad line\n"); err == nil {
+		t.Fatal("expected error parsing malformed line")
+	}
+}
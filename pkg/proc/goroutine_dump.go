@@ -0,0 +1,238 @@
+package proc
+
+import (
+	"errors"
+	"strings"
+)
+
+// GoroutineDump is implemented by processes that can not read the memory
+// of the target but know its goroutines and their stacktraces, for example
+// because they were created from the goroutine dump printed by the Go
+// runtime when a program crashes.
+// The goroutines of these processes are created by NewDumpedGoroutine, with
+// the process as their memory.
+type GoroutineDump interface {
+	// Goroutines returns the goroutines of the target, the first one is the
+	// goroutine running on the current thread.
+	Goroutines() []*G
+	// GoroutineStacktrace returns the stack frames of g.
+	GoroutineStacktrace(g *G) []Stackframe
+}
+
+// DumpedGoroutine is a goroutine read from a goroutine dump.
+type DumpedGoroutine struct {
+	ID int
+	// Status is the status of the goroutine as printed by the runtime, for
+	// example "running" or "chan receive".
+	Status string
+	Frames []DumpedFrame
+	// Elided is true if some of the frames of the goroutine were not printed.
+	Elided bool
+	// CreatedBy is the location of the go statement that created the
+	// goroutine, nil if it wasn't printed.
+	CreatedBy *DumpedFrame
+}
+
+// DumpedFrame is a stack frame of a DumpedGoroutine.
+type DumpedFrame struct {
+	Function string
+	File     string
+	Line     int
+	// Offset is the distance between the PC of the frame and the entry point
+	// of its function, -1 for inlined calls, which are printed without it.
+	Offset int64
+	// SP and FP of the frame, zero unless the goroutine dump was produced
+	// with GOTRACEBACK=system or higher.
+	SP, FP uint64
+	// RawArgs is the argument list printed for the frame, including the
+	// parentheses, exactly as the runtime printed it.
+	RawArgs string
+}
+
+// errGoroutineDump is the reason why goroutines read from a goroutine dump
+// are unreadable as variables.
+var errGoroutineDump = errors.New("memory is not available for goroutine dumps")
+
+// linkDumpedGoroutine associates the current thread of targets created
+// from a goroutine dump with its goroutine, which can not be read from the
+// TLS of the thread.
+func (t *Target) linkDumpedGoroutine() {
+	if gd, ok := t.proc.(GoroutineDump); ok {
+		if gs := gd.Goroutines(); len(gs) > 0 {
+			t.CurrentThread().Common().g = gs[0]
+		}
+	}
+}
+
+// dumpedGoroutinesInfo implements GoroutinesInfo for goroutine dumps.
+func dumpedGoroutinesInfo(gd GoroutineDump, start, count int) ([]*G, int) {
+	gs := gd.Goroutines()
+	if start >= len(gs) {
+		return nil, -1
+	}
+	gs = gs[start:]
+	if count != 0 && count < len(gs) {
+		return gs[:count], start + count
+	}
+	return gs, -1
+}
+
+// dumpedStacktrace returns the stack frames of g if it was read from a
+// goroutine dump.
+func (g *G) dumpedStacktrace() ([]Stackframe, bool) {
+	if g.variable == nil {
+		return nil, false
+	}
+	gd, ok := g.variable.mem.(GoroutineDump)
+	if !ok {
+		return nil, false
+	}
+	return gd.GoroutineStacktrace(g), true
+}
+
+// NewDumpedGoroutine converts a goroutine read from a goroutine dump into a
+// G struct and its stack frames, mem is the memory of the process the
+// goroutine belongs to, which must implement GoroutineDump.
+func NewDumpedGoroutine(bi *BinaryInfo, mem MemoryReadWriter, dg *DumpedGoroutine) (*G, []Stackframe) {
+	g := &G{
+		ID:     dg.ID,
+		Status: dumpedStatus(dg.Status),
+		variable: &Variable{
+			Name:       "runtime.curg",
+			bi:         bi,
+			mem:        mem,
+			Unreadable: errGoroutineDump,
+		},
+	}
+	frames := make([]Stackframe, 0, len(dg.Frames))
+
+	// Physical frames are printed with the offset of their PC, inlined calls
+	// share the PC of the physical frame that follows them.
+	pcs := make([]uint64, len(dg.Frames))
+	var pc uint64
+	for i := len(dg.Frames) - 1; i >= 0; i-- {
+		df := &dg.Frames[i]
+		if df.Offset >= 0 {
+			pc = 0
+			if fn := dumpedFunction(bi, df); fn != nil {
+				pc = fn.Entry + uint64(df.Offset)
+			}
+		}
+		pcs[i] = pc
+	}
+
+	// The PC of a physical frame is a return address, except for the topmost
+	// frame and frames interrupted by a signal, the printed position is the
+	// one of the call instruction.
+	callpcs := make([]uint64, len(dg.Frames))
+	var prev *DumpedFrame
+	for i := range dg.Frames {
+		df := &dg.Frames[i]
+		if df.Offset < 0 {
+			continue
+		}
+		callpcs[i] = pcs[i]
+		if prev != nil && pcs[i] > 0 && prev.Function != "runtime.sigpanic" {
+			callpcs[i]--
+		} else if prev == nil && pcs[i] > 0 {
+			// Frames above the topmost one can be hidden, in which case its PC
+			// is a return address too.
+			if _, line, _ := bi.PCToLine(pcs[i]); line != df.Line {
+				callpcs[i]--
+			}
+		}
+		prev = df
+	}
+	for i := len(dg.Frames) - 2; i >= 0; i-- {
+		if dg.Frames[i].Offset < 0 {
+			callpcs[i] = callpcs[i+1]
+		}
+	}
+
+	for i := range dg.Frames {
+		df := &dg.Frames[i]
+		fn := dumpedFunction(bi, df)
+		call := Location{PC: callpcs[i], File: df.File, Line: df.Line, Fn: fn}
+		current := call
+		current.PC = pcs[i]
+		if pcs[i] != callpcs[i] && fn != nil && fn.cu.lineInfo != nil {
+			current.File, current.Line = fn.cu.lineInfo.PCToLine(fn.Entry, pcs[i])
+		}
+		frame := Stackframe{
+			Current:  current,
+			Call:     call,
+			Regs:     bi.Arch.addrAndStackRegsToDwarfRegisters(0, pcs[i], df.SP, 0, 0),
+			Inlined:  df.Offset < 0,
+			lastpc:   callpcs[i],
+			RawArgs:  df.RawArgs,
+		}
+		frame.Regs.CFA = int64(df.FP)
+		frames = append(frames, frame)
+	}
+
+	if len(frames) > 0 {
+		top := frames[0]
+		g.PC = top.Current.PC
+		g.SP = dg.Frames[0].SP
+		g.CurrentLoc = top.Call
+		if !dg.Elided {
+			frames[len(frames)-1].Bottom = true
+			if fn := frames[len(frames)-1].Call.Fn; fn != nil {
+				g.StartPC = fn.Entry
+			}
+		}
+	}
+
+	if dg.CreatedBy != nil && dg.CreatedBy.Offset >= 0 {
+		if fn := dumpedFunction(bi, dg.CreatedBy); fn != nil {
+			g.GoPC = fn.Entry + uint64(dg.CreatedBy.Offset)
+		}
+	}
+
+	return g, frames
+}
+
+// dumpedFunction returns the function of a frame of a goroutine dump.
+func dumpedFunction(bi *BinaryInfo, df *DumpedFrame) *Function {
+	name := df.Function
+	if name == "panic" {
+		// runtime.gopanic is printed as 'panic'
+		name = "runtime.gopanic"
+	}
+	if fn := bi.LookupFunc[name]; fn != nil {
+		return fn
+	}
+	// The type parameters of generic functions are printed as '[...]',
+	// use the position of the frame to find it.
+	if i := strings.Index(name, "[...]"); i >= 0 {
+		name = name[:i]
+	}
+	pcs, _ := bi.LineToPC(df.File, df.Line)
+	for _, pc := range pcs {
+		if fn := bi.PCToFunc(pc); fn != nil && strings.HasPrefix(fn.Name, name) {
+			return fn
+		}
+	}
+	return nil
+}
+
+// dumpedStatus converts the status of a goroutine, as printed in a
+// goroutine dump, to one of the G status constants.
+func dumpedStatus(status string) uint64 {
+	switch status {
+	case "idle":
+		return Gidle
+	case "runnable":
+		return Grunnable
+	case "running":
+		return Grunning
+	case "syscall":
+		return Gsyscall
+	case "dead":
+		return Gdead
+	case "copystack":
+		return Gcopystack
+	default:
+		return Gwaiting
+	}
+}
@@ -30,6 +30,9 @@ type ProcessInternal interface {
 	// If pos starts with 'c' it's a checkpoint ID, otherwise it's an event
 	// number.
 	Restart(pos string) error
+	// SeekTicks moves the current recording position to the given number
+	// of ticks of the current thread.
+	SeekTicks(ticks uint64) error
 	Detach(bool) error
 	ContinueOnce() (trapthread Thread, stopReason StopReason, err error)
 }
@@ -45,6 +48,9 @@ type RecordingManipulation interface {
 	GetDirection() Direction
 	// When returns current recording position.
 	When() (string, error)
+	// WhenTicks returns the number of ticks, a measure of the progress of
+	// the current thread, at the current recording position.
+	WhenTicks() (uint64, error)
 	// Checkpoint sets a checkpoint at the current position.
 	Checkpoint(where string) (id int, err error)
 	// Checkpoints returns the list of currently set checkpoint.
@@ -20,6 +20,8 @@ var ErrTooManyLibraries = errors.New("number of loaded libraries exceeds maximum
 const (
 	_DT_NULL  = 0  // DT_NULL as defined by SysV ABI specification
 	_DT_DEBUG = 21 // DT_DEBUG as defined by SysV ABI specification
+
+	_RT_CONSISTENT = 0 // r_state of r_debug when the list of libraries is not being changed
 )
 
 // readUintRaw reads an integer of ptrSize bytes, with the specified byte order, from reader.
@@ -150,13 +152,36 @@ func ElfUpdateSharedObjects(p proc.Process) error {
 		return err
 	}
 	if debugAddr == 0 {
-		// no DT_DEBUG entry
+		// no DT_DEBUG entry, or the dynamic linker hasn't run yet: look again
+		// when the executable is started.
+		if entryPoint, err := p.EntryPoint(); err == nil {
+			setSharedLibraryBreakpoint(p, entryPoint)
+		}
 		return nil
 	}
 
 	// Offsets of the fields of the r_debug and link_map structs,
 	// see /usr/include/elf/link.h for a full description of those structs.
 	debugMapOffset := uint64(p.BinInfo().Arch.PtrSize())
+	debugBrkOffset := uint64(2 * p.BinInfo().Arch.PtrSize())
+	debugStateOffset := uint64(3 * p.BinInfo().Arch.PtrSize())
+
+	// r_brk is the address of the function called by the dynamic linker
+	// before and after it changes the list of libraries.
+	r_brk, err := readPtr(p, debugAddr+debugBrkOffset)
+	if err != nil {
+		return err
+	}
+	setSharedLibraryBreakpoint(p, r_brk)
+
+	r_state, err := readPtr(p, debugAddr+debugStateOffset)
+	if err != nil {
+		return err
+	}
+	if uint32(r_state) != _RT_CONSISTENT {
+		// the list of libraries is being changed
+		return nil
+	}
 
 	r_map, err := readPtr(p, debugAddr+debugMapOffset)
 	if err != nil {
@@ -183,3 +208,17 @@ func ElfUpdateSharedObjects(p proc.Process) error {
 
 	return nil
 }
+
+// setSharedLibraryBreakpoint sets a breakpoint used to stop the target
+// when the list of loaded libraries could have changed. Errors are
+// ignored, without this breakpoint new libraries are only noticed when the
+// target stops for other reasons.
+func setSharedLibraryBreakpoint(p proc.Process, addr uint64) {
+	if addr == 0 {
+		return
+	}
+	if bp, ok := p.Breakpoints().M[addr]; ok && bp.Kind&proc.SharedLibraryBreakpoint != 0 {
+		return
+	}
+	p.SetBreakpoint(addr, proc.SharedLibraryBreakpoint, nil)
+}
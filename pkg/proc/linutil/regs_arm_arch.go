@@ -0,0 +1,120 @@
+package linutil
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/arch/arm/armasm"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// ARMRegisters implements the proc.Registers interface for the core/linux
+// backend, on 32bit ARM.
+type ARMRegisters struct {
+	Regs   *ARMPtraceRegs  // general-purpose registers
+	Fpregs []proc.Register // formatted VFP registers
+}
+
+// ARMPtraceRegs is the struct used by the linux kernel to return the
+// general purpose registers for 32bit ARM CPUs: R0 to R15, CPSR and
+// ORIG_R0.
+type ARMPtraceRegs struct {
+	Uregs [18]uint32
+}
+
+// Slice returns the registers as a list of (name, value) pairs.
+func (r *ARMRegisters) Slice(floatingPoint bool) ([]proc.Register, error) {
+	out := make([]proc.Register, 0, 17+len(r.Fpregs))
+	for i := 0; i <= 12; i++ {
+		out = proc.AppendUint64Register(out, fmt.Sprintf("R%d", i), uint64(r.Regs.Uregs[i]))
+	}
+	out = proc.AppendUint64Register(out, "SP", uint64(r.Regs.Uregs[13]))
+	out = proc.AppendUint64Register(out, "LR", uint64(r.Regs.Uregs[14]))
+	out = proc.AppendUint64Register(out, "PC", uint64(r.Regs.Uregs[15]))
+	out = proc.AppendUint64Register(out, "CPSR", uint64(r.Regs.Uregs[16]))
+	if floatingPoint {
+		out = append(out, r.Fpregs...)
+	}
+	return out, nil
+}
+
+// PC returns the value of the PC register.
+func (r *ARMRegisters) PC() uint64 {
+	return uint64(r.Regs.Uregs[15])
+}
+
+// SP returns the value of the SP register.
+func (r *ARMRegisters) SP() uint64 {
+	return uint64(r.Regs.Uregs[13])
+}
+
+// BP returns the value of R11, the frame pointer for C code. Go does not
+// use a frame pointer on ARM.
+func (r *ARMRegisters) BP() uint64 {
+	return uint64(r.Regs.Uregs[11])
+}
+
+// TLS returns the address of the thread local storage memory segment.
+func (r *ARMRegisters) TLS() uint64 {
+	return 0
+}
+
+// GAddr returns the address of the G variable if it is known, 0 and false
+// otherwise.
+func (r *ARMRegisters) GAddr() (uint64, bool) {
+	return uint64(r.Regs.Uregs[10]), true
+}
+
+// Get returns the value of the n-th register (in armasm order).
+func (r *ARMRegisters) Get(n int) (uint64, error) {
+	reg := armasm.Reg(n)
+
+	if reg >= armasm.R0 && reg <= armasm.R15 {
+		return uint64(r.Regs.Uregs[reg-armasm.R0]), nil
+	}
+
+	return 0, proc.ErrUnknownRegister
+}
+
+// Copy returns a copy of these registers that is guarenteed not to change.
+func (r *ARMRegisters) Copy() (proc.Registers, error) {
+	var rr ARMRegisters
+	rr.Regs = &ARMPtraceRegs{}
+	*(rr.Regs) = *(r.Regs)
+	if r.Fpregs != nil {
+		rr.Fpregs = make([]proc.Register, len(r.Fpregs))
+		copy(rr.Fpregs, r.Fpregs)
+	}
+	return &rr, nil
+}
+
+// ARMVFPRegsLength is the size of the user_vfp struct, used by the linux
+// kernel for the NT_ARM_VFP note and PTRACE_GETVFPREGS.
+const ARMVFPRegsLength = 32*8 + 4
+
+// ARMVFPRegs is the contents of the VFP registers of an ARM thread.
+type ARMVFPRegs struct {
+	Vregs []byte
+	Fpscr uint32
+}
+
+// ARMVFPRegsRead decodes a user_vfp struct.
+func ARMVFPRegsRead(buf []byte) (*ARMVFPRegs, error) {
+	if len(buf) < ARMVFPRegsLength {
+		return nil, fmt.Errorf("VFP registers too short: %d bytes", len(buf))
+	}
+	fpregs := &ARMVFPRegs{Vregs: make([]byte, 32*8)}
+	copy(fpregs.Vregs, buf)
+	fpregs.Fpscr = binary.LittleEndian.Uint32(buf[32*8:])
+	return fpregs, nil
+}
+
+// Decode decodes the VFP registers to a list of name/value pairs.
+func (fpregs *ARMVFPRegs) Decode() (regs []proc.Register) {
+	for i := 0; i < len(fpregs.Vregs); i += 8 {
+		regs = proc.AppendBytesRegister(regs, fmt.Sprintf("D%d", i/8), fpregs.Vregs[i:i+8])
+	}
+	regs = proc.AppendUint64Register(regs, "FPSCR", uint64(fpregs.Fpscr))
+	return
+}
@@ -1,6 +1,7 @@
 package proc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -26,6 +27,27 @@ type MemoryReadWriter interface {
 	WriteMemory(addr uintptr, data []byte) (written int, err error)
 }
 
+// contextMemory is a MemoryReadWriter that fails with ctx.Err() once ctx
+// is done.
+type contextMemory struct {
+	MemoryReadWriter
+	ctx context.Context
+}
+
+func (m *contextMemory) ReadMemory(data []byte, addr uintptr) (int, error) {
+	if err := m.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return m.MemoryReadWriter.ReadMemory(data, addr)
+}
+
+func (m *contextMemory) WriteMemory(addr uintptr, data []byte) (int, error) {
+	if err := m.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return m.MemoryReadWriter.WriteMemory(addr, data)
+}
+
 type memCache struct {
 	loaded    bool
 	cacheAddr uintptr
@@ -90,6 +90,14 @@ func (p *nativeProcess) GetDirection() proc.Direction { return proc.Forward }
 // When will always return an empty string and nil, not supported on native proc backend.
 func (dbp *nativeProcess) When() (string, error) { return "", nil }
 
+// WhenTicks will always return an error on the native proc backend,
+// only supported for recorded traces.
+func (dbp *nativeProcess) WhenTicks() (uint64, error) { return 0, proc.ErrNotRecorded }
+
+// SeekTicks will always return an error on the native proc backend,
+// only supported for recorded traces.
+func (dbp *nativeProcess) SeekTicks(uint64) error { return proc.ErrNotRecorded }
+
 // Checkpoint will always return an error on the native proc backend,
 // only supported for recorded traces.
 func (dbp *nativeProcess) Checkpoint(string) (int, error) { return -1, proc.ErrNotRecorded }
@@ -37,7 +37,10 @@ type osProcessDetails struct {
 // custom fork/exec process in order to take advantage of
 // PT_SIGEXC on Darwin which will turn Unix signals into
 // Mach exceptions.
-func Launch(cmd []string, wd string, foreground bool, _ []string, _ string) (*proc.Target, error) {
+func Launch(cmd []string, wd string, foreground bool, _ []string, _ string, redirect proc.OutputRedirect) (*proc.Target, error) {
+	if redirect.Stdout != nil || redirect.Stderr != nil {
+		return nil, errors.New("output redirection is not supported by the native backend on macOS")
+	}
 	argv0Go, err := filepath.Abs(cmd[0])
 	if err != nil {
 		return nil, err
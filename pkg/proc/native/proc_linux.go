@@ -49,7 +49,7 @@ type osProcessDetails struct {
 // to be supplied to that process. `wd` is working directory of the program.
 // If the DWARF information cannot be found in the binary, Delve will look
 // for external debug files in the directories passed in.
-func Launch(cmd []string, wd string, foreground bool, debugInfoDirs []string, tty string) (*proc.Target, error) {
+func Launch(cmd []string, wd string, foreground bool, debugInfoDirs []string, tty string, redirect proc.OutputRedirect) (*proc.Target, error) {
 	var (
 		process *exec.Cmd
 		err     error
@@ -72,6 +72,12 @@ func Launch(cmd []string, wd string, foreground bool, debugInfoDirs []string, tt
 		process.Args = cmd
 		process.Stdout = os.Stdout
 		process.Stderr = os.Stderr
+		if redirect.Stdout != nil {
+			process.Stdout = redirect.Stdout
+		}
+		if redirect.Stderr != nil {
+			process.Stderr = redirect.Stderr
+		}
 		process.SysProcAttr = &syscall.SysProcAttr{
 			Ptrace:     true,
 			Setpgid:    true,
@@ -105,6 +111,11 @@ func Launch(cmd []string, wd string, foreground bool, debugInfoDirs []string, tt
 	if err != nil {
 		return nil, err
 	}
+	// The dynamic linker hasn't run yet, this sets a breakpoint used to read
+	// the list of shared libraries when it's done.
+	if err := linutil.ElfUpdateSharedObjects(dbp); err != nil {
+		return nil, err
+	}
 	return tgt, nil
 }
 
@@ -2,6 +2,7 @@ package native
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"syscall"
@@ -20,7 +21,7 @@ type osProcessDetails struct {
 }
 
 // Launch creates and begins debugging a new process.
-func Launch(cmd []string, wd string, foreground bool, _ []string, _ string) (*proc.Target, error) {
+func Launch(cmd []string, wd string, foreground bool, _ []string, _ string, redirect proc.OutputRedirect) (*proc.Target, error) {
 	argv0Go, err := filepath.Abs(cmd[0])
 	if err != nil {
 		return nil, err
@@ -28,12 +29,30 @@ func Launch(cmd []string, wd string, foreground bool, _ []string, _ string) (*pr
 
 	env := proc.DisableAsyncPreemptEnv()
 
+	files := []*os.File{os.Stdin, os.Stdout, os.Stderr}
+	for i, w := range []io.Writer{redirect.Stdout, redirect.Stderr} {
+		if w == nil {
+			continue
+		}
+		r, pw, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		// the write end is only needed by the child process
+		defer pw.Close()
+		go func(w io.Writer) {
+			io.Copy(w, r)
+			r.Close()
+		}(w)
+		files[i+1] = pw
+	}
+
 	var p *os.Process
 	dbp := newProcess(0)
 	dbp.execPtraceFunc(func() {
 		attr := &os.ProcAttr{
 			Dir:   wd,
-			Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+			Files: files,
 			Sys: &syscall.SysProcAttr{
 				CreationFlags: _DEBUG_ONLY_THIS_PROCESS,
 			},
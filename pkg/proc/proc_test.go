@@ -2,6 +2,7 @@ package proc_test
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"go/ast"
@@ -67,9 +68,9 @@ func withTestProcessArgs(name string, t testing.TB, wd string, args []string, bu
 
 	switch testBackend {
 	case "native":
-		p, err = native.Launch(append([]string{fixture.Path}, args...), wd, false, []string{}, "")
+		p, err = native.Launch(append([]string{fixture.Path}, args...), wd, false, []string{}, "", proc.OutputRedirect{})
 	case "lldb":
-		p, err = gdbserial.LLDBLaunch(append([]string{fixture.Path}, args...), wd, false, []string{}, "")
+		p, err = gdbserial.LLDBLaunch(append([]string{fixture.Path}, args...), wd, false, []string{}, "", proc.OutputRedirect{})
 	case "rr":
 		protest.MustHaveRecordingAllowed(t)
 		t.Log("recording")
@@ -2078,9 +2079,9 @@ func TestUnsupportedArch(t *testing.T) {
 
 	switch testBackend {
 	case "native":
-		p, err = native.Launch([]string{outfile}, ".", false, []string{}, "")
+		p, err = native.Launch([]string{outfile}, ".", false, []string{}, "", proc.OutputRedirect{})
 	case "lldb":
-		p, err = gdbserial.LLDBLaunch([]string{outfile}, ".", false, []string{}, "")
+		p, err = gdbserial.LLDBLaunch([]string{outfile}, ".", false, []string{}, "", proc.OutputRedirect{})
 	default:
 		t.Skip("test not valid for this backend")
 	}
@@ -2314,6 +2315,80 @@ func TestGoroutineLables(t *testing.T) {
 	})
 }
 
+func TestGoroutineFilterBreakpoint(t *testing.T) {
+	for _, tc := range []struct {
+		labels map[string]string
+		hit    bool
+	}{
+		{map[string]string{"k1": "v1"}, true},
+		{map[string]string{"k1": "v1", "k2": "v2"}, true},
+		{map[string]string{"k1": "v2"}, false},
+		{map[string]string{"k3": ""}, false},
+	} {
+		withTestProcess("goroutineLabels", t, func(p *proc.Target, fixture protest.Fixture) {
+			bp := setFunctionBreakpoint(p, t, "main.f")
+			bp.GoroutineFilter = &proc.GoroutineFilter{Labels: tc.labels}
+			assertNoError(p.Continue(), t, "Continue()") // first runtime.Breakpoint
+			assertNoError(p.Continue(), t, "Continue()")
+			bpstate := p.CurrentThread().Breakpoint()
+			if hit := bpstate.Breakpoint == bp; hit != tc.hit {
+				t.Errorf("%v: breakpoint hit %v, expected %v", tc.labels, hit, tc.hit)
+			}
+		})
+	}
+
+	withTestProcess("goroutineLabels", t, func(p *proc.Target, fixture protest.Fixture) {
+		bp := setFunctionBreakpoint(p, t, "main.f")
+		bp.GoroutineFilter = &proc.GoroutineFilter{ID: 1000}
+		assertNoError(p.Continue(), t, "Continue()")
+		assertNoError(p.Continue(), t, "Continue()")
+		if p.CurrentThread().Breakpoint().Breakpoint == bp {
+			t.Errorf("breakpoint hit by the wrong goroutine")
+		}
+	})
+}
+
+func TestStepIntoCall(t *testing.T) {
+	withTestProcess("stepintotarget", t, func(p *proc.Target, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture.Source, 18)
+		assertNoError(p.Continue(), t, "Continue()")
+		tgts, err := p.StepInTargets()
+		assertNoError(err, t, "StepInTargets()")
+		var names []string
+		for _, tgt := range tgts {
+			if tgt.Fn != nil {
+				names = append(names, tgt.Fn.Name)
+			}
+		}
+		if !reflect.DeepEqual(names, []string{"main.read", "main.parse", "main.process"}) {
+			t.Fatalf("wrong step in targets: %v", names)
+		}
+		// Only the call instructions on the current line are accepted.
+		for _, pc := range []uint64{currentPC(p, t), tgts[2].PC + 1} {
+			if err := p.StepIntoCall(pc); err == nil {
+				t.Fatalf("StepIntoCall(%#x) did not return an error", pc)
+			}
+		}
+		assertLineNumber(p, t, 18, "wrong line number after rejected StepIntoCall,")
+		callPC := tgts[2].PC
+		assertNoError(p.StepIntoCall(callPC), t, "StepIntoCall()")
+		assertLineNumber(p, t, 14, "wrong line number after StepIntoCall,")
+		if fn := p.BinInfo().PCToFunc(currentPC(p, t)); fn == nil || fn.Name != "main.process" {
+			t.Fatalf("wrong function after StepIntoCall: %v", fn)
+		}
+		assertNoError(p.StepOut(), t, "StepOut()")
+		tgts, err = p.StepInTargets()
+		assertNoError(err, t, "StepInTargets()")
+		if len(tgts) != 0 {
+			t.Fatalf("unexpected step in targets after executing all calls: %d", len(tgts))
+		}
+		// Calls that were already executed can not be stepped into.
+		if err := p.StepIntoCall(callPC); err == nil {
+			t.Fatal("StepIntoCall of an executed call did not return an error")
+		}
+	})
+}
+
 func TestStepOut(t *testing.T) {
 	testseq2(t, "testnextprog", "main.helloworld", []seqTest{{contContinue, 13}, {contStepout, 35}})
 }
@@ -4311,6 +4386,51 @@ func TestListImages(t *testing.T) {
 	})
 }
 
+func TestImagesLoaded(t *testing.T) {
+	// Continue must stop with StopLibraryLoaded when a plugin is loaded and
+	// ImagesLoaded returns true.
+	if runtime.GOOS != "linux" {
+		t.Skip("only implemented on linux")
+	}
+	pluginFixtures := protest.WithPlugins(t, protest.AllNonOptimized, "plugin1/", "plugin2/")
+
+	withTestProcessArgs("plugintest", t, ".", []string{pluginFixtures[0].Path, pluginFixtures[1].Path}, protest.AllNonOptimized, func(p *proc.Target, fixture protest.Fixture) {
+		loaded := []string{}
+		p.ImagesLoaded = func(images []*proc.Image) bool {
+			stop := false
+			for _, image := range images {
+				t.Logf("loaded %q", image.Path)
+				if image.Path == pluginFixtures[0].Path || image.Path == pluginFixtures[1].Path {
+					loaded = append(loaded, image.Path)
+					stop = true
+				}
+			}
+			return stop
+		}
+
+		for i, tc := range []struct {
+			stopReason proc.StopReason
+			nloaded    int
+		}{
+			{proc.StopLibraryLoaded, 1},
+			{proc.StopHardcodedBreakpoint, 1},
+			{proc.StopLibraryLoaded, 2},
+			{proc.StopHardcodedBreakpoint, 2},
+		} {
+			assertNoError(p.Continue(), t, fmt.Sprintf("Continue %d", i))
+			if p.StopReason != tc.stopReason {
+				t.Fatalf("%d: wrong stop reason %v, expected %v", i, p.StopReason, tc.stopReason)
+			}
+			if len(loaded) != tc.nloaded {
+				t.Fatalf("%d: wrong loaded images %q", i, loaded)
+			}
+		}
+		if loaded[0] != pluginFixtures[0].Path || loaded[1] != pluginFixtures[1].Path {
+			t.Fatalf("wrong loaded images %q", loaded)
+		}
+	})
+}
+
 func TestAncestors(t *testing.T) {
 	if !goversion.VersionAfterOrEqual(runtime.Version(), 1, 11) {
 		t.Skip("not supported on Go <= 1.10")
@@ -4792,3 +4912,33 @@ func TestStepIntoWrapperForEmbeddedPointer(t *testing.T) {
 
 	}
 }
+
+func TestCancelledStacktraceAndGoroutines(t *testing.T) {
+	withTestProcess("goroutinestackprog", t, func(p *proc.Target, fixture protest.Fixture) {
+		setFunctionBreakpoint(p, t, "main.stacktraceme")
+		assertNoError(p.Continue(), t, "Continue()")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, _, err := proc.GoroutinesInfoContext(ctx, p, 0, 0); err != context.Canceled {
+			t.Errorf("GoroutinesInfoContext with cancelled context returned %v", err)
+		}
+		if _, err := proc.ThreadStacktraceContext(ctx, p.CurrentThread(), 50); err != context.Canceled {
+			t.Errorf("ThreadStacktraceContext with cancelled context returned %v", err)
+		}
+
+		gs, _, err := proc.GoroutinesInfoContext(context.Background(), p, 0, 0)
+		assertNoError(err, t, "GoroutinesInfoContext()")
+		if len(gs) == 0 {
+			t.Errorf("no goroutines returned")
+		}
+
+		scope, err := proc.GoroutineScope(p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope()")
+		if _, err := scope.WithContext(ctx).EvalExpression("dummy", normalLoadConfig); err != context.Canceled {
+			t.Errorf("EvalExpression with cancelled context returned %v", err)
+		}
+		_, err = scope.WithContext(context.Background()).EvalExpression("dummy", normalLoadConfig)
+		assertNoError(err, t, "EvalExpression(dummy)")
+	})
+}
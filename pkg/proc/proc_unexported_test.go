@@ -1,7 +1,11 @@
 package proc
 
 import (
+	"encoding/binary"
+	"fmt"
 	"testing"
+
+	"github.com/go-delve/delve/pkg/dwarf/frame"
 )
 
 func TestAlignAddr(t *testing.T) {
@@ -73,3 +77,303 @@ func TestAlignAddr(t *testing.T) {
 		c(example.align, example.in+0x10000, example.tgt+0x10000)
 	}
 }
+
+func TestSharedBreakpoint(t *testing.T) {
+	bpmap := NewBreakpointMap()
+	writeBreakpoint := func(addr uint64) (string, int, *Function, []byte, error) {
+		return "panic.go", 10, &Function{Name: "runtime.gopanic"}, []byte{0}, nil
+	}
+	const addr = 0x1000
+	bp, err := bpmap.Set(addr, UserBreakpoint, nil, writeBreakpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bp.Name = "first"
+	bp.TotalHitCount = 2
+	shared, err := bpmap.SetShared(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared.Name = "second"
+	if shared.LogicalID == bp.LogicalID || shared.Addr != addr || shared.FunctionName != "runtime.gopanic" {
+		t.Fatalf("wrong shared breakpoint %#v", shared)
+	}
+	if _, err := bpmap.SetShared(0x2000); err == nil {
+		t.Fatal("SetShared on an address without breakpoints did not fail")
+	}
+
+	// Removing the logical breakpoint stored in the physical breakpoint
+	// replaces it with the shared one.
+	firstID, secondID := bp.LogicalID, shared.LogicalID
+	removed, ok := bpmap.ClearShared(addr, firstID)
+	if !ok || removed.LogicalID != firstID || removed.Name != "first" || removed.TotalHitCount != 2 {
+		t.Fatalf("wrong removed breakpoint %#v %v", removed, ok)
+	}
+	if bpmap.M[addr].LogicalID != secondID || bpmap.M[addr].Name != "second" || len(bpmap.M[addr].Shared) != 0 {
+		t.Fatalf("wrong remaining breakpoint %#v", bpmap.M[addr])
+	}
+	// The last logical breakpoint must be removed with Clear.
+	if _, ok := bpmap.ClearShared(addr, secondID); ok {
+		t.Fatal("ClearShared removed the last logical breakpoint")
+	}
+}
+
+// stackMemory implements MemoryReadWriter for a stack made of 8 byte words.
+type stackMemory map[uint64]uint64
+
+func (mem stackMemory) ReadMemory(buf []byte, addr uintptr) (int, error) {
+	v, ok := mem[uint64(addr)]
+	if !ok || len(buf) != 8 {
+		return 0, fmt.Errorf("could not read %d bytes at %#x", len(buf), addr)
+	}
+	binary.LittleEndian.PutUint64(buf, v)
+	return len(buf), nil
+}
+
+func (mem stackMemory) WriteMemory(uintptr, []byte) (int, error) {
+	return 0, fmt.Errorf("not implemented")
+}
+
+func TestFramePointerFallback(t *testing.T) {
+	const (
+		calleeEntry = 0x1000
+		callerEntry = 0x2000
+		sp          = 0xc000001000
+		bp          = 0xc000001040
+		callerbp    = 0xc000001080
+		ret         = callerEntry + 0x50
+	)
+
+	bi := NewBinaryInfo("linux", "amd64")
+	image := &Image{}
+	bi.Images = []*Image{image}
+	cu := &compileUnit{image: image}
+	bi.Functions = []Function{
+		{Name: "main.callee", Entry: calleeEntry, End: calleeEntry + 0x100, cu: cu},
+		{Name: "main.caller", Entry: callerEntry, End: callerEntry + 0x100, cu: cu},
+	}
+
+	// The frame descriptor entry of main.callee claims that the return
+	// address is at CFA-8 with CFA = SP+16, which can not be read.
+	debugFrame := []byte{
+		// CIE
+		0x10, 0x00, 0x00, 0x00, // length
+		0xff, 0xff, 0xff, 0xff, // CIE id
+		0x01,             // version
+		0x00,             // augmentation
+		0x01,             // code alignment factor
+		0x78,             // data alignment factor (-8)
+		0x10,             // return address register
+		0x0c, 0x07, 0x10, // DW_CFA_def_cfa rsp 16
+		0x90, 0x01, // DW_CFA_offset rip 1
+		0x00, 0x00, // padding
+
+		// FDE
+		0x14, 0x00, 0x00, 0x00, // length
+		0x00, 0x00, 0x00, 0x00, // CIE pointer
+		0x00, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // initial location
+		0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // address range
+	}
+	bi.frameEntries = frame.Parse(debugFrame, binary.LittleEndian, 0, 8)
+
+	mem := stackMemory{
+		bp:     callerbp,
+		bp + 8: ret,
+	}
+
+	regs := bi.Arch.addrAndStackRegsToDwarfRegisters(0, calleeEntry+0x10, sp, bp, 0)
+	it := newStackIterator(bi, mem, regs, 0, nil, 0, nil, 0)
+	callFrameRegs, gotret, retaddr, unwinder := it.advanceRegs()
+	if it.err != nil {
+		t.Fatalf("advanceRegs: %v", it.err)
+	}
+	if unwinder != UnwinderFramePointer {
+		t.Errorf("wrong unwinder %v", unwinder)
+	}
+	if gotret != ret || retaddr != bp+8 {
+		t.Errorf("wrong return address %#x at %#x", gotret, retaddr)
+	}
+	if callFrameRegs.SP() != bp+16 || callFrameRegs.BP() != callerbp {
+		t.Errorf("wrong caller registers SP=%#x BP=%#x", callFrameRegs.SP(), callFrameRegs.BP())
+	}
+
+	// The frame descriptor entry is readable but wrong, the return address
+	// it leads to doesn't belong to a known function.
+	mem[sp+8] = 0xdeadbeef
+	it = newStackIterator(bi, mem, regs, 0, nil, 0, nil, 0)
+	callFrameRegs, gotret, retaddr, unwinder = it.advanceRegs()
+	if it.err != nil || unwinder != UnwinderFramePointer || gotret != ret || retaddr != bp+8 {
+		t.Errorf("wrong unwind with a wrong return address %v %#x at %#x (%v)", unwinder, gotret, retaddr, it.err)
+	}
+	if callFrameRegs.SP() != bp+16 || callFrameRegs.BP() != callerbp {
+		t.Errorf("wrong caller registers SP=%#x BP=%#x", callFrameRegs.SP(), callFrameRegs.BP())
+	}
+
+	// If the frame pointer doesn't lead to a valid return address either
+	// the result of the frame descriptor entry is kept.
+	delete(mem, bp+8)
+	it = newStackIterator(bi, mem, regs, 0, nil, 0, nil, 0)
+	callFrameRegs, gotret, retaddr, unwinder = it.advanceRegs()
+	if unwinder != UnwinderDwarf || gotret != 0xdeadbeef || retaddr != sp+8 || callFrameRegs.SP() != sp+16 {
+		t.Errorf("wrong unwind without a valid frame pointer %v %#x at %#x SP=%#x", unwinder, gotret, retaddr, callFrameRegs.SP())
+	}
+	mem[bp+8] = ret
+
+	// With a valid return address the frame descriptor entry is used.
+	mem[sp+8] = ret
+	it = newStackIterator(bi, mem, regs, 0, nil, 0, nil, 0)
+	_, gotret, retaddr, unwinder = it.advanceRegs()
+	if unwinder != UnwinderDwarf || gotret != ret || retaddr != sp+8 {
+		t.Errorf("wrong unwind %v %#x at %#x", unwinder, gotret, retaddr)
+	}
+
+	// Without frame descriptor entries the frame pointer is used directly.
+	bi.frameEntries = nil
+	it = newStackIterator(bi, mem, regs, 0, nil, 0, nil, 0)
+	_, gotret, _, unwinder = it.advanceRegs()
+	if unwinder != UnwinderFramePointer || gotret != ret {
+		t.Errorf("wrong unwind without FDE %v %#x", unwinder, gotret)
+	}
+}
+
+func TestCExprToGo(t *testing.T) {
+	for _, tc := range []struct {
+		in, out string
+		changed bool
+	}{
+		{"p->field", "p.field", true},
+		{"p->next->val + 1", "p.next.val + 1", true},
+		{"a - >b", "a - >b", false},
+		{"(struct node *)ptr", "(*C.struct_node)(ptr)", true},
+		{"((struct node*)ptr)->next", "((*C.struct_node)(ptr)).next", true},
+		{"(union num)x.y[2]", "(C.union_num)(x.y[2])", true},
+		{"(const char *)s + 1", "(*C.char)(s) + 1", true},
+		{"(unsigned long)x", `("C.long unsigned int")(x)`, true},
+		{"(unsigned char)-x", `("C.unsigned char")(-x)`, true},
+		{"(void *)&x", "(unsafe.Pointer)(&x)", true},
+		{"(int)x", "(C.int)(x)", true},
+		{"(int)(x)", "(int)(x)", false},
+		{"(a) - b", "(a) - b", false},
+		{"(struct foo **)(char *)p", "(**C.struct_foo)((*C.char)(p))", true},
+	} {
+		out, changed := cExprToGo(tc.in)
+		if out != tc.out || changed != tc.changed {
+			t.Errorf("%q: expected %q %v got %q %v", tc.in, tc.out, tc.changed, out, changed)
+		}
+	}
+}
+
+func TestChangeLogicalID(t *testing.T) {
+	bpmap := NewBreakpointMap()
+	writeBreakpoint := func(addr uint64) (string, int, *Function, []byte, error) {
+		return "panic.go", 10, &Function{Name: "runtime.gopanic"}, []byte{0}, nil
+	}
+	const addr = 0x1000
+	bpmap.ReserveLogicalID(5)
+	bp, err := bpmap.Set(addr, UserBreakpoint, nil, writeBreakpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bp.LogicalID != 6 {
+		t.Fatalf("breakpoint created with reserved ID %d", bp.LogicalID)
+	}
+	shared, err := bpmap.SetShared(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bpmap.ChangeLogicalID(shared.LogicalID, 3)
+	bpmap.ChangeLogicalID(bp.LogicalID, 2)
+	if bp.LogicalID != 2 || shared.LogicalID != 3 {
+		t.Fatalf("wrong logical IDs %d %d", bp.LogicalID, shared.LogicalID)
+	}
+	if id := bpmap.NewLogicalID(); id != 6 {
+		t.Fatalf("wrong new logical ID %d", id)
+	}
+}
+
+func TestArmDecodeStrRegister(t *testing.T) {
+	for _, tc := range []struct {
+		enc  uint32
+		text string
+	}{
+		{0xe7801002, "STR R1, [R0, +R2]"},
+		{0xe780100b, "STR R1, [R0, +R11]"},
+		{0xe700100c, "STR R1, [R0, -R12]"},
+		{0xe7a0100a, "STR R1, [R0, +R10]!"},
+	} {
+		var buf [4]byte
+		binary.LittleEndian.PutUint32(buf[:], tc.enc)
+		inst, err := armDecode(buf[:])
+		if err != nil {
+			t.Errorf("%#x: %v", tc.enc, err)
+			continue
+		}
+		if inst.String() != tc.text || inst.Enc != tc.enc {
+			t.Errorf("%#x: got %q (%#x), expected %q", tc.enc, inst.String(), inst.Enc, tc.text)
+		}
+	}
+}
+
+func TestFramePointerFallbackARM64(t *testing.T) {
+	const (
+		calleeEntry = 0x1000
+		callerEntry = 0x2000
+		sp          = 0xc000001000
+		bp          = sp - 8 // the frame record is saved right below SP
+		callerbp    = 0xc000001078
+		ret         = callerEntry + 0x50
+	)
+
+	bi := NewBinaryInfo("linux", "arm64")
+	image := &Image{}
+	bi.Images = []*Image{image}
+	cu := &compileUnit{image: image}
+	bi.Functions = []Function{
+		{Name: "main.callee", Entry: calleeEntry, End: calleeEntry + 0x100, cu: cu},
+		{Name: "main.caller", Entry: callerEntry, End: callerEntry + 0x100, cu: cu},
+	}
+
+	// The frame descriptor entry of main.callee claims that the return
+	// address is at CFA-8 with CFA = SP+16, which can not be read.
+	debugFrame := []byte{
+		// CIE
+		0x10, 0x00, 0x00, 0x00, // length
+		0xff, 0xff, 0xff, 0xff, // CIE id
+		0x01,             // version
+		0x00,             // augmentation
+		0x04,             // code alignment factor
+		0x78,             // data alignment factor (-8)
+		0x1e,             // return address register
+		0x0c, 0x1f, 0x10, // DW_CFA_def_cfa sp 16
+		0x9e, 0x01, // DW_CFA_offset lr 1
+		0x00, 0x00, // padding
+
+		// FDE
+		0x14, 0x00, 0x00, 0x00, // length
+		0x00, 0x00, 0x00, 0x00, // CIE pointer
+		0x00, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // initial location
+		0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // address range
+	}
+	bi.frameEntries = frame.Parse(debugFrame, binary.LittleEndian, 0, 8)
+
+	mem := stackMemory{
+		bp:     callerbp,
+		bp + 8: ret,
+	}
+
+	regs := bi.Arch.addrAndStackRegsToDwarfRegisters(0, calleeEntry+0x10, sp, bp, 0)
+	it := newStackIterator(bi, mem, regs, 0, nil, 0, nil, 0)
+	callFrameRegs, gotret, retaddr, unwinder := it.advanceRegs()
+	if it.err != nil {
+		t.Fatalf("advanceRegs: %v", it.err)
+	}
+	if unwinder != UnwinderFramePointer {
+		t.Errorf("wrong unwinder %v", unwinder)
+	}
+	if gotret != ret || retaddr != sp {
+		t.Errorf("wrong return address %#x at %#x", gotret, retaddr)
+	}
+	if callFrameRegs.SP() != callerbp+8 || callFrameRegs.BP() != callerbp {
+		t.Errorf("wrong caller registers SP=%#x BP=%#x", callFrameRegs.SP(), callFrameRegs.BP())
+	}
+}
@@ -1,6 +1,7 @@
 package proc
 
 import (
+	"context"
 	"debug/dwarf"
 	"errors"
 	"fmt"
@@ -68,6 +69,41 @@ type Stackframe struct {
 
 	// Defers is the list of functions deferred by this stack frame (so far).
 	Defers []*Defer
+
+	// RawArgs is the argument list of the frame as printed by the runtime,
+	// only available for stacktraces read from a goroutine dump.
+	RawArgs string
+
+	// Unwinder is the method used to find this frame.
+	Unwinder FrameUnwinder
+}
+
+// FrameUnwinder is the method used to find a stack frame.
+type FrameUnwinder uint8
+
+const (
+	// UnwinderRegisters is used for frames read directly from the registers
+	// of a thread or goroutine, such as the topmost frame.
+	UnwinderRegisters FrameUnwinder = iota
+	// UnwinderDwarf is used for frames found using the call frame
+	// information of their callee.
+	UnwinderDwarf
+	// UnwinderFramePointer is used for frames found following the frame
+	// pointer of their callee.
+	UnwinderFramePointer
+)
+
+func (u FrameUnwinder) String() string {
+	switch u {
+	case UnwinderRegisters:
+		return "registers"
+	case UnwinderDwarf:
+		return "dwarf"
+	case UnwinderFramePointer:
+		return "frame pointer"
+	default:
+		return "unknown"
+	}
 }
 
 // FrameOffset returns the address of the stack frame, absolute for system
@@ -93,6 +129,12 @@ func (frame *Stackframe) FramePointerOffset() int64 {
 // ThreadStacktrace returns the stack trace for thread.
 // Note the locations in the array are return addresses not call addresses.
 func ThreadStacktrace(thread Thread, depth int) ([]Stackframe, error) {
+	return ThreadStacktraceContext(context.Background(), thread, depth)
+}
+
+// ThreadStacktraceContext is like ThreadStacktrace but stops unwinding the
+// stack, returning ctx.Err(), when ctx is done.
+func ThreadStacktraceContext(ctx context.Context, thread Thread, depth int) ([]Stackframe, error) {
 	g, _ := GetG(thread)
 	if g == nil {
 		regs, err := thread.Registers()
@@ -101,9 +143,10 @@ func ThreadStacktrace(thread Thread, depth int) ([]Stackframe, error) {
 		}
 		so := thread.BinInfo().PCToImage(regs.PC())
 		it := newStackIterator(thread.BinInfo(), thread, thread.BinInfo().Arch.RegistersToDwarfRegisters(so.StaticBase, regs), 0, nil, -1, nil, 0)
+		it.ctx = ctx
 		return it.stacktrace(depth)
 	}
-	return g.Stacktrace(depth, 0)
+	return g.StacktraceContext(ctx, depth, 0)
 }
 
 func (g *G) stackIterator(opts StacktraceOptions) (*stackIterator, error) {
@@ -150,10 +193,23 @@ const (
 // Stacktrace returns the stack trace for a goroutine.
 // Note the locations in the array are return addresses not call addresses.
 func (g *G) Stacktrace(depth int, opts StacktraceOptions) ([]Stackframe, error) {
+	return g.StacktraceContext(context.Background(), depth, opts)
+}
+
+// StacktraceContext is like Stacktrace but stops unwinding the stack,
+// returning ctx.Err(), when ctx is done.
+func (g *G) StacktraceContext(ctx context.Context, depth int, opts StacktraceOptions) ([]Stackframe, error) {
+	if frames, ok := g.dumpedStacktrace(); ok {
+		if depth+1 < len(frames) {
+			frames = frames[:depth+1]
+		}
+		return frames, nil
+	}
 	it, err := g.stackIterator(opts)
 	if err != nil {
 		return nil, err
 	}
+	it.ctx = ctx
 	frames, err := it.stacktrace(depth)
 	if err != nil {
 		return nil, err
@@ -190,12 +246,17 @@ type stackIterator struct {
 
 	// regs is the register set for the current frame
 	regs op.DwarfRegisters
+	// unwinder is the method used to compute regs
+	unwinder FrameUnwinder
 
 	g                  *G     // the goroutine being stacktraced, nil if we are stacktracing a goroutine-less thread
 	g0_sched_sp        uint64 // value of g0.sched.sp (see comments around its use)
 	g0_sched_sp_loaded bool   // g0_sched_sp was loaded from g0
 
 	opts StacktraceOptions
+
+	// ctx, if not nil, stops the iteration when it is done.
+	ctx context.Context
 }
 
 type savedLR struct {
@@ -235,9 +296,16 @@ func (it *stackIterator) Next() bool {
 	if it.err != nil || it.atend {
 		return false
 	}
+	if it.ctx != nil {
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+	}
 
-	callFrameRegs, ret, retaddr := it.advanceRegs()
+	callFrameRegs, ret, retaddr, unwinder := it.advanceRegs()
 	it.frame = it.newStackframe(ret, retaddr)
+	it.unwinder = unwinder
 
 	if it.stkbar != nil && it.frame.Ret == it.stackBarrierPC && it.frame.addrret == it.stkbar[0].ptr {
 		// Skip stack barrier frames
@@ -265,10 +333,11 @@ func (it *stackIterator) Next() bool {
 func (it *stackIterator) switchToGoroutineStack() {
 	it.systemstack = false
 	it.top = false
+	it.unwinder = UnwinderRegisters
 	it.pc = it.g.PC
 	it.regs.Reg(it.regs.SPRegNum).Uint64Val = it.g.SP
 	it.regs.AddReg(it.regs.BPRegNum, op.DwarfRegisterFromUint64(it.g.BP))
-	if it.bi.Arch.Name == "arm64" {
+	if it.bi.Arch.usesLR {
 		it.regs.Reg(it.regs.LRRegNum).Uint64Val = it.g.LR
 	}
 }
@@ -307,7 +376,7 @@ func (it *stackIterator) newStackframe(ret, retaddr uint64) Stackframe {
 	} else {
 		it.regs.FrameBase = it.frameBase(fn)
 	}
-	r := Stackframe{Current: Location{PC: it.pc, File: f, Line: l, Fn: fn}, Regs: it.regs, Ret: ret, addrret: retaddr, stackHi: it.stackhi, SystemStack: it.systemstack, lastpc: it.pc}
+	r := Stackframe{Current: Location{PC: it.pc, File: f, Line: l, Fn: fn}, Regs: it.regs, Ret: ret, addrret: retaddr, stackHi: it.stackhi, SystemStack: it.systemstack, lastpc: it.pc, Unwinder: it.unwinder}
 	r.Call = r.Current
 	if !it.top && r.Current.Fn != nil && it.pc != r.Current.Fn.Entry {
 		// if the return address is the entry point of the function that
@@ -343,7 +412,7 @@ func (it *stackIterator) stacktrace(depth int) ([]Stackframe, error) {
 		}
 	}
 	if err := it.Err(); err != nil {
-		if len(frames) == 0 {
+		if len(frames) == 0 || (it.ctx != nil && err == it.ctx.Err()) {
 			return nil, err
 		}
 		frames = append(frames, Stackframe{Err: err})
@@ -398,6 +467,7 @@ func (it *stackIterator) appendInlineCalls(frames []Stackframe, frame Stackframe
 			SystemStack: frame.SystemStack,
 			Inlined:     true,
 			lastpc:      frame.lastpc,
+			Unwinder:    frame.Unwinder,
 		})
 
 		frame.Call.File = frame.Current.Fn.cu.lineInfo.FileNames[fileidx-1].Path
@@ -408,9 +478,31 @@ func (it *stackIterator) appendInlineCalls(frames []Stackframe, frame Stackframe
 }
 
 // advanceRegs calculates it.callFrameRegs using it.regs and the frame
-// descriptor entry for the current stack frame.
+// descriptor entry for the current stack frame, falling back to the frame
+// pointer if the frame descriptor entry is missing (see
+// fixFrameUnwindContext) or the return address can not be computed from it
+// or doesn't belong to a known function. The result of the frame
+// descriptor entry is kept if the frame pointer doesn't lead to a valid
+// return address either.
 // it.regs.CallFrameCFA is updated.
-func (it *stackIterator) advanceRegs() (callFrameRegs op.DwarfRegisters, ret uint64, retaddr uint64) {
+func (it *stackIterator) advanceRegs() (callFrameRegs op.DwarfRegisters, ret uint64, retaddr uint64, unwinder FrameUnwinder) {
+	callFrameRegs, ret, retaddr, unwinder = it.advanceRegsDwarf()
+	if !it.bi.Arch.framePointers || unwinder == UnwinderFramePointer {
+		return
+	}
+	if it.err == nil && (ret == 0 || it.validReturnAddress(ret)) {
+		return
+	}
+	if fpRegs, fpRet, fpRetaddr, ok := it.advanceRegsFramePointer(); ok {
+		it.err = nil
+		return fpRegs, fpRet, fpRetaddr, UnwinderFramePointer
+	}
+	return
+}
+
+// advanceRegsDwarf calculates it.callFrameRegs using it.regs and the frame
+// descriptor entry for the current stack frame.
+func (it *stackIterator) advanceRegsDwarf() (callFrameRegs op.DwarfRegisters, ret uint64, retaddr uint64, unwinder FrameUnwinder) {
 	fde, err := it.bi.frameEntries.FDEForPC(it.pc)
 	var framectx *frame.FrameContext
 	if _, nofde := err.(*frame.ErrNoFDEForPC); nofde {
@@ -419,10 +511,17 @@ func (it *stackIterator) advanceRegs() (callFrameRegs op.DwarfRegisters, ret uin
 		framectx = it.bi.Arch.fixFrameUnwindContext(fde.EstablishFrame(it.pc), it.pc, it.bi)
 	}
 
+	unwinder = UnwinderDwarf
+	if framectx.CFA.Rule == frame.RuleCFA && framectx.CFA.Reg == it.regs.BPRegNum {
+		// the architecture specific rules replaced the frame descriptor entry
+		// with the frame pointer
+		unwinder = UnwinderFramePointer
+	}
+
 	cfareg, err := it.executeFrameRegRule(0, framectx.CFA, 0)
 	if cfareg == nil {
 		it.err = fmt.Errorf("CFA becomes undefined at PC %#x", it.pc)
-		return op.DwarfRegisters{}, 0, 0
+		return op.DwarfRegisters{}, 0, 0, unwinder
 	}
 	it.regs.CFA = int64(cfareg.Uint64Val)
 
@@ -455,13 +554,77 @@ func (it *stackIterator) advanceRegs() (callFrameRegs op.DwarfRegisters, ret uin
 		}
 	}
 
-	if it.bi.Arch.Name == "arm64" {
+	if it.bi.Arch.usesLR {
 		if ret == 0 && it.regs.Reg(it.regs.LRRegNum) != nil {
 			ret = it.regs.Reg(it.regs.LRRegNum).Uint64Val
 		}
 	}
 
-	return callFrameRegs, ret, retaddr
+	return callFrameRegs, ret, retaddr, unwinder
+}
+
+// advanceRegsFramePointer calculates it.callFrameRegs following the frame
+// pointer of the current stack frame. It returns false if the frame
+// pointer does not point to the stack or the return address it leads to
+// does not belong to a known function.
+// On both amd64 and arm64 the frame pointer points to a frame record
+// containing the frame pointer of the caller followed by the return
+// address. On amd64 the frame record is at the top of the frame and the
+// stack pointer of the caller is right above it. On arm64 the frame record
+// is saved right below the stack pointer (the return address is at SP) so
+// the stack pointer of the caller is right above the frame record of the
+// caller.
+func (it *stackIterator) advanceRegsFramePointer() (callFrameRegs op.DwarfRegisters, ret uint64, retaddr uint64, ok bool) {
+	bpreg := it.regs.Reg(it.regs.BPRegNum)
+	if bpreg == nil {
+		return op.DwarfRegisters{}, 0, 0, false
+	}
+	bp := bpreg.Uint64Val
+	ptrSize := uint64(it.bi.Arch.PtrSize())
+	if bp == 0 || bp+ptrSize < it.regs.SP() || (!it.systemstack && it.stackhi != 0 && bp >= it.stackhi) {
+		return op.DwarfRegisters{}, 0, 0, false
+	}
+
+	callerbp, err := it.readRegisterAt(it.regs.BPRegNum, bp)
+	if err != nil {
+		return op.DwarfRegisters{}, 0, 0, false
+	}
+	retaddr = bp + ptrSize
+	retreg, err := it.readRegisterAt(it.regs.PCRegNum, retaddr)
+	if err != nil {
+		return op.DwarfRegisters{}, 0, 0, false
+	}
+	ret = retreg.Uint64Val
+	if !it.validReturnAddress(ret) {
+		return op.DwarfRegisters{}, 0, 0, false
+	}
+
+	it.regs.CFA = int64(bp + 2*ptrSize)
+	if it.bi.Arch.usesLR && callerbp.Uint64Val != 0 {
+		// without a frame record for the caller bp+2*ptrSize is the best guess
+		it.regs.CFA = int64(callerbp.Uint64Val + ptrSize)
+	}
+
+	callimage := it.bi.PCToImage(it.pc)
+
+	callFrameRegs = op.DwarfRegisters{StaticBase: callimage.StaticBase, ByteOrder: it.regs.ByteOrder, PCRegNum: it.regs.PCRegNum, SPRegNum: it.regs.SPRegNum, BPRegNum: it.regs.BPRegNum, LRRegNum: it.regs.LRRegNum}
+	callFrameRegs.AddReg(callFrameRegs.SPRegNum, op.DwarfRegisterFromUint64(uint64(it.regs.CFA)))
+	callFrameRegs.AddReg(callFrameRegs.BPRegNum, callerbp)
+	callFrameRegs.AddReg(callFrameRegs.PCRegNum, retreg)
+
+	return callFrameRegs, ret, retaddr, true
+}
+
+// validReturnAddress returns true if ret is a plausible return address,
+// i.e. it belongs to a known function or it is the first instruction after
+// a call at the end of a known function or of code described by a frame
+// descriptor entry.
+func (it *stackIterator) validReturnAddress(ret uint64) bool {
+	if it.bi.PCToFunc(ret) != nil || it.bi.PCToFunc(ret-1) != nil {
+		return true
+	}
+	_, err := it.bi.frameEntries.FDEForPC(ret - 1)
+	return err == nil
 }
 
 func (it *stackIterator) executeFrameRegRule(regnum uint64, rule frame.DWRule, cfa int64) (*op.DwarfRegister, error) {
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"go/constant"
+	"io"
 	"os"
 	"strings"
 
@@ -48,6 +49,18 @@ type Target struct {
 	// have read and parsed from the targets memory.
 	// This must be cleared whenever the target is resumed.
 	gcache goroutineCache
+
+	// ImagesLoaded, if not nil, is called by Continue when it finds that
+	// the target loaded new images (shared libraries or plugins). If it
+	// returns true the target stops, with StopLibraryLoaded unless it also
+	// stopped for another reason.
+	// Images are never removed from BinInfo, unloading a library is not
+	// reported.
+	ImagesLoaded func(images []*Image) bool
+
+	// warnings are the problems found while loading the target that did
+	// not prevent it from being loaded.
+	warnings []string
 }
 
 // ErrProcessExited indicates that the process has exited and contains both
@@ -61,6 +74,14 @@ func (pe ErrProcessExited) Error() string {
 	return fmt.Sprintf("Process %d has exited with status %d", pe.Pid, pe.Status)
 }
 
+// OutputRedirect specifies where the standard output and standard error
+// of a target launched by Delve are written. A nil writer means that the
+// corresponding stream is inherited from Delve.
+type OutputRedirect struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
 // StopReason describes the reason why the target process is stopped.
 // A process could be stopped for multiple simultaneous reasons, in which
 // case only one will be reported.
@@ -76,6 +97,7 @@ const (
 	StopManual                         // A manual stop was requested
 	StopNextFinished                   // The next/step/stepout command terminated
 	StopCallReturned                   // An injected call completed
+	StopLibraryLoaded                  // A shared library or plugin was loaded
 )
 
 // NewTargetConfig contains the configuration for a new Target object,
@@ -85,6 +107,7 @@ type NewTargetConfig struct {
 	WriteBreakpoint     WriteBreakpointFn // Function to write a breakpoint to the target process
 	DisableAsyncPreempt bool              // Go 1.14 asynchronous preemption should be disabled
 	StopReason          StopReason        // Initial stop reason
+	Warnings            []string          // Problems found while loading the target that should be reported to the user
 }
 
 // DisableAsyncPreemptEnv returns a process environment (like os.Environ)
@@ -123,8 +146,11 @@ func NewTarget(p Process, cfg NewTargetConfig) (*Target, error) {
 		proc:       p.(ProcessInternal),
 		fncallForG: make(map[int]*callInjection),
 		StopReason: cfg.StopReason,
+		warnings:   cfg.Warnings,
 	}
 
+	t.linkDumpedGoroutine()
+
 	g, _ := GetG(p.CurrentThread())
 	t.selectedGoroutine = g
 
@@ -140,6 +166,13 @@ func NewTarget(p Process, cfg NewTargetConfig) (*Target, error) {
 	return t, nil
 }
 
+// Warnings returns the problems found while loading the target that did
+// not prevent it from being loaded, for example parts of its memory that
+// can not be read.
+func (t *Target) Warnings() []string {
+	return t.warnings
+}
+
 // SupportsFunctionCalls returns whether or not the backend supports
 // calling functions during a debug session.
 // Currently only non-recorded processes running on AMD64 support
@@ -158,6 +191,7 @@ func (t *Target) ClearAllGCache() {
 	for _, thread := range t.ThreadList() {
 		thread.Common().g = nil
 	}
+	t.linkDumpedGoroutine()
 }
 
 // Restart will start the process over from the location specified by the "from" locspec.
@@ -178,6 +212,18 @@ func (t *Target) Restart(from string) error {
 	return nil
 }
 
+// SeekTicks moves the current position of a recording to the given number
+// of ticks of the current thread.
+func (t *Target) SeekTicks(ticks uint64) error {
+	t.ClearAllGCache()
+	if err := t.proc.SeekTicks(ticks); err != nil {
+		return err
+	}
+	t.selectedGoroutine, _ = GetG(t.CurrentThread())
+	t.StopReason = StopManual
+	return nil
+}
+
 // SelectedGoroutine returns the currently selected goroutine.
 func (t *Target) SelectedGoroutine() *G {
 	return t.selectedGoroutine
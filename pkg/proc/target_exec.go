@@ -33,7 +33,7 @@ func (dbp *Target) Next() (err error) {
 		return fmt.Errorf("next while nexting")
 	}
 
-	if err = next(dbp, false, false); err != nil {
+	if err = next(dbp, false, false, 0); err != nil {
 		dbp.ClearInternalBreakpoints()
 		return
 	}
@@ -67,6 +67,7 @@ func (dbp *Target) Continue() error {
 			return nil
 		}
 		dbp.ClearAllGCache()
+		nimages := len(dbp.BinInfo().Images)
 		trapthread, stopReason, err := dbp.proc.ContinueOnce()
 		dbp.StopReason = stopReason
 		if err != nil {
@@ -76,6 +77,11 @@ func (dbp *Target) Continue() error {
 			dbp.ClearInternalBreakpoints()
 		}
 
+		stopOnLoad := false
+		if images := dbp.BinInfo().Images; len(images) > nimages && dbp.ImagesLoaded != nil {
+			stopOnLoad = dbp.ImagesLoaded(images[nimages:])
+		}
+
 		threads := dbp.ThreadList()
 
 		callInjectionDone, callErr := callInjectionProtocol(dbp, threads)
@@ -94,6 +100,15 @@ func (dbp *Target) Continue() error {
 		curthread := dbp.CurrentThread()
 		curbp := curthread.Breakpoint()
 
+		// A step breakpoint would resume the target, stop for the load instead.
+		// Every other breakpoint stops the target, the client finds out about
+		// the load from the on-load breakpoint that was hit.
+		if stopOnLoad && (!curbp.Active || (curbp.Internal && curbp.Kind == StepBreakpoint)) {
+			dbp.StopReason = StopLibraryLoaded
+			dbp.ClearInternalBreakpoints()
+			return conditionErrors(threads)
+		}
+
 		switch {
 		case curbp.Breakpoint == nil:
 			// runtime.Breakpoint, manual stop or debugCallV1-related stop
@@ -270,7 +285,7 @@ func (dbp *Target) Step() (err error) {
 		return fmt.Errorf("next while nexting")
 	}
 
-	if err = next(dbp, true, false); err != nil {
+	if err = next(dbp, true, false, 0); err != nil {
 		switch err.(type) {
 		case ErrThreadBlocked: // Noop
 		default:
@@ -287,6 +302,111 @@ func (dbp *Target) Step() (err error) {
 	return dbp.Continue()
 }
 
+// StepInTarget is a function call on the current line of the selected
+// goroutine, which can be stepped into with StepIntoCall.
+type StepInTarget struct {
+	// PC is the address of the call instruction.
+	PC uint64
+	// Fn is the called function, nil for indirect calls (for example calls
+	// of closures or of interface methods) which are only resolved when they
+	// are executed.
+	Fn *Function
+}
+
+// StepInTargets returns the function calls on the current line of the
+// selected goroutine that have not been executed yet, in the order they
+// appear in the function's code.
+// Calls to unexported runtime functions and inlined calls are not included.
+func (dbp *Target) StepInTargets() ([]StepInTarget, error) {
+	return dbp.GoroutineStepInTargets(dbp.SelectedGoroutine())
+}
+
+// GoroutineStepInTargets is like StepInTargets but lists the function
+// calls on the current line of g, without changing the selected goroutine.
+// If g is nil the calls on the current line of the current thread are
+// listed.
+func (dbp *Target) GoroutineStepInTargets(selg *G) ([]StepInTarget, error) {
+	if _, err := dbp.Valid(); err != nil {
+		return nil, err
+	}
+	curthread := dbp.CurrentThread()
+	topframe, _, err := topframe(selg, curthread)
+	if err != nil {
+		return nil, err
+	}
+	if topframe.Current.Fn == nil {
+		return nil, &ErrNoSourceForPC{topframe.Current.PC}
+	}
+
+	var thread MemoryReadWriter = curthread
+	var regs Registers
+	if selg != nil && selg.Thread != nil {
+		thread = selg.Thread
+		regs, err = selg.Thread.Registers()
+		if err != nil {
+			return nil, err
+		}
+	}
+	text, err := disassemble(thread, regs, dbp.Breakpoints(), dbp.BinInfo(), topframe.Current.Fn.Entry, topframe.Current.Fn.End, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var r []StepInTarget
+	for _, instr := range text {
+		if instr.Loc.PC < topframe.Current.PC || instr.Loc.File != topframe.Current.File || instr.Loc.Line != topframe.Current.Line || !instr.IsCall() {
+			continue
+		}
+		tgt := StepInTarget{PC: instr.Loc.PC}
+		if instr.DestLoc != nil {
+			pc := instr.DestLoc.PC
+			if fn := instr.DestLoc.Fn; fn == nil || fn.privateRuntime() || dbp.BinInfo().Arch.inhibitStepInto(dbp.BinInfo(), pc) {
+				continue
+			}
+			tgt.Fn, _ = skipAutogeneratedWrappersIn(dbp, instr.DestLoc.Fn, pc)
+		}
+		r = append(r, tgt)
+	}
+	return r, nil
+}
+
+// StepIntoCall continues until another source line is reached, like Step,
+// but only steps into the function called by the call instruction at
+// callPC, all other calls on the current line are stepped over.
+// callPC must be the PC of one of the targets returned by StepInTargets.
+func (dbp *Target) StepIntoCall(callPC uint64) (err error) {
+	if _, err := dbp.Valid(); err != nil {
+		return err
+	}
+	if dbp.Breakpoints().HasInternalBreakpoints() {
+		return fmt.Errorf("next while nexting")
+	}
+	if dbp.GetDirection() == Backward {
+		return errors.New("can not step into a specific call backwards")
+	}
+	tgts, err := dbp.StepInTargets()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, tgt := range tgts {
+		if tgt.PC == callPC {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("%#x is not a call on the current line that has yet to be executed", callPC)
+	}
+
+	if err = next(dbp, true, false, callPC); err != nil {
+		dbp.ClearInternalBreakpoints()
+		return err
+	}
+
+	return dbp.Continue()
+}
+
 // sameGoroutineCondition returns an expression that evaluates to true when
 // the current goroutine is g.
 func sameGoroutineCondition(g *G) ast.Expr {
@@ -327,7 +447,7 @@ func (dbp *Target) StepOut() error {
 	}()
 
 	if topframe.Inlined {
-		if err := next(dbp, false, true); err != nil {
+		if err := next(dbp, false, true, 0); err != nil {
 			return err
 		}
 
@@ -439,7 +559,9 @@ func (dbp *Target) StepInstruction() (err error) {
 // for an inlined function call. Everything works the same as normal except
 // when removing instructions belonging to inlined calls we also remove all
 // instructions belonging to the current inlined call.
-func next(dbp *Target, stepInto, inlinedStepOut bool) error {
+// If stepInto is true and stepIntoCall is not zero only the call
+// instruction at stepIntoCall is stepped into.
+func next(dbp *Target, stepInto, inlinedStepOut bool, stepIntoCall uint64) error {
 	backward := dbp.GetDirection() == Backward
 	selg := dbp.SelectedGoroutine()
 	curthread := dbp.CurrentThread()
@@ -513,7 +635,7 @@ func next(dbp *Target, stepInto, inlinedStepOut bool) error {
 	sameFrameCond := astutil.And(sameGCond, frameoffCondition(&topframe))
 
 	if stepInto && !backward {
-		err := setStepIntoBreakpoints(dbp, text, topframe, sameGCond)
+		err := setStepIntoBreakpoints(dbp, text, topframe, sameGCond, stepIntoCall)
 		if err != nil {
 			return err
 		}
@@ -546,7 +668,7 @@ func next(dbp *Target, stepInto, inlinedStepOut bool) error {
 		}
 	}
 
-	if !stepInto {
+	if !stepInto || stepIntoCall != 0 {
 		// Removing any PC range belonging to an inlined call
 		frame := topframe
 		if inlinedStepOut {
@@ -625,11 +747,14 @@ func next(dbp *Target, stepInto, inlinedStepOut bool) error {
 	return nil
 }
 
-func setStepIntoBreakpoints(dbp Process, text []AsmInstruction, topframe Stackframe, sameGCond ast.Expr) error {
+func setStepIntoBreakpoints(dbp Process, text []AsmInstruction, topframe Stackframe, sameGCond ast.Expr, callPC uint64) error {
 	for _, instr := range text {
 		if instr.Loc.File != topframe.Current.File || instr.Loc.Line != topframe.Current.Line || !instr.IsCall() {
 			continue
 		}
+		if callPC != 0 && instr.Loc.PC != callPC {
+			continue
+		}
 
 		if instr.DestLoc != nil {
 			if err := setStepIntoBreakpoint(dbp, []AsmInstruction{instr}, sameGCond); err != nil {
@@ -539,6 +539,154 @@ func fieldToType(mds []moduleData, _type *Variable, fieldName string) (string, e
 	return typename, err
 }
 
+// runtimeTypeImplements returns true if the type described by _type, a
+// runtime._type variable, implements the interface type ityp.
+// Like getitab in $GOROOT/src/runtime/iface.go it compares both the names
+// and the types of the methods.
+func runtimeTypeImplements(bi *BinaryInfo, mem MemoryReadWriter, _type *Variable, ityp *godwarf.InterfaceType) (bool, error) {
+	itypeAddr, _, found, err := dwarfToRuntimeType(bi, mem, ityp)
+	if err != nil || !found {
+		return false, err
+	}
+	mds, err := loadModuleData(bi, mem)
+	if err != nil {
+		return false, err
+	}
+	rtyp, err := bi.findType("runtime._type")
+	if err != nil {
+		return false, err
+	}
+	imethods, err := interfaceRuntimeTypeMethods(mds, newVariable("", uintptr(itypeAddr), rtyp, bi, mem))
+	if err != nil {
+		return false, err
+	}
+	methods, err := runtimeTypeMethods(mds, _type)
+	if err != nil {
+		return false, err
+	}
+	for name, mtyp := range imethods {
+		if mtyp2, ok := methods[name]; !ok || mtyp2 != mtyp {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// interfaceRuntimeTypeMethods returns the methods of the interface
+// described by _type, a runtime._type variable, as a map from method names
+// to the address of the runtime._type describing the method's signature.
+func interfaceRuntimeTypeMethods(mds []moduleData, _type *Variable) (map[string]uintptr, error) {
+	ityp, err := specificRuntimeType(_type, int64(reflect.Interface))
+	if err != nil {
+		return nil, err
+	}
+	methods, err := ityp.structMember(interfacetypeFieldMhdr)
+	if err != nil {
+		return nil, err
+	}
+	methods.loadArrayValues(0, LoadConfig{false, 1, 0, 4096, -1, 0})
+	if methods.Unreadable != nil {
+		return nil, methods.Unreadable
+	}
+
+	r := make(map[string]uintptr, len(methods.Children))
+	for _, im := range methods.Children {
+		var name string
+		var mtyp uintptr
+		for i := range im.Children {
+			switch im.Children[i].Name {
+			case imethodFieldName:
+				nameoff, _ := constant.Int64Val(im.Children[i].Value)
+				name, _, _, err = resolveNameOff(ityp.bi, mds, ityp.Addr, uintptr(nameoff), ityp.mem)
+				if err != nil {
+					return nil, err
+				}
+			case imethodFieldItyp:
+				typeoff, _ := constant.Int64Val(im.Children[i].Value)
+				mtyp, err = resolveTypeOffAddr(ityp.bi, mds, ityp.Addr, uintptr(typeoff), ityp.mem)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		r[name] = mtyp
+	}
+	return r, nil
+}
+
+// runtimeTypeMethods returns the methods of the type described by _type, a
+// runtime._type variable, in the same format used by
+// interfaceRuntimeTypeMethods.
+// See runtime.(*uncommontype).methods in $GOROOT/src/runtime/type.go.
+func runtimeTypeMethods(mds []moduleData, _type *Variable) (map[string]uintptr, error) {
+	var tflag, kind int64
+	if tflagField := _type.loadFieldNamed("tflag"); tflagField != nil && tflagField.Value != nil {
+		tflag, _ = constant.Int64Val(tflagField.Value)
+	}
+	if kindField := _type.loadFieldNamed("kind"); kindField != nil && kindField.Value != nil {
+		kind, _ = constant.Int64Val(kindField.Value)
+	}
+	styp, err := specificRuntimeType(_type, kind)
+	if err != nil {
+		return nil, err
+	}
+	ut := uncommon(styp, tflag)
+	if ut == nil {
+		return nil, nil
+	}
+	var mcount, moff int64
+	if mcountField := ut.loadFieldNamed("mcount"); mcountField != nil && mcountField.Value != nil {
+		mcount, _ = constant.Int64Val(mcountField.Value)
+	}
+	if moffField := ut.loadFieldNamed("moff"); moffField != nil && moffField.Value != nil {
+		moff, _ = constant.Int64Val(moffField.Value)
+	}
+	methodType, err := _type.bi.findType("runtime.method")
+	if err != nil {
+		return nil, err
+	}
+
+	r := make(map[string]uintptr, mcount)
+	for i := int64(0); i < mcount; i++ {
+		m := _type.newVariable("", ut.Addr+uintptr(moff+i*methodType.Size()), methodType, _type.mem)
+		var nameoff, mtypoff int64
+		if nameField := m.loadFieldNamed("name"); nameField != nil && nameField.Value != nil {
+			nameoff, _ = constant.Int64Val(nameField.Value)
+		}
+		if mtypField := m.loadFieldNamed("mtyp"); mtypField != nil && mtypField.Value != nil {
+			mtypoff, _ = constant.Int64Val(mtypField.Value)
+		}
+		if mtypoff == -1 {
+			// the linker removed this method because it is never called
+			// through an interface.
+			continue
+		}
+		name, _, _, err := resolveNameOff(_type.bi, mds, _type.Addr, uintptr(nameoff), _type.mem)
+		if err != nil {
+			return nil, err
+		}
+		r[name], err = resolveTypeOffAddr(_type.bi, mds, _type.Addr, uintptr(mtypoff), _type.mem)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// resolveTypeOffAddr is like resolveTypeOff but returns the address of the
+// runtime._type struct.
+func resolveTypeOffAddr(bi *BinaryInfo, mds []moduleData, typeAddr uintptr, off uintptr, mem MemoryReadWriter) (uintptr, error) {
+	typ, err := resolveTypeOff(bi, mds, typeAddr, off, mem)
+	if err != nil {
+		return 0, err
+	}
+	if _, isptr := typ.RealType.(*godwarf.PtrType); isptr {
+		// values of runtime.moduledata.typemap are *runtime._type
+		typ = typ.maybeDereference()
+	}
+	return typ.Addr, typ.Unreadable
+}
+
 func specificRuntimeType(_type *Variable, kind int64) (*Variable, error) {
 	typ, err := typeForKind(kind, _type.bi)
 	if err != nil {
@@ -2,6 +2,7 @@ package proc
 
 import (
 	"bytes"
+	"context"
 	"debug/dwarf"
 	"encoding/binary"
 	"errors"
@@ -107,6 +108,11 @@ type Variable struct {
 	stride    int64
 	fieldType godwarf.Type
 
+	// bitSize and bitOffset describe bit fields of C structs, the value of
+	// the variable is bitSize bits long and starts bitOffset bits after the
+	// least significant bit of the integer at Addr.
+	bitSize, bitOffset int64
+
 	// closureAddr is the closure address for function variables (0 for non-closures)
 	closureAddr uint64
 
@@ -114,6 +120,10 @@ type Variable struct {
 	mapSkip int
 
 	Children []Variable
+	// Offset is the index of the first element of Children, or of the
+	// first byte of Value for strings, when a range of them was loaded by
+	// LoadChildren.
+	Offset int64
 
 	loaded     bool
 	Unreadable error
@@ -289,9 +299,19 @@ func GetG(thread Thread) (*G, error) {
 // while scanning for all available goroutines, or -1 if there was an error
 // or if the index already reached the last possible value.
 func GoroutinesInfo(dbp *Target, start, count int) ([]*G, int, error) {
+	return GoroutinesInfoContext(context.Background(), dbp, start, count)
+}
+
+// GoroutinesInfoContext is like GoroutinesInfo but stops, returning
+// ctx.Err(), when ctx is done.
+func GoroutinesInfoContext(ctx context.Context, dbp *Target, start, count int) ([]*G, int, error) {
 	if _, err := dbp.Valid(); err != nil {
 		return nil, -1, err
 	}
+	if gd, ok := dbp.proc.(GoroutineDump); ok {
+		gs, nextg := dumpedGoroutinesInfo(gd, start, count)
+		return gs, nextg, nil
+	}
 	if dbp.gcache.allGCache != nil {
 		// We can't use the cached array to fulfill a subrange request
 		if start == 0 && (count == 0 || count >= len(dbp.gcache.allGCache)) {
@@ -324,6 +344,9 @@ func GoroutinesInfo(dbp *Target, start, count int) ([]*G, int, error) {
 		if count != 0 && len(allg) >= count {
 			return allg, int(i), nil
 		}
+		if err := ctx.Err(); err != nil {
+			return nil, -1, err
+		}
 		gvar, err := newGVariable(dbp.CurrentThread(), uintptr(allgptr+(i*uint64(dbp.BinInfo().Arch.PtrSize()))), true)
 		if err != nil {
 			allg = append(allg, &G{Unreadable: err})
@@ -481,17 +504,22 @@ func (g *G) Defer() *Defer {
 // UserCurrent returns the location the users code is at,
 // or was at before entering a runtime function.
 func (g *G) UserCurrent() Location {
+	if frames, ok := g.dumpedStacktrace(); ok {
+		for _, frame := range frames {
+			if frame.Call.Fn != nil && frame.Call.Fn.userFunction() {
+				return frame.Call
+			}
+		}
+		return g.CurrentLoc
+	}
 	it, err := g.stackIterator(0)
 	if err != nil {
 		return g.CurrentLoc
 	}
 	for it.Next() {
 		frame := it.Frame()
-		if frame.Call.Fn != nil {
-			name := frame.Call.Fn.Name
-			if strings.Contains(name, ".") && (!strings.HasPrefix(name, "runtime.") || frame.Call.Fn.exportedRuntime()) {
-				return frame.Call
-			}
+		if frame.Call.Fn != nil && frame.Call.Fn.userFunction() {
+			return frame.Call
 		}
 	}
 	return g.CurrentLoc
@@ -658,6 +686,15 @@ func newVariable(name string, addr uintptr, dwarfType godwarf.Type, bi *BinaryIn
 		v.Kind = reflect.Int
 	case *godwarf.UintType:
 		v.Kind = reflect.Uint
+	case *godwarf.CharType:
+		v.Kind = reflect.Int8
+	case *godwarf.UcharType:
+		v.Kind = reflect.Uint8
+	case *godwarf.EnumType:
+		v.Kind = reflect.Uint
+		if t.Signed {
+			v.Kind = reflect.Int
+		}
 	case *godwarf.FloatType:
 		switch t.ByteSize {
 		case 4:
@@ -754,9 +791,40 @@ func (v *Variable) toField(field *godwarf.StructField) (*Variable, error) {
 			name = fmt.Sprintf("%s.%s", v.Name, field.Name)
 		}
 	}
+	if field.BitSize > 0 {
+		return v.toBitField(name, field), nil
+	}
 	return v.newVariable(name, uintptr(int64(v.Addr)+field.ByteOffset), field.Type, v.mem), nil
 }
 
+// toBitField returns the bit field field of v. The value of the field is
+// read from the integer of the size of its type that contains it.
+func (v *Variable) toBitField(name string, field *godwarf.StructField) *Variable {
+	unitBits := field.Type.Size() * 8
+	if unitBits <= 0 || unitBits > 64 {
+		unitBits = 64
+	}
+	off := field.DataBitOffset - field.DataBitOffset%unitBits
+	if field.DataBitOffset+field.BitSize > off+unitBits {
+		// packed structs can have bit fields that straddle the boundary of
+		// their storage unit.
+		off = field.DataBitOffset - field.DataBitOffset%8
+	}
+	r := v.newVariable(name, v.Addr+uintptr(off/8), field.Type, v.mem)
+	r.bitSize = field.BitSize
+	r.bitOffset = field.DataBitOffset - off
+	return r
+}
+
+// extractBitField returns the value of the bit field v from the integer x.
+func (v *Variable) extractBitField(x uint64) uint64 {
+	x >>= uint(v.bitOffset)
+	if v.bitSize < 64 {
+		x &= 1<<uint(v.bitSize) - 1
+	}
+	return x
+}
+
 // ErrNoGoroutine returned when a G could not be found
 // for a specific thread.
 type ErrNoGoroutine struct {
@@ -1163,6 +1231,95 @@ func (v *Variable) loadValue(cfg LoadConfig) {
 	v.loadValueInternal(0, cfg)
 }
 
+// LoadChildren loads at most count children of v starting with the child
+// at index start: elements of arrays and slices, member fields of structs
+// and key/value pairs of maps. For strings the substring of at most count
+// bytes starting at start is loaded into Value.
+// If count is negative cfg.MaxArrayValues, cfg.MaxStringLen or
+// cfg.MaxStructFields is used instead, depending on the kind of v.
+// Children of any other kind of variable are loaded as they would be by
+// loadValue.
+// The children are loaded as if v was loaded at recursion level 0 with
+// cfg.
+func (v *Variable) LoadChildren(start, count int, cfg LoadConfig) error {
+	if v.Unreadable != nil {
+		return v.Unreadable
+	}
+	if v.loaded {
+		return errors.New("variable already loaded")
+	}
+	if start < 0 {
+		return errors.New("negative start index")
+	}
+
+	switch v.Kind {
+	case reflect.Array, reflect.Slice, reflect.String, reflect.Map, reflect.Struct:
+		if v.Kind == reflect.Map && v.mapIterator() == nil {
+			return v.Unreadable
+		}
+		if v.Kind == reflect.Struct {
+			v.Len = int64(len(v.RealType.(*godwarf.StructType).Field))
+		}
+		if int64(start) > v.Len {
+			return fmt.Errorf("index %d out of bounds [0:%d]", start, v.Len)
+		}
+		if count < 0 {
+			switch v.Kind {
+			case reflect.String:
+				count = cfg.MaxStringLen
+			case reflect.Struct:
+				count = cfg.MaxStructFields
+			default:
+				count = cfg.MaxArrayValues
+			}
+		}
+		if rem := v.Len - int64(start); count < 0 || int64(count) > rem {
+			count = int(rem)
+		}
+	default:
+		v.loadValueInternal(0, cfg)
+		return nil
+	}
+
+	v.loaded = true
+	v.Offset = int64(start)
+	switch v.Kind {
+	case reflect.Array, reflect.Slice:
+		sv := v.clone()
+		sv.Base += uintptr(int64(start) * v.stride)
+		sv.Len -= int64(start)
+		cfg.MaxArrayValues = count
+		sv.loadArrayValues(0, cfg)
+		v.Children = sv.Children
+
+	case reflect.String:
+		var val string
+		cfg.MaxStringLen = count
+		val, v.Unreadable = readStringValue(DereferenceMemory(v.mem), v.Base+uintptr(start), int64(count), cfg)
+		v.Value = constant.MakeString(val)
+
+	case reflect.Map:
+		if count == 0 {
+			return nil
+		}
+		v.mapSkip = start
+		cfg.MaxArrayValues = count
+		v.loadMap(0, cfg)
+
+	case reflect.Struct:
+		v.mem = cacheMemory(v.mem, v.Addr, int(v.RealType.Size()))
+		t := v.RealType.(*godwarf.StructType)
+		v.Children = make([]Variable, 0, count)
+		for _, field := range t.Field[start : start+count] {
+			f, _ := v.toField(field)
+			f.Name = field.Name
+			f.loadValueInternal(1, cfg)
+			v.Children = append(v.Children, *f)
+		}
+	}
+	return v.Unreadable
+}
+
 func (v *Variable) loadValueInternal(recurseLevel int, cfg LoadConfig) {
 	if v.Unreadable != nil || v.loaded || (v.Addr == 0 && v.Base == 0) {
 		return
@@ -1236,11 +1393,20 @@ func (v *Variable) loadValueInternal(recurseLevel int, cfg LoadConfig) {
 		v.readComplex(v.RealType.(*godwarf.ComplexType).ByteSize)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		var val int64
-		val, v.Unreadable = readIntRaw(v.mem, v.Addr, v.RealType.(*godwarf.IntType).ByteSize)
+		if v.bitSize > 0 {
+			var x uint64
+			x, v.Unreadable = readUintRaw(v.mem, v.Addr, v.RealType.Size())
+			val = int64(v.extractBitField(x)<<uint(64-v.bitSize)) >> uint(64-v.bitSize)
+		} else {
+			val, v.Unreadable = readIntRaw(v.mem, v.Addr, v.RealType.Size())
+		}
 		v.Value = constant.MakeInt64(val)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 		var val uint64
-		val, v.Unreadable = readUintRaw(v.mem, v.Addr, v.RealType.(*godwarf.UintType).ByteSize)
+		val, v.Unreadable = readUintRaw(v.mem, v.Addr, v.RealType.Size())
+		if v.bitSize > 0 {
+			val = v.extractBitField(val)
+		}
 		v.Value = constant.MakeUint64(val)
 
 	case reflect.Bool:
@@ -1248,6 +1414,9 @@ func (v *Variable) loadValueInternal(recurseLevel int, cfg LoadConfig) {
 		_, err := v.mem.ReadMemory(val, v.Addr)
 		v.Unreadable = err
 		if err == nil {
+			if v.bitSize > 0 {
+				val[0] = byte(v.extractBitField(uint64(val[0])))
+			}
 			v.Value = constant.MakeBool(val[0] != 0)
 		}
 	case reflect.Float32, reflect.Float64:
@@ -1562,6 +1731,22 @@ func (v *Variable) writeUint(value uint64, size int64) error {
 	return err
 }
 
+// writeBitField writes value to the bit field v, the other bits of the
+// integer containing it are preserved.
+func (v *Variable) writeBitField(value uint64) error {
+	size := v.RealType.Size()
+	x, err := readUintRaw(v.mem, v.Addr, size)
+	if err != nil {
+		return err
+	}
+	mask := ^uint64(0)
+	if v.bitSize < 64 {
+		mask = 1<<uint(v.bitSize) - 1
+	}
+	x = x&^(mask<<uint(v.bitOffset)) | (value&mask)<<uint(v.bitOffset)
+	return v.writeUint(x, size)
+}
+
 func readUintRaw(mem MemoryReadWriter, addr uintptr, size int64) (uint64, error) {
 	var n uint64
 
@@ -2119,6 +2304,18 @@ func (v *Variable) ConstDescr() string {
 	if v.bi == nil || (v.Flags&VariableConstant != 0) {
 		return ""
 	}
+	if etyp, isenum := v.RealType.(*godwarf.EnumType); isenum {
+		if v.Value == nil {
+			return ""
+		}
+		n, _ := constant.Int64Val(v.Value)
+		for _, val := range etyp.Val {
+			if val.Val == n {
+				return val.Name
+			}
+		}
+		return ""
+	}
 	ctyp := v.bi.consts.Get(v.DwarfType)
 	if ctyp == nil {
 		return ""
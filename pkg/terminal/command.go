@@ -37,12 +37,15 @@ const (
 	onPrefix = cmdPrefix(1 << iota)
 	deferredPrefix
 	revPrefix
+	jsonPrefix
 )
 
 type callContext struct {
 	Prefix     cmdPrefix
 	Scope      api.EvalScope
 	Breakpoint *api.Breakpoint
+	// JSON is true if the command should print its output as JSON.
+	JSON bool
 }
 
 func (ctx *callContext) scoped() bool {
@@ -114,12 +117,46 @@ func DebugCommands(client service.Client) *Commands {
 	help [command]
 
 Type "help" followed by the name of a command for more information about it.`},
+		{aliases: []string{"-json"}, allowedPrefixes: deferredPrefix, cmdFn: c.jsonCmd, helpMsg: `Prints the output of a command as JSON.
+
+	-json <command> [args...]
+
+The data printed by the command is written to standard output as a single line of JSON, using the same structures returned by the API (see https://godoc.org/github.com/go-delve/delve/service/api). Commands that print more than one structure, for example 'stack -a', write one line for each.
+
+The following commands support JSON output: args, breakpoints, funcs, goroutine, goroutines, libraries, locals, print, regs, sources, stack, threads, types and vars. The prefixes goroutine, frame, up, down and deferred can be combined with -json, as long as the command they execute supports JSON output, for example:
+
+	-json goroutine 1 frame 2 locals
+
+When a command prefixed with -json is executed by the dlv_command starlark built-in the data is returned to the script instead of being printed.
+
+Starting delve with --output-format=json has the same effect as prefixing every command that supports it with -json.`},
 		{aliases: []string{"break", "b"}, group: breakCmds, cmdFn: breakpoint, helpMsg: `Sets a breakpoint.
 
-	break [name] <linespec>
+	break [name] [-g <goroutine id>] [-label <key>=<value>]... <linespec>
+	break [name] [-g <goroutine id>] [-label <key>=<value>]... -panic [type] [/regex/]
+	break [name] -pending <linespec>
+	break [name] -on-load <regex>
 
 See $GOPATH/src/github.com/go-delve/delve/Documentation/cli/locspec.md for the syntax of linespec.
 
+The -g option restricts the breakpoint to the goroutine with the specified ID, the -label option restricts it to the goroutines that have the specified pprof label (it can be repeated to require multiple labels). Breakpoints restricted by goroutine ID are discarded when the target is restarted, unless it is a recording, while breakpoints restricted by label are kept.
+
+The second form stops when a panic is raised, even if it is later recovered. If type is specified only panics with a value of that type, or implementing that interface, will stop execution, for example:
+
+	break -panic *main.MyErr
+	break -panic runtime.Error /index out of range/
+
+If a regular expression is specified only panics where the panic value, or one of the string fields it contains, matches the regular expression will stop execution. Multiple panic breakpoints with different filters can be set, execution stops when any of them matches.
+
+The -pending option creates the breakpoint even if linespec can not be found, the breakpoint will be set as soon as a shared library or plugin containing it is loaded (for example with plugin.Open). The linespec of a pending breakpoint must be either a function name or a file name ending in '.go' followed by a line number:
+
+	break -pending plugin.go:12
+	break -pending myplugin.Run
+
+After a restart pending breakpoints that were set become pending again, until the library or plugin is loaded again.
+
+The -on-load option stops execution when a shared library or plugin whose path matches the regular expression is loaded. Unloading a library does not stop execution.
+
 See also: "help on", "help cond" and "help clear"`},
 		{aliases: []string{"trace", "t"}, group: breakCmds, cmdFn: tracepoint, helpMsg: `Set tracepoint.
 
@@ -144,7 +181,16 @@ If newargv is omitted the process is restarted (or re-recorded) with the same ar
 If -noargs is specified instead, the argument vector is cleared.
 `},
 		{aliases: []string{"continue", "c"}, group: runCmds, cmdFn: c.cont, allowedPrefixes: revPrefix, helpMsg: "Run until breakpoint or program termination."},
-		{aliases: []string{"step", "s"}, group: runCmds, cmdFn: c.step, allowedPrefixes: revPrefix, helpMsg: "Single step through program."},
+		{aliases: []string{"step", "s", "stepin"}, group: runCmds, cmdFn: c.step, allowedPrefixes: revPrefix, helpMsg: `Single step through program.
+
+	step [<funcname>]
+	step -target [<n>]
+
+If the current line contains more than one function call, step enters the first one. When funcname is specified only the call to that function is entered, the other calls on the current line are stepped over.
+
+	step -target
+
+lists the function calls on the current line that haven't been executed yet, 'step -target <n>' enters the n-th call of the list. The stepin alias is provided for users of other debuggers, 'stepin -target <n>' is the same as 'step -target <n>'.`},
 		{aliases: []string{"step-instruction", "si"}, group: runCmds, allowedPrefixes: revPrefix, cmdFn: c.stepInstruction, helpMsg: "Single step a single cpu instruction."},
 		{aliases: []string{"next", "n"}, group: runCmds, cmdFn: c.next, allowedPrefixes: revPrefix, helpMsg: `Step over to next source line.
 
@@ -169,7 +215,7 @@ Current limitations:
 - calling a function will resume execution of all goroutines.
 - only supported on linux's native backend.
 `},
-		{aliases: []string{"threads"}, group: goroutineCmds, cmdFn: threads, helpMsg: "Print out info for every traced thread."},
+		{aliases: []string{"threads"}, group: goroutineCmds, allowedPrefixes: jsonPrefix, cmdFn: threads, helpMsg: "Print out info for every traced thread."},
 		{aliases: []string{"thread", "tr"}, group: goroutineCmds, cmdFn: thread, helpMsg: `Switch to the specified thread.
 
 	thread <id>`},
@@ -181,9 +227,9 @@ Current limitations:
 	clearall [<linespec>]
 
 If called with the linespec argument it will delete all the breakpoints matching the linespec. If linespec is omitted all breakpoints are deleted.`},
-		{aliases: []string{"goroutines", "grs"}, group: goroutineCmds, cmdFn: goroutines, helpMsg: `List program goroutines.
+		{aliases: []string{"goroutines", "grs"}, group: goroutineCmds, allowedPrefixes: jsonPrefix, cmdFn: goroutines, helpMsg: `List program goroutines.
 
-	goroutines [-u (default: user location)|-r (runtime location)|-g (go statement location)|-s (start location)] [-t (stack trace)] [-l (labels)]
+	goroutines [-u (default: user location)|-r (runtime location)|-g (go statement location)|-s (start location)] [-t (stack trace)] [-l (labels)] [-group]
 
 Print out info for every goroutine. The flag controls what information is shown along with each goroutine:
 
@@ -193,9 +239,10 @@ Print out info for every goroutine. The flag controls what information is shown
 	-s	displays location of the start function
 	-t	displays goroutine's stacktrace
 	-l	displays goroutine's labels
+	-group	groups goroutines by the selected location, printing how many goroutines are at each location
 
 If no flag is specified the default is -u.`},
-		{aliases: []string{"goroutine", "gr"}, group: goroutineCmds, allowedPrefixes: onPrefix, cmdFn: c.goroutine, helpMsg: `Shows or changes current goroutine
+		{aliases: []string{"goroutine", "gr"}, group: goroutineCmds, allowedPrefixes: onPrefix | jsonPrefix, cmdFn: c.goroutine, helpMsg: `Shows or changes current goroutine
 
 	goroutine
 	goroutine <id>
@@ -204,10 +251,14 @@ If no flag is specified the default is -u.`},
 Called without arguments it will show information about the current goroutine.
 Called with a single argument it will switch to the specified goroutine.
 Called with more arguments it will execute a command on the specified goroutine.`},
-		{aliases: []string{"breakpoints", "bp"}, group: breakCmds, cmdFn: breakpoints, helpMsg: "Print out info for active breakpoints."},
-		{aliases: []string{"print", "p"}, group: dataCmds, allowedPrefixes: onPrefix | deferredPrefix, cmdFn: printVar, helpMsg: `Evaluate an expression.
+		{aliases: []string{"breakpoints", "bp"}, group: breakCmds, allowedPrefixes: jsonPrefix, cmdFn: breakpoints, helpMsg: "Print out info for active breakpoints."},
+		{aliases: []string{"print", "p"}, group: dataCmds, allowedPrefixes: onPrefix | deferredPrefix | jsonPrefix, cmdFn: printVar, helpMsg: `Evaluate an expression.
+
+	[goroutine <n>] [frame <m>] print [-offset <start>] [-count <n>] <expression>
 
-	[goroutine <n>] [frame <m>] print <expression>
+With -offset and -count only n elements of arrays and slices, key/value pairs of maps, fields of structs or bytes of strings are loaded, starting with the one at index start. This can be used to see the part of a value that is truncated by the limits set with the config command, for example:
+
+	print -offset 64 -count 64 m
 
 See $GOPATH/src/github.com/go-delve/delve/Documentation/cli/expr.md for a description of supported expressions.`},
 		{aliases: []string{"whatis"}, group: dataCmds, cmdFn: whatisCommand, helpMsg: `Prints type of an expression.
@@ -218,39 +269,39 @@ See $GOPATH/src/github.com/go-delve/delve/Documentation/cli/expr.md for a descri
 	[goroutine <n>] [frame <m>] set <variable> = <value>
 
 See $GOPATH/src/github.com/go-delve/delve/Documentation/cli/expr.md for a description of supported expressions. Only numerical variables and pointers can be changed.`},
-		{aliases: []string{"sources"}, cmdFn: sources, helpMsg: `Print list of source files.
+		{aliases: []string{"sources"}, allowedPrefixes: jsonPrefix, cmdFn: sources, helpMsg: `Print list of source files.
 
 	sources [<regex>]
 
 If regex is specified only the source files matching it will be returned.`},
-		{aliases: []string{"funcs"}, cmdFn: funcs, helpMsg: `Print list of functions.
+		{aliases: []string{"funcs"}, allowedPrefixes: jsonPrefix, cmdFn: funcs, helpMsg: `Print list of functions.
 
 	funcs [<regex>]
 
 If regex is specified only the functions matching it will be returned.`},
-		{aliases: []string{"types"}, cmdFn: types, helpMsg: `Print list of types
+		{aliases: []string{"types"}, allowedPrefixes: jsonPrefix, cmdFn: types, helpMsg: `Print list of types
 
 	types [<regex>]
 
 If regex is specified only the types matching it will be returned.`},
-		{aliases: []string{"args"}, allowedPrefixes: onPrefix | deferredPrefix, group: dataCmds, cmdFn: args, helpMsg: `Print function arguments.
+		{aliases: []string{"args"}, allowedPrefixes: onPrefix | deferredPrefix | jsonPrefix, group: dataCmds, cmdFn: args, helpMsg: `Print function arguments.
 
 	[goroutine <n>] [frame <m>] args [-v] [<regex>]
 
 If regex is specified only function arguments with a name matching it will be returned. If -v is specified more information about each function argument will be shown.`},
-		{aliases: []string{"locals"}, allowedPrefixes: onPrefix | deferredPrefix, group: dataCmds, cmdFn: locals, helpMsg: `Print local variables.
+		{aliases: []string{"locals"}, allowedPrefixes: onPrefix | deferredPrefix | jsonPrefix, group: dataCmds, cmdFn: locals, helpMsg: `Print local variables.
 
 	[goroutine <n>] [frame <m>] locals [-v] [<regex>]
 
 The name of variables that are shadowed in the current scope will be shown in parenthesis.
 
 If regex is specified only local variables with a name matching it will be returned. If -v is specified more information about each local variable will be shown.`},
-		{aliases: []string{"vars"}, cmdFn: vars, group: dataCmds, helpMsg: `Print package variables.
+		{aliases: []string{"vars"}, allowedPrefixes: jsonPrefix, cmdFn: vars, group: dataCmds, helpMsg: `Print package variables.
 
 	vars [-v] [<regex>]
 
 If regex is specified only package variables with a name matching it will be returned. If -v is specified more information about each package variable will be shown.`},
-		{aliases: []string{"regs"}, cmdFn: regs, group: dataCmds, helpMsg: `Print contents of CPU registers.
+		{aliases: []string{"regs"}, allowedPrefixes: jsonPrefix, cmdFn: regs, group: dataCmds, helpMsg: `Print contents of CPU registers.
 
 	regs [-a]
 
@@ -272,12 +323,12 @@ For example:
 	list testvariables.go:10000
 	list main.main:30
 	list 40`},
-		{aliases: []string{"stack", "bt"}, allowedPrefixes: onPrefix, group: stackCmds, cmdFn: stackCommand, helpMsg: `Print stack trace.
+		{aliases: []string{"stack", "bt"}, allowedPrefixes: onPrefix | jsonPrefix, group: stackCmds, cmdFn: stackCommand, helpMsg: `Print stack trace.
 
 	[goroutine <n>] [frame <m>] stack [<depth>] [-full] [-offsets] [-defer] [-a <n>] [-adepth <depth>] [-mode <mode>]
 
 	-full		every stackframe is decorated with the value of its local variables and arguments.
-	-offsets	prints frame offset of each frame.
+	-offsets	prints frame offset of each frame and the method used to find it.
 	-defer		prints deferred function call stack for each frame.
 	-a <n>		prints stacktrace of n ancestors of the selected goroutine (target process must have tracebackancestors enabled)
 	-adepth <depth>	configures depth of ancestor stacktrace
@@ -285,9 +336,12 @@ For example:
 			normal	- attempts to automatically switch between cgo frames and go frames
 			simple	- disables automatic switch between cgo and go
 			fromg	- starts from the registers stored in the runtime.g struct
+
+Stack frames are found using the call frame information of the executable, on amd64 and arm64 when it is missing, or the return address can not be read from it or does not belong to a known function, the frame pointer is followed instead. Frames found this way are marked with "(frame pointer unwind)".
 `},
 		{aliases: []string{"frame"},
-			group: stackCmds,
+			group:           stackCmds,
+			allowedPrefixes: jsonPrefix,
 			cmdFn: func(t *Term, ctx callContext, arg string) error {
 				return c.frameCommand(t, ctx, arg, frameSet)
 			},
@@ -299,7 +353,8 @@ For example:
 The first form sets frame used by subsequent commands such as "print" or "set".
 The second form runs the command on the given frame.`},
 		{aliases: []string{"up"},
-			group: stackCmds,
+			group:           stackCmds,
+			allowedPrefixes: jsonPrefix,
 			cmdFn: func(t *Term, ctx callContext, arg string) error {
 				return c.frameCommand(t, ctx, arg, frameUp)
 			},
@@ -310,7 +365,8 @@ The second form runs the command on the given frame.`},
 
 Move the current frame up by <m>. The second form runs the command on the given frame.`},
 		{aliases: []string{"down"},
-			group: stackCmds,
+			group:           stackCmds,
+			allowedPrefixes: jsonPrefix,
 			cmdFn: func(t *Term, ctx callContext, arg string) error {
 				return c.frameCommand(t, ctx, arg, frameDown)
 			},
@@ -320,7 +376,7 @@ Move the current frame up by <m>. The second form runs the command on the given
 	down [<m>] <command>
 
 Move the current frame down by <m>. The second form runs the command on the given frame.`},
-		{aliases: []string{"deferred"}, group: stackCmds, cmdFn: c.deferredCommand, helpMsg: `Executes command in the context of a deferred call.
+		{aliases: []string{"deferred"}, group: stackCmds, allowedPrefixes: jsonPrefix, cmdFn: c.deferredCommand, helpMsg: `Executes command in the context of a deferred call.
 
 	deferred <n> <command>
 
@@ -379,7 +435,7 @@ Defines <alias> as an alias to <command> or removes an alias.`},
 	edit [locspec]
 	
 If locspec is omitted edit will open the current source file in the editor, otherwise it will open the specified location.`},
-		{aliases: []string{"libraries"}, cmdFn: libraries, helpMsg: `List loaded dynamic libraries`},
+		{aliases: []string{"libraries"}, allowedPrefixes: jsonPrefix, cmdFn: libraries, helpMsg: `List loaded dynamic libraries`},
 
 		{aliases: []string{"examinemem", "x"}, group: dataCmds, cmdFn: examineMemoryCmd, helpMsg: `Examine memory:
 
@@ -441,6 +497,34 @@ The "note" is arbitrary text that can be used to identify the checkpoint, if it
 				helpMsg: `Deletes checkpoint.
 
 	clear-checkpoint <id>`,
+			},
+			command{
+				aliases: []string{"when"},
+				cmdFn:   when,
+				helpMsg: `Prints the current position in the recording.
+
+The position is printed as the current rr event and the number of ticks of the current thread, both can be passed to the seek command.`,
+			},
+			command{
+				aliases: []string{"seek"},
+				group:   runCmds,
+				cmdFn:   c.seek,
+				helpMsg: `Moves to a position in the recording.
+
+	seek <event>
+	seek -ticks <n>
+
+The first form moves to the start of the given rr event, the second one to the point where the current thread executed the given number of ticks. Breakpoints are not hit while moving.`,
+			},
+			command{
+				aliases: []string{"processes"},
+				cmdFn:   processes,
+				helpMsg: `Lists the processes recorded in the trace or switches to one of them.
+
+	processes
+	processes <pid>
+
+The second form starts a new replay of the trace that debugs the process with the given pid, breakpoints are kept.`,
 			},
 			command{
 				aliases: []string{"rev"},
@@ -503,6 +587,9 @@ func (c *Commands) CallWithContext(cmdstr string, t *Term, ctx callContext) erro
 // Call takes a command to execute.
 func (c *Commands) Call(cmdstr string, t *Term) error {
 	ctx := callContext{Prefix: noPrefix, Scope: api.EvalScope{GoroutineID: -1, Frame: c.frame, DeferredCall: 0}}
+	if t != nil {
+		ctx.JSON = t.JSONOutput
+	}
 	return c.CallWithContext(cmdstr, t, ctx)
 }
 
@@ -593,6 +680,9 @@ func threads(t *Term, ctx callContext, args string) error {
 		return err
 	}
 	sort.Sort(byThreadID(threads))
+	if ctx.JSON {
+		return t.printJSON(threads)
+	}
 	for _, th := range threads {
 		prefix := "  "
 		if state.CurrentThread != nil && state.CurrentThread.ID == th.ID {
@@ -652,6 +742,7 @@ type printGoroutinesFlags uint8
 const (
 	printGoroutinesStack printGoroutinesFlags = 1 << iota
 	printGoroutinesLabels
+	printGoroutinesGroup
 )
 
 func printGoroutines(t *Term, gs []*api.Goroutine, fgl formatGoroutineLoc, flags printGoroutinesFlags, state *api.DebuggerState) error {
@@ -675,6 +766,123 @@ func printGoroutines(t *Term, gs []*api.Goroutine, fgl formatGoroutineLoc, flags
 	return nil
 }
 
+// printGoroutinesGrouped prints the number of goroutines at each location,
+// most common locations first.
+func printGoroutinesGrouped(t *Term, fgl formatGoroutineLoc, flags printGoroutinesFlags) error {
+	const maxGroupIDs = 5
+
+	type goroutineGroup struct {
+		loc string
+		gs  []*api.Goroutine
+	}
+	var groups []*goroutineGroup
+	groupOf := map[string]*goroutineGroup{}
+	gslen := 0
+
+	for start := 0; start >= 0; {
+		var gs []*api.Goroutine
+		var err error
+		gs, start, err = t.client.ListGoroutines(start, goroutineBatchSize)
+		if err != nil {
+			return err
+		}
+		sort.Sort(byGoroutineID(gs))
+		for _, g := range gs {
+			loc := goroutineGroupLocation(g, fgl)
+			group := groupOf[loc]
+			if group == nil {
+				group = &goroutineGroup{loc: loc}
+				groupOf[loc] = group
+				groups = append(groups, group)
+			}
+			group.gs = append(group.gs, g)
+		}
+		gslen += len(gs)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i].gs) > len(groups[j].gs) })
+
+	for _, group := range groups {
+		ids := make([]string, 0, maxGroupIDs)
+		for i, g := range group.gs {
+			if i >= maxGroupIDs {
+				ids = append(ids, "...")
+				break
+			}
+			ids = append(ids, strconv.Itoa(g.ID))
+		}
+		plural := "s"
+		if len(group.gs) == 1 {
+			plural = ""
+		}
+		fmt.Printf("  %d goroutine%s at %s [%s]\n", len(group.gs), plural, group.loc, strings.Join(ids, " "))
+		if flags&printGoroutinesLabels != 0 {
+			writeGoroutineLabels(os.Stdout, group.gs[0], "\t")
+		}
+		if flags&printGoroutinesStack != 0 {
+			stack, err := t.client.Stacktrace(group.gs[0].ID, 10, 0, nil)
+			if err != nil {
+				return err
+			}
+			printStack(os.Stdout, stack, "\t", false)
+		}
+	}
+	fmt.Printf("[%d goroutines, %d groups]\n", gslen, len(groups))
+	return nil
+}
+
+// goroutineGroupLocation returns the location used to group g, the PC is
+// not part of it since goroutines stopped on the same line can have
+// different PCs.
+func goroutineGroupLocation(g *api.Goroutine, fgl formatGoroutineLoc) string {
+	if g.Unreadable != "" {
+		return fmt.Sprintf("(unreadable %s)", g.Unreadable)
+	}
+	var loc api.Location
+	switch fgl {
+	case fglRuntimeCurrent:
+		loc = g.CurrentLoc
+	case fglUserCurrent:
+		loc = g.UserCurrentLoc
+	case fglGo:
+		loc = g.GoStatementLoc
+	case fglStart:
+		loc = g.StartLoc
+	}
+	return fmt.Sprintf("%s:%d %s", shortenFilePath(loc.File), loc.Line, loc.Function.Name())
+}
+
+// goroutineJSON is the JSON output of the goroutines command, Stack is
+// only set if the -t flag is used.
+type goroutineJSON struct {
+	*api.Goroutine
+	Stack []api.Stackframe `json:"stack,omitempty"`
+}
+
+func printGoroutinesJSON(t *Term, flags printGoroutinesFlags) error {
+	r := []goroutineJSON{}
+	for start := 0; start >= 0; {
+		var gs []*api.Goroutine
+		var err error
+		gs, start, err = t.client.ListGoroutines(start, goroutineBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, g := range gs {
+			gj := goroutineJSON{Goroutine: g}
+			if flags&printGoroutinesStack != 0 {
+				gj.Stack, err = t.client.Stacktrace(g.ID, 10, 0, nil)
+				if err != nil {
+					return err
+				}
+			}
+			r = append(r, gj)
+		}
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].ID < r[j].ID })
+	return t.printJSON(r)
+}
+
 func goroutines(t *Term, ctx callContext, argstr string) error {
 	args := strings.Split(argstr, " ")
 	var fgl = fglUserCurrent
@@ -683,7 +891,7 @@ func goroutines(t *Term, ctx callContext, argstr string) error {
 	switch len(args) {
 	case 0:
 		// nothing to do
-	case 1, 2:
+	case 1, 2, 3, 4:
 		for _, arg := range args {
 			switch arg {
 			case "-u":
@@ -698,6 +906,8 @@ func goroutines(t *Term, ctx callContext, argstr string) error {
 				flags |= printGoroutinesStack
 			case "-l":
 				flags |= printGoroutinesLabels
+			case "-group":
+				flags |= printGoroutinesGroup
 			case "":
 				// nothing to do
 			default:
@@ -716,6 +926,15 @@ func goroutines(t *Term, ctx callContext, argstr string) error {
 		gslen = 0
 		gs    []*api.Goroutine
 	)
+	if ctx.JSON {
+		if flags&printGoroutinesGroup != 0 {
+			return errors.New("the -group flag can not be used with json output")
+		}
+		return printGoroutinesJSON(t, flags)
+	}
+	if flags&printGoroutinesGroup != 0 {
+		return printGoroutinesGrouped(t, fgl, flags)
+	}
 	for start >= 0 {
 		gs, start, err = t.client.ListGoroutines(start, goroutineBatchSize)
 		if err != nil {
@@ -760,6 +979,13 @@ func (c *Commands) goroutine(t *Term, ctx callContext, argstr string) error {
 
 	if len(args) == 1 {
 		if args[0] == "" {
+			if ctx.JSON {
+				state, err := t.client.GetState()
+				if err != nil {
+					return err
+				}
+				return t.printJSON(state.SelectedGoroutine)
+			}
 			return printscope(t)
 		}
 		gid, err := strconv.Atoi(argstr)
@@ -776,6 +1002,9 @@ func (c *Commands) goroutine(t *Term, ctx callContext, argstr string) error {
 			return err
 		}
 		c.frame = 0
+		if ctx.JSON {
+			return t.printJSON(newState.SelectedGoroutine)
+		}
 		fmt.Printf("Switched from %d to %d (thread %d)\n", selectedGID(oldState), gid, newState.CurrentThread.ID)
 		return nil
 	}
@@ -827,12 +1056,15 @@ func (c *Commands) frameCommand(t *Term, ctx callContext, argstr string, directi
 		return fmt.Errorf("Invalid frame %d", frame)
 	}
 	c.frame = frame
+	th := stack[frame]
+	if ctx.JSON {
+		return t.printJSON(th)
+	}
 	state, err := t.client.GetState()
 	if err != nil {
 		return err
 	}
 	printcontext(t, state)
-	th := stack[frame]
 	fmt.Printf("Frame %d: %s:%d (PC: %x)\n", frame, shortenFilePath(th.File), th.Line, th.PC)
 	printfile(t, th.File, th.Line, true)
 	return nil
@@ -1144,6 +1376,18 @@ func (c *Commands) step(t *Term, ctx callContext, args string) error {
 	if ctx.Prefix == revPrefix {
 		stepfn = t.client.ReverseStep
 	}
+	if args = strings.TrimSpace(args); args != "" {
+		if ctx.Prefix == revPrefix {
+			return errors.New("can not step into a specific call backwards")
+		}
+		tgt, err := findStepInTarget(t, args)
+		if err != nil || tgt == nil {
+			return err
+		}
+		stepfn = func() (*api.DebuggerState, error) {
+			return t.client.StepIntoCall(tgt.PC)
+		}
+	}
 	state, err := exitedToError(stepfn())
 	if err != nil {
 		printcontextNoState(t)
@@ -1153,6 +1397,67 @@ func (c *Commands) step(t *Term, ctx callContext, args string) error {
 	return continueUntilCompleteNext(t, state, "step", true)
 }
 
+// findStepInTarget returns the call on the current line selected by the
+// arguments of the step command. If the arguments are '-target' the calls
+// are printed and nil is returned.
+func findStepInTarget(t *Term, args string) (*api.StepInTarget, error) {
+	tgts, err := t.client.StepInTargets()
+	if err != nil {
+		return nil, err
+	}
+	argv := strings.Fields(args)
+	if argv[0] == "-target" {
+		switch len(argv) {
+		case 1:
+			if len(tgts) == 0 {
+				fmt.Println("No function calls on the current line")
+			}
+			for i := range tgts {
+				fmt.Printf("%d. %s\n", i+1, formatStepInTarget(&tgts[i]))
+			}
+			return nil, nil
+		case 2:
+			n, err := strconv.Atoi(argv[1])
+			if err != nil || n <= 0 || n > len(tgts) {
+				return nil, fmt.Errorf("invalid target %q, %d function calls on the current line", argv[1], len(tgts))
+			}
+			return &tgts[n-1], nil
+		default:
+			return nil, errors.New("too many arguments")
+		}
+	}
+	if len(argv) != 1 {
+		return nil, errors.New("too many arguments")
+	}
+	var found *api.StepInTarget
+	for i := range tgts {
+		if tgts[i].Function == nil {
+			continue
+		}
+		name := tgts[i].Function.Name()
+		if name != argv[0] && !strings.HasSuffix(name, "."+argv[0]) && !strings.HasSuffix(name, "/"+argv[0]) {
+			continue
+		}
+		if found != nil && found.Function.Name() != name {
+			return nil, fmt.Errorf("%q is ambiguous: %s and %s are both called on the current line", argv[0], found.Function.Name(), name)
+		}
+		if found == nil {
+			found = &tgts[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no call to %s on the current line", argv[0])
+	}
+	return found, nil
+}
+
+func formatStepInTarget(tgt *api.StepInTarget) string {
+	if tgt.Function == nil {
+		return fmt.Sprintf("indirect call at %#x", tgt.PC)
+	}
+	return tgt.Function.Name()
+}
+
 var notOnFrameZeroErr = errors.New("not on topmost frame")
 
 func (c *Commands) stepInstruction(t *Term, ctx callContext, args string) error {
@@ -1191,6 +1496,46 @@ func (c *Commands) revCmd(t *Term, ctx callContext, args string) error {
 	return c.CallWithContext(args, t, ctx)
 }
 
+func (c *Commands) jsonCmd(t *Term, ctx callContext, args string) error {
+	if len(args) == 0 {
+		return errors.New("not enough arguments")
+	}
+
+	// The goroutine, frame, up, down and deferred commands pass the JSON
+	// output mode to the command they execute, which must support it too.
+	words := strings.Fields(args)
+	for len(words) > 0 {
+		cmdname := words[0]
+		var found *command
+		for i := range c.cmds {
+			if c.cmds[i].match(cmdname) {
+				found = &c.cmds[i]
+				break
+			}
+		}
+		if found == nil {
+			break
+		}
+		if found.allowedPrefixes&jsonPrefix == 0 {
+			return fmt.Errorf("command %s does not support JSON output", cmdname)
+		}
+		switch found.aliases[0] {
+		case "goroutine", "frame", "up", "down", "deferred":
+		default:
+			words = nil
+			continue
+		}
+		words = words[1:]
+		if len(words) > 0 {
+			if _, err := strconv.Atoi(words[0]); err == nil {
+				words = words[1:]
+			}
+		}
+	}
+	ctx.JSON = true
+	return c.CallWithContext(args, t, ctx)
+}
+
 func (c *Commands) next(t *Term, ctx callContext, args string) error {
 	if err := scopePrefixSwitch(t, ctx); err != nil {
 		return err
@@ -1343,6 +1688,9 @@ func breakpoints(t *Term, ctx callContext, args string) error {
 		return err
 	}
 	sort.Sort(byID(breakPoints))
+	if ctx.JSON {
+		return t.printJSON(breakPoints)
+	}
 	for _, bp := range breakPoints {
 		fmt.Printf("%s at %v (%d)\n", formatBreakpointName(bp, true), formatBreakpointLocation(bp), bp.TotalHitCount)
 
@@ -1356,6 +1704,12 @@ func breakpoints(t *Term, ctx callContext, args string) error {
 		if bp.Goroutine {
 			attrs = append(attrs, "\tgoroutine")
 		}
+		if bp.Panic != nil {
+			attrs = append(attrs, "\tpanic"+formatPanicFilter(bp.Panic))
+		}
+		if bp.GoroutineFilter != nil {
+			attrs = append(attrs, "\tonly"+formatGoroutineFilter(bp.GoroutineFilter))
+		}
 		if bp.LoadArgs != nil {
 			if *(bp.LoadArgs) == longLoadConfig {
 				attrs = append(attrs, "\targs -v")
@@ -1380,29 +1734,174 @@ func breakpoints(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
-func setBreakpoint(t *Term, ctx callContext, tracepoint bool, argstr string) error {
+// parsePanicBreakpoint parses the arguments of 'break [name] -panic [type]
+// [/regex/]', ok is false if argstr does not have this form.
+func parsePanicBreakpoint(argstr string) (name string, filter *api.PanicFilter, ok bool, err error) {
+	name, rest, ok := splitBreakpointFlag(argstr, "-panic")
+	if !ok {
+		return "", nil, false, nil
+	}
+	filter = &api.PanicFilter{}
+	if rest == "" {
+		return name, filter, true, nil
+	}
+	if rest[0] != '/' {
+		args := split2PartsBySpace(rest)
+		filter.Types = []string{args[0]}
+		rest = ""
+		if len(args) > 1 {
+			rest = args[1]
+		}
+	}
+	if rest != "" {
+		if len(rest) < 2 || rest[0] != '/' || rest[len(rest)-1] != '/' {
+			return "", nil, true, fmt.Errorf("wrong argument: %q, the regular expression must be enclosed by '/'", rest)
+		}
+		filter.Message = rest[1 : len(rest)-1]
+	}
+	return name, filter, true, nil
+}
+
+// splitBreakpointFlag checks if argstr has the form '[name] flag [rest]',
+// ok is false if it doesn't.
+func splitBreakpointFlag(argstr, flag string) (name, rest string, ok bool) {
 	args := split2PartsBySpace(argstr)
+	if args[0] != flag {
+		if len(args) < 2 || !strings.HasPrefix(args[1], flag) {
+			return "", "", false
+		}
+		name = args[0]
+		args = split2PartsBySpace(args[1])
+		if args[0] != flag {
+			return "", "", false
+		}
+	}
+	if len(args) > 1 {
+		rest = args[1]
+	}
+	return name, rest, true
+}
+
+// parseGoroutineFilter removes the -g and -label options, which can appear
+// before or after the breakpoint name, from argstr and returns the
+// corresponding filter.
+func parseGoroutineFilter(argstr string) (string, *api.GoroutineFilter, error) {
+	isOption := func(s string) bool {
+		s = split2PartsBySpace(s)[0]
+		return s == "-g" || s == "-label"
+	}
+	var name string
+	var filter *api.GoroutineFilter
+	rest := strings.TrimSpace(argstr)
+	for rest != "" {
+		args := split2PartsBySpace(rest)
+		if !isOption(args[0]) {
+			if name != "" || len(args) < 2 || !isOption(args[1]) {
+				break
+			}
+			name, rest = args[0], args[1]
+			continue
+		}
+		if len(args) < 2 {
+			return "", nil, fmt.Errorf("%s requires an argument", args[0])
+		}
+		val := split2PartsBySpace(args[1])
+		rest = ""
+		if len(val) > 1 {
+			rest = val[1]
+		}
+		if filter == nil {
+			filter = &api.GoroutineFilter{}
+		}
+		switch args[0] {
+		case "-g":
+			id, err := strconv.Atoi(val[0])
+			if err != nil || id <= 0 {
+				return "", nil, fmt.Errorf("invalid goroutine ID %q", val[0])
+			}
+			filter.ID = id
+		case "-label":
+			kv := strings.SplitN(val[0], "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return "", nil, fmt.Errorf("invalid label %q, must be <key>=<value>", val[0])
+			}
+			if filter.Labels == nil {
+				filter.Labels = make(map[string]string)
+			}
+			filter.Labels[kv[0]] = kv[1]
+		}
+	}
+	if name != "" {
+		rest = strings.TrimSpace(name + " " + rest)
+	}
+	return rest, filter, nil
+}
+
+func setBreakpoint(t *Term, ctx callContext, tracepoint bool, argstr string) error {
+	argstr, gfilter, err := parseGoroutineFilter(argstr)
+	if err != nil {
+		return err
+	}
+	if name, filter, ok, err := parsePanicBreakpoint(argstr); ok {
+		if err != nil {
+			return err
+		}
+		bp, err := t.client.CreateBreakpoint(&api.Breakpoint{Name: name, Tracepoint: tracepoint, Panic: filter, GoroutineFilter: gfilter, Variables: []string{"e"}})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s set at %s\n", formatBreakpointName(bp, true), formatBreakpointLocation(bp))
+		return nil
+	}
+	if name, re, ok := splitBreakpointFlag(argstr, "-on-load"); ok {
+		if re == "" {
+			return errors.New("-on-load requires a regular expression")
+		}
+		bp, err := t.client.CreateBreakpoint(&api.Breakpoint{Name: name, Tracepoint: tracepoint, OnLoad: re, GoroutineFilter: gfilter})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s set at %s\n", formatBreakpointName(bp, true), formatBreakpointLocation(bp))
+		return nil
+	}
 
 	requestedBp := &api.Breakpoint{}
 	spec := ""
-	switch len(args) {
-	case 1:
-		spec = argstr
-	case 2:
-		if api.ValidBreakpointName(args[0]) == nil {
-			requestedBp.Name = args[0]
-			spec = args[1]
-		} else {
+	pending := false
+	if name, rest, ok := splitBreakpointFlag(argstr, "-pending"); ok {
+		if name != "" {
+			if err := api.ValidBreakpointName(name); err != nil {
+				return err
+			}
+		}
+		requestedBp.Name = name
+		spec = rest
+		pending = true
+	} else {
+		args := split2PartsBySpace(argstr)
+		switch len(args) {
+		case 1:
 			spec = argstr
+		case 2:
+			if api.ValidBreakpointName(args[0]) == nil {
+				requestedBp.Name = args[0]
+				spec = args[1]
+			} else {
+				spec = argstr
+			}
+		default:
+			return fmt.Errorf("address required")
 		}
-	default:
-		return fmt.Errorf("address required")
 	}
 
 	requestedBp.Tracepoint = tracepoint
+	requestedBp.GoroutineFilter = gfilter
 	locs, err := t.client.FindLocation(ctx.Scope, spec, true)
+	if err != nil && pending {
+		return setPendingBreakpoint(t, requestedBp, spec)
+	}
 	if err != nil {
-		if requestedBp.Name == "" {
+		if requestedBp.Name == "" || pending {
 			return err
 		}
 		requestedBp.Name = ""
@@ -1464,6 +1963,42 @@ func setBreakpoint(t *Term, ctx callContext, tracepoint bool, argstr string) err
 	return nil
 }
 
+// setPendingBreakpoint creates a breakpoint on a location that will be
+// found when the shared library or plugin containing it is loaded. Specs
+// ending in '.go:<line>' are file locations, other specs are functions.
+func setPendingBreakpoint(t *Term, requestedBp *api.Breakpoint, spec string) error {
+	loc, err := locspec.Parse(spec)
+	if err != nil {
+		return err
+	}
+	nloc, ok := loc.(*locspec.NormalLocationSpec)
+	if !ok {
+		return fmt.Errorf("pending breakpoints must be set on a function or on a file:line location")
+	}
+	if strings.HasSuffix(nloc.Base, ".go") {
+		if nloc.LineOffset < 0 {
+			return fmt.Errorf("Malformed breakpoint location, no line offset specified")
+		}
+		requestedBp.File = nloc.Base
+		requestedBp.Line = nloc.LineOffset
+	} else {
+		requestedBp.FunctionName = nloc.Base
+		if nloc.LineOffset > 0 {
+			requestedBp.Line = nloc.LineOffset
+		}
+	}
+	if requestedBp.Tracepoint {
+		requestedBp.LoadArgs = &ShortLoadConfig
+	}
+	requestedBp.Pending = true
+	bp, err := t.client.CreateBreakpoint(requestedBp)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s set at %s\n", formatBreakpointName(bp, true), formatBreakpointLocation(bp))
+	return nil
+}
+
 func breakpoint(t *Term, ctx callContext, args string) error {
 	return setBreakpoint(t, ctx, false, args)
 }
@@ -1571,19 +2106,70 @@ func printVar(t *Term, ctx callContext, args string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("not enough arguments")
 	}
+	offset, count, args, err := parsePrintRange(args)
+	if err != nil {
+		return err
+	}
+	ranged := offset != 0 || count != 0
 	if ctx.Prefix == onPrefix {
+		if ranged {
+			return fmt.Errorf("-offset and -count can not be used with on")
+		}
 		ctx.Breakpoint.Variables = append(ctx.Breakpoint.Variables, args)
 		return nil
 	}
-	val, err := t.client.EvalVariable(ctx.Scope, args, t.loadConfig())
+	var val *api.Variable
+	if ranged {
+		val, err = t.client.EvalVariableRange(ctx.Scope, args, offset, count, t.loadConfig())
+	} else {
+		val, err = t.client.EvalVariable(ctx.Scope, args, t.loadConfig())
+	}
 	if err != nil {
 		return err
 	}
 
+	if ctx.JSON {
+		return t.printJSON(val)
+	}
 	fmt.Println(val.MultilineString(""))
 	return nil
 }
 
+// parsePrintRange parses the -offset and -count options at the start of
+// the arguments of print and returns their values and the expression that
+// follows them.
+func parsePrintRange(args string) (offset, count int, expr string, err error) {
+	expr = strings.TrimSpace(args)
+	for {
+		var opt string
+		switch {
+		case strings.HasPrefix(expr, "-offset "):
+			opt = "-offset"
+		case strings.HasPrefix(expr, "-count "):
+			opt = "-count"
+		default:
+			if expr == "" {
+				return 0, 0, "", fmt.Errorf("not enough arguments")
+			}
+			return offset, count, expr, nil
+		}
+		v := strings.SplitN(strings.TrimSpace(expr[len(opt):]), " ", 2)
+		n, err := strconv.Atoi(v[0])
+		if err != nil || n < 0 {
+			return 0, 0, "", fmt.Errorf("%s must be a non-negative integer", opt)
+		}
+		if opt == "-offset" {
+			offset = n
+		} else {
+			count = n
+		}
+		expr = ""
+		if len(v) > 1 {
+			expr = strings.TrimSpace(v[1])
+		}
+	}
+}
+
 func whatisCommand(t *Term, ctx callContext, args string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("not enough arguments")
@@ -1624,11 +2210,20 @@ func setVar(t *Term, ctx callContext, args string) error {
 	return t.client.SetVariable(ctx.Scope, lexpr, rexpr)
 }
 
-func printFilteredVariables(varType string, vars []api.Variable, filter string, cfg api.LoadConfig) error {
+func printFilteredVariables(t *Term, ctx callContext, varType string, vars []api.Variable, filter string, cfg api.LoadConfig) error {
 	reg, err := regexp.Compile(filter)
 	if err != nil {
 		return err
 	}
+	if ctx.JSON {
+		r := []api.Variable{}
+		for _, v := range vars {
+			if reg.MatchString(v.Name) {
+				r = append(r, v)
+			}
+		}
+		return t.printJSON(r)
+	}
 	match := false
 	for _, v := range vars {
 		if reg == nil || reg.Match([]byte(v.Name)) {
@@ -1650,11 +2245,14 @@ func printFilteredVariables(varType string, vars []api.Variable, filter string,
 	return nil
 }
 
-func printSortedStrings(v []string, err error) error {
+func printSortedStrings(t *Term, ctx callContext, v []string, err error) error {
 	if err != nil {
 		return err
 	}
 	sort.Strings(v)
+	if ctx.JSON {
+		return t.printJSON(v)
+	}
 	for _, d := range v {
 		fmt.Println(d)
 	}
@@ -1662,15 +2260,18 @@ func printSortedStrings(v []string, err error) error {
 }
 
 func sources(t *Term, ctx callContext, args string) error {
-	return printSortedStrings(t.client.ListSources(args))
+	v, err := t.client.ListSources(args)
+	return printSortedStrings(t, ctx, v, err)
 }
 
 func funcs(t *Term, ctx callContext, args string) error {
-	return printSortedStrings(t.client.ListFunctions(args))
+	v, err := t.client.ListFunctions(args)
+	return printSortedStrings(t, ctx, v, err)
 }
 
 func types(t *Term, ctx callContext, args string) error {
-	return printSortedStrings(t.client.ListTypes(args))
+	v, err := t.client.ListTypes(args)
+	return printSortedStrings(t, ctx, v, err)
 }
 
 func parseVarArguments(args string, t *Term) (filter string, cfg api.LoadConfig) {
@@ -1697,7 +2298,7 @@ func args(t *Term, ctx callContext, args string) error {
 	if err != nil {
 		return err
 	}
-	return printFilteredVariables("args", vars, filter, cfg)
+	return printFilteredVariables(t, ctx, "args", vars, filter, cfg)
 }
 
 func locals(t *Term, ctx callContext, args string) error {
@@ -1713,7 +2314,7 @@ func locals(t *Term, ctx callContext, args string) error {
 	if err != nil {
 		return err
 	}
-	return printFilteredVariables("locals", locals, filter, cfg)
+	return printFilteredVariables(t, ctx, "locals", locals, filter, cfg)
 }
 
 func vars(t *Term, ctx callContext, args string) error {
@@ -1722,7 +2323,7 @@ func vars(t *Term, ctx callContext, args string) error {
 	if err != nil {
 		return err
 	}
-	return printFilteredVariables("vars", vars, filter, cfg)
+	return printFilteredVariables(t, ctx, "vars", vars, filter, cfg)
 }
 
 func regs(t *Term, ctx callContext, args string) error {
@@ -1740,6 +2341,9 @@ func regs(t *Term, ctx callContext, args string) error {
 	if err != nil {
 		return err
 	}
+	if ctx.JSON {
+		return t.printJSON(regs)
+	}
 	fmt.Println(regs)
 	return nil
 }
@@ -1761,12 +2365,21 @@ func stackCommand(t *Term, ctx callContext, args string) error {
 	if err != nil {
 		return err
 	}
-	printStack(os.Stdout, stack, "", sa.offsets)
+	if ctx.JSON {
+		if err := t.printJSON(stack); err != nil {
+			return err
+		}
+	} else {
+		printStack(os.Stdout, stack, "", sa.offsets)
+	}
 	if sa.ancestors > 0 {
 		ancestors, err := t.client.Ancestors(ctx.Scope.GoroutineID, sa.ancestors, sa.ancestorDepth)
 		if err != nil {
 			return err
 		}
+		if ctx.JSON {
+			return t.printJSON(ancestors)
+		}
 		for _, ancestor := range ancestors {
 			fmt.Printf("Created by Goroutine %d:\n", ancestor.ID)
 			if ancestor.Unreadable != "" {
@@ -2019,6 +2632,9 @@ func libraries(t *Term, ctx callContext, args string) error {
 	if err != nil {
 		return err
 	}
+	if ctx.JSON {
+		return t.printJSON(libs)
+	}
 	d := digits(len(libs))
 	for i := range libs {
 		fmt.Printf("%"+strconv.Itoa(d)+"d. %#x %s\n", i, libs[i].Address, libs[i].Path)
@@ -2057,11 +2673,16 @@ func printStack(out io.Writer, stack []api.Stackframe, ind string, offsets bool)
 			fmt.Fprintf(out, "%serror: %s\n", s, stack[i].Err)
 			continue
 		}
-		fmt.Fprintf(out, fmtstr, ind, i, stack[i].PC, stack[i].Function.Name())
-		fmt.Fprintf(out, "%sat %s:%d\n", s, shortenFilePath(stack[i].File), stack[i].Line)
+		fmt.Fprintf(out, fmtstr, ind, i, stack[i].PC, stack[i].Function.Name()+stack[i].RawArgs)
+		unwinder := ""
+		if stack[i].Unwinder == "frame pointer" {
+			// frames found following the frame pointer are less reliable
+			unwinder = " (frame pointer unwind)"
+		}
+		fmt.Fprintf(out, "%sat %s:%d%s\n", s, shortenFilePath(stack[i].File), stack[i].Line, unwinder)
 
 		if offsets {
-			fmt.Fprintf(out, "%sframe: %+#x frame pointer %+#x\n", s, stack[i].FrameOffset, stack[i].FramePointerOffset)
+			fmt.Fprintf(out, "%sframe: %+#x frame pointer %+#x unwinder: %s\n", s, stack[i].FrameOffset, stack[i].FramePointerOffset, stack[i].Unwinder)
 		}
 
 		for j, d := range stack[i].Defers {
@@ -2132,6 +2753,12 @@ func printcontext(t *Term, state *api.DebuggerState) {
 
 	printcontextThread(t, th)
 
+	if bp := state.OnLoadBreakpoint; bp != nil && (th.Breakpoint == nil || th.Breakpoint.ID != bp.ID) {
+		// the library was loaded while the target was stopping for
+		// something else
+		printOnLoadBreakpoint(bp, th.PC)
+	}
+
 	if state.When != "" {
 		fmt.Println(state.When)
 	}
@@ -2144,6 +2771,14 @@ func printcontextLocation(loc api.Location) {
 	}
 }
 
+func printOnLoadBreakpoint(bp *api.Breakpoint, pc uint64) {
+	bpname := ""
+	if bp.Name != "" {
+		bpname = fmt.Sprintf("[%s] ", bp.Name)
+	}
+	fmt.Printf("> %sloaded library matching %q (hits total:%d) (PC: %#v)\n", bpname, bp.OnLoad, bp.TotalHitCount, pc)
+}
+
 func printReturnValues(th *api.Thread) {
 	if th.ReturnValues == nil {
 		return
@@ -2189,6 +2824,11 @@ func printcontextThread(t *Term, th *api.Thread) {
 		bpname = fmt.Sprintf("[%s] ", th.Breakpoint.Name)
 	}
 
+	if th.Breakpoint.OnLoad != "" {
+		printOnLoadBreakpoint(th.Breakpoint, th.PC)
+		return
+	}
+
 	if th.Breakpoint.Tracepoint || th.Breakpoint.TraceReturn {
 		printTracepoint(th, bpname, fn, args, hasReturnValue)
 		return
@@ -2488,6 +3128,66 @@ func clearCheckpoint(t *Term, ctx callContext, args string) error {
 	return t.client.ClearCheckpoint(id)
 }
 
+func when(t *Term, ctx callContext, args string) error {
+	pos, err := t.client.When()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Event: %d\nTicks: %d\n", pos.Event, pos.Ticks)
+	return nil
+}
+
+func (c *Commands) seek(t *Term, ctx callContext, args string) error {
+	v := strings.Fields(args)
+	ticks := len(v) == 2 && v[0] == "-ticks"
+	if ticks {
+		v = v[1:]
+	}
+	if len(v) != 1 {
+		return errors.New("wrong number of arguments to seek")
+	}
+	pos, err := strconv.ParseUint(v[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("could not parse %q: %v", v[0], err)
+	}
+	var state *api.DebuggerState
+	if ticks {
+		state, err = t.client.SeekTicks(pos)
+	} else {
+		state, err = t.client.SeekEvent(pos)
+	}
+	if err != nil {
+		return err
+	}
+	c.frame = 0
+	printcontext(t, state)
+	printfile(t, state.CurrentThread.File, state.CurrentThread.Line, true)
+	return nil
+}
+
+func processes(t *Term, ctx callContext, args string) error {
+	if args != "" {
+		pid, err := strconv.Atoi(args)
+		if err != nil {
+			return fmt.Errorf("not a pid: %q", args)
+		}
+		discarded, err := t.client.ReplayProcess(pid)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Replaying process %d\n", pid)
+		for i := range discarded {
+			fmt.Printf("Discarded %s at %s: %v\n", formatBreakpointName(discarded[i].Breakpoint, false), formatBreakpointLocation(discarded[i].Breakpoint), discarded[i].Reason)
+		}
+		return nil
+	}
+	procs, err := t.client.ListTraceProcesses()
+	if err != nil {
+		return err
+	}
+	return api.PrintTraceProcesses(os.Stdout, procs)
+}
+
 func display(t *Term, ctx callContext, args string) error {
 	const (
 		addOption = "-a "
@@ -2534,8 +3234,50 @@ func formatBreakpointName(bp *api.Breakpoint, upcase bool) string {
 	return fmt.Sprintf("%s %s", thing, id)
 }
 
+func formatPanicFilter(filter *api.PanicFilter) string {
+	var out bytes.Buffer
+	for _, typ := range filter.Types {
+		fmt.Fprintf(&out, " %s", typ)
+	}
+	if filter.Message != "" {
+		fmt.Fprintf(&out, " /%s/", filter.Message)
+	}
+	return out.String()
+}
+
+func formatGoroutineFilter(filter *api.GoroutineFilter) string {
+	var out bytes.Buffer
+	if filter.ID != 0 {
+		fmt.Fprintf(&out, " -g %d", filter.ID)
+	}
+	keys := make([]string, 0, len(filter.Labels))
+	for k := range filter.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&out, " -label %s=%s", k, filter.Labels[k])
+	}
+	return out.String()
+}
+
 func formatBreakpointLocation(bp *api.Breakpoint) string {
 	var out bytes.Buffer
+	if bp.OnLoad != "" {
+		return fmt.Sprintf("load of libraries matching %q", bp.OnLoad)
+	}
+	if bp.Pending {
+		if bp.FunctionName != "" {
+			fmt.Fprintf(&out, "%s()", bp.FunctionName)
+			if bp.Line > 0 {
+				fmt.Fprintf(&out, ":%d", bp.Line)
+			}
+		} else {
+			fmt.Fprintf(&out, "%s:%d", bp.File, bp.Line)
+		}
+		fmt.Fprintf(&out, " (pending)")
+		return out.String()
+	}
 	if len(bp.Addrs) > 0 {
 		for i, addr := range bp.Addrs {
 			if i == 0 {
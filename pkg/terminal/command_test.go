@@ -1,6 +1,8 @@
 package terminal
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -8,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"runtime"
 	"strconv"
@@ -253,6 +256,23 @@ func TestIssue354(t *testing.T) {
 			Bottom: true}}, "", false)
 }
 
+func TestPrintStackRawArgs(t *testing.T) {
+	// Frames read from a goroutine dump print their arguments exactly as the
+	// runtime printed them.
+	var buf bytes.Buffer
+	printStack(&buf, []api.Stackframe{
+		{Location: api.Location{PC: 0x48c870, File: "/tmp/tb/main.go", Line: 13, Function: &api.Function{Name_: "main.f"}},
+			RawArgs: "(0x3, {0x48d19d, 0x5}, {0x1?, 0x2?}, ...)"},
+		{Location: api.Location{PC: 0x48ca91, File: "/tmp/tb/main.go", Line: 32, Function: &api.Function{Name_: "main.main"}},
+			RawArgs: "()", Bottom: true},
+	}, "", false)
+	for _, tgt := range []string{"in main.f(0x3, {0x48d19d, 0x5}, {0x1?, 0x2?}, ...)\n", "in main.main()\n"} {
+		if !strings.Contains(buf.String(), tgt) {
+			t.Errorf("%q not found in:\n%s", tgt, buf.String())
+		}
+	}
+}
+
 func TestIssue411(t *testing.T) {
 	if runtime.GOARCH == "arm64" {
 		t.Skip("test is not valid on ARM64")
@@ -980,6 +1000,39 @@ func TestOptimizationCheck(t *testing.T) {
 	}
 }
 
+func TestCompleteLine(t *testing.T) {
+	find := func(tgt string, completions []string) bool {
+		for _, s := range completions {
+			if s == tgt {
+				return true
+			}
+		}
+		return false
+	}
+	withTestTerminal("continuetestprog", t, func(term *FakeTerminal) {
+		if c := term.completeLine("brea"); !find("break", c) {
+			t.Errorf("command names: %q", c)
+		}
+		if c := term.completeLine("break main.say"); !find("break main.sayhi", c) {
+			t.Errorf("functions: %q", c)
+		}
+		if c := term.completeLine("list continuetestpr"); !find("list continuetestprog.go:", c) {
+			t.Errorf("files: %q", c)
+		}
+
+		// Locations can still be completed once the target has exited.
+		if _, err := term.Exec("continue"); err == nil || !strings.Contains(err.Error(), "exited") {
+			t.Fatalf("target did not exit: %v", err)
+		}
+		if c := term.completeLine("b main.say"); !find("b main.sayhi", c) {
+			t.Errorf("functions after exit: %q", c)
+		}
+		if c := term.completeLine("print main.sa"); c != nil {
+			t.Errorf("completed expression after exit: %q", c)
+		}
+	})
+}
+
 func TestTruncateStacktrace(t *testing.T) {
 	withTestTerminal("stacktraceprog", t, func(term *FakeTerminal) {
 		term.MustExec("break main.stacktraceme")
@@ -1075,3 +1128,200 @@ func TestExamineMemoryCmd(t *testing.T) {
 		}
 	})
 }
+
+func TestStackCgoCallback(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH == "386" {
+		t.Skip("mixed Go/C stacks are only tested on linux")
+	}
+	test.MustHaveCgo(t)
+	// The stack must go through the C frames that called back into Go,
+	// stop at the breakpoint in helloworld_pt4.
+	tgt := []string{"C.helloworld_pt4", "C.helloworld_pt3", "main.helloWorldS", "main.helloWorld", "C.helloworld_pt2", "C.helloworld", "main.main"}
+	withTestTerminal("cgostacktest/", t, func(term *FakeTerminal) {
+		for i := 0; i < 4; i++ {
+			term.MustExec("continue")
+		}
+		out := term.MustExec("stack")
+		t.Logf("stack: %s", out)
+		fnrx := regexp.MustCompile(`^\s*\d+\s+0x[0-9a-f]+ in (\S+)`)
+		var fns []string
+		for _, line := range strings.Split(out, "\n") {
+			if m := fnrx.FindStringSubmatch(line); m != nil {
+				fns = append(fns, m[1])
+			}
+		}
+		i := 0
+		for _, fn := range fns {
+			if i < len(tgt) && fn == tgt[i] {
+				i++
+			}
+		}
+		if i != len(tgt) {
+			t.Fatalf("frame %s missing from stack %v", tgt[i], fns)
+		}
+	})
+}
+
+func TestStepInTarget(t *testing.T) {
+	test.AllowRecording(t)
+	withTestTerminal("stepintotarget", t, func(term *FakeTerminal) {
+		term.MustExec("break _fixtures/stepintotarget.go:18")
+		term.MustExec("continue")
+		out := term.MustExec("stepin -target")
+		if !strings.Contains(out, "1. main.read\n2. main.parse\n3. main.process\n") {
+			t.Fatalf("wrong step in targets: %q", out)
+		}
+		term.MustExec("stepin -target 2")
+		out = term.MustExec("stack")
+		if !strings.Contains(out, "main.parse") {
+			t.Fatalf("not stopped in main.parse: %q", out)
+		}
+		term.AssertExecError("stepin -target 4", `invalid target "4", 0 function calls on the current line`)
+	})
+}
+
+func TestJSONOutput(t *testing.T) {
+	test.AllowRecording(t)
+	withTestTerminal("testvariables2", t, func(term *FakeTerminal) {
+		term.MustExec("continue")
+
+		var v api.Variable
+		out := term.MustExec("-json print as1")
+		if err := json.Unmarshal([]byte(out), &v); err != nil {
+			t.Fatalf("could not unmarshal %q: %v", out, err)
+		}
+		if v.Name != "as1" || len(v.Children) != 2 {
+			t.Fatalf("wrong variable: %#v", v)
+		}
+
+		var stack []api.Stackframe
+		out = term.MustExec("-json goroutine 1 stack")
+		if err := json.Unmarshal([]byte(out), &stack); err != nil {
+			t.Fatalf("could not unmarshal %q: %v", out, err)
+		}
+		if len(stack) == 0 || stack[0].Function == nil {
+			t.Fatalf("wrong stack: %#v", stack)
+		}
+
+		term.AssertExecError("-json continue", "command continue does not support JSON output")
+		term.AssertExecError("-json goroutine 1 frame 1 continue", "command continue does not support JSON output")
+
+		var frame api.Stackframe
+		out = term.MustExec("-json frame 1")
+		if err := json.Unmarshal([]byte(out), &frame); err != nil {
+			t.Fatalf("could not unmarshal %q: %v", out, err)
+		}
+		if frame.Function == nil || frame.Function.Name() != "runtime.main" {
+			t.Fatalf("wrong frame: %#v", frame)
+		}
+		term.MustExec("frame 0")
+
+		term.JSONOutput = true
+		var locals []api.Variable
+		out = term.MustExec("locals as1")
+		if err := json.Unmarshal([]byte(out), &locals); err != nil {
+			t.Fatalf("could not unmarshal %q: %v", out, err)
+		}
+		if len(locals) != 1 || locals[0].Name != "as1" {
+			t.Fatalf("wrong locals: %#v", locals)
+		}
+		term.JSONOutput = false
+
+		out = strings.TrimSpace(term.MustExecStarlark(`v = dlv_command("-json print as1")
+print(v.Name, v.Value.A)`))
+		if out != "as1 1" {
+			t.Fatalf("wrong output of starlark script: %q", out)
+		}
+	})
+}
+
+func TestParseGoroutineFilter(t *testing.T) {
+	for _, tc := range []struct {
+		in, rest string
+		filter   *api.GoroutineFilter
+	}{
+		{"main.go:40", "main.go:40", nil},
+		{"-g 17 main.go:40", "main.go:40", &api.GoroutineFilter{ID: 17}},
+		{"name -g 17 main.go:40", "name main.go:40", &api.GoroutineFilter{ID: 17}},
+		{"-label request_id=abc -label k=v main.main", "main.main", &api.GoroutineFilter{Labels: map[string]string{"request_id": "abc", "k": "v"}}},
+		{"-g 2 -panic runtime.Error", "-panic runtime.Error", &api.GoroutineFilter{ID: 2}},
+		{"name main.go:40", "name main.go:40", nil},
+	} {
+		rest, filter, err := parseGoroutineFilter(tc.in)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tc.in, err)
+			continue
+		}
+		if rest != tc.rest || !reflect.DeepEqual(filter, tc.filter) {
+			t.Errorf("%q: got %q %#v, expected %q %#v", tc.in, rest, filter, tc.rest, tc.filter)
+		}
+	}
+
+	for _, in := range []string{"-g", "-g abc main.go:40", "-label k main.go:40"} {
+		if _, _, err := parseGoroutineFilter(in); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+}
+
+func TestParsePrintRange(t *testing.T) {
+	for _, tc := range []struct {
+		in            string
+		offset, count int
+		expr          string
+	}{
+		{"m", 0, 0, "m"},
+		{"-offset 64 -count 32 m", 64, 32, "m"},
+		{"-count 10 s[1:]", 0, 10, "s[1:]"},
+		{"-offset 3 a + b", 3, 0, "a + b"},
+		{"-x", 0, 0, "-x"},
+	} {
+		offset, count, expr, err := parsePrintRange(tc.in)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tc.in, err)
+			continue
+		}
+		if offset != tc.offset || count != tc.count || expr != tc.expr {
+			t.Errorf("%q: got %d %d %q, expected %d %d %q", tc.in, offset, count, expr, tc.offset, tc.count, tc.expr)
+		}
+	}
+
+	for _, in := range []string{"-offset 10", "-offset abc m", "-count -1 m"} {
+		if _, _, _, err := parsePrintRange(in); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+}
+
+func TestPrintcontextOnLoadBreakpoint(t *testing.T) {
+	// A library matching an on-load breakpoint was loaded while the target
+	// was stopping at a user breakpoint, both stops are printed.
+	outfh, err := ioutil.TempFile("", "cmdtestout")
+	if err != nil {
+		t.Fatalf("could not create temporary file: %v", err)
+	}
+	defer os.Remove(outfh.Name())
+	stdout := os.Stdout
+	os.Stdout = outfh
+	defer func() { os.Stdout = stdout }()
+
+	th := &api.Thread{ID: 1, PC: 0x4a1f20, File: "/tmp/main.go", Line: 12, Function: &api.Function{Name_: "main.main"}, GoroutineID: 1,
+		Breakpoint: &api.Breakpoint{ID: 1, TotalHitCount: 1}}
+	printcontext(nil, &api.DebuggerState{
+		CurrentThread:    th,
+		Threads:          []*api.Thread{th},
+		OnLoadBreakpoint: &api.Breakpoint{ID: 2, OnLoad: "plugin", TotalHitCount: 1},
+	})
+	os.Stdout = stdout
+	outfh.Close()
+	buf, err := ioutil.ReadFile(outfh.Name())
+	if err != nil {
+		t.Fatalf("could not read temporary output file: %v", err)
+	}
+	out := string(buf)
+	for _, tgt := range []string{"> main.main() /tmp/main.go:12 (hits total:1)", "> loaded library matching \"plugin\" (hits total:1)"} {
+		if !strings.Contains(out, tgt) {
+			t.Errorf("%q not found in:\n%s", tgt, out)
+		}
+	}
+}
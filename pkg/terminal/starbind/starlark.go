@@ -43,7 +43,7 @@ func init() {
 type Context interface {
 	Client() service.Client
 	RegisterCommand(name, helpMsg string, cmdfn func(args string) error)
-	CallCommand(cmdstr string) error
+	CallCommand(cmdstr string) ([]interface{}, error)
 	Scope() api.EvalScope
 	LoadConfig() api.LoadConfig
 }
@@ -76,11 +76,25 @@ func New(ctx Context) *Env {
 			}
 			argstrs[i] = string(a)
 		}
-		err := env.ctx.CallCommand(strings.Join(argstrs, " "))
+		out, err := env.ctx.CallCommand(strings.Join(argstrs, " "))
 		if err != nil && strings.Contains(err.Error(), " has exited with status ") {
 			return env.interfaceToStarlarkValue(err), nil
 		}
-		return starlark.None, decorateError(thread, err)
+		if err != nil {
+			return starlark.None, decorateError(thread, err)
+		}
+		switch len(out) {
+		case 0:
+			return starlark.None, nil
+		case 1:
+			return env.interfaceToStarlarkValue(out[0]), nil
+		default:
+			r := make(starlark.Tuple, len(out))
+			for i := range out {
+				r[i] = env.interfaceToStarlarkValue(out[i])
+			}
+			return r, nil
+		}
 	})
 	env.env[readFileBuiltinName] = starlark.NewBuiltin(readFileBuiltinName, func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 		if len(args) != 1 {
@@ -46,8 +46,15 @@ func (ctx starlarkContext) RegisterCommand(name, helpMsg string, fn func(args st
 	}
 }
 
-func (ctx starlarkContext) CallCommand(cmdstr string) error {
-	return ctx.term.cmds.Call(cmdstr, ctx.term)
+func (ctx starlarkContext) CallCommand(cmdstr string) ([]interface{}, error) {
+	var out []interface{}
+	oldCapture := ctx.term.jsonCapture
+	ctx.term.jsonCapture = &out
+	defer func() {
+		ctx.term.jsonCapture = oldCapture
+	}()
+	err := ctx.term.cmds.Call(cmdstr, ctx.term)
+	return out, err
 }
 
 func (ctx starlarkContext) Scope() api.EvalScope {
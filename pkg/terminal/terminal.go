@@ -1,6 +1,7 @@
 package terminal
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/rpc"
@@ -56,6 +57,13 @@ type Term struct {
 	InitFile string
 	displays []string
 
+	// JSONOutput makes commands that support it print their output as JSON,
+	// as if they were prefixed with -json.
+	JSONOutput bool
+	// jsonCapture, if not nil, collects the values that commands would
+	// print as JSON instead of writing them to stdout.
+	jsonCapture *[]interface{}
+
 	historyFile *os.File
 
 	starlarkEnv *starbind.Env
@@ -179,24 +187,7 @@ func (t *Term) Run() (int, error) {
 	signal.Notify(ch, syscall.SIGINT)
 	go t.sigintGuard(ch, multiClient)
 
-	t.line.SetCompleter(func(line string) (c []string) {
-		if strings.HasPrefix(line, "break ") || strings.HasPrefix(line, "b ") {
-			filter := line[strings.Index(line, " ")+1:]
-			funcs, _ := t.client.ListFunctions(filter)
-			for _, f := range funcs {
-				c = append(c, "break "+f)
-			}
-			return
-		}
-		for _, cmd := range t.cmds.cmds {
-			for _, alias := range cmd.aliases {
-				if strings.HasPrefix(alias, strings.ToLower(line)) {
-					c = append(c, alias)
-				}
-			}
-		}
-		return
-	})
+	t.line.SetCompleter(t.completeLine)
 
 	fullHistoryFile, err := config.GetConfigFilePath(historyFile)
 	if err != nil {
@@ -211,6 +202,12 @@ func (t *Term) Run() (int, error) {
 		fmt.Printf("Unable to read history file: %v", err)
 	}
 
+	if warnings, err := t.client.Warnings(); err == nil {
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+	}
+
 	fmt.Println("Type 'help' for list of commands.")
 
 	if t.InitFile != "" {
@@ -267,6 +264,63 @@ func (t *Term) Run() (int, error) {
 	}
 }
 
+// completeLine returns the completion candidates for line: the arguments
+// of commands accepting a location or an expression are completed by the
+// debugger, anything else is completed with the command names.
+func (t *Term) completeLine(line string) (c []string) {
+	cmdstr, args := line, ""
+	if sp := strings.Index(line, " "); sp >= 0 {
+		cmdstr, args = line[:sp], line[sp+1:]
+	}
+	if cmdstr == line {
+		for _, cmd := range t.cmds.cmds {
+			for _, alias := range cmd.aliases {
+				if strings.HasPrefix(alias, strings.ToLower(line)) {
+					c = append(c, alias)
+				}
+			}
+		}
+		return
+	}
+
+	var kind api.CompletionKind
+	switch cmdstr {
+	case "break", "b", "trace", "t", "list", "ls", "l":
+		// the location is always the last argument and can not contain spaces
+		kind = api.CompleteLocation
+		args = args[strings.LastIndex(args, " ")+1:]
+	case "print", "p", "whatis", "set", "call":
+		kind = api.CompleteExpression
+	case "display":
+		if !strings.HasPrefix(args, "-a ") {
+			return
+		}
+		kind = api.CompleteExpression
+		args = args[len("-a "):]
+	default:
+		return
+	}
+
+	argStart := len(line) - len(args)
+	start, completions, err := t.client.Complete(api.EvalScope{GoroutineID: -1, Frame: t.cmds.frame}, kind, args)
+	if err != nil {
+		return
+	}
+	for _, completion := range completions {
+		c = append(c, line[:argStart+start]+completion.Text)
+	}
+	return
+}
+
+// printJSON prints v as a single line of JSON.
+func (t *Term) printJSON(v interface{}) error {
+	if t.jsonCapture != nil {
+		*t.jsonCapture = append(*t.jsonCapture, v)
+		return nil
+	}
+	return json.NewEncoder(os.Stdout).Encode(v)
+}
+
 // Println prints a line to the terminal.
 func (t *Term) Println(prefix, str string) {
 	if !t.dumb {
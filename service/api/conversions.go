@@ -8,6 +8,7 @@ import (
 	"go/token"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/go-delve/delve/pkg/dwarf/godwarf"
 	"github.com/go-delve/delve/pkg/dwarf/op"
@@ -44,6 +45,16 @@ func ConvertBreakpoint(bp *proc.Breakpoint) *Breakpoint {
 	printer.Fprint(&buf, token.NewFileSet(), bp.Cond)
 	b.Cond = buf.String()
 
+	if bp.Panic != nil {
+		b.Panic = &PanicFilter{Types: bp.Panic.Types}
+		if bp.Panic.Message != nil {
+			b.Panic.Message = bp.Panic.Message.String()
+		}
+	}
+	if bp.GoroutineFilter != nil {
+		b.GoroutineFilter = &GoroutineFilter{ID: bp.GoroutineFilter.ID, Labels: bp.GoroutineFilter.Labels}
+	}
+
 	return b
 }
 
@@ -114,6 +125,16 @@ func prettyTypeName(typ godwarf.Type) string {
 		return ""
 	}
 	if typ.Common().Name != "" {
+		switch t := typ.(type) {
+		case *godwarf.StructType:
+			if isCTagName(t.Name) {
+				return t.Kind + " " + t.Name
+			}
+		case *godwarf.EnumType:
+			if isCTagName(t.Name) {
+				return "enum " + t.Name
+			}
+		}
 		return typ.Common().Name
 	}
 	r := typ.String()
@@ -123,6 +144,12 @@ func prettyTypeName(typ godwarf.Type) string {
 	return r
 }
 
+// isCTagName returns true if name is the tag of a C struct, union or
+// enum, Go type names are qualified by their package.
+func isCTagName(name string) bool {
+	return !strings.ContainsAny(name, ".<{[ ")
+}
+
 func convertFloatValue(v *proc.Variable, sz int) string {
 	switch v.FloatSpecial {
 	case proc.FloatIsPosInf:
@@ -147,6 +174,7 @@ func ConvertVar(v *proc.Variable) *Variable {
 		Cap:      v.Cap,
 		Flags:    VariableFlags(v.Flags),
 		Base:     v.Base,
+		Offset:   v.Offset,
 
 		LocationExpr: v.LocationExpr.String(),
 		DeclLine:     v.DeclLine,
@@ -159,6 +187,10 @@ func ConvertVar(v *proc.Variable) *Variable {
 		r.Unreadable = v.Unreadable.Error()
 	}
 
+	if v.Addr != 0 && !v.OnlyAddr && v.Flags&proc.VariableFakeAddress == 0 && v.DwarfType != nil && v.DwarfType.Common().Offset != 0 {
+		r.Ref = &VariableRef{Addr: v.Addr, Image: v.DwarfType.Common().Index, TypeOffset: uint64(v.DwarfType.Common().Offset)}
+	}
+
 	if v.Value != nil {
 		switch v.Kind {
 		case reflect.Float32:
@@ -143,8 +143,11 @@ func (v *Variable) writeTo(buf io.Writer, top, newlines, includeType bool, inden
 
 func (v *Variable) writeStringTo(buf io.Writer) {
 	s := v.Value
-	if len(s) != int(v.Len) {
-		s = fmt.Sprintf("%s...+%d more", s, int(v.Len)-len(s))
+	if v.Offset > 0 {
+		s = fmt.Sprintf("...%d skipped...%s", v.Offset, s)
+	}
+	if int(v.Offset)+len(v.Value) != int(v.Len) {
+		s = fmt.Sprintf("%s...+%d more", s, int(v.Len)-int(v.Offset)-len(v.Value))
 	}
 	fmt.Fprintf(buf, "%q", s)
 }
@@ -185,12 +188,23 @@ func (v *Variable) writeStructTo(buf io.Writer, newlines, includeType bool, inde
 
 	fmt.Fprint(buf, "{")
 
+	if v.Offset > 0 {
+		fmt.Fprintf(buf, "...%d skipped,", v.Offset)
+		if !nl {
+			fmt.Fprint(buf, " ")
+		}
+	}
+
 	for i := range v.Children {
 		if nl {
 			fmt.Fprintf(buf, "\n%s%s", indent, indentString)
 		}
-		fmt.Fprintf(buf, "%s: ", v.Children[i].Name)
-		v.Children[i].writeTo(buf, false, nl, true, indent+indentString)
+		// anonymous members of C structs are printed without their name and type
+		anonymous := v.Children[i].Name == ""
+		if !anonymous {
+			fmt.Fprintf(buf, "%s: ", v.Children[i].Name)
+		}
+		v.Children[i].writeTo(buf, false, nl, !anonymous, indent+indentString)
 		if i != len(v.Children)-1 || nl {
 			fmt.Fprint(buf, ",")
 			if !nl {
@@ -199,13 +213,13 @@ func (v *Variable) writeStructTo(buf io.Writer, newlines, includeType bool, inde
 		}
 	}
 
-	if len(v.Children) != int(v.Len) {
+	if int(v.Offset)+len(v.Children) != int(v.Len) {
 		if nl {
 			fmt.Fprintf(buf, "\n%s%s", indent, indentString)
 		} else {
 			fmt.Fprint(buf, ",")
 		}
-		fmt.Fprintf(buf, "...+%d more", int(v.Len)-len(v.Children))
+		fmt.Fprintf(buf, "...+%d more", int(v.Len)-int(v.Offset)-len(v.Children))
 	}
 
 	fmt.Fprint(buf, "}")
@@ -224,6 +238,10 @@ func (v *Variable) writeMapTo(buf io.Writer, newlines, includeType bool, indent
 
 	fmt.Fprint(buf, "[")
 
+	if v.Offset > 0 {
+		fmt.Fprintf(buf, "...%d skipped, ", v.Offset)
+	}
+
 	for i := 0; i < len(v.Children); i += 2 {
 		key := &v.Children[i]
 		value := &v.Children[i+1]
@@ -240,14 +258,14 @@ func (v *Variable) writeMapTo(buf io.Writer, newlines, includeType bool, indent
 		}
 	}
 
-	if len(v.Children)/2 != int(v.Len) {
+	if int(v.Offset)+len(v.Children)/2 != int(v.Len) {
 		if len(v.Children) != 0 {
 			if nl {
 				fmt.Fprintf(buf, "\n%s%s", indent, indentString)
 			} else {
 				fmt.Fprint(buf, ",")
 			}
-			fmt.Fprintf(buf, "...+%d more", int(v.Len)-(len(v.Children)/2))
+			fmt.Fprintf(buf, "...+%d more", int(v.Len)-int(v.Offset)-(len(v.Children)/2))
 		} else {
 			fmt.Fprint(buf, "...")
 		}
@@ -327,6 +345,13 @@ func (v *Variable) writeSliceOrArrayTo(buf io.Writer, newlines bool, indent stri
 	nl := v.shouldNewlineArray(newlines)
 	fmt.Fprint(buf, "[")
 
+	if v.Offset > 0 {
+		if nl {
+			fmt.Fprintf(buf, "\n%s%s", indent, indentString)
+		}
+		fmt.Fprintf(buf, "...%d skipped,", v.Offset)
+	}
+
 	for i := range v.Children {
 		if nl {
 			fmt.Fprintf(buf, "\n%s%s", indent, indentString)
@@ -337,14 +362,14 @@ func (v *Variable) writeSliceOrArrayTo(buf io.Writer, newlines bool, indent stri
 		}
 	}
 
-	if len(v.Children) != int(v.Len) {
+	if int(v.Offset)+len(v.Children) != int(v.Len) {
 		if len(v.Children) != 0 {
 			if nl {
 				fmt.Fprintf(buf, "\n%s%s", indent, indentString)
 			} else {
 				fmt.Fprint(buf, ",")
 			}
-			fmt.Fprintf(buf, "...+%d more", int(v.Len)-len(v.Children))
+			fmt.Fprintf(buf, "...+%d more", int(v.Len)-int(v.Offset)-len(v.Children))
 		} else {
 			fmt.Fprint(buf, "...")
 		}
@@ -411,3 +436,19 @@ func PrettyExamineMemory(address uintptr, memArea []byte, format byte) string {
 	w.Flush()
 	return b.String()
 }
+
+// PrintTraceProcesses writes a table of the processes recorded in a trace
+// to w. The parent of the first process of the trace is printed as '-'.
+func PrintTraceProcesses(w io.Writer, procs []TraceProcess) error {
+	tw := new(tabwriter.Writer)
+	tw.Init(w, 4, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PID\tPPID\tExit\tCommand")
+	for _, p := range procs {
+		ppid := "-"
+		if p.Ppid != 0 {
+			ppid = strconv.Itoa(p.Ppid)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", p.Pid, ppid, p.Exit, p.Cmd)
+	}
+	return tw.Flush()
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -32,3 +33,38 @@ func TestPrettyExamineMemory(t *testing.T) {
 		}
 	}
 }
+
+func TestPrettyPrintOffset(t *testing.T) {
+	ints := func(vals ...string) []Variable {
+		r := make([]Variable, len(vals))
+		for i := range vals {
+			r[i] = Variable{Kind: reflect.Int, Value: vals[i], Type: "int"}
+		}
+		return r
+	}
+	for _, tc := range []struct {
+		v   Variable
+		out string
+	}{
+		{Variable{Kind: reflect.Slice, Type: "[]int", Len: 10, Cap: 10, Base: 0x1000, Offset: 4, Children: ints("4", "5")}, "[]int len: 10, cap: 10, [...4 skipped,4,5,...+4 more]"},
+		{Variable{Kind: reflect.Slice, Type: "[]int", Len: 6, Cap: 6, Base: 0x1000, Offset: 4, Children: ints("4", "5")}, "[]int len: 6, cap: 6, [...4 skipped,4,5]"},
+		{Variable{Kind: reflect.String, Type: "string", Len: 20, Offset: 5, Value: "long"}, `"...5 skipped...long...+11 more"`},
+		{Variable{Kind: reflect.Map, Type: "map[int]int", Len: 3, Base: 0x1000, Offset: 2, Children: ints("1", "2")}, "map[int]int [...2 skipped, 1: 2, ]"},
+	} {
+		if out := tc.v.SinglelineString(); out != tc.out {
+			t.Errorf("got %q, expected %q", out, tc.out)
+		}
+	}
+}
+
+func TestPrintTraceProcesses(t *testing.T) {
+	var buf strings.Builder
+	PrintTraceProcesses(&buf, []TraceProcess{
+		{Pid: 123, Ppid: 0, Exit: "0", Cmd: "/bin/prog a"},
+		{Pid: 130, Ppid: 123, Exit: "-9", Cmd: "sh -c x"},
+	})
+	const tgt = "PID  PPID  Exit  Command\n123  -     0     /bin/prog a\n130  123   -9    sh -c x\n"
+	if buf.String() != tgt {
+		t.Fatalf("mismatch:\ngot:      %q\nexpected: %q", buf.String(), tgt)
+	}
+}
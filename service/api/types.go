@@ -40,6 +40,11 @@ type DebuggerState struct {
 	ExitStatus int  `json:"exitStatus"`
 	// When contains a description of the current position in a recording
 	When string
+	// OnLoadBreakpoint is the on-load breakpoint that was hit since the
+	// target was last resumed. The target may have stopped at the same time
+	// for another reason, for example at a user breakpoint, otherwise
+	// OnLoadBreakpoint is also the breakpoint of CurrentThread.
+	OnLoadBreakpoint *Breakpoint `json:"onLoadBreakpoint,omitempty"`
 	// Filled by RPCClient.Continue, indicates an error
 	Err error `json:"-"`
 }
@@ -85,6 +90,45 @@ type Breakpoint struct {
 	HitCount map[string]uint64 `json:"hitCount"`
 	// number of times a breakpoint has been reached
 	TotalHitCount uint64 `json:"totalHitCount"`
+	// Panic, if not nil, makes this a breakpoint on runtime.gopanic that is
+	// only triggered by the panics matching the filter. Location fields are
+	// ignored when creating a breakpoint with a panic filter.
+	Panic *PanicFilter `json:"panic,omitempty"`
+	// GoroutineFilter, if not nil, restricts the breakpoint to the goroutines
+	// matching the filter.
+	GoroutineFilter *GoroutineFilter `json:"goroutineFilter,omitempty"`
+	// Pending is true for breakpoints whose location has not been found,
+	// they are set when a shared library or plugin containing the location
+	// is loaded. When creating a breakpoint setting Pending allows File (or
+	// FunctionName) to be a location that doesn't exist yet, File can be a
+	// partial path.
+	Pending bool `json:"pending,omitempty"`
+	// OnLoad, if not empty, is a regular expression, the target stops when
+	// it loads a shared library or plugin whose path matches it. Location
+	// fields are ignored when creating a breakpoint with OnLoad. Unloading
+	// a library does not trigger on-load breakpoints.
+	OnLoad string `json:"onLoad,omitempty"`
+}
+
+// GoroutineFilter describes the goroutines that trigger a breakpoint.
+type GoroutineFilter struct {
+	// ID, if not zero, is the ID of the goroutine. Goroutine IDs are not
+	// preserved when the target is restarted, breakpoints filtered by ID are
+	// discarded by Restart.
+	ID int `json:"id,omitempty"`
+	// Labels, if not empty, are pprof labels that the goroutine must have.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// PanicFilter describes the panics that trigger a panic breakpoint.
+type PanicFilter struct {
+	// Types is a list of type names (for example "*main.MyErr" or
+	// "runtime.Error"), if it is not empty the panic value must have one of
+	// these types or implement one of these interfaces.
+	Types []string `json:"types,omitempty"`
+	// Message is a regular expression, if it is not empty it must match the
+	// panic value (if it is a string) or one of the string fields it contains.
+	Message string `json:"message,omitempty"`
 }
 
 // ValidBreakpointName returns an error if
@@ -156,6 +200,15 @@ type Stackframe struct {
 
 	Bottom bool `json:"Bottom,omitempty"` // Bottom is true if this is the bottom frame of the stack
 
+	// RawArgs is the argument list of the frame, including the parentheses,
+	// as printed by the runtime. Only available when the stacktrace was read
+	// from a goroutine dump.
+	RawArgs string `json:"RawArgs,omitempty"`
+
+	// Unwinder is the method used to find the frame, one of "registers",
+	// "dwarf" or "frame pointer".
+	Unwinder string `json:"Unwinder,omitempty"`
+
 	Err string
 }
 
@@ -267,6 +320,10 @@ type Variable struct {
 	// This field's length is capped at proc.maxArrayValues for slices and arrays and 2*proc.maxArrayValues for maps, in the circumstances where the cap takes effect len(Children) != Len
 	// The other length cap applied to this field is related to maximum recursion depth, when the maximum recursion depth is reached this field is left empty, contrary to the previous one this cap also applies to structs (otherwise structs will always have all their member fields returned)
 	Children []Variable `json:"children"`
+	// Offset is the index of the first element of Children (of the first
+	// key/value pair for maps, of the first byte of Value for strings) when
+	// only a range of them was requested
+	Offset int64 `json:"offset"`
 
 	// Base address of arrays, Base address of the backing array for slices (0 for nil slices)
 	// Base address of the backing byte array for strings
@@ -281,6 +338,22 @@ type Variable struct {
 	LocationExpr string
 	// DeclLine is the line number of this variable's declaration
 	DeclLine int64
+
+	// Ref identifies this variable in the target's memory, it can be passed
+	// to ListVariableChildren to load its children. It is nil for variables
+	// that aren't stored in memory.
+	Ref *VariableRef `json:"ref,omitempty"`
+}
+
+// VariableRef identifies a variable by its address and the DWARF entry
+// of its type.
+type VariableRef struct {
+	// Addr is the address of the variable.
+	Addr uintptr `json:"addr"`
+	// Image is the index of the image containing the type of the variable.
+	Image int `json:"image"`
+	// TypeOffset is the offset of the DWARF entry of the variable's type.
+	TypeOffset uint64 `json:"typeOffset"`
 }
 
 // LoadConfig describes how to load values from target's memory
@@ -347,6 +420,21 @@ type DebuggerCommand struct {
 	// violate the rules about stack objects you can disable this safety check
 	// by setting UnsafeCall to true.
 	UnsafeCall bool `json:"unsafeCall,omitempty"`
+
+	// CallPC is the address of the call instruction to step into for the
+	// StepIntoCall command, it must be the PC of one of the targets returned
+	// by StepInTargets.
+	CallPC uint64 `json:"callPC,omitempty"`
+}
+
+// StepInTarget is a function call on the current line that can be stepped
+// into with the StepIntoCall command.
+type StepInTarget struct {
+	// PC is the address of the call instruction.
+	PC uint64 `json:"pc"`
+	// Function is the called function, nil for indirect calls (for example
+	// calls to closures or interface methods).
+	Function *Function `json:"function,omitempty"`
 }
 
 // BreakpointInfo contains informations about the current breakpoint
@@ -377,6 +465,9 @@ const (
 	Step = "step"
 	// ReverseStep continues backward to the previous line of source code, entering function calls.
 	ReverseStep = "reverseStep"
+	// StepIntoCall continues to the next source line, entering only the
+	// function call at CallPC.
+	StepIntoCall = "stepIntoCall"
 	// StepOut continues to the return address of the current function
 	StepOut = "stepOut"
 	// ReverseStepOut continues backward to the calle rof the current function.
@@ -495,6 +586,25 @@ type Checkpoint struct {
 	Where string
 }
 
+// RecordingPosition is a position in a recording made with rr.
+type RecordingPosition struct {
+	// Event is the number of the current rr event.
+	Event uint64
+	// Ticks is the number of ticks, a count of the branches executed, of
+	// the current thread. It identifies a position between two events.
+	Ticks uint64
+}
+
+// TraceProcess is a process recorded in a rr trace.
+type TraceProcess struct {
+	Pid  int
+	Ppid int // 0 for the first process of the trace
+	// Exit is the exit status of the process, either an exit code, a
+	// negative signal number or "none".
+	Exit string
+	Cmd  string
+}
+
 // Image represents a loaded shared object (go plugin or shared library)
 type Image struct {
 	Path    string
@@ -534,3 +644,94 @@ type PackageBuildInfo struct {
 	DirectoryPath string
 	Files         []string
 }
+
+// CompletionKind selects the syntax used to interpret the text passed to
+// Complete.
+type CompletionKind uint8
+
+const (
+	// CompleteLocation completes a location specification, as accepted by
+	// FindLocation and by the break, trace and list commands.
+	CompleteLocation CompletionKind = iota
+	// CompleteExpression completes the last operand of an expression.
+	CompleteExpression
+)
+
+// Completion describes a single completion candidate.
+type Completion struct {
+	// Text is the replacement for the completed portion of the input.
+	Text string
+	// Kind describes what Text refers to: "function", "file", "variable",
+	// "field", "package" or "key".
+	Kind string
+}
+
+// EventKind describes what happened in an Event.
+type EventKind string
+
+const (
+	// EventRunning is sent when the target is resumed by a command.
+	EventRunning EventKind = "running"
+	// EventStopped is sent when the command that resumed the target
+	// completes, State is the state of the debugger after the command.
+	EventStopped EventKind = "stopped"
+	// EventBreakpointHit is sent, before EventStopped, for every thread
+	// stopped at a breakpoint.
+	EventBreakpointHit EventKind = "breakpointHit"
+	// EventExited is sent when the target process exits.
+	EventExited EventKind = "exited"
+	// EventBreakpointCreated is sent when a breakpoint is created.
+	EventBreakpointCreated EventKind = "breakpointCreated"
+	// EventBreakpointChanged is sent when a breakpoint is amended.
+	EventBreakpointChanged EventKind = "breakpointChanged"
+	// EventBreakpointCleared is sent when a breakpoint is removed.
+	EventBreakpointCleared EventKind = "breakpointCleared"
+	// EventRestarted is sent when the target is restarted.
+	EventRestarted EventKind = "restarted"
+	// EventDetached is sent when the debugger detaches from the target,
+	// no other event will be sent after it.
+	EventDetached EventKind = "detached"
+	// EventOutput is sent when the target writes to its standard output or
+	// standard error and the debugger is capturing the output of the target.
+	EventOutput EventKind = "output"
+	// EventLibraryLoaded is sent when the target loads a shared library or
+	// plugin.
+	EventLibraryLoaded EventKind = "libraryLoaded"
+)
+
+// Event is a notification of a change in the state of the debugger.
+type Event struct {
+	// Seq is the sequence number of the event, sequence numbers start at 1
+	// and increase by one for every event.
+	Seq  uint64    `json:"seq"`
+	Kind EventKind `json:"kind"`
+	// State is set for EventStopped and EventExited.
+	State *DebuggerState `json:"state,omitempty"`
+	// Err is set for EventStopped if the command that resumed the target
+	// failed.
+	Err string `json:"err,omitempty"`
+	// Breakpoint is set for EventBreakpointHit and for the events describing
+	// changes to the list of breakpoints.
+	Breakpoint *Breakpoint `json:"breakpoint,omitempty"`
+	// Thread is the thread stopped at Breakpoint for EventBreakpointHit.
+	Thread *Thread `json:"thread,omitempty"`
+	// Output is set for EventOutput.
+	Output *TargetOutput `json:"output,omitempty"`
+	// Image is set for EventLibraryLoaded.
+	Image *Image `json:"image,omitempty"`
+	// Sources are the source files of Image, set for EventLibraryLoaded.
+	Sources []string `json:"sources,omitempty"`
+}
+
+// TargetOutput is a chunk of output written by the target process.
+type TargetOutput struct {
+	// Stream is either "stdout" or "stderr".
+	Stream string `json:"stream"`
+	// Offset is the position of the first byte of Data in the output
+	// captured so far, counting both streams.
+	Offset uint64 `json:"offset"`
+	// Data is the output exactly as written by the target, it is not
+	// necessarily valid UTF-8 and a multibyte character can be split
+	// between two chunks.
+	Data []byte `json:"data"`
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// authTokenPrefix starts the line that clients of a server started with an
+// authentication token must send, before anything else, on every
+// connection. The rest of the line is the token.
+const authTokenPrefix = "Delve-Auth-Token: "
+
+// authTimeout is the maximum time a client has to authenticate after
+// connecting.
+const authTimeout = 10 * time.Second
+
+// maxAuthLineLen is the maximum length of the authentication line.
+const maxAuthLineLen = 4096
+
+// ErrAuthFailed is returned by CheckAuthToken when the client does not send
+// the expected token.
+var ErrAuthFailed = errors.New("authentication failed")
+
+// CheckAuthToken reads the authentication line sent by the client
+// connected to conn and checks that it contains token.
+// Nothing past the end of the line is read, so conn can then be used to
+// serve the client's requests.
+func CheckAuthToken(conn net.Conn, token string) error {
+	if h, ok := conn.(interface{ Handshake() error }); ok {
+		// Complete the TLS or WebSocket handshake first, it has its own
+		// timeout.
+		if err := h.Handshake(); err != nil {
+			return err
+		}
+	}
+	conn.SetReadDeadline(time.Now().Add(authTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			return fmt.Errorf("could not read authentication token: %v", err)
+		}
+		if buf[0] == '\n' {
+			break
+		}
+		if len(line) >= maxAuthLineLen {
+			return ErrAuthFailed
+		}
+		line = append(line, buf[0])
+	}
+	s := strings.TrimSuffix(string(line), "\r")
+	if !strings.HasPrefix(s, authTokenPrefix) {
+		return ErrAuthFailed
+	}
+	if subtle.ConstantTimeCompare([]byte(s[len(authTokenPrefix):]), []byte(token)) != 1 {
+		return ErrAuthFailed
+	}
+	return nil
+}
+
+// SendAuthToken sends the authentication line containing token on conn.
+func SendAuthToken(conn net.Conn, token string) error {
+	_, err := conn.Write([]byte(authTokenPrefix + token + "\r\n"))
+	return err
+}
+
+// DialConfig describes how a client connects to a debugging server.
+type DialConfig struct {
+	// AuthToken is sent to the server, before anything else, if it isn't
+	// empty.
+	AuthToken string
+	// TLSConfig, if not nil, is used to establish a TLS connection to the
+	// server.
+	TLSConfig *tls.Config
+}
+
+// DialWithConfig connects to a debugging server listening on addr, see
+// Listen for the format of addr, and authenticates as specified by cfg.
+func DialWithConfig(addr string, cfg DialConfig) (net.Conn, error) {
+	tlscfg := cfg.TLSConfig
+	if tlscfg != nil && tlscfg.ServerName == "" && !tlscfg.InsecureSkipVerify {
+		tlscfg = tlscfg.Clone()
+		if host, _, err := net.SplitHostPort(strings.TrimPrefix(addr, wsAddrPrefix)); err == nil {
+			tlscfg.ServerName = host
+		}
+	}
+	var conn net.Conn
+	var err error
+	if strings.HasPrefix(addr, wsAddrPrefix) {
+		conn, err = dialWebSocket(addr[len(wsAddrPrefix):], tlscfg)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		conn, err = Dial(addr)
+		if err != nil {
+			return nil, err
+		}
+		if tlscfg != nil {
+			tlsconn := tls.Client(conn, tlscfg)
+			if err := tlsconn.Handshake(); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			conn = tlsconn
+		}
+	}
+	if cfg.AuthToken != "" {
+		if err := SendAuthToken(conn, cfg.AuthToken); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// NewTLSListener returns a listener that only accepts TLS connections,
+// with the configuration cfg, on listener. For WebSocket listeners TLS is
+// used below the WebSocket handshake, as browsers expect.
+func NewTLSListener(listener net.Listener, cfg *tls.Config) net.Listener {
+	if wsl, ok := listener.(*wsListener); ok {
+		return &wsListener{Listener: tls.NewListener(wsl.Listener, cfg), allowedOrigins: wsl.allowedOrigins}
+	}
+	return tls.NewListener(listener, cfg)
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+func TestCheckAuthToken(t *testing.T) {
+	for _, tc := range []struct {
+		sent string
+		ok   bool
+	}{
+		{authTokenPrefix + "secret\r\n", true},
+		{authTokenPrefix + "secret\n", true},
+		{authTokenPrefix + "wrong\r\n", false},
+		{authTokenPrefix + "secret2\r\n", false},
+		{"secret\r\n", false},
+		{"", false},
+	} {
+		server, client := net.Pipe()
+		go func() {
+			client.Write([]byte(tc.sent + "rest"))
+			client.Close()
+		}()
+		err := CheckAuthToken(server, "secret")
+		if (err == nil) != tc.ok {
+			t.Errorf("%q: unexpected result %v", tc.sent, err)
+		}
+		if err == nil {
+			// the data following the token must not be consumed
+			rest, _ := ioutil.ReadAll(server)
+			if string(rest) != "rest" {
+				t.Errorf("%q: data after the token was consumed, got %q", tc.sent, rest)
+			}
+		}
+		server.Close()
+	}
+}
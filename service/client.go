@@ -42,6 +42,10 @@ type Client interface {
 	Step() (*api.DebuggerState, error)
 	// ReverseStep continues backward to the previous line of source code, entering function calls.
 	ReverseStep() (*api.DebuggerState, error)
+	// StepIntoCall continues to the next source line, entering only the function called by the call instruction at callPC.
+	StepIntoCall(callPC uint64) (*api.DebuggerState, error)
+	// StepInTargets returns the function calls on the current line that can be stepped into with StepIntoCall.
+	StepInTargets() ([]api.StepInTarget, error)
 	// StepOut continues to the return address of the current function.
 	StepOut() (*api.DebuggerState, error)
 	// ReverseStepOut continues backward to the calle rof the current function.
@@ -87,6 +91,10 @@ type Client interface {
 	ListPackageVariables(filter string, cfg api.LoadConfig) ([]api.Variable, error)
 	// EvalVariable returns a variable in the context of the current thread.
 	EvalVariable(scope api.EvalScope, symbol string, cfg api.LoadConfig) (*api.Variable, error)
+	// EvalVariableRange returns a variable in the context of the current thread, loading at most count of its children starting at index start.
+	EvalVariableRange(scope api.EvalScope, symbol string, start, count int, cfg api.LoadConfig) (*api.Variable, error)
+	// ListVariableChildren returns the variable identified by ref with at most count of its children, starting at index start, loaded.
+	ListVariableChildren(scope api.EvalScope, ref api.VariableRef, start, count int, cfg api.LoadConfig) (*api.Variable, error)
 
 	// SetVariable sets the value of a variable
 	SetVariable(scope api.EvalScope, symbol, value string) error
@@ -132,6 +140,10 @@ type Client interface {
 	// If findInstruction is true FindLocation will only return locations that correspond to instructions.
 	FindLocation(scope api.EvalScope, loc string, findInstruction bool) ([]api.Location, error)
 
+	// Complete returns completion candidates for a partially typed location
+	// specification or expression. Each candidate replaces text[start:].
+	Complete(scope api.EvalScope, kind api.CompletionKind, text string) (start int, completions []api.Completion, err error)
+
 	// Disassemble code between startPC and endPC
 	DisassembleRange(scope api.EvalScope, startPC, endPC uint64, flavour api.AssemblyFlavour) (api.AsmInstructions, error)
 	// Disassemble code of the function containing PC
@@ -141,12 +153,25 @@ type Client interface {
 	Recorded() bool
 	// TraceDirectory returns the path to the trace directory for a recording.
 	TraceDirectory() (string, error)
+	// Warnings returns the problems found while loading the target.
+	Warnings() ([]string, error)
 	// Checkpoint sets a checkpoint at the current position.
 	Checkpoint(where string) (checkpointID int, err error)
 	// ListCheckpoints gets all checkpoints.
 	ListCheckpoints() ([]api.Checkpoint, error)
 	// ClearCheckpoint removes a checkpoint
 	ClearCheckpoint(id int) error
+	// When returns the current position in the recording.
+	When() (api.RecordingPosition, error)
+	// SeekEvent moves to the start of an rr event.
+	SeekEvent(event uint64) (*api.DebuggerState, error)
+	// SeekTicks moves to a number of ticks of the current thread.
+	SeekTicks(ticks uint64) (*api.DebuggerState, error)
+	// ListTraceProcesses lists the processes recorded in the trace.
+	ListTraceProcesses() ([]api.TraceProcess, error)
+	// ReplayProcess starts a new replay of the trace that debugs the process
+	// with the given pid.
+	ReplayProcess(pid int) ([]api.DiscardedBreakpoint, error)
 
 	// SetReturnValuesLoadConfig sets the load configuration for return values.
 	SetReturnValuesLoadConfig(*api.LoadConfig)
@@ -169,6 +194,17 @@ type Client interface {
 	// If cont is true a continue command will be sent instead.
 	Disconnect(cont bool) error
 
+	// Events returns a channel on which the events published by the debugger after this call are delivered.
+	// The channel is closed when the connection to the server is closed.
+	Events() <-chan api.Event
+
+	// Cancel interrupts the Command, Eval, Stacktrace and ListGoroutines requests in progress made by this client.
+	// It returns the number of requests that were cancelled.
+	Cancel() (int, error)
+
+	// GetOutput returns the captured output of the target starting at offset and the offset of the next call.
+	GetOutput(offset uint64) ([]api.TargetOutput, uint64, error)
+
 	// CallAPI allows calling an arbitrary rpc method (used by starlark bindings)
 	CallAPI(method string, args, reply interface{}) error
 }
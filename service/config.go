@@ -1,6 +1,7 @@
 package service
 
 import (
+	"crypto/tls"
 	"net"
 
 	"github.com/go-delve/delve/service/debugger"
@@ -36,4 +37,11 @@ type Config struct {
 
 	// DisconnectChan will be closed by the server when the client disconnects
 	DisconnectChan chan<- struct{}
+
+	// AuthToken, if not empty, must be sent by clients as the first line of
+	// every connection, see CheckAuthToken.
+	AuthToken string
+
+	// TLSConfig, if not nil, makes the server only accept TLS connections.
+	TLSConfig *tls.Config
 }
@@ -112,6 +112,11 @@ func (c *Client) ExpectInitializedEvent(t *testing.T) *dap.InitializedEvent {
 	return c.expectReadProtocolMessage(t).(*dap.InitializedEvent)
 }
 
+func (c *Client) ExpectCapabilitiesEvent(t *testing.T) *dap.CapabilitiesEvent {
+	t.Helper()
+	return c.expectReadProtocolMessage(t).(*dap.CapabilitiesEvent)
+}
+
 func (c *Client) ExpectLaunchResponse(t *testing.T) *dap.LaunchResponse {
 	t.Helper()
 	return c.expectReadProtocolMessage(t).(*dap.LaunchResponse)
@@ -147,6 +152,16 @@ func (c *Client) ExpectStackTraceResponse(t *testing.T) *dap.StackTraceResponse
 	return c.expectReadProtocolMessage(t).(*dap.StackTraceResponse)
 }
 
+func (c *Client) ExpectScopesResponse(t *testing.T) *dap.ScopesResponse {
+	t.Helper()
+	return c.expectReadProtocolMessage(t).(*dap.ScopesResponse)
+}
+
+func (c *Client) ExpectVariablesResponse(t *testing.T) *dap.VariablesResponse {
+	t.Helper()
+	return c.expectReadProtocolMessage(t).(*dap.VariablesResponse)
+}
+
 func (c *Client) ExpectTerminateResponse(t *testing.T) *dap.TerminateResponse {
 	t.Helper()
 	return c.expectReadProtocolMessage(t).(*dap.TerminateResponse)
@@ -368,20 +383,27 @@ func (c *Client) ThreadsRequest() {
 }
 
 // StackTraceRequest sends a 'stackTrace' request.
-func (c *Client) StackTraceRequest() {
+func (c *Client) StackTraceRequest(threadID, startFrame, levels int) {
 	request := &dap.StackTraceRequest{Request: *c.newRequest("stackTrace")}
+	request.Arguments.ThreadId = threadID
+	request.Arguments.StartFrame = startFrame
+	request.Arguments.Levels = levels
 	c.send(request)
 }
 
 // ScopesRequest sends a 'scopes' request.
-func (c *Client) ScopesRequest() {
+func (c *Client) ScopesRequest(frameID int) {
 	request := &dap.ScopesRequest{Request: *c.newRequest("scopes")}
+	request.Arguments.FrameId = frameID
 	c.send(request)
 }
 
-// VariablesRequest sends a 'scopes' request.
-func (c *Client) VariablesRequest() {
-	request := &dap.ScopesRequest{Request: *c.newRequest("variables")}
+// VariablesRequest sends a 'variables' request.
+func (c *Client) VariablesRequest(variablesReference, start, count int) {
+	request := &dap.VariablesRequest{Request: *c.newRequest("variables")}
+	request.Arguments.VariablesReference = variablesReference
+	request.Arguments.Start = start
+	request.Arguments.Count = count
 	c.send(request)
 }
 
@@ -401,13 +423,17 @@ func (c *Client) SetFunctionBreakpointsRequest() {
 }
 
 // StepBackRequest sends a 'stepBack' request.
-func (c *Client) StepBackRequest() {
-	c.send(&dap.StepBackRequest{Request: *c.newRequest("stepBack")})
+func (c *Client) StepBackRequest(thread int) {
+	request := &dap.StepBackRequest{Request: *c.newRequest("stepBack")}
+	request.Arguments.ThreadId = thread
+	c.send(request)
 }
 
 // ReverseContinueRequest sends a 'reverseContinue' request.
-func (c *Client) ReverseContinueRequest() {
-	c.send(&dap.ReverseContinueRequest{Request: *c.newRequest("reverseContinue")})
+func (c *Client) ReverseContinueRequest(thread int) {
+	request := &dap.ReverseContinueRequest{Request: *c.newRequest("reverseContinue")}
+	request.Arguments.ThreadId = thread
+	c.send(request)
 }
 
 // SetVariableRequest sends a 'setVariable' request.
@@ -416,8 +442,10 @@ func (c *Client) SetVariableRequest() {
 }
 
 // RestartFrameRequest sends a 'restartFrame' request.
-func (c *Client) RestartFrameRequest() {
-	c.send(&dap.RestartFrameRequest{Request: *c.newRequest("restartFrame")})
+func (c *Client) RestartFrameRequest(frameID int) {
+	request := &dap.RestartFrameRequest{Request: *c.newRequest("restartFrame")}
+	request.Arguments.FrameId = frameID
+	c.send(request)
 }
 
 // GotoRequest sends a 'goto' request.
@@ -446,8 +474,10 @@ func (c *Client) EvaluateRequest() {
 }
 
 // StepInTargetsRequest sends a 'stepInTargets' request.
-func (c *Client) StepInTargetsRequest() {
-	c.send(&dap.StepInTargetsRequest{Request: *c.newRequest("stepInTargets")})
+func (c *Client) StepInTargetsRequest(frameID int) {
+	request := &dap.StepInTargetsRequest{Request: *c.newRequest("stepInTargets")}
+	request.Arguments.FrameId = frameID
+	c.send(request)
 }
 
 // GotoTargetsRequest sends a 'gotoTargets' request.
@@ -456,8 +486,12 @@ func (c *Client) GotoTargetsRequest() {
 }
 
 // CompletionsRequest sends a 'completions' request.
-func (c *Client) CompletionsRequest() {
-	c.send(&dap.CompletionsRequest{Request: *c.newRequest("completions")})
+func (c *Client) CompletionsRequest(frameID int, text string, column int) {
+	request := &dap.CompletionsRequest{Request: *c.newRequest("completions")}
+	request.Arguments.FrameId = frameID
+	request.Arguments.Text = text
+	request.Arguments.Column = column
+	c.send(request)
 }
 
 // ExceptionInfoRequest sends a 'exceptionInfo' request.
@@ -490,9 +524,12 @@ func (c *Client) DisassembleRequest() {
 	c.send(&dap.DisassembleRequest{Request: *c.newRequest("disassemble")})
 }
 
-// CancelRequest sends a 'cancel' request.
-func (c *Client) CancelRequest() {
-	c.send(&dap.CancelRequest{Request: *c.newRequest("cancel")})
+// CancelRequest sends a 'cancel' request for the request with sequence
+// number requestID, or for the request being handled if it is 0.
+func (c *Client) CancelRequest(requestID int) {
+	request := &dap.CancelRequest{Request: *c.newRequest("cancel")}
+	request.Arguments.RequestId = requestID
+	c.send(request)
 }
 
 // BreakpointLocationsRequest sends a 'breakpointLocations' request.
@@ -12,7 +12,19 @@ const (
 	// TODO(polina): confirm if the extension expects specific ids
 	// for specific cases, and we must match the existing adaptor
 	// or if these codes can evolve.
-	FailedToContinue       = 3000
-	UnableToDisplayThreads = 2003
+	FailedToContinue                = 3000
+	UnableToDisplayThreads          = 2003
+	UnableToProduceStackTrace       = 2004
+	UnableToListLocals              = 2005
+	UnableToListArgs                = 2006
+	UnableToLookupVariable          = 2008
+	UnableToComplete                = 2010
+	UnableToSetExceptionBreakpoints = 2011
+	UnableToGetExceptionInfo        = 2012
+	UnableToStepIn                  = 2013
+	UnableToListStepInTargets       = 2014
+	RequestCancelled                = 2015
+	UnableToStepBack                = 2016
+	UnableToRestartFrame            = 2017
 	// Add more codes as we support more requests
 )
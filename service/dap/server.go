@@ -10,12 +10,19 @@ package dap
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/go-delve/delve/pkg/gobuild"
 	"github.com/go-delve/delve/pkg/logflags"
@@ -56,21 +63,119 @@ type Server struct {
 	stopOnEntry bool
 	// binaryToRemove is the compiled binary to be removed on disconnect.
 	binaryToRemove string
+	// panicBreakpoint is the ID of the breakpoint created for the 'panic'
+	// exception filter, or 0 if the filter is not enabled.
+	panicBreakpoint int
+	// sendMutex serializes the messages sent to the client, which are also
+	// sent by the goroutine forwarding the output of the target.
+	sendMutex sync.Mutex
+	// outputMutex protects outputOffset.
+	outputMutex sync.Mutex
+	// outputOffset is the offset of the first byte of target output not
+	// yet sent to the client.
+	outputOffset uint64
+	// requestCtx is the context of the request being handled, it is done
+	// when the client cancels the request.
+	requestCtx context.Context
+	// requestMutex protects the fields below, which are also accessed by
+	// the goroutine reading requests while a request is being handled.
+	requestMutex sync.Mutex
+	// currentSeq and currentCancel identify and cancel the request being
+	// handled.
+	currentSeq    int
+	currentCancel context.CancelFunc
+	// queued contains the sequence numbers of the requests read from the
+	// client and not yet handled, mapped to true if they were cancelled.
+	// Ids of requests that aren't queued are ignored by cancel requests, so
+	// that they can't accumulate here.
+	queued map[int]bool
+	// stackFrameHandles maps the ids of the frames sent to the client to
+	// stackFrame values.
+	stackFrameHandles *handlesMap
+	// variableHandles maps the variable references sent to the client to
+	// *variable values.
+	variableHandles *handlesMap
+	// recorded is true if the target is a rr recording, in 'replay' and
+	// 'record' modes, and can be executed backwards.
+	recorded bool
+	// frameCheckpoints maps the frames restarted by restartFrame to the rr
+	// checkpoint taken at the entry of their function, restarting one of
+	// them again goes straight back to its checkpoint. A frame is forgotten,
+	// and its checkpoint cleared, once it isn't known to be the same
+	// invocation of the function anymore.
+	frameCheckpoints map[frameInstance]int
+	// loadedSources contains the source files of the target known to the
+	// client, only accessed by the goroutine forwarding events once the
+	// debugger is started.
+	loadedSources map[string]bool
 }
 
+// stackFrame identifies a frame in the stack of a goroutine.
+type stackFrame struct {
+	goroutineID int
+	frameIndex  int
+}
+
+// frameInstance identifies an invocation of a function, across stops,
+// by its goroutine and the offset of its frame.
+type frameInstance struct {
+	goroutineID int
+	frameOffset int64
+	function    string
+}
+
+func newFrameInstance(goroutineID int, frame *api.Stackframe) frameInstance {
+	fi := frameInstance{goroutineID: goroutineID, frameOffset: frame.FrameOffset}
+	if frame.Function != nil {
+		fi.function = frame.Function.Name()
+	}
+	return fi
+}
+
+// variable is a variable shown to the client, along with the scope used
+// to load its children.
+type variable struct {
+	v     *api.Variable
+	scope api.EvalScope
+}
+
+// loadConfig is used to load the variables shown to the client, children
+// of big values are loaded lazily by variables requests.
+var loadConfig = proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 512, MaxArrayValues: 64, MaxStructFields: -1}
+
+// defaultStackDepth is the number of frames returned by a stackTrace
+// request that doesn't specify levels.
+const defaultStackDepth = 50
+
+// maxVariablesPage is the maximum number of children returned by a
+// variables request, clients page through bigger values using the
+// indexedVariables and namedVariables fields of each variable.
+const maxVariablesPage = 1000
+
+// maxQueuedRequests is the number of requests read from the client while
+// another request is being handled before the server stops reading.
+const maxQueuedRequests = 100
+
 // NewServer creates a new DAP Server. It takes an opened Listener
 // via config and assumes its ownership. config.disconnectChan has to be set;
 // it will be closed by the server when the client disconnects or requests
 // shutdown. Once disconnectChan is closed, Server.Stop() must be called.
 func NewServer(config *service.Config) *Server {
 	logger := logflags.DAPLogger()
-	logflags.WriteDAPListeningMessage(config.Listener.Addr().String())
+	logflags.WriteDAPListeningMessage(service.ListenerAddr(config.Listener))
 	logger.Debug("DAP server pid = ", os.Getpid())
+	listener := config.Listener
+	if config.TLSConfig != nil {
+		listener = service.NewTLSListener(listener, config.TLSConfig)
+	}
 	return &Server{
-		config:   config,
-		listener: config.Listener,
-		stopChan: make(chan struct{}),
-		log:      logger,
+		config:            config,
+		listener:          listener,
+		stopChan:          make(chan struct{}),
+		log:               logger,
+		stackFrameHandles: newHandlesMap(),
+		variableHandles:   newHandlesMap(),
+		frameCheckpoints:  make(map[frameInstance]int),
 	}
 }
 
@@ -89,7 +194,7 @@ func (s *Server) Stop() {
 		s.conn.Close()
 	}
 	if s.debugger != nil {
-		kill := s.config.Debugger.AttachPid == 0
+		kill := !s.config.Debugger.AttachedToExistingProcess()
 		if err := s.debugger.Detach(kill); err != nil {
 			s.log.Error(err)
 		}
@@ -134,29 +239,52 @@ func (s *Server) signalDisconnect() {
 // so the editor needs to launch delve only once?
 func (s *Server) Run() {
 	go func() {
-		conn, err := s.listener.Accept()
-		if err != nil {
-			select {
-			case <-s.stopChan:
-			default:
-				s.log.Errorf("Error accepting client connection: %s\n", err)
+		for {
+			conn, err := s.listener.Accept()
+			if err != nil {
+				select {
+				case <-s.stopChan:
+				default:
+					s.log.Errorf("Error accepting client connection: %s\n", err)
+				}
+				s.signalDisconnect()
+				return
 			}
-			s.signalDisconnect()
-			return
+			if s.config.AuthToken != "" {
+				if err := service.CheckAuthToken(conn, s.config.AuthToken); err != nil {
+					s.log.Errorf("closing connection from %v: %v", conn.RemoteAddr(), err)
+					conn.Close()
+					continue
+				}
+			}
+			s.conn = conn
+			break
 		}
-		s.conn = conn
 		s.serveDAPCodec()
 	}()
 }
 
-// serveDAPCodec reads and decodes requests from the client
-// until it encounters an error or EOF, when it sends
-// the disconnect signal and returns.
+// serveDAPCodec handles the requests read from the client, one at a
+// time, until it encounters an error or EOF, when it sends the disconnect
+// signal and returns.
 func (s *Server) serveDAPCodec() {
 	defer s.signalDisconnect()
 	s.reader = bufio.NewReader(s.conn)
+	requests := make(chan dap.Message, maxQueuedRequests)
+	go s.readRequests(requests)
+	for request := range requests {
+		s.handleRequest(request)
+	}
+}
+
+// readRequests reads and decodes requests from the client and sends them
+// to the requests channel, which is closed when the connection is closed.
+// Cancel requests are handled immediately so that they can interrupt the
+// request being handled.
+func (s *Server) readRequests(requests chan<- dap.Message) {
+	defer close(requests)
 	for {
-		request, err := dap.ReadProtocolMessage(s.reader)
+		request, err := readProtocolMessage(s.reader)
 		// TODO(polina): Differentiate between errors and handle them
 		// gracefully. For example,
 		// -- "Request command 'foo' is not supported" means we
@@ -176,10 +304,43 @@ func (s *Server) serveDAPCodec() {
 			}
 			return
 		}
-		s.handleRequest(request)
+		if request, ok := request.(*dap.CancelRequest); ok {
+			jsonmsg, _ := json.Marshal(request)
+			s.log.Debug("[<- from client]", string(jsonmsg))
+			s.onCancelRequest(request)
+			continue
+		}
+		s.requestMutex.Lock()
+		if s.queued == nil {
+			s.queued = make(map[int]bool)
+		}
+		s.queued[request.GetSeq()] = false
+		s.requestMutex.Unlock()
+		requests <- request
 	}
 }
 
+// readProtocolMessage reads a message from r, like dap.ReadProtocolMessage,
+// but also decodes the arguments that go-dap doesn't know about.
+func readProtocolMessage(r *bufio.Reader) (dap.Message, error) {
+	content, err := dap.ReadBaseMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	request, err := dap.DecodeProtocolMessage(content)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := request.(*dap.SetExceptionBreakpointsRequest); ok {
+		r := &setExceptionBreakpointsRequest{}
+		if err := json.Unmarshal(content, r); err != nil {
+			return nil, err
+		}
+		request = r
+	}
+	return request, nil
+}
+
 func (s *Server) handleRequest(request dap.Message) {
 	defer func() {
 		// In case a handler panics, we catch the panic and send an error response
@@ -192,6 +353,27 @@ func (s *Server) handleRequest(request dap.Message) {
 	jsonmsg, _ := json.Marshal(request)
 	s.log.Debug("[<- from client]", string(jsonmsg))
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.requestMutex.Lock()
+	cancelled := s.queued[request.GetSeq()]
+	delete(s.queued, request.GetSeq())
+	if cancelled {
+		s.requestMutex.Unlock()
+		var req dap.Request
+		json.Unmarshal(jsonmsg, &req)
+		s.sendErrorResponse(req, RequestCancelled, "cancelled", "the request was cancelled by the client")
+		return
+	}
+	s.currentSeq, s.currentCancel = request.GetSeq(), cancel
+	s.requestMutex.Unlock()
+	defer func() {
+		s.requestMutex.Lock()
+		s.currentSeq, s.currentCancel = 0, nil
+		s.requestMutex.Unlock()
+	}()
+	s.requestCtx = ctx
+
 	switch request := request.(type) {
 	case *dap.InitializeRequest:
 		// Required
@@ -221,7 +403,7 @@ func (s *Server) handleRequest(request dap.Message) {
 		// Optional (capability ‘supportsFunctionBreakpoints’)
 		// TODO: implement this request in V1
 		s.onSetFunctionBreakpointsRequest(request)
-	case *dap.SetExceptionBreakpointsRequest:
+	case *setExceptionBreakpointsRequest:
 		// Optional (capability ‘exceptionBreakpointFilters’)
 		s.onSetExceptionBreakpointsRequest(request)
 	case *dap.ConfigurationDoneRequest:
@@ -237,7 +419,6 @@ func (s *Server) handleRequest(request dap.Message) {
 		s.onNextRequest(request)
 	case *dap.StepInRequest:
 		// Required
-		// TODO: implement this request in V0
 		s.onStepInRequest(request)
 	case *dap.StepOutRequest:
 		// Required
@@ -245,15 +426,13 @@ func (s *Server) handleRequest(request dap.Message) {
 		s.onStepOutRequest(request)
 	case *dap.StepBackRequest:
 		// Optional (capability ‘supportsStepBack’)
-		// TODO: implement this request in V1
 		s.onStepBackRequest(request)
 	case *dap.ReverseContinueRequest:
 		// Optional (capability ‘supportsStepBack’)
-		// TODO: implement this request in V1
 		s.onReverseContinueRequest(request)
 	case *dap.RestartFrameRequest:
 		// Optional (capability ’supportsRestartFrame’)
-		s.sendUnsupportedErrorResponse(request.Request)
+		s.onRestartFrameRequest(request)
 	case *dap.GotoRequest:
 		// Optional (capability ‘supportsGotoTargetsRequest’)
 		s.sendUnsupportedErrorResponse(request.Request)
@@ -263,15 +442,12 @@ func (s *Server) handleRequest(request dap.Message) {
 		s.onPauseRequest(request)
 	case *dap.StackTraceRequest:
 		// Required
-		// TODO: implement this request in V0
 		s.onStackTraceRequest(request)
 	case *dap.ScopesRequest:
 		// Required
-		// TODO: implement this request in V0
 		s.onScopesRequest(request)
 	case *dap.VariablesRequest:
 		// Required
-		// TODO: implement this request in V0
 		s.onVariablesRequest(request)
 	case *dap.SetVariableRequest:
 		// Optional (capability ‘supportsSetVariable’)
@@ -299,17 +475,16 @@ func (s *Server) handleRequest(request dap.Message) {
 		s.onEvaluateRequest(request)
 	case *dap.StepInTargetsRequest:
 		// Optional (capability ‘supportsStepInTargetsRequest’)
-		s.sendUnsupportedErrorResponse(request.Request)
+		s.onStepInTargetsRequest(request)
 	case *dap.GotoTargetsRequest:
 		// Optional (capability ‘supportsGotoTargetsRequest’)
 		s.sendUnsupportedErrorResponse(request.Request)
 	case *dap.CompletionsRequest:
 		// Optional (capability ‘supportsCompletionsRequest’)
-		s.sendUnsupportedErrorResponse(request.Request)
+		s.onCompletionsRequest(request)
 	case *dap.ExceptionInfoRequest:
 		// Optional (capability ‘supportsExceptionInfoRequest’)
-		// TODO: does this request make sense for delve?
-		s.sendUnsupportedErrorResponse(request.Request)
+		s.onExceptionInfoRequest(request)
 	case *dap.LoadedSourcesRequest:
 		// Optional (capability ‘supportsLoadedSourcesRequest’)
 		// TODO: implement this request in V1
@@ -330,7 +505,7 @@ func (s *Server) handleRequest(request dap.Message) {
 		s.onDisassembleRequest(request)
 	case *dap.CancelRequest:
 		// Optional (capability ‘supportsCancelRequest’)
-		// TODO: does this request make sense for delve?
+		// Handled by readRequests.
 		s.onCancelRequest(request)
 	case *dap.BreakpointLocationsRequest:
 		// Optional (capability ‘supportsBreakpointLocationsRequest’)
@@ -348,14 +523,127 @@ func (s *Server) handleRequest(request dap.Message) {
 }
 
 func (s *Server) send(message dap.Message) {
+	s.sendMutex.Lock()
+	defer s.sendMutex.Unlock()
 	jsonmsg, _ := json.Marshal(message)
 	s.log.Debug("[-> to client]", string(jsonmsg))
-	dap.WriteProtocolMessage(s.conn, message)
+	// Write each message with a single call so that, on WebSocket
+	// connections, it is sent as a single WebSocket message.
+	var buf bytes.Buffer
+	dap.WriteProtocolMessage(&buf, message)
+	s.conn.Write(buf.Bytes())
+}
+
+// outputPollInterval is the maximum time forwardOutput waits for new
+// output before checking whether the server was stopped.
+const outputPollInterval = time.Second
+
+// forwardOutput sends the output captured from the target to the client,
+// as output events, until the server is stopped or the debugger detaches.
+func (s *Server) forwardOutput() {
+	var seq uint64
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		default:
+		}
+		events, lastSeq := s.debugger.Events(seq, outputPollInterval)
+		seq = lastSeq
+		s.sendOutput()
+		for _, ev := range events {
+			switch ev.Kind {
+			case api.EventDetached:
+				return
+			case api.EventLibraryLoaded:
+				s.sendLibraryLoaded(ev.Image, ev.Sources)
+			}
+		}
+	}
+}
+
+// sendLibraryLoaded sends a module event for a shared library or plugin
+// loaded by the target, followed by a loadedSource event for each of its
+// source files that the target didn't already contain.
+// There is no 'removed' module event, unloaded libraries are never
+// removed from the target's images.
+func (s *Server) sendLibraryLoaded(image *api.Image, sources []string) {
+	e := &dap.ModuleEvent{Event: *newEvent("module")}
+	e.Body.Reason = "new"
+	e.Body.Module = dap.Module{
+		Id:           image.Path,
+		Name:         filepath.Base(image.Path),
+		Path:         image.Path,
+		AddressRange: fmt.Sprintf("%#x", image.Address),
+	}
+	if len(sources) > 0 {
+		e.Body.Module.SymbolStatus = "Symbols loaded."
+	}
+	s.send(e)
+	for _, path := range sources {
+		if s.loadedSources[path] {
+			continue
+		}
+		s.loadedSources[path] = true
+		e := &dap.LoadedSourceEvent{Event: *newEvent("loadedSource")}
+		e.Body.Reason = "new"
+		e.Body.Source = dap.Source{Name: filepath.Base(path), Path: path}
+		s.send(e)
+	}
+}
+
+// sendOutput sends the output captured from the target since the last
+// call as output events with category stdout or stderr.
+func (s *Server) sendOutput() {
+	s.outputMutex.Lock()
+	defer s.outputMutex.Unlock()
+	output, next := s.debugger.Output(s.outputOffset)
+	// Consecutive chunks written to the same stream are sent as a single
+	// event.
+	var merged []api.TargetOutput
+	for _, chunk := range output {
+		if n := len(merged); n > 0 && merged[n-1].Stream == chunk.Stream {
+			merged[n-1].Data = append(merged[n-1].Data, chunk.Data...)
+			continue
+		}
+		merged = append(merged, chunk)
+	}
+	for i, chunk := range merged {
+		if i == len(merged)-1 {
+			// A multibyte character can be split between writes, keep the
+			// incomplete character for the next call.
+			n := incompleteRuneLen(chunk.Data)
+			chunk.Data = chunk.Data[:len(chunk.Data)-n]
+			next -= uint64(n)
+		}
+		if len(chunk.Data) == 0 {
+			continue
+		}
+		e := &dap.OutputEvent{Event: *newEvent("output")}
+		e.Body.Category = chunk.Stream
+		e.Body.Output = string(chunk.Data)
+		s.send(e)
+	}
+	s.outputOffset = next
+}
+
+// incompleteRuneLen returns the length of the incomplete UTF-8 encoded
+// character at the end of data, if any.
+func incompleteRuneLen(data []byte) int {
+	for n := 1; n < utf8.UTFMax && n <= len(data); n++ {
+		if utf8.RuneStart(data[len(data)-n]) {
+			if utf8.FullRune(data[len(data)-n:]) {
+				return 0
+			}
+			return n
+		}
+	}
+	return 0
 }
 
 func (s *Server) onInitializeRequest(request *dap.InitializeRequest) {
 	// TODO(polina): Respond with an error if debug session is in progress?
-	response := &dap.InitializeResponse{Response: *newResponse(request.Request)}
+	response := &initializeResponse{Response: *newResponse(request.Request)}
 	response.Body.SupportsConfigurationDoneRequest = true
 	// TODO(polina): support this to match vscode-go functionality
 	response.Body.SupportsSetVariable = false
@@ -363,12 +651,26 @@ func (s *Server) onInitializeRequest(request *dap.InitializeRequest) {
 	response.Body.SupportsTerminateRequest = false
 	response.Body.SupportsRestartRequest = false
 	response.Body.SupportsFunctionBreakpoints = false
+	// Reverse execution is only possible with recordings, the capability is
+	// enabled by a capabilities event once the launch mode is known.
 	response.Body.SupportsStepBack = false
 	response.Body.SupportsSetExpression = false
 	response.Body.SupportsLoadedSourcesRequest = false
 	response.Body.SupportsReadMemoryRequest = false
 	response.Body.SupportsDisassembleRequest = false
-	response.Body.SupportsCancelRequest = false
+	response.Body.SupportsCancelRequest = true
+	response.Body.SupportsCompletionsRequest = true
+	response.Body.SupportsStepInTargetsRequest = true
+	response.Body.ExceptionBreakpointFilters = []exceptionBreakpointsFilter{
+		{
+			ExceptionBreakpointsFilter: dap.ExceptionBreakpointsFilter{Filter: panicExceptionFilter, Label: "Panics"},
+			SupportsCondition:          true,
+			ConditionDescription:       "Regular expression matching the panic message",
+		},
+	}
+	response.Body.SupportsExceptionFilterOptions = true
+	response.Body.SupportsExceptionOptions = true
+	response.Body.SupportsExceptionInfoRequest = true
 	s.send(response)
 }
 
@@ -378,6 +680,16 @@ const debugBinary string = "./__debug_bin"
 func (s *Server) onLaunchRequest(request *dap.LaunchRequest) {
 	// TODO(polina): Respond with an error if debug session is in progress?
 
+	mode, ok := request.Arguments["mode"]
+	if !ok || mode == "" {
+		mode = "debug"
+	}
+
+	if mode == "replay" {
+		s.launchReplay(request)
+		return
+	}
+
 	program, ok := request.Arguments["program"].(string)
 	if !ok || program == "" {
 		s.sendErrorResponse(request.Request,
@@ -386,12 +698,7 @@ func (s *Server) onLaunchRequest(request *dap.LaunchRequest) {
 		return
 	}
 
-	mode, ok := request.Arguments["mode"]
-	if !ok || mode == "" {
-		mode = "debug"
-	}
-
-	if mode == "debug" || mode == "test" {
+	if mode == "debug" || mode == "test" || mode == "record" {
 		output, ok := request.Arguments["output"].(string)
 		if !ok || output == "" {
 			output = debugBinary
@@ -408,7 +715,7 @@ func (s *Server) onLaunchRequest(request *dap.LaunchRequest) {
 		}
 
 		switch mode {
-		case "debug":
+		case "debug", "record":
 			err = gobuild.GoBuild(debugname, []string{program}, buildFlags)
 		case "test":
 			err = gobuild.GoTestBuild(debugname, []string{program}, buildFlags)
@@ -424,7 +731,7 @@ func (s *Server) onLaunchRequest(request *dap.LaunchRequest) {
 	}
 
 	// TODO(polina): support "remote" mode
-	if mode != "exec" && mode != "debug" && mode != "test" {
+	if mode != "exec" && mode != "debug" && mode != "test" && mode != "record" {
 		s.sendErrorResponse(request.Request,
 			FailedToContinue, "Failed to launch",
 			fmt.Sprintf("Unsupported 'mode' value %q in debug configuration.", mode))
@@ -458,13 +765,58 @@ func (s *Server) onLaunchRequest(request *dap.LaunchRequest) {
 
 	s.config.ProcessArgs = append([]string{program}, targetArgs...)
 	s.config.Debugger.WorkingDir = filepath.Dir(program)
+	if mode == "record" {
+		s.config.Debugger.Backend = "rr"
+		s.recorded = true
+	}
+
+	s.startDebugger(request)
+}
+
+// launchReplay handles a launch request in 'replay' mode, which opens the
+// rr trace in traceDirPath instead of starting a new process.
+func (s *Server) launchReplay(request *dap.LaunchRequest) {
+	tracedir, ok := request.Arguments["traceDirPath"].(string)
+	if !ok || tracedir == "" {
+		s.sendErrorResponse(request.Request,
+			FailedToContinue, "Failed to launch",
+			"The 'traceDirPath' attribute is missing in debug configuration.")
+		return
+	}
+	stop, ok := request.Arguments["stopOnEntry"]
+	s.stopOnEntry = ok && stop == true
+
+	s.config.ProcessArgs = []string{}
+	s.config.Debugger.Backend = "rr"
+	s.config.Debugger.CoreFile = tracedir
+	s.recorded = true
 
+	s.startDebugger(request)
+}
+
+// startDebugger creates the debugger described by s.config and ends the
+// launch request.
+func (s *Server) startDebugger(request *dap.LaunchRequest) {
 	var err error
 	if s.debugger, err = debugger.New(&s.config.Debugger, s.config.ProcessArgs); err != nil {
+		s.recorded = false
 		s.sendErrorResponse(request.Request,
 			FailedToContinue, "Failed to launch", err.Error())
 		return
 	}
+	sources, _ := s.debugger.Sources("")
+	s.loadedSources = make(map[string]bool, len(sources))
+	for _, path := range sources {
+		s.loadedSources[path] = true
+	}
+	go s.forwardOutput()
+
+	if s.recorded {
+		e := &dap.CapabilitiesEvent{Event: *newEvent("capabilities")}
+		e.Body.Capabilities.SupportsStepBack = true
+		e.Body.Capabilities.SupportsRestartFrame = true
+		s.send(e)
+	}
 
 	// Notify the client that the debugger is ready to start accepting
 	// configuration requests for setting breakpoints, etc. The client
@@ -479,11 +831,12 @@ func (s *Server) onLaunchRequest(request *dap.LaunchRequest) {
 func (s *Server) onDisconnectRequest(request *dap.DisconnectRequest) {
 	s.send(&dap.DisconnectResponse{Response: *newResponse(request.Request)})
 	if s.debugger != nil {
-		_, err := s.debugger.Command(&api.DebuggerCommand{Name: api.Halt})
+		_, err := s.debugger.Command(context.Background(), &api.DebuggerCommand{Name: api.Halt})
 		if err != nil {
 			s.log.Error(err)
 		}
-		kill := s.config.Debugger.AttachPid == 0
+		s.forgetFrames(func(frameInstance) bool { return true })
+		kill := !s.config.Debugger.AttachedToExistingProcess()
 		err = s.debugger.Detach(kill)
 		if err != nil {
 			s.log.Error(err)
@@ -518,9 +871,96 @@ func (s *Server) onSetBreakpointsRequest(request *dap.SetBreakpointsRequest) {
 	s.send(response)
 }
 
-func (s *Server) onSetExceptionBreakpointsRequest(request *dap.SetExceptionBreakpointsRequest) {
-	// Unlike what DAP documentation claims, this request is always sent
-	// even though we specified no filters at initialization. Handle as no-op.
+// panicExceptionFilter is the exception filter that stops the target
+// when a goroutine panics, even if the panic is later recovered.
+const panicExceptionFilter = "panic"
+
+// The following types add the exception filter options, which go-dap
+// doesn't support, to the initialize and setExceptionBreakpoints messages.
+
+type initializeResponse struct {
+	dap.Response
+	Body capabilities `json:"body"`
+}
+
+type capabilities struct {
+	dap.Capabilities
+	ExceptionBreakpointFilters     []exceptionBreakpointsFilter `json:"exceptionBreakpointFilters,omitempty"`
+	SupportsExceptionFilterOptions bool                         `json:"supportsExceptionFilterOptions,omitempty"`
+}
+
+type exceptionBreakpointsFilter struct {
+	dap.ExceptionBreakpointsFilter
+	SupportsCondition    bool   `json:"supportsCondition,omitempty"`
+	ConditionDescription string `json:"conditionDescription,omitempty"`
+}
+
+type setExceptionBreakpointsRequest struct {
+	dap.SetExceptionBreakpointsRequest
+	Arguments setExceptionBreakpointsArguments `json:"arguments"`
+}
+
+type setExceptionBreakpointsArguments struct {
+	dap.SetExceptionBreakpointsArguments
+	FilterOptions []exceptionFilterOptions `json:"filterOptions,omitempty"`
+}
+
+type exceptionFilterOptions struct {
+	FilterID  string `json:"filterId"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// onSetExceptionBreakpointsRequest replaces the panic breakpoint with one
+// matching the requested filters. The names in the paths of exceptionOptions
+// with a break mode other than 'never' restrict the breakpoint to panics
+// with a value of those types, the condition of the panic filter options
+// is a regular expression restricting the breakpoint to panics with a
+// matching message.
+func (s *Server) onSetExceptionBreakpointsRequest(request *setExceptionBreakpointsRequest) {
+	if s.debugger == nil {
+		s.sendErrorResponse(request.Request, UnableToSetExceptionBreakpoints, "Unable to set exception breakpoints", "debugger is nil")
+		return
+	}
+	if s.panicBreakpoint != 0 {
+		if bp := s.debugger.FindBreakpoint(s.panicBreakpoint); bp != nil {
+			if _, err := s.debugger.ClearBreakpoint(bp); err != nil {
+				s.log.Error(err)
+			}
+		}
+		s.panicBreakpoint = 0
+	}
+	enabled := false
+	for _, filter := range request.Arguments.Filters {
+		if filter == panicExceptionFilter {
+			enabled = true
+		}
+	}
+	message := ""
+	for _, opt := range request.Arguments.FilterOptions {
+		if opt.FilterID == panicExceptionFilter {
+			enabled = true
+			message = opt.Condition
+		}
+	}
+	if enabled {
+		filter := &api.PanicFilter{Message: message}
+		for _, opt := range request.Arguments.ExceptionOptions {
+			if opt.BreakMode == "never" {
+				continue
+			}
+			for _, seg := range opt.Path {
+				if !seg.Negate {
+					filter.Types = append(filter.Types, seg.Names...)
+				}
+			}
+		}
+		bp, err := s.debugger.CreateBreakpoint(&api.Breakpoint{Panic: filter, Variables: []string{"e"}})
+		if err != nil {
+			s.sendErrorResponse(request.Request, UnableToSetExceptionBreakpoints, "Unable to set exception breakpoints", err.Error())
+			return
+		}
+		s.panicBreakpoint = bp.ID
+	}
 	s.send(&dap.SetExceptionBreakpointsResponse{Response: *newResponse(request.Request)})
 }
 
@@ -548,8 +988,12 @@ func (s *Server) onThreadsRequest(request *dap.ThreadsRequest) {
 		s.sendErrorResponse(request.Request, UnableToDisplayThreads, "Unable to display threads", "debugger is nil")
 		return
 	}
-	gs, _, err := s.debugger.Goroutines(0, 0)
+	gs, _, err := s.debugger.Goroutines(s.requestCtx, 0, 0)
 	if err != nil {
+		if err == context.Canceled {
+			s.sendErrorResponse(request.Request, RequestCancelled, "cancelled", "the request was cancelled by the client")
+			return
+		}
 		switch err.(type) {
 		case *proc.ErrProcessExited:
 			// If the program exits very quickly, the initial threads request will complete after it has exited.
@@ -586,6 +1030,96 @@ func (s *Server) onThreadsRequest(request *dap.ThreadsRequest) {
 	s.send(response)
 }
 
+// onCompletionsRequest completes the expression typed in the debug console,
+// up to the cursor position, in the context of the frame specified by
+// frameId or, if it is missing, of the selected goroutine.
+func (s *Server) onCompletionsRequest(request *dap.CompletionsRequest) {
+	if s.debugger == nil {
+		s.sendErrorResponse(request.Request, UnableToComplete, "Unable to complete", "debugger is nil")
+		return
+	}
+	scope := api.EvalScope{GoroutineID: -1}
+	if request.Arguments.FrameId != 0 {
+		sf, ok := s.stackFrameHandles.get(request.Arguments.FrameId)
+		if !ok {
+			s.sendErrorResponse(request.Request, UnableToComplete, "Unable to complete", fmt.Sprintf("unknown frame id %d", request.Arguments.FrameId))
+			return
+		}
+		frame := sf.(stackFrame)
+		scope = api.EvalScope{GoroutineID: frame.goroutineID, Frame: frame.frameIndex}
+	}
+	text := request.Arguments.Text
+	// Columns start at 1, which is the default for the 'columnsStartAt1'
+	// client capability.
+	if col := request.Arguments.Column - 1; col >= 0 && col < len(text) {
+		text = text[:col]
+	}
+	start, completions, err := s.debugger.Complete(scope, api.CompleteExpression, text)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToComplete, "Unable to complete", err.Error())
+		return
+	}
+	targets := make([]dap.CompletionItem, len(completions))
+	for i, c := range completions {
+		targets[i] = dap.CompletionItem{
+			Label:  c.Text,
+			Text:   c.Text,
+			Type:   completionItemType(c.Kind),
+			Start:  start + 1,
+			Length: len(text) - start,
+		}
+	}
+	response := &dap.CompletionsResponse{
+		Response: *newResponse(request.Request),
+		Body:     dap.CompletionsResponseBody{Targets: targets},
+	}
+	s.send(response)
+}
+
+// completionItemType maps the kind of a completion returned by the
+// debugger to the closest DAP completion item type.
+func completionItemType(kind string) dap.CompletionItemType {
+	switch kind {
+	case "package":
+		return "module"
+	case "key":
+		return "value"
+	case "function", "file", "variable", "field":
+		return dap.CompletionItemType(kind)
+	}
+	return "text"
+}
+
+// onExceptionInfoRequest reports the value passed to panic by the goroutine
+// stopped at the panic breakpoint.
+func (s *Server) onExceptionInfoRequest(request *dap.ExceptionInfoRequest) {
+	if s.debugger == nil {
+		s.sendErrorResponse(request.Request, UnableToGetExceptionInfo, "Unable to get exception info", "debugger is nil")
+		return
+	}
+	scope := api.EvalScope{GoroutineID: request.Arguments.ThreadId}
+	v, err := s.debugger.EvalVariableInScope(s.requestCtx, scope, "e", proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 256, MaxArrayValues: 64, MaxStructFields: -1})
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToGetExceptionInfo, "Unable to get exception info", err.Error())
+		return
+	}
+	response := &dap.ExceptionInfoResponse{Response: *newResponse(request.Request)}
+	response.Body.ExceptionId = "panic"
+	response.Body.BreakMode = "always"
+	response.Body.Details.EvaluateName = "e"
+	if len(v.Children) > 0 {
+		val := &v.Children[0]
+		response.Body.ExceptionId = val.Type
+		response.Body.Details.TypeName = val.Type
+		response.Body.Details.FullTypeName = val.RealType
+		response.Body.Description = val.SinglelineString()
+		response.Body.Details.Message = response.Body.Description
+	} else {
+		response.Body.Description = "panic(nil)"
+	}
+	s.send(response)
+}
+
 // onAttachRequest sends a not-yet-implemented error response.
 // This is a mandatory request to support.
 func (s *Server) onAttachRequest(request *dap.AttachRequest) { // TODO V0
@@ -598,10 +1132,73 @@ func (s *Server) onNextRequest(request *dap.NextRequest) { // TODO V0
 	s.sendNotYetImplementedErrorResponse(request.Request)
 }
 
-// onStepInRequest sends a not-yet-implemented error response.
-// This is a mandatory request to support.
-func (s *Server) onStepInRequest(request *dap.StepInRequest) { // TODO V0
-	s.sendNotYetImplementedErrorResponse(request.Request)
+// onStepInRequest steps the goroutine specified by threadId to the next
+// source line, entering function calls. If targetId is specified only the
+// call with that id, as returned by the last stepInTargets request, is
+// entered.
+func (s *Server) onStepInRequest(request *dap.StepInRequest) {
+	if s.debugger == nil {
+		s.sendErrorResponse(request.Request, UnableToStepIn, "Unable to step in", "debugger is nil")
+		return
+	}
+	if _, err := s.debugger.Command(context.Background(), &api.DebuggerCommand{Name: api.SwitchGoroutine, GoroutineID: request.Arguments.ThreadId}); err != nil {
+		s.sendErrorResponse(request.Request, UnableToStepIn, "Unable to step in", err.Error())
+		return
+	}
+	command := &api.DebuggerCommand{Name: api.Step}
+	if id := request.Arguments.TargetId; id != 0 {
+		tgts, err := s.debugger.StepInTargets(request.Arguments.ThreadId)
+		if err != nil {
+			s.sendErrorResponse(request.Request, UnableToStepIn, "Unable to step in", err.Error())
+			return
+		}
+		if id < 1 || id > len(tgts) {
+			s.sendErrorResponse(request.Request, UnableToStepIn, "Unable to step in", fmt.Sprintf("unknown target %d", id))
+			return
+		}
+		command = &api.DebuggerCommand{Name: api.StepIntoCall, CallPC: tgts[id-1].PC}
+	}
+	s.send(&dap.StepInResponse{Response: *newResponse(request.Request)})
+	s.runUntilStop(command, "step")
+}
+
+// onStepInTargetsRequest lists the function calls on the current line of
+// the goroutine of the frame specified by frameId. The ids of the targets
+// are their position in the list, starting at 1.
+// Since stepIn always steps from the topmost frame the list is empty for
+// the other frames.
+func (s *Server) onStepInTargetsRequest(request *dap.StepInTargetsRequest) {
+	if s.debugger == nil {
+		s.sendErrorResponse(request.Request, UnableToListStepInTargets, "Unable to list step in targets", "debugger is nil")
+		return
+	}
+	sf, ok := s.stackFrameHandles.get(request.Arguments.FrameId)
+	if !ok {
+		s.sendErrorResponse(request.Request, UnableToListStepInTargets, "Unable to list step in targets", fmt.Sprintf("unknown frame id %d", request.Arguments.FrameId))
+		return
+	}
+	frame := sf.(stackFrame)
+	response := &dap.StepInTargetsResponse{Response: *newResponse(request.Request)}
+	if frame.frameIndex != 0 {
+		response.Body.Targets = []dap.StepInTarget{}
+		s.send(response)
+		return
+	}
+	tgts, err := s.debugger.StepInTargets(frame.goroutineID)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToListStepInTargets, "Unable to list step in targets", err.Error())
+		return
+	}
+	response.Body.Targets = make([]dap.StepInTarget, len(tgts))
+	for i, tgt := range tgts {
+		response.Body.Targets[i].Id = i + 1
+		if tgt.Function != nil {
+			response.Body.Targets[i].Label = tgt.Function.Name()
+		} else {
+			response.Body.Targets[i].Label = fmt.Sprintf("indirect call at %#x", tgt.PC)
+		}
+	}
+	s.send(response)
 }
 
 // onStepOutRequest sends a not-yet-implemented error response.
@@ -616,22 +1213,183 @@ func (s *Server) onPauseRequest(request *dap.PauseRequest) { // TODO V0
 	s.sendNotYetImplementedErrorResponse(request.Request)
 }
 
-// onStackTraceRequest sends a not-yet-implemented error response.
-// This is a mandatory request to support.
-func (s *Server) onStackTraceRequest(request *dap.StackTraceRequest) { // TODO V0
-	s.sendNotYetImplementedErrorResponse(request.Request)
+// onStackTraceRequest sends the frames of the stack of the goroutine with
+// id ThreadId, starting at StartFrame.
+func (s *Server) onStackTraceRequest(request *dap.StackTraceRequest) {
+	if s.debugger == nil {
+		s.sendErrorResponse(request.Request, UnableToProduceStackTrace, "Unable to produce stack trace", "debugger is nil")
+		return
+	}
+	goroutineID := request.Arguments.ThreadId
+	start := request.Arguments.StartFrame
+	levels := request.Arguments.Levels
+	if levels <= 0 {
+		levels = defaultStackDepth
+	}
+	frames, err := s.debugger.Stacktrace(s.requestCtx, goroutineID, start+levels-1, 0, nil)
+	if err != nil {
+		if err == context.Canceled {
+			s.sendErrorResponse(request.Request, RequestCancelled, "cancelled", "the request was cancelled by the client")
+			return
+		}
+		s.sendErrorResponse(request.Request, UnableToProduceStackTrace, "Unable to produce stack trace", err.Error())
+		return
+	}
+
+	stackFrames := []dap.StackFrame{}
+	for i := start; i < len(frames); i++ {
+		loc := &frames[i].Location
+		sf := dap.StackFrame{
+			Id:   s.stackFrameHandles.create(stackFrame{goroutineID: goroutineID, frameIndex: i}),
+			Name: "?",
+			Line: loc.Line,
+		}
+		if loc.Function != nil {
+			sf.Name = loc.Function.Name()
+		}
+		if loc.File != "<autogenerated>" {
+			sf.Source = dap.Source{Name: filepath.Base(loc.File), Path: loc.File}
+		}
+		stackFrames = append(stackFrames, sf)
+	}
+	response := &dap.StackTraceResponse{
+		Response: *newResponse(request.Request),
+		Body:     dap.StackTraceResponseBody{StackFrames: stackFrames},
+	}
+	s.send(response)
 }
 
-// onScopesRequest sends a not-yet-implemented error response.
-// This is a mandatory request to support.
-func (s *Server) onScopesRequest(request *dap.ScopesRequest) { // TODO V0
-	s.sendNotYetImplementedErrorResponse(request.Request)
+// onScopesRequest sends the Arguments and Locals scopes of a frame
+// returned by a previous stackTrace request.
+func (s *Server) onScopesRequest(request *dap.ScopesRequest) {
+	sf, ok := s.stackFrameHandles.get(request.Arguments.FrameId)
+	if !ok {
+		s.sendErrorResponse(request.Request, UnableToListLocals, "Unable to list locals", fmt.Sprintf("unknown frame id %d", request.Arguments.FrameId))
+		return
+	}
+	frame := sf.(stackFrame)
+	scope := api.EvalScope{GoroutineID: frame.goroutineID, Frame: frame.frameIndex}
+
+	args, err := s.debugger.FunctionArguments(scope, loadConfig)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToListArgs, "Unable to list args", err.Error())
+		return
+	}
+	locals, err := s.debugger.LocalVariables(scope, loadConfig)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToListLocals, "Unable to list locals", err.Error())
+		return
+	}
+
+	argScope := &variable{v: &api.Variable{Name: "Arguments", Children: args, Len: int64(len(args))}, scope: scope}
+	localScope := &variable{v: &api.Variable{Name: "Locals", Children: locals, Len: int64(len(locals))}, scope: scope}
+	response := &dap.ScopesResponse{
+		Response: *newResponse(request.Request),
+		Body: dap.ScopesResponseBody{Scopes: []dap.Scope{
+			{Name: "Arguments", VariablesReference: s.variableHandles.create(argScope), NamedVariables: len(args)},
+			{Name: "Locals", VariablesReference: s.variableHandles.create(localScope), NamedVariables: len(locals)},
+		}},
+	}
+	s.send(response)
 }
 
-// onVariablesRequest sends a not-yet-implemented error response.
-// This is a mandatory request to support.
-func (s *Server) onVariablesRequest(request *dap.VariablesRequest) { // TODO V0
-	s.sendNotYetImplementedErrorResponse(request.Request)
+// onVariablesRequest sends the children of a scope or of a variable.
+// The children of variables stored in memory are loaded when they are
+// requested, Start and Count select the range of elements of arrays,
+// slices and maps (or fields of structs) to load, this is how clients page
+// through values with many indexedVariables or namedVariables.
+func (s *Server) onVariablesRequest(request *dap.VariablesRequest) {
+	args := request.Arguments
+	val, ok := s.variableHandles.get(args.VariablesReference)
+	if !ok {
+		s.sendErrorResponse(request.Request, UnableToLookupVariable, "Unable to lookup variable", fmt.Sprintf("unknown reference %d", args.VariablesReference))
+		return
+	}
+	vh := val.(*variable)
+	v := vh.v
+
+	indexed := v.Kind == reflect.Array || v.Kind == reflect.Slice || v.Kind == reflect.Map
+	if (args.Filter == "indexed" && !indexed) || (args.Filter == "named" && indexed) {
+		s.send(&dap.VariablesResponse{Response: *newResponse(request.Request), Body: dap.VariablesResponseBody{Variables: []dap.Variable{}}})
+		return
+	}
+
+	if v.Ref != nil {
+		count := args.Count
+		if count <= 0 {
+			count = maxVariablesPage
+		}
+		var err error
+		v, err = s.debugger.VariableChildren(vh.scope, *v.Ref, args.Start, count, loadConfig)
+		if err != nil {
+			s.sendErrorResponse(request.Request, UnableToLookupVariable, "Unable to lookup variable", err.Error())
+			return
+		}
+	}
+
+	children := []dap.Variable{}
+	switch v.Kind {
+	case reflect.Map:
+		for i := 0; i+1 < len(v.Children); i += 2 {
+			children = append(children, s.convertVariable(v.Children[i].SinglelineString(), &v.Children[i+1], vh.scope))
+		}
+	case reflect.Array, reflect.Slice:
+		for i := range v.Children {
+			children = append(children, s.convertVariable(fmt.Sprintf("[%d]", int(v.Offset)+i), &v.Children[i], vh.scope))
+		}
+	case reflect.Ptr:
+		if len(v.Children) > 0 {
+			children = append(children, s.convertVariable("*"+vh.v.Name, &v.Children[0], vh.scope))
+		}
+	default:
+		for i := range v.Children {
+			name := v.Children[i].Name
+			if name == "" && v.Kind == reflect.Interface {
+				name = "data"
+			}
+			children = append(children, s.convertVariable(name, &v.Children[i], vh.scope))
+		}
+	}
+	response := &dap.VariablesResponse{
+		Response: *newResponse(request.Request),
+		Body:     dap.VariablesResponseBody{Variables: children},
+	}
+	s.send(response)
+}
+
+// convertVariable converts v to a DAP variable with the given name,
+// creating a reference for its children if it has any.
+func (s *Server) convertVariable(name string, v *api.Variable, scope api.EvalScope) dap.Variable {
+	r := dap.Variable{Name: name, Value: v.SinglelineString(), Type: v.Type}
+	if !hasChildren(v) {
+		return r
+	}
+	r.VariablesReference = s.variableHandles.create(&variable{v: v, scope: scope})
+	switch v.Kind {
+	case reflect.Array, reflect.Slice, reflect.Map:
+		r.IndexedVariables = int(v.Len)
+	case reflect.Struct:
+		r.NamedVariables = int(v.Len)
+	}
+	return r
+}
+
+// hasChildren returns true if v can be expanded by the client.
+func hasChildren(v *api.Variable) bool {
+	if v.Unreadable != "" {
+		return false
+	}
+	switch v.Kind {
+	case reflect.Array, reflect.Slice, reflect.Map, reflect.Struct:
+		return v.Len > 0
+	case reflect.Ptr:
+		return len(v.Children) > 0 && v.Children[0].Addr != 0
+	case reflect.Interface:
+		return len(v.Children) > 0 && v.Children[0].Kind != reflect.Invalid
+	case reflect.Chan:
+		return len(v.Children) > 0
+	}
+	return false
 }
 
 // onEvaluateRequest sends a not-yet-implemented error response.
@@ -658,16 +1416,225 @@ func (s *Server) onSetFunctionBreakpointsRequest(request *dap.SetFunctionBreakpo
 	s.sendNotYetImplementedErrorResponse(request.Request)
 }
 
-// onStepBackRequest sends a not-yet-implemented error response.
-// Capability 'supportsStepBack' is not set 'initialize' response.
+// onStepBackRequest executes the goroutine specified by threadId backwards
+// to the previous source line, without entering function calls.
+// Capability 'supportsStepBack' is only set for recordings.
 func (s *Server) onStepBackRequest(request *dap.StepBackRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+	if err := s.checkRecorded(); err != nil {
+		s.sendErrorResponse(request.Request, UnableToStepBack, "Unable to step back", err.Error())
+		return
+	}
+	if _, err := s.debugger.Command(context.Background(), &api.DebuggerCommand{Name: api.SwitchGoroutine, GoroutineID: request.Arguments.ThreadId}); err != nil {
+		s.sendErrorResponse(request.Request, UnableToStepBack, "Unable to step back", err.Error())
+		return
+	}
+	s.send(&dap.StepBackResponse{Response: *newResponse(request.Request)})
+	s.runUntilStop(&api.DebuggerCommand{Name: api.ReverseNext}, "step")
 }
 
-// onReverseContinueRequest sends a not-yet-implemented error response.
-// Capability 'supportsStepBack' is not set 'initialize' response.
+// onReverseContinueRequest executes the target backwards until a
+// breakpoint is hit or the start of the recording is reached.
+// Capability 'supportsStepBack' is only set for recordings.
 func (s *Server) onReverseContinueRequest(request *dap.ReverseContinueRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+	if err := s.checkRecorded(); err != nil {
+		s.sendErrorResponse(request.Request, FailedToContinue, "Unable to reverse continue", err.Error())
+		return
+	}
+	s.send(&dap.ReverseContinueResponse{Response: *newResponse(request.Request)})
+	s.runUntilStop(&api.DebuggerCommand{Name: api.Rewind}, "breakpoint")
+}
+
+// onRestartFrameRequest moves the goroutine of the frame back to the entry
+// of the function of the frame, see restartFrame.
+// Capability 'supportsRestartFrame' is only set for recordings.
+func (s *Server) onRestartFrameRequest(request *dap.RestartFrameRequest) {
+	if err := s.checkRecorded(); err != nil {
+		s.sendErrorResponse(request.Request, UnableToRestartFrame, "Unable to restart frame", err.Error())
+		return
+	}
+	sf, ok := s.stackFrameHandles.get(request.Arguments.FrameId)
+	if !ok {
+		s.sendErrorResponse(request.Request, UnableToRestartFrame, "Unable to restart frame", fmt.Sprintf("unknown frame id %d", request.Arguments.FrameId))
+		return
+	}
+	frame := sf.(stackFrame)
+	frames, err := s.debugger.Stacktrace(s.requestCtx, frame.goroutineID, frame.frameIndex, 0, nil)
+	if err != nil || len(frames) <= frame.frameIndex {
+		s.sendErrorResponse(request.Request, UnableToRestartFrame, "Unable to restart frame", fmt.Sprintf("could not find frame %d of goroutine %d", frame.frameIndex, frame.goroutineID))
+		return
+	}
+	s.send(&dap.RestartFrameResponse{Response: *newResponse(request.Request)})
+
+	s.stackFrameHandles.reset()
+	s.variableHandles.reset()
+	state, err := s.restartFrame(s.requestCtx, frame.goroutineID, frame.frameIndex, &frames[frame.frameIndex])
+	s.forgetReturnedFrames(err != nil)
+	if err != nil {
+		s.log.Error(err)
+		e := &dap.OutputEvent{Event: *newEvent("output")}
+		e.Body.Category = "stderr"
+		e.Body.Output = fmt.Sprintf("Unable to restart frame: %v\n", err)
+		s.send(e)
+		if state, err = s.debugger.State(false); err != nil {
+			s.log.Error(err)
+			return
+		}
+	}
+	e := &dap.StoppedEvent{Event: *newEvent("stopped")}
+	e.Body.Reason = "restart"
+	e.Body.AllThreadsStopped = true
+	if state.SelectedGoroutine != nil {
+		e.Body.ThreadId = state.SelectedGoroutine.ID
+	}
+	s.send(e)
+}
+
+// restartFrame moves the target back to the entry of the function of
+// frame, number frameIndex in the stack of the goroutine.
+// The first time a frame is restarted the goroutine reverse steps out of
+// the frames up to and including frame, which stops at the call
+// instruction of frame, then executes the call instruction. A checkpoint
+// is taken there so that restarting the frame again only needs to restart
+// the recording at the checkpoint.
+// If the entry of the function can not be reached, for example because a
+// breakpoint was hit while going backwards, the target is moved back to
+// where it was stopped and an error is returned.
+func (s *Server) restartFrame(ctx context.Context, goroutineID, frameIndex int, frame *api.Stackframe) (*api.DebuggerState, error) {
+	fi := newFrameInstance(goroutineID, frame)
+	if cpid, ok := s.frameCheckpoints[fi]; ok {
+		state, err := s.restartCheckpoint(ctx, cpid, goroutineID)
+		if err != nil {
+			return nil, err
+		}
+		return state, s.checkFrameEntry(ctx, goroutineID, frame)
+	}
+
+	start, err := s.debugger.Checkpoint("restartFrame")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := s.debugger.ClearCheckpoint(start); err != nil {
+			s.log.Error(err)
+		}
+	}()
+	state, err := s.stepBackToEntry(ctx, goroutineID, frameIndex, frame)
+	if err != nil {
+		if _, err := s.restartCheckpoint(ctx, start, goroutineID); err != nil {
+			s.log.Error(err)
+		}
+		return nil, err
+	}
+	if cpid, err := s.debugger.Checkpoint(fi.function); err != nil {
+		s.log.Error(err)
+	} else {
+		s.frameCheckpoints[fi] = cpid
+	}
+	return state, nil
+}
+
+// stepBackToEntry reverse steps out of the frames of the goroutine up to
+// and including frame number frameIndex, then executes the call
+// instruction of that frame.
+func (s *Server) stepBackToEntry(ctx context.Context, goroutineID, frameIndex int, frame *api.Stackframe) (*api.DebuggerState, error) {
+	state, err := s.debugger.Command(ctx, &api.DebuggerCommand{Name: api.SwitchGoroutine, GoroutineID: goroutineID})
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i <= frameIndex; i++ {
+		state, err = s.debugger.Command(ctx, &api.DebuggerCommand{Name: api.ReverseStepOut})
+		if err != nil {
+			return nil, err
+		}
+		if state.NextInProgress {
+			if err := s.debugger.CancelNext(); err != nil {
+				s.log.Error(err)
+			}
+			return nil, errors.New("interrupted by a breakpoint")
+		}
+	}
+	state, err = s.debugger.Command(ctx, &api.DebuggerCommand{Name: api.StepInstruction})
+	if err != nil {
+		return nil, err
+	}
+	return state, s.checkFrameEntry(ctx, goroutineID, frame)
+}
+
+// restartCheckpoint restarts the recording at checkpoint cpid and selects
+// the goroutine.
+func (s *Server) restartCheckpoint(ctx context.Context, cpid, goroutineID int) (*api.DebuggerState, error) {
+	if _, err := s.debugger.Restart(false, fmt.Sprintf("c%d", cpid), false, nil); err != nil {
+		return nil, err
+	}
+	return s.debugger.Command(ctx, &api.DebuggerCommand{Name: api.SwitchGoroutine, GoroutineID: goroutineID})
+}
+
+// checkFrameEntry returns an error if the topmost frame of the goroutine
+// is not frame.
+func (s *Server) checkFrameEntry(ctx context.Context, goroutineID int, frame *api.Stackframe) error {
+	frames, err := s.debugger.Stacktrace(ctx, goroutineID, 0, 0, nil)
+	if err != nil {
+		return err
+	}
+	if len(frames) == 0 || newFrameInstance(goroutineID, &frames[0]) != newFrameInstance(goroutineID, frame) {
+		return errors.New("could not go back to the entry of the function")
+	}
+	return nil
+}
+
+// forgetReturnedFrames forgets the restarted frames that are not on the
+// stack of their goroutine anymore, a new frame at the same offset is a
+// different invocation of the function. If all is true, because the target
+// was resumed in a way that could have returned from a frame and entered
+// the function again before stopping, all frames are forgotten.
+func (s *Server) forgetReturnedFrames(all bool) {
+	if len(s.frameCheckpoints) == 0 {
+		return
+	}
+	live := make(map[frameInstance]bool)
+	if !all {
+		stacked := make(map[int]bool)
+		for fi := range s.frameCheckpoints {
+			if stacked[fi.goroutineID] {
+				continue
+			}
+			stacked[fi.goroutineID] = true
+			frames, err := s.debugger.Stacktrace(s.requestCtx, fi.goroutineID, defaultStackDepth, 0, nil)
+			if err != nil {
+				continue
+			}
+			for i := range frames {
+				live[newFrameInstance(fi.goroutineID, &frames[i])] = true
+			}
+		}
+	}
+	s.forgetFrames(func(fi frameInstance) bool { return !live[fi] })
+}
+
+// forgetFrames removes the restarted frames for which forget returns true
+// and clears their checkpoints.
+func (s *Server) forgetFrames(forget func(fi frameInstance) bool) {
+	for fi, cpid := range s.frameCheckpoints {
+		if !forget(fi) {
+			continue
+		}
+		delete(s.frameCheckpoints, fi)
+		if err := s.debugger.ClearCheckpoint(cpid); err != nil {
+			s.log.Error(err)
+		}
+	}
+}
+
+// checkRecorded returns an error if the target can not be executed
+// backwards.
+func (s *Server) checkRecorded() error {
+	if s.debugger == nil {
+		return errors.New("debugger is nil")
+	}
+	if !s.recorded {
+		return proc.ErrNotRecorded
+	}
+	return nil
 }
 
 // onSetVariableRequest sends a not-yet-implemented error response.
@@ -700,10 +1667,22 @@ func (s *Server) onDisassembleRequest(request *dap.DisassembleRequest) {
 	s.sendNotYetImplementedErrorResponse(request.Request)
 }
 
-// onCancelRequest sends a not-yet-implemented error response.
-// Capability 'supportsCancelRequest' is not set 'initialize' response.
+// onCancelRequest cancels the request with sequence number RequestId, or
+// the request being handled if RequestId is not set.
+// A request cancelled before it is handled fails with a "cancelled" error
+// response. Cancelling continue or step requests while the target is
+// running stops the target, other requests fail if they did not complete.
 func (s *Server) onCancelRequest(request *dap.CancelRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+	s.requestMutex.Lock()
+	if id := request.Arguments.RequestId; id == 0 || id == s.currentSeq {
+		if s.currentCancel != nil {
+			s.currentCancel()
+		}
+	} else if _, ok := s.queued[id]; ok {
+		s.queued[id] = true
+	}
+	s.requestMutex.Unlock()
+	s.send(&dap.CancelResponse{Response: *newResponse(request.Request)})
 }
 
 func (s *Server) sendErrorResponse(request dap.Request, id int, summary, details string) {
@@ -767,10 +1746,26 @@ func newEvent(event string) *dap.Event {
 }
 
 func (s *Server) doContinue() {
+	s.runUntilStop(&api.DebuggerCommand{Name: api.Continue}, "breakpoint")
+}
+
+// runUntilStop executes command and sends a stopped event, with the given
+// reason, or a terminated event once the target stops.
+func (s *Server) runUntilStop(command *api.DebuggerCommand, stopReason string) {
 	if s.debugger == nil {
 		return
 	}
-	state, err := s.debugger.Command(&api.DebuggerCommand{Name: api.Continue})
+	// Frames and variables sent to the client are only valid while the
+	// target is stopped.
+	s.stackFrameHandles.reset()
+	s.variableHandles.reset()
+	state, err := s.debugger.Command(s.requestCtx, command)
+	// Continuing, or hitting a breakpoint while stepping, could return from
+	// a restarted frame and call the function again before stopping.
+	s.forgetReturnedFrames(command.Name == api.Continue || command.Name == api.Rewind || err != nil || state.Exited || state.NextInProgress || (state.CurrentThread != nil && state.CurrentThread.Breakpoint != nil))
+	// Send the output written by the target before it stopped ahead of the
+	// stopped or terminated event.
+	s.sendOutput()
 	if err != nil {
 		s.log.Error(err)
 		switch err.(type) {
@@ -787,7 +1782,15 @@ func (s *Server) doContinue() {
 	} else {
 		e := &dap.StoppedEvent{Event: *newEvent("stopped")}
 		// TODO(polina): differentiate between breakpoint and pause on halt.
-		e.Body.Reason = "breakpoint"
+		e.Body.Reason = stopReason
+		if s.requestCtx != nil && s.requestCtx.Err() != nil {
+			// the request was cancelled and the target was halted
+			e.Body.Reason = "pause"
+		}
+		if th := state.CurrentThread; th != nil && th.Breakpoint != nil && s.panicBreakpoint != 0 && th.Breakpoint.ID == s.panicBreakpoint {
+			e.Body.Reason = "exception"
+			e.Body.Description = "panic"
+		}
 		e.Body.AllThreadsStopped = true
 		e.Body.ThreadId = state.SelectedGoroutine.ID
 		s.send(e)
@@ -1,10 +1,14 @@
 package dap
 
 import (
+	"bufio"
+	"bytes"
 	"flag"
+	"fmt"
 	"io"
 	"net"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
 	"strings"
@@ -15,6 +19,7 @@ import (
 	"github.com/go-delve/delve/pkg/logflags"
 	protest "github.com/go-delve/delve/pkg/proc/test"
 	"github.com/go-delve/delve/service"
+	"github.com/go-delve/delve/service/api"
 	"github.com/go-delve/delve/service/dap/daptest"
 	"github.com/go-delve/delve/service/debugger"
 	"github.com/google/go-dap"
@@ -32,6 +37,14 @@ func TestMain(m *testing.M) {
 
 // name is for _fixtures/<name>.go
 func runTest(t *testing.T, name string, test func(c *daptest.Client, f protest.Fixture)) {
+	runTestWithServer(t, name, func(c *daptest.Client, f protest.Fixture, _ *Server) {
+		test(c, f)
+	})
+}
+
+// runTestWithServer is like runTest but also passes the server to test, to
+// check its state between requests.
+func runTestWithServer(t *testing.T, name string, test func(c *daptest.Client, f protest.Fixture, s *Server)) {
 	var buildFlags protest.BuildFlags
 	fixture := protest.BuildFixture(name, buildFlags)
 
@@ -45,7 +58,8 @@ func runTest(t *testing.T, name string, test func(c *daptest.Client, f protest.F
 		Listener:       listener,
 		DisconnectChan: disconnectChan,
 		Debugger: debugger.Config{
-			Backend: "default",
+			Backend:       "default",
+			CaptureOutput: true,
 		},
 	})
 	server.Run()
@@ -68,7 +82,7 @@ func runTest(t *testing.T, name string, test func(c *daptest.Client, f protest.F
 		stopOnce.Do(func() { server.Stop() })
 	}()
 
-	test(client, fixture)
+	test(client, fixture, server)
 }
 
 // TestStopOnEntry emulates the message exchange that can be observed with
@@ -166,17 +180,17 @@ func TestStopOnEntry(t *testing.T) {
 		}
 
 		// 8 >> stackTrace, << stackTrace
-		client.StackTraceRequest()
+		client.StackTraceRequest(1, 0, 20)
 		stResp := client.ExpectErrorResponse(t)
-		if stResp.Seq != 0 || stResp.RequestSeq != 8 || stResp.Message != "Not yet implemented" {
-			t.Errorf("\ngot %#v\nwant Seq=0, RequestSeq=8 Message=\"Not yet implemented\"", stResp)
+		if stResp.Seq != 0 || stResp.RequestSeq != 8 || stResp.Message != "Unable to produce stack trace" {
+			t.Errorf("\ngot %#v\nwant Seq=0, RequestSeq=8 Message=\"Unable to produce stack trace\"", stResp)
 		}
 
 		// 9 >> stackTrace, << stackTrace
-		client.StackTraceRequest()
+		client.StackTraceRequest(1, 0, 20)
 		stResp = client.ExpectErrorResponse(t)
-		if stResp.Seq != 0 || stResp.RequestSeq != 9 || stResp.Message != "Not yet implemented" {
-			t.Errorf("\ngot %#v\nwant Seq=0, RequestSeq=9 Message=\"Not yet implemented\"", stResp)
+		if stResp.Seq != 0 || stResp.RequestSeq != 9 || stResp.Message != "Unable to produce stack trace" {
+			t.Errorf("\ngot %#v\nwant Seq=0, RequestSeq=9 Message=\"Unable to produce stack trace\"", stResp)
 		}
 
 		// 10 >> continue, << continue, << terminated
@@ -306,8 +320,38 @@ func TestSetBreakpoint(t *testing.T) {
 			}
 		}
 
-		// TODO(polina): add other status checking requests
-		// that are not yet supported (stackTrace, scopes, variables)
+		client.StackTraceRequest(stopEvent1.Body.ThreadId, 0, 20)
+		stResp := client.ExpectStackTraceResponse(t)
+		if len(stResp.Body.StackFrames) < 4 {
+			t.Fatalf("got %#v, want len(StackFrames)>=4", stResp.Body.StackFrames)
+		}
+		// Increment(0) called by Increment(1) called by Increment(3)
+		// called by main.
+		for i, want := range []struct {
+			name string
+			line int
+		}{{"main.Increment", 8}, {"main.Increment", 11}, {"main.Increment", 11}, {"main.main", 17}} {
+			got := stResp.Body.StackFrames[i]
+			if got.Name != want.name || got.Line != want.line || got.Source.Path != fixture.Source {
+				t.Errorf("got frame %d %#v, want Name=%s Line=%d Source.Path=%s", i, got, want.name, want.line, fixture.Source)
+			}
+		}
+
+		client.ScopesRequest(stResp.Body.StackFrames[1].Id)
+		scResp := client.ExpectScopesResponse(t)
+		if len(scResp.Body.Scopes) != 2 || scResp.Body.Scopes[0].Name != "Arguments" || scResp.Body.Scopes[1].Name != "Locals" {
+			t.Fatalf("got %#v, want Arguments and Locals scopes", scResp.Body.Scopes)
+		}
+		client.VariablesRequest(scResp.Body.Scopes[0].VariablesReference, 0, 0)
+		vResp := client.ExpectVariablesResponse(t)
+		if len(vResp.Body.Variables) < 1 || vResp.Body.Variables[0].Name != "y" || vResp.Body.Variables[0].Value != "1" {
+			t.Errorf("got %#v, want y=1 in the arguments of frame 1", vResp.Body.Variables)
+		}
+
+		client.ScopesRequest(1)
+		if er := client.ExpectErrorResponse(t); er.Body.Error.Id != UnableToListLocals {
+			t.Errorf("got %#v, want Id=%d", er, UnableToListLocals)
+		}
 
 		client.ContinueRequest(1)
 		client.ExpectContinueResponse(t)
@@ -319,6 +363,248 @@ func TestSetBreakpoint(t *testing.T) {
 	})
 }
 
+func TestCompletions(t *testing.T) {
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponse(t)
+
+		client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+
+		client.SetBreakpointsRequest(fixture.Source, []int{8})
+		client.ExpectSetBreakpointsResponse(t)
+
+		client.ConfigurationDoneRequest()
+		client.ExpectConfigurationDoneResponse(t)
+		stopEvent := client.ExpectStoppedEvent(t)
+
+		client.StackTraceRequest(stopEvent.Body.ThreadId, 0, 20)
+		stResp := client.ExpectStackTraceResponse(t)
+		if len(stResp.Body.StackFrames) < 4 || stResp.Body.StackFrames[3].Name != "main.main" {
+			t.Fatalf("got %#v, want StackFrames[3].Name=main.main", stResp.Body.StackFrames)
+		}
+
+		hasTarget := func(targets []dap.CompletionItem, label string) bool {
+			for _, item := range targets {
+				if item.Label == label {
+					return true
+				}
+			}
+			return false
+		}
+
+		// y is an argument of main.Increment, not of main.main.
+		client.CompletionsRequest(0, "y", 2)
+		if targets := client.ExpectCompletionsResponse(t).Body.Targets; !hasTarget(targets, "y") {
+			t.Errorf("got %#v, want completion y in the selected goroutine", targets)
+		}
+		client.CompletionsRequest(stResp.Body.StackFrames[1].Id, "y", 2)
+		if targets := client.ExpectCompletionsResponse(t).Body.Targets; !hasTarget(targets, "y") {
+			t.Errorf("got %#v, want completion y in frame 1", targets)
+		}
+		client.CompletionsRequest(stResp.Body.StackFrames[3].Id, "y", 2)
+		if targets := client.ExpectCompletionsResponse(t).Body.Targets; hasTarget(targets, "y") {
+			t.Errorf("got %#v, want no completion y in main.main", targets)
+		}
+
+		client.CompletionsRequest(1, "y", 2)
+		if er := client.ExpectErrorResponse(t); er.Body.Error.Id != UnableToComplete {
+			t.Errorf("got %#v, want Id=%d", er, UnableToComplete)
+		}
+
+		client.DisconnectRequest()
+		client.ExpectDisconnectResponse(t)
+	})
+}
+
+func TestStepInTargets(t *testing.T) {
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponse(t)
+
+		client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+
+		client.SetBreakpointsRequest(fixture.Source, []int{11})
+		client.ExpectSetBreakpointsResponse(t)
+
+		client.ConfigurationDoneRequest()
+		client.ExpectConfigurationDoneResponse(t)
+		stopEvent := client.ExpectStoppedEvent(t)
+
+		client.StackTraceRequest(stopEvent.Body.ThreadId, 0, 20)
+		stResp := client.ExpectStackTraceResponse(t)
+		if len(stResp.Body.StackFrames) < 2 || stResp.Body.StackFrames[0].Name != "main.Increment" {
+			t.Fatalf("got %#v, want StackFrames[0].Name=main.Increment", stResp.Body.StackFrames)
+		}
+		top, caller := stResp.Body.StackFrames[0].Id, stResp.Body.StackFrames[1].Id
+
+		client.StepInTargetsRequest(top)
+		sResp := client.ExpectStepInTargetsResponse(t)
+		if len(sResp.Body.Targets) == 0 || sResp.Body.Targets[0].Label != "main.Increment" {
+			t.Errorf("got %#v, want main.Increment", sResp.Body.Targets)
+		}
+
+		client.StepInTargetsRequest(caller)
+		sResp = client.ExpectStepInTargetsResponse(t)
+		if len(sResp.Body.Targets) != 0 {
+			t.Errorf("got %#v, want no step in targets for frame %d", sResp.Body.Targets, caller)
+		}
+
+		client.StepInTargetsRequest(1)
+		if er := client.ExpectErrorResponse(t); er.Body.Error.Id != UnableToListStepInTargets {
+			t.Errorf("got %#v, want Id=%d", er, UnableToListStepInTargets)
+		}
+
+		client.DisconnectRequest()
+		client.ExpectDisconnectResponse(t)
+	})
+}
+
+// TestVariablesPaging stops at the first runtime.Breakpoint of
+// testvariables2 and checks that the elements of slices and maps can be
+// loaded in pages through the variables request.
+func TestVariablesPaging(t *testing.T) {
+	runTest(t, "testvariables2", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponse(t)
+
+		client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+
+		client.ConfigurationDoneRequest()
+		client.ExpectConfigurationDoneResponse(t)
+		stopEvent := client.ExpectStoppedEvent(t)
+
+		client.StackTraceRequest(stopEvent.Body.ThreadId, 0, 20)
+		stResp := client.ExpectStackTraceResponse(t)
+		if len(stResp.Body.StackFrames) == 0 || stResp.Body.StackFrames[0].Name != "main.main" {
+			t.Fatalf("got %#v, want StackFrames[0].Name=main.main", stResp.Body.StackFrames)
+		}
+
+		client.ScopesRequest(stResp.Body.StackFrames[0].Id)
+		scResp := client.ExpectScopesResponse(t)
+		if len(scResp.Body.Scopes) != 2 || scResp.Body.Scopes[1].Name != "Locals" {
+			t.Fatalf("got %#v, want Arguments and Locals scopes", scResp.Body.Scopes)
+		}
+
+		client.VariablesRequest(scResp.Body.Scopes[1].VariablesReference, 0, 0)
+		locals := client.ExpectVariablesResponse(t).Body.Variables
+		findLocal := func(name string) dap.Variable {
+			t.Helper()
+			for _, v := range locals {
+				if v.Name == name {
+					return v
+				}
+			}
+			t.Fatalf("local variable %s not found in %#v", name, locals)
+			return dap.Variable{}
+		}
+
+		s4 := findLocal("s4")
+		if s4.IndexedVariables != 10 || s4.VariablesReference == 0 {
+			t.Fatalf("got %#v, want IndexedVariables=10 and a reference", s4)
+		}
+		client.VariablesRequest(s4.VariablesReference, 3, 4)
+		elems := client.ExpectVariablesResponse(t).Body.Variables
+		if len(elems) != 4 {
+			t.Fatalf("got %#v, want 4 elements", elems)
+		}
+		for i, elem := range elems {
+			if elem.Name != fmt.Sprintf("[%d]", i+3) || elem.Value != fmt.Sprintf("%d", i+4) {
+				t.Errorf("got %#v for element %d", elem, i+3)
+			}
+		}
+
+		m3 := findLocal("m3")
+		if m3.IndexedVariables != 2 || m3.VariablesReference == 0 {
+			t.Fatalf("got %#v, want IndexedVariables=2 and a reference", m3)
+		}
+		client.VariablesRequest(m3.VariablesReference, 1, 1)
+		entries := client.ExpectVariablesResponse(t).Body.Variables
+		if len(entries) != 1 || (entries[0].Value != "42" && entries[0].Value != "43") {
+			t.Errorf("got %#v, want one map entry", entries)
+		}
+
+		client.DisconnectRequest()
+		client.ExpectDisconnectResponse(t)
+	})
+}
+
+func TestOutputEvents(t *testing.T) {
+	runTest(t, "outputprog", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponse(t)
+
+		client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+
+		client.ConfigurationDoneRequest()
+		client.ExpectConfigurationDoneResponse(t)
+
+		// The output captured after the target exits is sent after the
+		// terminated event.
+		output := map[string]string{}
+		readOutput := func(until func(dap.Message) bool) {
+			t.Helper()
+			for {
+				m, err := client.ReadMessage()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if e, ok := m.(*dap.OutputEvent); ok {
+					output[e.Body.Category] += e.Body.Output
+					continue
+				}
+				if until(m) {
+					return
+				}
+				t.Fatalf("got %#v, want output events", m)
+			}
+		}
+		readOutput(func(m dap.Message) bool {
+			_, ok := m.(*dap.TerminatedEvent)
+			return ok
+		})
+		client.DisconnectRequest()
+		readOutput(func(m dap.Message) bool {
+			_, ok := m.(*dap.DisconnectResponse)
+			return ok
+		})
+		if output["stdout"] != "hello, stdout\n" || output["stderr"] != "hello, stderr\n" {
+			t.Errorf("wrong output events %#v", output)
+		}
+	})
+}
+
+func TestCancelUnknownRequest(t *testing.T) {
+	runTestWithServer(t, "increment", func(client *daptest.Client, fixture protest.Fixture, s *Server) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponse(t)
+
+		// Cancelling requests that were already handled, or that were
+		// never sent, must not leave anything behind.
+		client.CancelRequest(1)
+		client.ExpectCancelResponse(t)
+		client.CancelRequest(1000)
+		client.ExpectCancelResponse(t)
+
+		s.requestMutex.Lock()
+		n := len(s.queued)
+		s.requestMutex.Unlock()
+		if n != 0 {
+			t.Errorf("got %d queued requests after cancelling unknown requests, want 0", n)
+		}
+
+		client.DisconnectRequest()
+		client.ExpectDisconnectResponse(t)
+	})
+}
+
 // runDebugSesion is a helper for executing the standard init and shutdown
 // sequences for a program that does not stop on entry
 // while specifying unique launch criteria via parameters.
@@ -389,9 +675,6 @@ func TestUnupportedCommandResponses(t *testing.T) {
 			seqCnt++
 		}
 
-		client.RestartFrameRequest()
-		expectUnsupportedCommand("restartFrame")
-
 		client.GotoRequest()
 		expectUnsupportedCommand("goto")
 
@@ -401,18 +684,9 @@ func TestUnupportedCommandResponses(t *testing.T) {
 		client.TerminateThreadsRequest()
 		expectUnsupportedCommand("terminateThreads")
 
-		client.StepInTargetsRequest()
-		expectUnsupportedCommand("stepInTargets")
-
 		client.GotoTargetsRequest()
 		expectUnsupportedCommand("gotoTargets")
 
-		client.CompletionsRequest()
-		expectUnsupportedCommand("completions")
-
-		client.ExceptionInfoRequest()
-		expectUnsupportedCommand("exceptionInfo")
-
 		client.DataBreakpointInfoRequest()
 		expectUnsupportedCommand("dataBreakpointInfo")
 
@@ -446,24 +720,12 @@ func TestRequiredNotYetImplementedResponses(t *testing.T) {
 		client.NextRequest()
 		expectNotYetImplemented("next")
 
-		client.StepInRequest()
-		expectNotYetImplemented("stepIn")
-
 		client.StepOutRequest()
 		expectNotYetImplemented("stepOut")
 
 		client.PauseRequest()
 		expectNotYetImplemented("pause")
 
-		client.StackTraceRequest()
-		expectNotYetImplemented("stackTrace")
-
-		client.ScopesRequest()
-		expectNotYetImplemented("scopes")
-
-		client.VariablesRequest()
-		expectNotYetImplemented("variables")
-
 		client.EvaluateRequest()
 		expectNotYetImplemented("evaluate")
 	})
@@ -491,12 +753,6 @@ func TestOptionalNotYetImplementedResponses(t *testing.T) {
 		client.SetFunctionBreakpointsRequest()
 		expectNotYetImplemented("setFunctionBreakpoints")
 
-		client.StepBackRequest()
-		expectNotYetImplemented("stepBack")
-
-		client.ReverseContinueRequest()
-		expectNotYetImplemented("reverseContinue")
-
 		client.SetVariableRequest()
 		expectNotYetImplemented("setVariable")
 
@@ -511,9 +767,149 @@ func TestOptionalNotYetImplementedResponses(t *testing.T) {
 
 		client.DisassembleRequest()
 		expectNotYetImplemented("disassemble")
+	})
+}
 
-		client.CancelRequest()
-		expectNotYetImplemented("cancel")
+// TestReverseExecutionNotRecorded checks that the requests executing the
+// target backwards fail when the target is not a recording.
+func TestReverseExecutionNotRecorded(t *testing.T) {
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		initResp := client.ExpectInitializeResponse(t)
+		if initResp.Body.SupportsStepBack || initResp.Body.SupportsRestartFrame {
+			t.Errorf("got %#v, want SupportsStepBack=false SupportsRestartFrame=false", initResp.Body)
+		}
+
+		client.LaunchRequest("exec", fixture.Path, stopOnEntry)
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+
+		expectError := func(id int, message string) {
+			t.Helper()
+			got := client.ExpectErrorResponse(t)
+			if got.Body.Error.Id != id || got.Message != message || got.Body.Error.Format != message+": not a recording" {
+				t.Errorf("\ngot  %#v\nwant Id=%d Message=%q", got, id, message)
+			}
+		}
+
+		client.StepBackRequest(1)
+		expectError(UnableToStepBack, "Unable to step back")
+
+		client.ReverseContinueRequest(1)
+		expectError(FailedToContinue, "Unable to reverse continue")
+
+		client.RestartFrameRequest(startHandle)
+		expectError(UnableToRestartFrame, "Unable to restart frame")
+
+		client.DisconnectRequest()
+		client.ExpectDisconnectResponse(t)
+	})
+}
+
+// TestStepBack records increment, stops at a breakpoint and steps back to
+// the previous line.
+func TestStepBack(t *testing.T) {
+	if _, err := exec.LookPath("rr"); err != nil {
+		t.Skip("test skipped, rr not found")
+	}
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponse(t)
+
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "record", "program": fixture.Source})
+		capEvent := client.ExpectCapabilitiesEvent(t)
+		if !capEvent.Body.Capabilities.SupportsStepBack || !capEvent.Body.Capabilities.SupportsRestartFrame {
+			t.Errorf("got %#v, want SupportsStepBack=true SupportsRestartFrame=true", capEvent.Body)
+		}
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+
+		client.SetBreakpointsRequest(fixture.Source, []int{8})
+		client.ExpectSetBreakpointsResponse(t)
+		client.ConfigurationDoneRequest()
+		client.ExpectConfigurationDoneResponse(t)
+		client.ExpectStoppedEvent(t)
+
+		client.StepBackRequest(1)
+		client.ExpectStepBackResponse(t)
+		stopEvent := client.ExpectStoppedEvent(t)
+		if stopEvent.Body.Reason != "step" {
+			t.Errorf("got %#v, want Reason=\"step\"", stopEvent)
+		}
+
+		client.StackTraceRequest(stopEvent.Body.ThreadId, 0, 1)
+		stResp := client.ExpectStackTraceResponse(t)
+		if len(stResp.Body.StackFrames) != 1 || stResp.Body.StackFrames[0].Line != 7 {
+			t.Errorf("got %#v, want one frame at line 7", stResp.Body.StackFrames)
+		}
+
+		client.DisconnectRequest()
+		client.ExpectDisconnectResponse(t)
+	})
+}
+
+// TestRestartFrame records increment, stops in a recursive call and
+// restarts the frame of its caller, which goes back to the entry of the
+// caller.
+func TestRestartFrame(t *testing.T) {
+	if _, err := exec.LookPath("rr"); err != nil {
+		t.Skip("test skipped, rr not found")
+	}
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponse(t)
+
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "record", "program": fixture.Source})
+		client.ExpectCapabilitiesEvent(t)
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+
+		client.SetBreakpointsRequest(fixture.Source, []int{11})
+		client.ExpectSetBreakpointsResponse(t)
+		client.ConfigurationDoneRequest()
+		client.ExpectConfigurationDoneResponse(t)
+
+		stackTrace := func(threadID int) []dap.StackFrame {
+			t.Helper()
+			client.StackTraceRequest(threadID, 0, 20)
+			return client.ExpectStackTraceResponse(t).Body.StackFrames
+		}
+
+		// Increment(3)
+		stopEvent := client.ExpectStoppedEvent(t)
+
+		// Increment(1), called by Increment(3)
+		client.ContinueRequest(stopEvent.Body.ThreadId)
+		client.ExpectContinueResponse(t)
+		stopEvent = client.ExpectStoppedEvent(t)
+		frames := stackTrace(stopEvent.Body.ThreadId)
+		if len(frames) < 3 || frames[1].Name != "main.Increment" || frames[2].Name != "main.main" {
+			t.Fatalf("got %#v, want StackFrames[1].Name=main.Increment StackFrames[2].Name=main.main", frames)
+		}
+
+		client.RestartFrameRequest(frames[1].Id)
+		client.ExpectRestartFrameResponse(t)
+		stopEvent = client.ExpectStoppedEvent(t)
+		if stopEvent.Body.Reason != "restart" {
+			t.Errorf("got %#v, want Reason=\"restart\"", stopEvent)
+		}
+		frames = stackTrace(stopEvent.Body.ThreadId)
+		if len(frames) < 2 || frames[0].Name != "main.Increment" || frames[0].Line != 6 || frames[1].Name != "main.main" {
+			t.Errorf("got %#v, want main.Increment at line 6 called by main.main", frames)
+		}
+
+		// Restarting the same frame again goes back to the checkpoint taken
+		// at its entry.
+		client.RestartFrameRequest(frames[0].Id)
+		client.ExpectRestartFrameResponse(t)
+		stopEvent = client.ExpectStoppedEvent(t)
+		frames = stackTrace(stopEvent.Body.ThreadId)
+		if len(frames) < 2 || frames[0].Name != "main.Increment" || frames[0].Line != 6 || frames[1].Name != "main.main" {
+			t.Errorf("got %#v, want main.Increment at line 6 called by main.main", frames)
+		}
+
+		client.DisconnectRequest()
+		client.ExpectDisconnectResponse(t)
 	})
 }
 
@@ -566,6 +962,10 @@ func TestBadLaunchRequests(t *testing.T) {
 		expectFailedToLaunchWithMessage(client.ExpectErrorResponse(t),
 			"Failed to launch: Unsupported 'mode' value \"remote\" in debug configuration.")
 
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "replay", "program": fixture.Path})
+		expectFailedToLaunchWithMessage(client.ExpectErrorResponse(t),
+			"Failed to launch: The 'traceDirPath' attribute is missing in debug configuration.")
+
 		client.LaunchRequest("notamode", fixture.Path, stopOnEntry)
 		expectFailedToLaunchWithMessage(client.ExpectErrorResponse(t),
 			"Failed to launch: Unsupported 'mode' value \"notamode\" in debug configuration.")
@@ -619,3 +1019,75 @@ func TestBadlyFormattedMessageToServer(t *testing.T) {
 		}
 	})
 }
+
+func TestReadExceptionFilterOptions(t *testing.T) {
+	msg := `{"seq":3,"type":"request","command":"setExceptionBreakpoints","arguments":{"filters":[],"filterOptions":[{"filterId":"panic","condition":"custom.*"}]}}`
+	buf := new(bytes.Buffer)
+	dap.WriteBaseMessage(buf, []byte(msg))
+	request, err := readProtocolMessage(bufio.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, ok := request.(*setExceptionBreakpointsRequest)
+	if !ok {
+		t.Fatalf("wrong request type %T", request)
+	}
+	if req.Seq != 3 || len(req.Arguments.FilterOptions) != 1 || req.Arguments.FilterOptions[0].FilterID != panicExceptionFilter || req.Arguments.FilterOptions[0].Condition != "custom.*" {
+		t.Errorf("wrong request %#v", req)
+	}
+}
+
+func TestSendLibraryLoaded(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	s := &Server{conn: serverConn, log: logflags.DAPLogger(), loadedSources: map[string]bool{"/go/src/runtime/proc.go": true}}
+	go func() {
+		defer serverConn.Close()
+		sources := []string{"/go/src/runtime/proc.go", "/plugin/plugin.go"}
+		s.sendLibraryLoaded(&api.Image{Path: "/plugin/plugin.so"}, sources)
+		s.sendLibraryLoaded(&api.Image{Path: "/plugin/plugin2.so"}, sources)
+	}()
+
+	// Only the sources that weren't already known are sent.
+	var got []string
+	rd := bufio.NewReader(clientConn)
+	for {
+		msg, err := dap.ReadProtocolMessage(rd)
+		if err != nil {
+			break
+		}
+		switch e := msg.(type) {
+		case *dap.ModuleEvent:
+			got = append(got, "module "+e.Body.Module.Path)
+		case *dap.LoadedSourceEvent:
+			got = append(got, "loadedSource "+e.Body.Source.Path)
+		default:
+			t.Fatalf("unexpected message %#v", msg)
+		}
+	}
+	tgt := []string{"module /plugin/plugin.so", "loadedSource /plugin/plugin.go", "module /plugin/plugin2.so"}
+	if !reflect.DeepEqual(got, tgt) {
+		t.Errorf("wrong events %q, expected %q", got, tgt)
+	}
+}
+
+func TestIncompleteRuneLen(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		n    int
+	}{
+		{"", 0},
+		{"hello", 0},
+		{"hé", 0},
+		{"h\xc3", 1},
+		{"€", 0},
+		{"h\xe2\x82", 2},
+		{"\U0001F600", 0},
+		{"\xf0\x9f\x98", 3},
+		{"\x98\x80", 0},
+	} {
+		if n := incompleteRuneLen([]byte(tc.data)); n != tc.n {
+			t.Errorf("incompleteRuneLen(%q) = %d, expected %d", tc.data, n, tc.n)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package debugger
+
+import (
+	"go/constant"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/service/api"
+)
+
+// maxCompletions is the maximum number of candidates returned by Complete.
+const maxCompletions = 1000
+
+// Complete returns the list of possible completions for text, interpreted
+// according to kind. The returned integer is the offset of the portion of
+// text that is being completed, each candidate is meant to replace
+// text[start:].
+func (d *Debugger) Complete(scope api.EvalScope, kind api.CompletionKind, text string) (int, []api.Completion, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	var c completer
+	var start int
+	switch kind {
+	case api.CompleteLocation:
+		// Locations only need the debug info, they can be completed after the
+		// target exits.
+		c.completeLocation(d.target.BinInfo(), text)
+	case api.CompleteExpression:
+		if _, err := d.target.Valid(); err != nil {
+			return 0, nil, err
+		}
+		s, err := proc.ConvertEvalScope(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
+		if err != nil {
+			return 0, nil, err
+		}
+		start = c.completeExpression(s, text)
+	}
+	return start, c.result(), nil
+}
+
+type completer struct {
+	seen map[string]bool
+	r    []api.Completion
+}
+
+func (c *completer) add(text, kind string) {
+	if c.seen == nil {
+		c.seen = make(map[string]bool)
+	}
+	if c.seen[text] || len(c.r) >= maxCompletions {
+		return
+	}
+	c.seen[text] = true
+	c.r = append(c.r, api.Completion{Text: text, Kind: kind})
+}
+
+func (c *completer) result() []api.Completion {
+	sort.Slice(c.r, func(i, j int) bool { return c.r[i].Text < c.r[j].Text })
+	return c.r
+}
+
+// completeLocation adds the functions and source files that match a
+// partial location specification. Every suffix of a function name or
+// file path starting after a '/' is considered, so that "pkg.Fn" and
+// "file.go" complete as well as their fully qualified forms.
+func (c *completer) completeLocation(bi *proc.BinaryInfo, text string) {
+	if strings.Contains(text, ":") || isNumber(text) || text != "" && strings.ContainsAny(text[:1], "*+-") {
+		// Line numbers, offsets and addresses can not be completed.
+		return
+	}
+	for i := range bi.Functions {
+		for _, s := range pathSuffixes(bi.Functions[i].Name) {
+			if strings.HasPrefix(s, text) {
+				c.add(s, "function")
+			}
+		}
+	}
+	for _, file := range bi.Sources {
+		for _, s := range pathSuffixes(file) {
+			if strings.HasPrefix(s, text) {
+				c.add(s+":", "file")
+			}
+		}
+	}
+}
+
+// pathSuffixes returns s followed by every suffix of s that starts after a
+// '/' character.
+func pathSuffixes(s string) []string {
+	r := []string{s}
+	for i := range s {
+		if s[i] == '/' && i+1 < len(s) {
+			r = append(r, s[i+1:])
+		}
+	}
+	return r
+}
+
+func isNumber(s string) bool {
+	for _, ch := range s {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// completeExpression adds candidates for the last operand of the
+// expression text and returns the offset where that operand starts.
+// Three forms are recognized:
+//  - a partial identifier, completed with local variables, function
+//    arguments and package variables
+//  - a partial selector <expr>.<ident>, completed with the fields of
+//    <expr> or, if <expr> is a package name, with the members of the package
+//  - a partial index <expr>[<key>, completed with the keys of the map <expr>
+func (c *completer) completeExpression(scope *proc.EvalScope, text string) int {
+	if p := unmatchedBracket(text); p >= 0 && isPartialLiteral(text[p+1:]) {
+		c.completeMapKey(scope, text[:p], text[p+1:])
+		return p + 1
+	}
+
+	start := len(text)
+	for start > 0 {
+		ch := rune(text[start-1])
+		if ch != '_' && !unicode.IsLetter(ch) && !unicode.IsDigit(ch) {
+			break
+		}
+		start--
+	}
+	prefix := text[start:]
+
+	if start > 0 && text[start-1] == '.' {
+		lhs := text[operandStart(text, start-1) : start-1]
+		if lhs != "" {
+			c.completeSelector(scope, lhs, prefix)
+		}
+		return start
+	}
+
+	if prefix == "" || unicode.IsDigit(rune(prefix[0])) {
+		return start
+	}
+
+	if vars, err := scope.Locals(); err == nil {
+		for _, v := range vars {
+			if strings.HasPrefix(v.Name, prefix) {
+				c.add(v.Name, "variable")
+			}
+		}
+	}
+	curpkg := ""
+	if scope.Fn != nil {
+		curpkg = scope.Fn.PackageName()
+	}
+	for _, name := range scope.BinInfo.PackageVarNames() {
+		pkg, short := splitPackageVarName(name)
+		if pkg == curpkg && strings.HasPrefix(short, prefix) {
+			c.add(short, "variable")
+		}
+		if pkgname := pkg[strings.LastIndex(pkg, "/")+1:]; strings.HasPrefix(pkgname, prefix) && isIdentifier(pkgname) {
+			c.add(pkgname+".", "package")
+		}
+	}
+	return start
+}
+
+func (c *completer) completeSelector(scope *proc.EvalScope, lhs, prefix string) {
+	if isIdentifier(lhs) {
+		if paths, ispkg := scope.BinInfo.PackageMap[lhs]; ispkg {
+			for _, path := range paths {
+				c.completePackageMembers(scope.BinInfo, path, prefix)
+			}
+			return
+		}
+	}
+
+	v, err := scope.EvalExpression(lhs, proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 0, MaxStringLen: 0, MaxArrayValues: 0, MaxStructFields: -1})
+	if err != nil || v.Unreadable != nil {
+		return
+	}
+	typ := v.RealType
+	if v.Kind == reflect.Interface && len(v.Children) > 0 {
+		typ = v.Children[0].RealType
+	}
+	c.completeFields(typ, prefix, 0)
+}
+
+// completeFields adds the fields of typ, and the fields promoted from its
+// embedded structs, starting with prefix.
+func (c *completer) completeFields(typ godwarf.Type, prefix string, depth int) {
+	const maxEmbeddingDepth = 5
+	if depth > maxEmbeddingDepth {
+		return
+	}
+	typ = resolveTypedef(typ)
+	if ptyp, isptr := typ.(*godwarf.PtrType); isptr {
+		typ = resolveTypedef(ptyp.Type)
+	}
+	styp, isstruct := typ.(*godwarf.StructType)
+	if !isstruct {
+		return
+	}
+	for _, field := range styp.Field {
+		if strings.HasPrefix(field.Name, prefix) {
+			c.add(field.Name, "field")
+		}
+		if field.Embedded {
+			c.completeFields(field.Type, prefix, depth+1)
+		}
+	}
+}
+
+func (c *completer) completePackageMembers(bi *proc.BinaryInfo, path, prefix string) {
+	for _, name := range bi.PackageVarNames() {
+		if pkg, short := splitPackageVarName(name); pkg == path && strings.HasPrefix(short, prefix) {
+			c.add(short, "variable")
+		}
+	}
+	for i := range bi.Functions {
+		fn := &bi.Functions[i]
+		if fn.PackageName() != path || fn.ReceiverName() != "" {
+			continue
+		}
+		if short := fn.BaseName(); strings.HasPrefix(short, prefix) && isIdentifier(short) {
+			c.add(short, "function")
+		}
+	}
+}
+
+func (c *completer) completeMapKey(scope *proc.EvalScope, text, prefix string) {
+	lhs := text[operandStart(text, len(text)):]
+	if lhs == "" {
+		return
+	}
+	v, err := scope.EvalExpression(lhs, proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 0, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1})
+	if err != nil || v.Unreadable != nil || v.Kind != reflect.Map {
+		return
+	}
+	for i := 0; i+1 < len(v.Children); i += 2 {
+		key := &v.Children[i]
+		if key.Unreadable != nil || key.Value == nil {
+			continue
+		}
+		var s string
+		switch key.Value.Kind() {
+		case constant.String:
+			s = strconv.Quote(constant.StringVal(key.Value))
+		case constant.Int, constant.Bool:
+			s = key.Value.ExactString()
+		default:
+			continue
+		}
+		if strings.HasPrefix(s, prefix) {
+			c.add(s+"]", "key")
+		}
+	}
+}
+
+// unmatchedBracket returns the position of the last '[' in text that is
+// not closed by a matching ']', or -1.
+func unmatchedBracket(text string) int {
+	var open []int
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		if quote != 0 {
+			switch {
+			case ch == '\\' && quote != '`':
+				i++
+			case ch == quote:
+				quote = 0
+			}
+			continue
+		}
+		switch ch {
+		case '"', '\'', '`':
+			quote = ch
+		case '[':
+			open = append(open, i)
+		case ']':
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	if len(open) == 0 {
+		return -1
+	}
+	return open[len(open)-1]
+}
+
+// isPartialLiteral returns true if s could be the beginning of a basic
+// literal.
+func isPartialLiteral(s string) bool {
+	if s == "" {
+		return true
+	}
+	if s[0] == '"' || s[0] == '`' {
+		return !strings.ContainsAny(s[1:], s[:1])
+	}
+	return s == "-" || isNumber(strings.TrimPrefix(s, "-")) || strings.HasPrefix("true", s) || strings.HasPrefix("false", s)
+}
+
+// operandStart returns the starting position of the operand that ends at
+// position end of text. Balanced parenthesis and square brackets are
+// considered part of the operand.
+func operandStart(text string, end int) int {
+	depth := 0
+	i := end
+	for i > 0 {
+		ch := text[i-1]
+		switch {
+		case ch == ')' || ch == ']':
+			depth++
+		case ch == '(' || ch == '[':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case depth > 0:
+			// anything goes inside brackets
+		case ch == '.' || ch == '_' || ch == '*' && i-1 > 0 && text[i-2] == '(' || unicode.IsLetter(rune(ch)) || unicode.IsDigit(rune(ch)):
+			// part of an identifier or of a selector
+		default:
+			return i
+		}
+		i--
+	}
+	return i
+}
+
+func isIdentifier(s string) bool {
+	for i, ch := range s {
+		if ch != '_' && !unicode.IsLetter(ch) && (i == 0 || !unicode.IsDigit(ch)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// splitPackageVarName splits the name of a package variable into the
+// package path and the name of the variable.
+func splitPackageVarName(name string) (pkg, short string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	dot += slash + 1
+	return name[:dot], name[dot+1:]
+}
+
+func resolveTypedef(typ godwarf.Type) godwarf.Type {
+	for {
+		if tttyp, ok := typ.(*godwarf.TypedefType); ok {
+			typ = tttyp.Type
+		} else {
+			return typ
+		}
+	}
+}
@@ -2,6 +2,7 @@ package debugger
 
 import (
 	"bytes"
+	"context"
 	"debug/dwarf"
 	"errors"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"regexp"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -61,6 +63,24 @@ type Debugger struct {
 
 	stopRecording func() error
 	recordMutex   sync.Mutex
+
+	events eventQueue
+	output outputBuffer
+
+	// pendingBreakpoints are the pending and on-load breakpoints, see
+	// libraries.go.
+	pendingBreakpoints []*api.Breakpoint
+	// resolvedPending maps the IDs of the breakpoints that were created as
+	// pending breakpoints and have been set since to their pending
+	// definition, so that Restart can make them pending again.
+	resolvedPending map[int]*api.Breakpoint
+	// onLoadStop is the on-load breakpoint hit since the target was last
+	// resumed.
+	onLoadStop *api.Breakpoint
+
+	// warnings are the problems found while loading the target, they do
+	// not change after New returns.
+	warnings []string
 }
 
 // Config provides the configuration to start a Debugger.
@@ -79,6 +99,17 @@ type Config struct {
 
 	// CoreFile specifies the path to the core dump to open.
 	CoreFile string
+	// CoreSysroot is the directory used as the root when looking for the
+	// files mapped by the process of a core dump, the memory of mappings that
+	// are missing from the core file is read from them.
+	CoreSysroot string
+	// CoreCacheDir is the directory where the decompressed contents of a
+	// compressed core file are stored, the default directory for temporary
+	// files if empty.
+	CoreCacheDir string
+	// CoreTraceback is true if CoreFile is the output of a Go program that
+	// crashed, containing a goroutine dump, instead of a core file.
+	CoreTraceback bool
 
 	// Backend specifies the debugger backend.
 	Backend string
@@ -98,6 +129,31 @@ type Config struct {
 	// TTY is passed along to the target process on creation. Used to specify a
 	// TTY for that process.
 	TTY string
+
+	// CaptureOutput is true if the standard output and standard error of
+	// the target process should be captured and made available to clients
+	// instead of being inherited from the debugger.
+	CaptureOutput bool
+
+	// OutputBufferSize is the number of bytes of captured output kept for
+	// clients that connect after it was written. If zero
+	// DefaultOutputBufferSize is used.
+	OutputBufferSize int
+
+	// RrOnProcessPid is the pid of the process to replay, when replaying a
+	// trace with the rr backend. If zero the first process of the trace is
+	// replayed.
+	RrOnProcessPid int
+
+	// GdbRemoteAddr is the address of a stub speaking the gdb remote serial
+	// protocol, already debugging the target process, to connect to.
+	GdbRemoteAddr string
+}
+
+// AttachedToExistingProcess returns true if the target process was not
+// started by the debugger.
+func (c *Config) AttachedToExistingProcess() bool {
+	return c.AttachPid != 0 || c.GdbRemoteAddr != ""
 }
 
 // New creates a new Debugger. ProcessArgs specify the commandline arguments for the
@@ -105,13 +161,32 @@ type Config struct {
 func New(config *Config, processArgs []string) (*Debugger, error) {
 	logger := logflags.DebuggerLogger()
 	d := &Debugger{
-		config:      config,
-		processArgs: processArgs,
-		log:         logger,
+		config:          config,
+		processArgs:     processArgs,
+		log:             logger,
+		resolvedPending: make(map[int]*api.Breakpoint),
 	}
+	d.output.max = config.OutputBufferSize
 
 	// Create the process by either attaching or launching.
 	switch {
+	case d.config.GdbRemoteAddr != "":
+		d.log.Infof("connecting to stub at %s", d.config.GdbRemoteAddr)
+		path := ""
+		if len(d.processArgs) > 0 {
+			path = d.processArgs[0]
+		}
+		p, err := gdbserial.ConnectRemote(d.config.GdbRemoteAddr, path, d.config.DebugInfoDirectories)
+		if err != nil {
+			err = go11DecodeErrorCheck(err)
+			return nil, fmt.Errorf("could not connect to %s: %v", d.config.GdbRemoteAddr, err)
+		}
+		d.target = p
+		if err := d.checkGoVersion(); err != nil {
+			d.target.Detach(false)
+			return nil, err
+		}
+
 	case d.config.AttachPid > 0:
 		d.log.Infof("attaching to pid %d", d.config.AttachPid)
 		path := ""
@@ -131,10 +206,15 @@ func New(config *Config, processArgs []string) (*Debugger, error) {
 		switch d.config.Backend {
 		case "rr":
 			d.log.Infof("opening trace %s", d.config.CoreFile)
-			p, err = gdbserial.Replay(d.config.CoreFile, false, false, d.config.DebugInfoDirectories)
+			p, err = gdbserial.Replay(d.config.CoreFile, false, false, d.config.DebugInfoDirectories, d.config.RrOnProcessPid)
 		default:
+			if d.config.CoreTraceback {
+				d.log.Infof("opening traceback %s (executable %s)", d.config.CoreFile, d.processArgs[0])
+				p, err = core.OpenTraceback(d.config.CoreFile, d.processArgs[0], d.config.DebugInfoDirectories)
+				break
+			}
 			d.log.Infof("opening core file %s (executable %s)", d.config.CoreFile, d.processArgs[0])
-			p, err = core.OpenCore(d.config.CoreFile, d.processArgs[0], d.config.DebugInfoDirectories)
+			p, err = core.OpenCore(d.config.CoreFile, d.processArgs[0], d.config.DebugInfoDirectories, d.config.CoreSysroot, d.config.CoreCacheDir)
 		}
 		if err != nil {
 			err = go11DecodeErrorCheck(err)
@@ -145,6 +225,7 @@ func New(config *Config, processArgs []string) (*Debugger, error) {
 			d.target.Detach(true)
 			return nil, err
 		}
+		d.warnings = p.Warnings()
 
 	default:
 		d.log.Infof("launching process with args: %v", d.processArgs)
@@ -168,10 +249,16 @@ func New(config *Config, processArgs []string) (*Debugger, error) {
 	return d, nil
 }
 
+// Warnings returns the problems found while loading the target that did
+// not prevent it from being loaded.
+func (d *Debugger) Warnings() []string {
+	return d.warnings
+}
+
 // canRestart returns true if the target was started with Launch and can be restarted
 func (d *Debugger) canRestart() bool {
 	switch {
-	case d.config.AttachPid > 0:
+	case d.config.AttachedToExistingProcess():
 		return false
 	case d.config.CoreFile != "":
 		return false
@@ -202,9 +289,9 @@ func (d *Debugger) Launch(processArgs []string, wd string) (*proc.Target, error)
 	}
 	switch d.config.Backend {
 	case "native":
-		return native.Launch(processArgs, wd, d.config.Foreground, d.config.DebugInfoDirectories, d.config.TTY)
+		return native.Launch(processArgs, wd, d.config.Foreground, d.config.DebugInfoDirectories, d.config.TTY, d.outputRedirect())
 	case "lldb":
-		return betterGdbserialLaunchError(gdbserial.LLDBLaunch(processArgs, wd, d.config.Foreground, d.config.DebugInfoDirectories, d.config.TTY))
+		return betterGdbserialLaunchError(gdbserial.LLDBLaunch(processArgs, wd, d.config.Foreground, d.config.DebugInfoDirectories, d.config.TTY, d.outputRedirect()))
 	case "rr":
 		if d.target != nil {
 			// restart should not call us if the backend is 'rr'
@@ -246,9 +333,9 @@ func (d *Debugger) Launch(processArgs []string, wd string) (*proc.Target, error)
 
 	case "default":
 		if runtime.GOOS == "darwin" {
-			return betterGdbserialLaunchError(gdbserial.LLDBLaunch(processArgs, wd, d.config.Foreground, d.config.DebugInfoDirectories, d.config.TTY))
+			return betterGdbserialLaunchError(gdbserial.LLDBLaunch(processArgs, wd, d.config.Foreground, d.config.DebugInfoDirectories, d.config.TTY, d.outputRedirect()))
 		}
-		return native.Launch(processArgs, wd, d.config.Foreground, d.config.DebugInfoDirectories, d.config.TTY)
+		return native.Launch(processArgs, wd, d.config.Foreground, d.config.DebugInfoDirectories, d.config.TTY, d.outputRedirect())
 	default:
 		return nil, fmt.Errorf("unknown backend %q", d.config.Backend)
 	}
@@ -278,7 +365,7 @@ func (d *Debugger) recordingRun(run func() (string, error)) (*proc.Target, error
 		return nil, err
 	}
 
-	return gdbserial.Replay(tracedir, false, true, d.config.DebugInfoDirectories)
+	return gdbserial.Replay(tracedir, false, true, d.config.DebugInfoDirectories, 0)
 }
 
 // Attach will attach to the process specified by 'pid'.
@@ -379,9 +466,10 @@ func (d *Debugger) Detach(kill bool) error {
 }
 
 func (d *Debugger) detach(kill bool) error {
-	if d.config.AttachPid == 0 {
+	if !d.config.AttachedToExistingProcess() {
 		kill = true
 	}
+	defer d.events.publish(api.Event{Kind: api.EventDetached})
 	return d.target.Detach(kill)
 }
 
@@ -437,18 +525,99 @@ func (d *Debugger) Restart(rerecord bool, pos string, resetArgs bool, newArgs []
 		return nil, fmt.Errorf("could not launch process: %s", err)
 	}
 
+	discarded, err := d.recreateBreakpoints(p)
+	if err != nil {
+		return nil, err
+	}
+	d.target = p
+	d.events.publish(api.Event{Kind: api.EventRestarted})
+	return discarded, nil
+}
+
+// ReplayProcess replaces the target, which must be a recording, with a new
+// replay of the same trace debugging the process with the given pid.
+// Breakpoints are recreated in the new target.
+func (d *Debugger) ReplayProcess(pid int) ([]api.DiscardedBreakpoint, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	p, err := gdbserial.ReplayProcess(d.target, pid, false, d.config.DebugInfoDirectories)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.detach(true); err != nil {
+		d.log.Warnf("could not detach from the previous replay: %v", err)
+	}
+	discarded, err := d.recreateBreakpoints(p)
+	d.target = p
+	d.events.publish(api.Event{Kind: api.EventRestarted})
+	if err != nil {
+		return nil, err
+	}
+	return discarded, nil
+}
+
+// recreateBreakpoints recreates the breakpoints of the current target in p.
+func (d *Debugger) recreateBreakpoints(p *proc.Target) ([]api.DiscardedBreakpoint, error) {
+	// Breakpoints are recreated in order of ID and keep their IDs.
 	discarded := []api.DiscardedBreakpoint{}
-	for _, oldBp := range api.ConvertBreakpoints(d.breakpoints()) {
+	// Recordings execute deterministically, goroutine IDs are only lost when
+	// a new process is launched.
+	recorded, _ := p.Recorded()
+	resolvedPending := d.resolvedPending
+	d.resolvedPending = make(map[int]*api.Breakpoint)
+	for _, oldBp := range d.allBreakpoints() {
 		if oldBp.ID < 0 {
 			continue
 		}
-		if len(oldBp.File) > 0 {
+		p.Breakpoints().ReserveLogicalID(oldBp.ID)
+		if oldBp.Pending || oldBp.OnLoad != "" {
+			// still in d.pendingBreakpoints
+			continue
+		}
+		if oldBp.GoroutineFilter != nil && oldBp.GoroutineFilter.ID != 0 && !recorded {
+			discarded = append(discarded, api.DiscardedBreakpoint{Breakpoint: oldBp, Reason: "goroutine IDs are not preserved across restarts"})
+			continue
+		}
+		var newID int
+		if orig := resolvedPending[oldBp.ID]; orig != nil {
+			addrs, err := pendingBreakpointLocation(p, orig)
+			if err == errPendingLocation {
+				d.restorePendingBreakpoint(oldBp, orig)
+				continue
+			}
+			var createdBp *api.Breakpoint
+			if err == nil {
+				createdBp, err = createLogicalBreakpoint(p, addrs, oldBp)
+			}
+			if err != nil {
+				discarded = append(discarded, api.DiscardedBreakpoint{Breakpoint: oldBp, Reason: err.Error()})
+				continue
+			}
+			d.resolvedPending[oldBp.ID] = orig
+			newID = createdBp.ID
+		} else if oldBp.Panic != nil {
+			addrs, err := proc.FindFunctionLocation(p, "runtime.gopanic", 0)
+			var createdBp *api.Breakpoint
+			if err == nil {
+				createdBp, err = createPanicBreakpoint(p, addrs, oldBp)
+			}
+			if err != nil {
+				discarded = append(discarded, api.DiscardedBreakpoint{Breakpoint: oldBp, Reason: err.Error()})
+				continue
+			}
+			newID = createdBp.ID
+		} else if len(oldBp.File) > 0 {
 			addrs, err := proc.FindFileLocation(p, oldBp.File, oldBp.Line)
+			var createdBp *api.Breakpoint
+			if err == nil {
+				createdBp, err = createLogicalBreakpoint(p, addrs, oldBp)
+			}
 			if err != nil {
 				discarded = append(discarded, api.DiscardedBreakpoint{Breakpoint: oldBp, Reason: err.Error()})
 				continue
 			}
-			createLogicalBreakpoint(p, addrs, oldBp)
+			newID = createdBp.ID
 		} else {
 			newBp, err := p.SetBreakpoint(oldBp.Addr, proc.UserBreakpoint, nil)
 			if err != nil {
@@ -457,9 +626,10 @@ func (d *Debugger) Restart(rerecord bool, pos string, resetArgs bool, newArgs []
 			if err := copyBreakpointInfo(newBp, oldBp); err != nil {
 				return nil, err
 			}
+			newID = newBp.LogicalID
 		}
+		p.Breakpoints().ChangeLogicalID(newID, oldBp.ID)
 	}
-	d.target = p
 	return discarded, nil
 }
 
@@ -515,6 +685,14 @@ func (d *Debugger) state(retLoadCfg *proc.LoadConfig) (*api.DebuggerState, error
 		}
 	}
 
+	if d.onLoadStop != nil {
+		bp := *d.onLoadStop
+		state.OnLoadBreakpoint = &bp
+		if d.target.StopReason == proc.StopLibraryLoaded && state.CurrentThread != nil {
+			state.CurrentThread.Breakpoint = &bp
+		}
+	}
+
 	state.NextInProgress = d.target.Breakpoints().HasInternalBreakpoints()
 
 	if recorded, _ := d.target.Recorded(); recorded {
@@ -544,8 +722,17 @@ func (d *Debugger) CreateBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoin
 	}
 
 	switch {
+	case requestedBp.OnLoad != "":
+		return d.createPendingBreakpoint(requestedBp)
+	case requestedBp.Pending:
+		addrs, err = pendingBreakpointLocation(d.target, requestedBp)
+		if err != nil {
+			return d.createPendingBreakpoint(requestedBp)
+		}
 	case requestedBp.TraceReturn:
 		addrs = []uint64{requestedBp.Addr}
+	case requestedBp.Panic != nil:
+		addrs, err = proc.FindFunctionLocation(d.target, "runtime.gopanic", 0)
 	case len(requestedBp.File) > 0:
 		fileName := requestedBp.File
 		if runtime.GOOS == "windows" {
@@ -571,11 +758,17 @@ func (d *Debugger) CreateBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoin
 		return nil, err
 	}
 
-	createdBp, err := createLogicalBreakpoint(d.target, addrs, requestedBp)
+	var createdBp *api.Breakpoint
+	if requestedBp.Panic != nil {
+		createdBp, err = createPanicBreakpoint(d.target, addrs, requestedBp)
+	} else {
+		createdBp, err = createLogicalBreakpoint(d.target, addrs, requestedBp)
+	}
 	if err != nil {
 		return nil, err
 	}
 	d.log.Infof("created breakpoint: %#v", createdBp)
+	d.events.publish(api.Event{Kind: api.EventBreakpointCreated, Breakpoint: createdBp})
 	return createdBp, nil
 }
 
@@ -616,6 +809,24 @@ func createLogicalBreakpoint(p proc.Process, addrs []uint64, requestedBp *api.Br
 	return createdBp[0], nil // we created a single logical breakpoint, the slice here will always have len == 1
 }
 
+// createPanicBreakpoint creates a panic breakpoint at addrs, which must be
+// the location of runtime.gopanic. Panic breakpoints with different filters
+// share the same physical breakpoint.
+func createPanicBreakpoint(p *proc.Target, addrs []uint64, requestedBp *api.Breakpoint) (*api.Breakpoint, error) {
+	if bp, ok := p.Breakpoints().M[addrs[0]]; !ok || !bp.IsUser() || bp.Panic == nil {
+		return createLogicalBreakpoint(p, addrs, requestedBp)
+	}
+	shared, err := p.Breakpoints().SetShared(addrs[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := copyBreakpointInfo(shared, requestedBp); err != nil {
+		p.Breakpoints().ClearShared(addrs[0], shared.LogicalID)
+		return nil, err
+	}
+	return api.ConvertBreakpoint(shared), nil
+}
+
 func isBreakpointExistsErr(err error) bool {
 	_, r := err.(proc.BreakpointExistsError)
 	return r
@@ -626,18 +837,27 @@ func (d *Debugger) AmendBreakpoint(amend *api.Breakpoint) error {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
+	if err := api.ValidBreakpointName(amend.Name); err != nil {
+		return err
+	}
+	if bp := d.findPendingBreakpoint(amend.ID, ""); bp != nil {
+		if err := amendPendingBreakpoint(bp, amend); err != nil {
+			return err
+		}
+		changedBp := *bp
+		d.events.publish(api.Event{Kind: api.EventBreakpointChanged, Breakpoint: &changedBp})
+		return nil
+	}
 	originals := d.findBreakpoint(amend.ID)
 	if originals == nil {
 		return fmt.Errorf("no breakpoint with ID %d", amend.ID)
 	}
-	if err := api.ValidBreakpointName(amend.Name); err != nil {
-		return err
-	}
 	for _, original := range originals {
 		if err := copyBreakpointInfo(original, amend); err != nil {
 			return err
 		}
 	}
+	d.events.publish(api.Event{Kind: api.EventBreakpointChanged, Breakpoint: api.ConvertBreakpoints(originals)[0]})
 	return nil
 }
 
@@ -661,8 +881,25 @@ func copyBreakpointInfo(bp *proc.Breakpoint, requested *api.Breakpoint) (err err
 	bp.Cond = nil
 	if requested.Cond != "" {
 		bp.Cond, err = parser.ParseExpr(requested.Cond)
+		if err != nil {
+			return err
+		}
+	}
+	bp.Panic = nil
+	if requested.Panic != nil {
+		bp.Panic = &proc.PanicFilter{Types: requested.Panic.Types}
+		if requested.Panic.Message != "" {
+			bp.Panic.Message, err = regexp.Compile(requested.Panic.Message)
+			if err != nil {
+				return fmt.Errorf("invalid panic message filter: %v", err)
+			}
+		}
+	}
+	bp.GoroutineFilter = nil
+	if requested.GoroutineFilter != nil {
+		bp.GoroutineFilter = &proc.GoroutineFilter{ID: requested.GoroutineFilter.ID, Labels: requested.GoroutineFilter.Labels}
 	}
-	return err
+	return nil
 }
 
 // ClearBreakpoint clears a breakpoint.
@@ -670,6 +907,19 @@ func (d *Debugger) ClearBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoint
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
+	if bp := d.clearPendingBreakpoint(requestedBp.ID); bp != nil {
+		d.log.Infof("cleared breakpoint: %#v", bp)
+		d.events.publish(api.Event{Kind: api.EventBreakpointCleared, Breakpoint: bp})
+		return bp, nil
+	}
+
+	if bp, ok := d.target.Breakpoints().ClearShared(requestedBp.Addr, requestedBp.ID); ok {
+		clearedBp := api.ConvertBreakpoint(bp)
+		d.log.Infof("cleared breakpoint: %#v", clearedBp)
+		d.events.publish(api.Event{Kind: api.EventBreakpointCleared, Breakpoint: clearedBp})
+		return clearedBp, nil
+	}
+
 	var bps []*proc.Breakpoint
 	var errs []error
 
@@ -713,7 +963,9 @@ func (d *Debugger) ClearBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoint
 	if len(clearedBp) < 0 {
 		return nil, nil
 	}
+	delete(d.resolvedPending, clearedBp[0].ID)
 	d.log.Infof("cleared breakpoint: %#v", clearedBp)
+	d.events.publish(api.Event{Kind: api.EventBreakpointCleared, Breakpoint: clearedBp[0]})
 	return clearedBp[0], nil
 }
 
@@ -721,7 +973,7 @@ func (d *Debugger) ClearBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoint
 func (d *Debugger) Breakpoints() []*api.Breakpoint {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
-	return api.ConvertBreakpoints(d.breakpoints())
+	return d.allBreakpoints()
 }
 
 func (d *Debugger) breakpoints() []*proc.Breakpoint {
@@ -729,6 +981,7 @@ func (d *Debugger) breakpoints() []*proc.Breakpoint {
 	for _, bp := range d.target.Breakpoints().M {
 		if bp.IsUser() {
 			bps = append(bps, bp)
+			bps = append(bps, bp.Shared...)
 		}
 	}
 	sort.Sort(breakpointsByLogicalID(bps))
@@ -741,6 +994,10 @@ func (d *Debugger) FindBreakpoint(id int) *api.Breakpoint {
 	defer d.targetMutex.Unlock()
 	bps := api.ConvertBreakpoints(d.findBreakpoint(id))
 	if len(bps) <= 0 {
+		if bp := d.findPendingBreakpoint(id, ""); bp != nil {
+			r := *bp
+			return &r
+		}
 		return nil
 	}
 	return bps[0]
@@ -752,6 +1009,11 @@ func (d *Debugger) findBreakpoint(id int) []*proc.Breakpoint {
 		if bp.LogicalID == id {
 			bps = append(bps, bp)
 		}
+		for _, shared := range bp.Shared {
+			if shared.LogicalID == id {
+				bps = append(bps, shared)
+			}
+		}
 	}
 	return bps
 }
@@ -771,6 +1033,10 @@ func (d *Debugger) findBreakpointByName(name string) *api.Breakpoint {
 		}
 	}
 	if len(bps) == 0 {
+		if bp := d.findPendingBreakpoint(0, name); bp != nil {
+			r := *bp
+			return &r
+		}
 		return nil
 	}
 	sort.Sort(breakpointsByLogicalID(bps))
@@ -823,10 +1089,10 @@ func (d *Debugger) isRunning() bool {
 	return d.running
 }
 
-// Command handles commands which control the debugger lifecycle
-func (d *Debugger) Command(command *api.DebuggerCommand) (*api.DebuggerState, error) {
-	var err error
-
+// Command handles commands which control the debugger lifecycle.
+// If ctx is done while the target is running, the target is stopped as if
+// a Halt command had been received.
+func (d *Debugger) Command(ctx context.Context, command *api.DebuggerCommand) (state *api.DebuggerState, err error) {
 	if command.Name == api.Halt {
 		// RequestManualStop does not invoke any ptrace syscalls, so it's safe to
 		// access the process directly.
@@ -847,6 +1113,41 @@ func (d *Debugger) Command(command *api.DebuggerCommand) (*api.DebuggerState, er
 	d.setRunning(true)
 	defer d.setRunning(false)
 
+	if ctx.Done() != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				select {
+				case <-done:
+					// the command completed before being cancelled
+					return
+				default:
+				}
+				d.log.Debug("command cancelled, halting")
+				d.recordMutex.Lock()
+				if d.stopRecording == nil {
+					d.target.RequestManualStop()
+				}
+				d.recordMutex.Unlock()
+			case <-done:
+			}
+		}()
+	}
+
+	switch command.Name {
+	case api.SwitchThread, api.SwitchGoroutine, api.Halt:
+		// these commands do not resume the target
+	default:
+		d.target.ImagesLoaded = d.imagesLoaded
+		d.onLoadStop = nil
+		d.events.publish(api.Event{Kind: api.EventRunning})
+		defer func() {
+			d.publishCommandResult(state, err)
+		}()
+	}
+
 	switch command.Name {
 	case api.Continue:
 		d.log.Debug("continuing")
@@ -903,6 +1204,12 @@ func (d *Debugger) Command(command *api.DebuggerCommand) (*api.DebuggerState, er
 			return nil, err
 		}
 		err = d.target.Step()
+	case api.StepIntoCall:
+		d.log.Debugf("stepping into call at %#x", command.CallPC)
+		if err := d.target.ChangeDirection(proc.Forward); err != nil {
+			return nil, err
+		}
+		err = d.target.StepIntoCall(command.CallPC)
 	case api.StepInstruction:
 		d.log.Debug("single stepping")
 		if err := d.target.ChangeDirection(proc.Forward); err != nil {
@@ -1251,7 +1558,8 @@ func (d *Debugger) FunctionArguments(scope api.EvalScope, cfg proc.LoadConfig) (
 
 // EvalVariableInScope will attempt to evaluate the variable represented by 'symbol'
 // in the scope provided.
-func (d *Debugger) EvalVariableInScope(scope api.EvalScope, symbol string, cfg proc.LoadConfig) (*api.Variable, error) {
+// The evaluation is abandoned if ctx is done.
+func (d *Debugger) EvalVariableInScope(ctx context.Context, scope api.EvalScope, symbol string, cfg proc.LoadConfig) (*api.Variable, error) {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
@@ -1259,13 +1567,51 @@ func (d *Debugger) EvalVariableInScope(scope api.EvalScope, symbol string, cfg p
 	if err != nil {
 		return nil, err
 	}
-	v, err := s.EvalVariable(symbol, cfg)
+	v, err := s.WithContext(ctx).EvalVariable(symbol, cfg)
 	if err != nil {
 		return nil, err
 	}
 	return api.ConvertVar(v), err
 }
 
+// EvalVariableRangeInScope evaluates symbol in the given scope, loading at
+// most count of the children of the result, starting at index start.
+// The evaluation is abandoned if ctx is done.
+func (d *Debugger) EvalVariableRangeInScope(ctx context.Context, scope api.EvalScope, symbol string, start, count int, cfg proc.LoadConfig) (*api.Variable, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	s, err := proc.ConvertEvalScope(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.WithContext(ctx).EvalExpressionRange(symbol, start, count, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return api.ConvertVar(v), err
+}
+
+// VariableChildren returns the variable identified by ref with at most count
+// of its children, starting with the child at index start, loaded.
+func (d *Debugger) VariableChildren(scope api.EvalScope, ref api.VariableRef, start, count int, cfg proc.LoadConfig) (*api.Variable, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	s, err := proc.ConvertEvalScope(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.VariableAt(ref.Addr, ref.Image, dwarf.Offset(ref.TypeOffset))
+	if err != nil {
+		return nil, err
+	}
+	if err := v.LoadChildren(start, count, cfg); err != nil {
+		return nil, err
+	}
+	return api.ConvertVar(v), nil
+}
+
 // SetVariableInScope will set the value of the variable represented by
 // 'symbol' to the value given, in the given scope.
 func (d *Debugger) SetVariableInScope(scope api.EvalScope, symbol, value string) error {
@@ -1280,12 +1626,13 @@ func (d *Debugger) SetVariableInScope(scope api.EvalScope, symbol, value string)
 }
 
 // Goroutines will return a list of goroutines in the target process.
-func (d *Debugger) Goroutines(start, count int) ([]*api.Goroutine, int, error) {
+// The scan of the goroutines is abandoned if ctx is done.
+func (d *Debugger) Goroutines(ctx context.Context, start, count int) ([]*api.Goroutine, int, error) {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
 	goroutines := []*api.Goroutine{}
-	gs, nextg, err := proc.GoroutinesInfo(d.target, start, count)
+	gs, nextg, err := proc.GoroutinesInfoContext(ctx, d.target, start, count)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -1298,7 +1645,8 @@ func (d *Debugger) Goroutines(start, count int) ([]*api.Goroutine, int, error) {
 // Stacktrace returns a list of Stackframes for the given goroutine. The
 // length of the returned list will be min(stack_len, depth).
 // If 'full' is true, then local vars, function args, etc will be returned as well.
-func (d *Debugger) Stacktrace(goroutineID, depth int, opts api.StacktraceOptions, cfg *proc.LoadConfig) ([]api.Stackframe, error) {
+// The stack unwinding is abandoned if ctx is done.
+func (d *Debugger) Stacktrace(ctx context.Context, goroutineID, depth int, opts api.StacktraceOptions, cfg *proc.LoadConfig) ([]api.Stackframe, error) {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
@@ -1314,9 +1662,9 @@ func (d *Debugger) Stacktrace(goroutineID, depth int, opts api.StacktraceOptions
 	}
 
 	if g == nil {
-		rawlocs, err = proc.ThreadStacktrace(d.target.CurrentThread(), depth)
+		rawlocs, err = proc.ThreadStacktraceContext(ctx, d.target.CurrentThread(), depth)
 	} else {
-		rawlocs, err = g.Stacktrace(depth, proc.StacktraceOptions(opts))
+		rawlocs, err = g.StacktraceContext(ctx, depth, proc.StacktraceOptions(opts))
 	}
 	if err != nil {
 		return nil, err
@@ -1325,6 +1673,30 @@ func (d *Debugger) Stacktrace(goroutineID, depth int, opts api.StacktraceOptions
 	return d.convertStacktrace(rawlocs, cfg)
 }
 
+// StepInTargets returns the function calls, on the current line of the
+// selected goroutine, that can be stepped into with the StepIntoCall command.
+func (d *Debugger) StepInTargets(goroutineID int) ([]api.StepInTarget, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	if _, err := d.target.Valid(); err != nil {
+		return nil, err
+	}
+	g, err := proc.FindGoroutine(d.target, goroutineID)
+	if err != nil {
+		return nil, err
+	}
+	tgts, err := d.target.GoroutineStepInTargets(g)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]api.StepInTarget, len(tgts))
+	for i := range tgts {
+		r[i] = api.StepInTarget{PC: tgts[i].PC, Function: api.ConvertFunction(tgts[i].Fn)}
+	}
+	return r, nil
+}
+
 // Ancestors returns the stacktraces for the ancestors of a goroutine.
 func (d *Debugger) Ancestors(goroutineID, numAncestors, depth int) ([]api.Ancestor, error) {
 	d.targetMutex.Lock()
@@ -1379,6 +1751,9 @@ func (d *Debugger) convertStacktrace(rawlocs []proc.Stackframe, cfg *proc.LoadCo
 			Defers: d.convertDefers(rawlocs[i].Defers),
 
 			Bottom: rawlocs[i].Bottom,
+
+			RawArgs:  rawlocs[i].RawArgs,
+			Unwinder: rawlocs[i].Unwinder.String(),
 		}
 		if rawlocs[i].Err != nil {
 			frame.Err = rawlocs[i].Err.Error()
@@ -1535,6 +1910,74 @@ func (d *Debugger) Checkpoints() ([]api.Checkpoint, error) {
 	return r, nil
 }
 
+// When returns the current position in the recording being replayed.
+func (d *Debugger) When() (api.RecordingPosition, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	if _, tracedir := d.target.Recorded(); tracedir == "" {
+		return api.RecordingPosition{}, proc.ErrNotRecorded
+	}
+	when, err := d.target.When()
+	if err != nil {
+		return api.RecordingPosition{}, err
+	}
+	ticks, err := d.target.WhenTicks()
+	if err != nil {
+		return api.RecordingPosition{}, err
+	}
+	// rr describes the current event as "Current event: <n>"
+	fields := strings.Fields(when)
+	if len(fields) == 0 {
+		return api.RecordingPosition{}, fmt.Errorf("can not parse current event %q", when)
+	}
+	event, err := strconv.ParseUint(fields[len(fields)-1], 10, 64)
+	if err != nil {
+		return api.RecordingPosition{}, fmt.Errorf("can not parse current event %q", when)
+	}
+	return api.RecordingPosition{Event: event, Ticks: ticks}, nil
+}
+
+// Seek moves the current position in the recording being replayed to the
+// start of the given event or, if ticks is true, to the given number of
+// ticks of the current thread.
+func (d *Debugger) Seek(pos uint64, ticks bool) (*api.DebuggerState, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	if _, tracedir := d.target.Recorded(); tracedir == "" {
+		return nil, proc.ErrNotRecorded
+	}
+	var err error
+	if ticks {
+		err = d.target.SeekTicks(pos)
+	} else {
+		err = d.target.Restart(strconv.FormatUint(pos, 10))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.state(nil)
+}
+
+// TraceProcesses returns the list of processes recorded in the trace being
+// replayed.
+func (d *Debugger) TraceProcesses() ([]api.TraceProcess, error) {
+	d.targetMutex.Lock()
+	_, tracedir := d.target.Recorded()
+	d.targetMutex.Unlock()
+	if tracedir == "" {
+		return nil, proc.ErrNotRecorded
+	}
+	procs, err := gdbserial.ListTraceProcesses(tracedir)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]api.TraceProcess, len(procs))
+	for i := range procs {
+		r[i] = api.TraceProcess(procs[i])
+	}
+	return r, nil
+}
+
 // ClearCheckpoint will clear the checkpoint of the given ID.
 func (d *Debugger) ClearCheckpoint(id int) error {
 	d.targetMutex.Lock()
@@ -1575,7 +2018,9 @@ func (d *Debugger) ExamineMemory(address uintptr, length int) ([]byte, error) {
 }
 
 func (d *Debugger) GetVersion(out *api.GetVersionOut) error {
-	if d.config.CoreFile != "" {
+	if d.config.GdbRemoteAddr != "" {
+		out.Backend = "gdbremote"
+	} else if d.config.CoreFile != "" {
 		if d.config.Backend == "rr" {
 			out.Backend = "rr"
 		} else {
@@ -0,0 +1,125 @@
+package debugger
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+// maxQueuedEvents is the number of past events kept for clients that are
+// not waiting for new events when they are published.
+const maxQueuedEvents = 1000
+
+// eventQueue keeps the most recent events published by the debugger and
+// lets clients wait for new ones.
+type eventQueue struct {
+	mu     sync.Mutex
+	events []api.Event
+	// output contains the EventOutput events, they are kept separately so
+	// that a target writing a lot of output can not push the other events
+	// out of the queue.
+	output  []api.Event
+	lastSeq uint64
+	// notify is closed, and replaced, when a new event is published.
+	notify chan struct{}
+}
+
+func (q *eventQueue) publish(ev api.Event) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.lastSeq++
+	ev.Seq = q.lastSeq
+	if ev.Kind == api.EventOutput {
+		q.output = appendEvent(q.output, ev)
+	} else {
+		q.events = appendEvent(q.events, ev)
+	}
+	if q.notify != nil {
+		close(q.notify)
+		q.notify = nil
+	}
+}
+
+// appendEvent appends ev to events, discarding the oldest event if there
+// are already maxQueuedEvents of them.
+func appendEvent(events []api.Event, ev api.Event) []api.Event {
+	if len(events) >= maxQueuedEvents {
+		copy(events, events[1:])
+		events = events[:len(events)-1]
+	}
+	return append(events, ev)
+}
+
+// since returns the queued events with a sequence number greater than
+// seq, the sequence number of the last event published and a channel that
+// will be closed when the next event is published.
+func (q *eventQueue) since(seq uint64) ([]api.Event, uint64, <-chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var r []api.Event
+	events, output := eventsSince(q.events, seq), eventsSince(q.output, seq)
+	for len(events) > 0 || len(output) > 0 {
+		if len(output) == 0 || (len(events) > 0 && events[0].Seq < output[0].Seq) {
+			r = append(r, events[0])
+			events = events[1:]
+		} else {
+			r = append(r, output[0])
+			output = output[1:]
+		}
+	}
+	if q.notify == nil {
+		q.notify = make(chan struct{})
+	}
+	return r, q.lastSeq, q.notify
+}
+
+// eventsSince returns the suffix of events with a sequence number greater
+// than seq.
+func eventsSince(events []api.Event, seq uint64) []api.Event {
+	i := sort.Search(len(events), func(i int) bool { return events[i].Seq > seq })
+	return events[i:]
+}
+
+// Events returns the events published after the event with sequence number
+// seq and the sequence number of the last event published.
+// If there are no such events and wait is greater than zero Events waits,
+// at most for the specified duration, for a new event to be published.
+// Only the last 1000 output events and the last 1000 events of other kinds
+// are kept, a client that falls behind can detect
+// lost events by looking for gaps in the sequence numbers.
+func (d *Debugger) Events(seq uint64, wait time.Duration) ([]api.Event, uint64) {
+	events, lastSeq, notify := d.events.since(seq)
+	if len(events) > 0 || wait <= 0 {
+		return events, lastSeq
+	}
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	select {
+	case <-notify:
+		events, lastSeq, _ = d.events.since(seq)
+	case <-timeout.C:
+	}
+	return events, lastSeq
+}
+
+// publishCommandResult publishes the events describing the outcome of a
+// command that resumed the target.
+func (d *Debugger) publishCommandResult(state *api.DebuggerState, err error) {
+	switch {
+	case err != nil:
+		d.events.publish(api.Event{Kind: api.EventStopped, State: state, Err: err.Error()})
+	case state == nil:
+		d.events.publish(api.Event{Kind: api.EventStopped})
+	case state.Exited:
+		d.events.publish(api.Event{Kind: api.EventExited, State: state})
+	default:
+		for _, th := range state.Threads {
+			if th.Breakpoint != nil {
+				d.events.publish(api.Event{Kind: api.EventBreakpointHit, Breakpoint: th.Breakpoint, Thread: th})
+			}
+		}
+		d.events.publish(api.Event{Kind: api.EventStopped, State: state})
+	}
+}
@@ -0,0 +1,59 @@
+package debugger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+func TestEvents(t *testing.T) {
+	d := new(Debugger)
+	d.events.publish(api.Event{Kind: api.EventRunning})
+	d.events.publish(api.Event{Kind: api.EventStopped})
+
+	events, last := d.Events(0, 0)
+	if len(events) != 2 || last != 2 || events[0].Seq != 1 || events[1].Kind != api.EventStopped {
+		t.Fatalf("wrong events %#v (last %d)", events, last)
+	}
+
+	events, last = d.Events(last, 10*time.Millisecond)
+	if len(events) != 0 || last != 2 {
+		t.Fatalf("unexpected events %#v (last %d)", events, last)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		d.events.publish(api.Event{Kind: api.EventDetached})
+	}()
+	events, last = d.Events(last, time.Minute)
+	if len(events) != 1 || last != 3 || events[0].Kind != api.EventDetached {
+		t.Fatalf("wrong events %#v (last %d)", events, last)
+	}
+
+	for i := 0; i < 2*maxQueuedEvents; i++ {
+		d.events.publish(api.Event{Kind: api.EventRunning})
+	}
+	events, last = d.Events(0, 0)
+	if len(events) != maxQueuedEvents || events[len(events)-1].Seq != last {
+		t.Fatalf("wrong number of queued events %d (last %d)", len(events), last)
+	}
+
+	// Output events can not push other events out of the queue.
+	d.events.publish(api.Event{Kind: api.EventStopped})
+	for i := 0; i < 2*maxQueuedEvents; i++ {
+		d.events.publish(api.Event{Kind: api.EventOutput, Output: &api.TargetOutput{}})
+	}
+	events, last = d.Events(0, 0)
+	if len(events) != 2*maxQueuedEvents || events[len(events)-1].Seq != last {
+		t.Fatalf("wrong number of queued events %d (last %d)", len(events), last)
+	}
+	for i := range events[1:] {
+		if events[i].Seq >= events[i+1].Seq {
+			t.Fatalf("events out of order %d %d", events[i].Seq, events[i+1].Seq)
+		}
+	}
+	if ev := events[maxQueuedEvents-1]; ev.Kind != api.EventStopped || ev.Seq != last-2*maxQueuedEvents {
+		t.Fatalf("stop event lost %#v", ev)
+	}
+}
@@ -0,0 +1,232 @@
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/go-delve/delve/pkg/locspec"
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/service/api"
+)
+
+// Pending breakpoints and on-load breakpoints are logical breakpoints that
+// don't have any physical breakpoint. Pending breakpoints are converted
+// into normal breakpoints when the target loads a shared library or
+// plugin that contains their location, on-load breakpoints stop the
+// target when it loads a shared library or plugin whose path matches
+// their regular expression.
+
+var errPendingLocation = errors.New("location not found")
+
+// createPendingBreakpoint creates a pending breakpoint or an on-load
+// breakpoint.
+func (d *Debugger) createPendingBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoint, error) {
+	bp := *requestedBp
+	bp.Addr = 0
+	bp.Addrs = nil
+	bp.HitCount = map[string]uint64{}
+	bp.TotalHitCount = 0
+	if bp.OnLoad != "" {
+		if _, err := regexp.Compile(bp.OnLoad); err != nil {
+			return nil, fmt.Errorf("invalid library regular expression: %v", err)
+		}
+		bp.Pending = false
+		bp.File = ""
+		bp.Line = 0
+		bp.FunctionName = ""
+	} else {
+		if bp.File == "" && bp.FunctionName == "" {
+			return nil, errors.New("pending breakpoints need a file or function name")
+		}
+		bp.Pending = true
+	}
+	// check that the breakpoint can be set
+	if err := copyBreakpointInfo(&proc.Breakpoint{}, &bp); err != nil {
+		return nil, err
+	}
+	bp.ID = d.target.Breakpoints().NewLogicalID()
+	d.pendingBreakpoints = append(d.pendingBreakpoints, &bp)
+
+	createdBp := bp
+	d.log.Infof("created breakpoint: %#v", &createdBp)
+	d.events.publish(api.Event{Kind: api.EventBreakpointCreated, Breakpoint: &createdBp})
+	return &createdBp, nil
+}
+
+// findPendingBreakpoint returns the pending or on-load breakpoint with
+// the specified ID or name.
+func (d *Debugger) findPendingBreakpoint(id int, name string) *api.Breakpoint {
+	for _, bp := range d.pendingBreakpoints {
+		if (name == "" && bp.ID == id) || (name != "" && bp.Name == name) {
+			return bp
+		}
+	}
+	return nil
+}
+
+// clearPendingBreakpoint removes the pending or on-load breakpoint with
+// the specified ID, returns nil if there isn't one.
+func (d *Debugger) clearPendingBreakpoint(id int) *api.Breakpoint {
+	for i, bp := range d.pendingBreakpoints {
+		if bp.ID == id {
+			copy(d.pendingBreakpoints[i:], d.pendingBreakpoints[i+1:])
+			d.pendingBreakpoints = d.pendingBreakpoints[:len(d.pendingBreakpoints)-1]
+			return bp
+		}
+	}
+	return nil
+}
+
+// restorePendingBreakpoint converts oldBp, a breakpoint of the previous
+// target process that was created as the pending breakpoint orig, back to
+// a pending breakpoint, keeping its ID and the properties that don't
+// describe its location.
+func (d *Debugger) restorePendingBreakpoint(oldBp, orig *api.Breakpoint) {
+	bp := *oldBp
+	bp.File = orig.File
+	bp.Line = orig.Line
+	bp.FunctionName = orig.FunctionName
+	bp.Addr = 0
+	bp.Addrs = nil
+	bp.HitCount = map[string]uint64{}
+	bp.TotalHitCount = 0
+	bp.Pending = true
+	d.pendingBreakpoints = append(d.pendingBreakpoints, &bp)
+}
+
+// amendPendingBreakpoint updates a pending or on-load breakpoint with the
+// properties of amend that don't describe its location.
+func amendPendingBreakpoint(bp, amend *api.Breakpoint) error {
+	if err := copyBreakpointInfo(&proc.Breakpoint{}, amend); err != nil {
+		return err
+	}
+	bp.Name = amend.Name
+	bp.Cond = amend.Cond
+	bp.Tracepoint = amend.Tracepoint
+	bp.Goroutine = amend.Goroutine
+	bp.Stacktrace = amend.Stacktrace
+	bp.Variables = amend.Variables
+	bp.LoadArgs = amend.LoadArgs
+	bp.LoadLocals = amend.LoadLocals
+	bp.GoroutineFilter = amend.GoroutineFilter
+	return nil
+}
+
+// allBreakpoints returns all user breakpoints, including pending and
+// on-load breakpoints, sorted by ID.
+func (d *Debugger) allBreakpoints() []*api.Breakpoint {
+	bps := api.ConvertBreakpoints(d.breakpoints())
+	for _, bp := range d.pendingBreakpoints {
+		bp := *bp
+		bps = append(bps, &bp)
+	}
+	sort.Slice(bps, func(i, j int) bool { return bps[i].ID < bps[j].ID })
+	return bps
+}
+
+// imagesLoaded is called by the target when it loads new shared libraries
+// or plugins, it sets the pending breakpoints contained in them and
+// returns true if the target should stop because of an on-load
+// breakpoint.
+func (d *Debugger) imagesLoaded(images []*proc.Image) bool {
+	for _, image := range images {
+		d.log.Debugf("image loaded: %s", image.Path)
+		img := api.ConvertImage(image)
+		d.events.publish(api.Event{Kind: api.EventLibraryLoaded, Image: &img, Sources: image.Sources()})
+	}
+
+	pending := d.pendingBreakpoints[:0]
+	for _, bp := range d.pendingBreakpoints {
+		if !bp.Pending {
+			pending = append(pending, bp)
+			continue
+		}
+		addrs, err := pendingBreakpointLocation(d.target, bp)
+		if err == nil {
+			var createdBp *api.Breakpoint
+			createdBp, err = createLogicalBreakpoint(d.target, addrs, bp)
+			if err == nil {
+				d.target.Breakpoints().ChangeLogicalID(createdBp.ID, bp.ID)
+				createdBp.ID = bp.ID
+				d.resolvedPending[bp.ID] = bp
+				d.log.Infof("pending breakpoint set: %#v", createdBp)
+				d.events.publish(api.Event{Kind: api.EventBreakpointChanged, Breakpoint: createdBp})
+				continue
+			}
+		}
+		if err != errPendingLocation {
+			d.log.Warnf("could not set pending breakpoint %d: %v", bp.ID, err)
+		}
+		pending = append(pending, bp)
+	}
+	d.pendingBreakpoints = pending
+
+	stop := false
+	for _, bp := range d.pendingBreakpoints {
+		if bp.OnLoad == "" {
+			continue
+		}
+		re, err := regexp.Compile(bp.OnLoad)
+		if err != nil {
+			continue
+		}
+		for _, image := range images {
+			if re.MatchString(image.Path) {
+				bp.TotalHitCount++
+				d.onLoadStop = bp
+				stop = true
+				break
+			}
+		}
+		if stop {
+			break
+		}
+	}
+	return stop
+}
+
+// pendingBreakpointLocation returns the addresses of the location of a
+// pending breakpoint. The File and FunctionName fields are matched the
+// same way locspec matches the base of a location, the location is only
+// considered found if there is exactly one match.
+func pendingBreakpointLocation(t *proc.Target, bp *api.Breakpoint) ([]uint64, error) {
+	bi := t.BinInfo()
+	if bp.File != "" {
+		spec := &locspec.NormalLocationSpec{Base: bp.File}
+		var candidates []string
+		for _, file := range bi.Sources {
+			if spec.FileMatch(file) {
+				candidates = append(candidates, file)
+			}
+		}
+		if len(candidates) != 1 {
+			return nil, errPendingLocation
+		}
+		return proc.FindFileLocation(t, candidates[0], bp.Line)
+	}
+
+	loc, err := locspec.Parse(bp.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	spec, ok := loc.(*locspec.NormalLocationSpec)
+	if !ok || spec.FuncBase == nil {
+		return nil, fmt.Errorf("invalid function name %q", bp.FunctionName)
+	}
+	var candidates []string
+	for _, fn := range bi.Functions {
+		if fn.Name == bp.FunctionName {
+			candidates = []string{fn.Name}
+			break
+		}
+		if spec.FuncBase.Match(fn, bi.PackageMap) {
+			candidates = append(candidates, fn.Name)
+		}
+	}
+	if len(candidates) != 1 {
+		return nil, errPendingLocation
+	}
+	return proc.FindFunctionLocation(t, candidates[0], bp.Line)
+}
@@ -0,0 +1,105 @@
+package debugger
+
+import (
+	"sync"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/service/api"
+)
+
+// DefaultOutputBufferSize is the number of bytes of captured target output
+// kept when Config.OutputBufferSize is not set.
+const DefaultOutputBufferSize = 1 << 20
+
+// outputBuffer keeps the most recent output written by the target, up to
+// a maximum number of bytes.
+type outputBuffer struct {
+	mu     sync.Mutex
+	max    int
+	size   int
+	next   uint64
+	chunks []api.TargetOutput
+}
+
+// append records data as written to stream and returns the chunk that was
+// added to the buffer.
+func (b *outputBuffer) append(stream string, data []byte) api.TargetOutput {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	max := b.max
+	if max <= 0 {
+		max = DefaultOutputBufferSize
+	}
+	chunk := api.TargetOutput{Stream: stream, Offset: b.next, Data: append([]byte(nil), data...)}
+	b.next += uint64(len(data))
+	b.chunks = append(b.chunks, chunk)
+	b.size += len(data)
+	for b.size > max && len(b.chunks) > 0 {
+		first := &b.chunks[0]
+		if excess := b.size - max; excess < len(first.Data) {
+			first.Data = first.Data[excess:]
+			first.Offset += uint64(excess)
+			b.size -= excess
+			break
+		}
+		b.size -= len(first.Data)
+		b.chunks = b.chunks[1:]
+	}
+	return chunk
+}
+
+// since returns the buffered output starting at offset and the offset
+// following the last byte of output captured so far.
+func (b *outputBuffer) since(offset uint64) ([]api.TargetOutput, uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var r []api.TargetOutput
+	for _, chunk := range b.chunks {
+		end := chunk.Offset + uint64(len(chunk.Data))
+		if end <= offset {
+			continue
+		}
+		if chunk.Offset < offset {
+			chunk.Data = chunk.Data[offset-chunk.Offset:]
+			chunk.Offset = offset
+		}
+		r = append(r, chunk)
+	}
+	return r, b.next
+}
+
+// outputWriter captures one of the output streams of the target.
+type outputWriter struct {
+	d      *Debugger
+	stream string
+}
+
+func (w *outputWriter) Write(data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	chunk := w.d.output.append(w.stream, data)
+	w.d.events.publish(api.Event{Kind: api.EventOutput, Output: &chunk})
+	return len(data), nil
+}
+
+// outputRedirect returns the redirection used when launching the target,
+// output is only captured if Config.CaptureOutput is set.
+func (d *Debugger) outputRedirect() proc.OutputRedirect {
+	if !d.config.CaptureOutput {
+		return proc.OutputRedirect{}
+	}
+	return proc.OutputRedirect{
+		Stdout: &outputWriter{d: d, stream: "stdout"},
+		Stderr: &outputWriter{d: d, stream: "stderr"},
+	}
+}
+
+// Output returns the captured output of the target starting at offset and
+// the offset at which the next call should start.
+// Output older than Config.OutputBufferSize bytes is discarded, a client
+// can detect lost output when the first chunk returned starts after
+// offset.
+func (d *Debugger) Output(offset uint64) ([]api.TargetOutput, uint64) {
+	return d.output.since(offset)
+}
@@ -0,0 +1,45 @@
+package debugger
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/service/api"
+)
+
+func TestOutputBuffer(t *testing.T) {
+	d := &Debugger{config: &Config{CaptureOutput: true}}
+	d.output.max = 8
+	redirect := d.outputRedirect()
+	redirect.Stdout.Write([]byte("hello"))
+	redirect.Stderr.Write([]byte("err"))
+
+	output, next := d.Output(0)
+	exp := []api.TargetOutput{{Stream: "stdout", Offset: 0, Data: []byte("hello")}, {Stream: "stderr", Offset: 5, Data: []byte("err")}}
+	if !reflect.DeepEqual(output, exp) || next != 8 {
+		t.Fatalf("wrong output %#v (next %d)", output, next)
+	}
+
+	output, _ = d.Output(3)
+	exp = []api.TargetOutput{{Stream: "stdout", Offset: 3, Data: []byte("lo")}, {Stream: "stderr", Offset: 5, Data: []byte("err")}}
+	if !reflect.DeepEqual(output, exp) {
+		t.Fatalf("wrong output from offset 3 %#v", output)
+	}
+
+	redirect.Stdout.Write([]byte("world"))
+	output, next = d.Output(0)
+	exp = []api.TargetOutput{{Stream: "stderr", Offset: 5, Data: []byte("err")}, {Stream: "stdout", Offset: 8, Data: []byte("world")}}
+	if !reflect.DeepEqual(output, exp) || next != 13 {
+		t.Fatalf("wrong output after overflow %#v (next %d)", output, next)
+	}
+
+	events, _ := d.Events(0, 0)
+	if len(events) != 3 || events[2].Kind != api.EventOutput || string(events[2].Output.Data) != "world" {
+		t.Fatalf("wrong events %#v", events)
+	}
+
+	if (&Debugger{config: &Config{}}).outputRedirect() != (proc.OutputRedirect{}) {
+		t.Fatalf("output captured without CaptureOutput")
+	}
+}
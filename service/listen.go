@@ -0,0 +1,80 @@
+package service
+
+import (
+	"net"
+	"os"
+	"strings"
+)
+
+// unixAddrPrefix is the prefix of addresses specifying the path of a unix
+// domain socket instead of a TCP host:port.
+const unixAddrPrefix = "unix:"
+
+// ListenConfig contains the options used to create the listener of a
+// debugging server.
+type ListenConfig struct {
+	// AllowedOrigins lists the origins of the web pages allowed to open
+	// WebSocket connections, in addition to the ones served from the same
+	// host as the server. The value "*" allows all origins.
+	AllowedOrigins []string
+}
+
+// Listen creates a listener for the debugging server on addr, see
+// ListenWithConfig.
+func Listen(addr string) (net.Listener, error) {
+	return ListenWithConfig(addr, ListenConfig{})
+}
+
+// ListenWithConfig creates a listener for the debugging server on addr,
+// which is either a TCP host:port, "unix:" followed by the path of a unix
+// domain socket or "ws:" followed by a TCP host:port on which WebSocket
+// connections are accepted.
+// Unix domain sockets are created so that only the user running Delve can
+// connect to them, a stale socket left at the same path by a previous
+// instance is removed.
+func ListenWithConfig(addr string, cfg ListenConfig) (net.Listener, error) {
+	if strings.HasPrefix(addr, wsAddrPrefix) {
+		listener, err := net.Listen("tcp", addr[len(wsAddrPrefix):])
+		if err != nil {
+			return nil, err
+		}
+		return &wsListener{Listener: listener, allowedOrigins: cfg.AllowedOrigins}, nil
+	}
+	if !strings.HasPrefix(addr, unixAddrPrefix) {
+		return net.Listen("tcp", addr)
+	}
+	path := addr[len(unixAddrPrefix):]
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+		} else {
+			os.Remove(path)
+		}
+	}
+	return listenUnixOwnerOnly(path)
+}
+
+// Dial connects to a debugging server listening on addr, see
+// ListenWithConfig for the format of addr.
+func Dial(addr string) (net.Conn, error) {
+	if strings.HasPrefix(addr, wsAddrPrefix) {
+		return dialWebSocket(addr[len(wsAddrPrefix):], nil)
+	}
+	if strings.HasPrefix(addr, unixAddrPrefix) {
+		return net.Dial("unix", addr[len(unixAddrPrefix):])
+	}
+	return net.Dial("tcp", addr)
+}
+
+// ListenerAddr returns the address of listener in the format accepted by
+// Dial.
+func ListenerAddr(listener net.Listener) string {
+	if _, ok := listener.(*wsListener); ok {
+		return wsAddrPrefix + listener.Addr().String()
+	}
+	addr := listener.Addr()
+	if addr.Network() == "unix" {
+		return unixAddrPrefix + addr.String()
+	}
+	return addr.String()
+}
@@ -0,0 +1,63 @@
+// +build !windows
+
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dlv-listen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	addr := "unix:" + filepath.Join(dir, "dlv.sock")
+
+	listener, err := Listen(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if got := ListenerAddr(listener); got != addr {
+		t.Errorf("wrong listener address %q, expected %q", got, addr)
+	}
+	fi, err := os.Stat(addr[len("unix:"):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Errorf("wrong socket permissions %#o", perm)
+	}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Write([]byte("x"))
+			conn.Close()
+		}
+	}()
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1)
+	if _, err := conn.Read(buf); err != nil || buf[0] != 'x' {
+		t.Fatalf("read %q %v", buf, err)
+	}
+	conn.Close()
+
+	if _, err := Listen(addr); err == nil {
+		t.Fatal("listening twice on the same socket succeeded")
+	}
+
+	// Closing the listener removes the socket and nothing else is left in
+	// the directory.
+	listener.Close()
+	if fis, err := ioutil.ReadDir(dir); err != nil || len(fis) != 0 {
+		t.Errorf("directory not empty after closing the listener: %v %v", fis, err)
+	}
+}
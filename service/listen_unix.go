@@ -0,0 +1,58 @@
+// +build !windows
+
+package service
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// listenUnixOwnerOnly creates a unix domain socket at path that is never
+// accessible to users other than the current one, not even briefly before
+// its permissions can be changed.
+// The socket is created inside a temporary directory only accessible to
+// the current user, next to path, and linked to path once its permissions
+// are set.
+func listenUnixOwnerOnly(path string) (net.Listener, error) {
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".dlv-sock")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmppath := filepath.Join(dir, "sock")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmppath, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	listener.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmppath, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	if err := os.Link(tmppath, path); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &unixListener{UnixListener: listener, path: path}, nil
+}
+
+// unixListener is a listener on a unix domain socket that was moved to
+// path after being created.
+type unixListener struct {
+	*net.UnixListener
+	path string
+}
+
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	if err == nil {
+		os.Remove(l.path)
+	}
+	return err
+}
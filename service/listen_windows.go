@@ -0,0 +1,7 @@
+package service
+
+import "net"
+
+func listenUnixOwnerOnly(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
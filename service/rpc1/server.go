@@ -1,6 +1,7 @@
 package rpc1
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -43,7 +44,7 @@ func (s *RPCServer) Detach(kill bool, ret *int) error {
 }
 
 func (s *RPCServer) Restart(arg1 interface{}, arg2 *int) error {
-	if s.config.Debugger.AttachPid != 0 {
+	if s.config.Debugger.AttachedToExistingProcess() {
 		return errors.New("cannot restart process Delve did not create")
 	}
 	_, err := s.debugger.Restart(false, "", false, nil)
@@ -60,7 +61,7 @@ func (s *RPCServer) State(arg interface{}, state *api.DebuggerState) error {
 }
 
 func (s *RPCServer) Command(command *api.DebuggerCommand, cb service.RPCCallback) {
-	st, err := s.debugger.Command(command)
+	st, err := s.debugger.Command(context.Background(), command)
 	cb.Return(st, err)
 }
 
@@ -93,7 +94,7 @@ func (s *RPCServer) StacktraceGoroutine(args *StacktraceGoroutineArgs, locations
 	if args.Full {
 		loadcfg = &defaultLoadConfig
 	}
-	locs, err := s.debugger.Stacktrace(args.Id, args.Depth, 0, loadcfg)
+	locs, err := s.debugger.Stacktrace(context.Background(), args.Id, args.Depth, 0, loadcfg)
 	if err != nil {
 		return err
 	}
@@ -242,7 +243,7 @@ type EvalSymbolArgs struct {
 }
 
 func (s *RPCServer) EvalSymbol(args EvalSymbolArgs, variable *api.Variable) error {
-	v, err := s.debugger.EvalVariableInScope(args.Scope, args.Symbol, defaultLoadConfig)
+	v, err := s.debugger.EvalVariableInScope(context.Background(), args.Scope, args.Symbol, defaultLoadConfig)
 	if err != nil {
 		return err
 	}
@@ -289,7 +290,7 @@ func (s *RPCServer) ListTypes(filter string, types *[]string) error {
 }
 
 func (s *RPCServer) ListGoroutines(arg interface{}, goroutines *[]*api.Goroutine) error {
-	gs, _, err := s.debugger.Goroutines(0, 0)
+	gs, _, err := s.debugger.Goroutines(context.Background(), 0, 0)
 	if err != nil {
 		return err
 	}
@@ -298,7 +299,7 @@ func (s *RPCServer) ListGoroutines(arg interface{}, goroutines *[]*api.Goroutine
 }
 
 func (c *RPCServer) AttachedToExistingProcess(arg interface{}, answer *bool) error {
-	if c.config.Debugger.AttachPid != 0 {
+	if c.config.Debugger.AttachedToExistingProcess() {
 		*answer = true
 	}
 	return nil
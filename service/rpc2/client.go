@@ -23,12 +23,24 @@ type RPCClient struct {
 var _ service.Client = &RPCClient{}
 
 // NewClient creates a new RPCClient.
+// The address can be either a TCP host:port or "unix:" followed by the path
+// of a unix domain socket.
 func NewClient(addr string) *RPCClient {
-	client, err := jsonrpc.Dial("tcp", addr)
+	conn, err := service.Dial(addr)
 	if err != nil {
 		log.Fatal("dialing:", err)
 	}
-	return newFromRPCClient(client)
+	return NewClientFromConn(conn)
+}
+
+// NewClientWithConfig creates a new RPCClient connected to addr, using the
+// authentication token and TLS configuration specified by cfg.
+func NewClientWithConfig(addr string, cfg service.DialConfig) (*RPCClient, error) {
+	conn, err := service.DialWithConfig(addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientFromConn(conn), nil
 }
 
 func newFromRPCClient(client *rpc.Client) *RPCClient {
@@ -158,6 +170,18 @@ func (c *RPCClient) ReverseStep() (*api.DebuggerState, error) {
 	return &out.State, err
 }
 
+func (c *RPCClient) StepIntoCall(callPC uint64) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.StepIntoCall, CallPC: callPC, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) StepInTargets() ([]api.StepInTarget, error) {
+	var out StepInTargetsOut
+	err := c.call("StepInTargets", StepInTargetsIn{}, &out)
+	return out.Targets, err
+}
+
 func (c *RPCClient) StepOut() (*api.DebuggerState, error) {
 	var out CommandOut
 	err := c.call("Command", api.DebuggerCommand{Name: api.StepOut, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
@@ -275,7 +299,19 @@ func (c *RPCClient) GetThread(id int) (*api.Thread, error) {
 
 func (c *RPCClient) EvalVariable(scope api.EvalScope, expr string, cfg api.LoadConfig) (*api.Variable, error) {
 	var out EvalOut
-	err := c.call("Eval", EvalIn{scope, expr, &cfg}, &out)
+	err := c.call("Eval", EvalIn{scope, expr, &cfg, 0, 0}, &out)
+	return out.Variable, err
+}
+
+func (c *RPCClient) EvalVariableRange(scope api.EvalScope, expr string, start, count int, cfg api.LoadConfig) (*api.Variable, error) {
+	var out EvalOut
+	err := c.call("Eval", EvalIn{scope, expr, &cfg, start, count}, &out)
+	return out.Variable, err
+}
+
+func (c *RPCClient) ListVariableChildren(scope api.EvalScope, ref api.VariableRef, start, count int, cfg api.LoadConfig) (*api.Variable, error) {
+	var out ListVariableChildrenOut
+	err := c.call("ListVariableChildren", ListVariableChildrenIn{scope, ref, start, count, &cfg}, &out)
 	return out.Variable, err
 }
 
@@ -362,6 +398,13 @@ func (c *RPCClient) FindLocation(scope api.EvalScope, loc string, findInstructio
 	return out.Locations, err
 }
 
+// Complete returns the completion candidates for text.
+func (c *RPCClient) Complete(scope api.EvalScope, kind api.CompletionKind, text string) (int, []api.Completion, error) {
+	var out CompleteOut
+	err := c.call("Complete", CompleteIn{scope, kind, text}, &out)
+	return out.Start, out.Completions, err
+}
+
 // Disassemble code between startPC and endPC
 func (c *RPCClient) DisassembleRange(scope api.EvalScope, startPC, endPC uint64, flavour api.AssemblyFlavour) (api.AsmInstructions, error) {
 	var out DisassembleOut
@@ -390,6 +433,13 @@ func (c *RPCClient) TraceDirectory() (string, error) {
 	return out.TraceDirectory, err
 }
 
+// Warnings returns the problems found while loading the target.
+func (c *RPCClient) Warnings() ([]string, error) {
+	var out WarningsOut
+	err := c.call("Warnings", WarningsIn{}, &out)
+	return out.Warnings, err
+}
+
 // Checkpoint sets a checkpoint at the current position.
 func (c *RPCClient) Checkpoint(where string) (checkpointID int, err error) {
 	var out CheckpointOut
@@ -411,6 +461,42 @@ func (c *RPCClient) ClearCheckpoint(id int) error {
 	return err
 }
 
+// When returns the current position in the recording.
+func (c *RPCClient) When() (api.RecordingPosition, error) {
+	var out WhenOut
+	err := c.call("When", WhenIn{}, &out)
+	return out.Position, err
+}
+
+// SeekEvent moves to the start of an rr event.
+func (c *RPCClient) SeekEvent(event uint64) (*api.DebuggerState, error) {
+	var out SeekOut
+	err := c.call("Seek", SeekIn{Event: event}, &out)
+	return &out.State, err
+}
+
+// SeekTicks moves to a number of ticks of the current thread.
+func (c *RPCClient) SeekTicks(ticks uint64) (*api.DebuggerState, error) {
+	var out SeekOut
+	err := c.call("Seek", SeekIn{Ticks: ticks, UseTicks: true}, &out)
+	return &out.State, err
+}
+
+// ListTraceProcesses lists the processes recorded in the trace.
+func (c *RPCClient) ListTraceProcesses() ([]api.TraceProcess, error) {
+	var out ListTraceProcessesOut
+	err := c.call("ListTraceProcesses", ListTraceProcessesIn{}, &out)
+	return out.Processes, err
+}
+
+// ReplayProcess starts a new replay of the trace that debugs the process
+// with the given pid.
+func (c *RPCClient) ReplayProcess(pid int) ([]api.DiscardedBreakpoint, error) {
+	var out ReplayProcessOut
+	err := c.call("ReplayProcess", ReplayProcessIn{pid}, &out)
+	return out.DiscardedBreakpoints, err
+}
+
 func (c *RPCClient) SetReturnValuesLoadConfig(cfg *api.LoadConfig) {
 	c.retValLoadCfg = cfg
 }
@@ -456,6 +542,55 @@ func (c *RPCClient) StopRecording() error {
 	return c.call("StopRecording", StopRecordingIn{}, &StopRecordingOut{})
 }
 
+// Events returns a channel on which the events published by the debugger
+// after this call are delivered, including the ones caused by other
+// clients. The channel is closed when the connection to the server is
+// closed.
+func (c *RPCClient) Events() <-chan api.Event {
+	ch := make(chan api.Event, 100)
+	// The sequence number of the last event is read before returning, so
+	// that the events published after this call aren't missed.
+	var out EventsOut
+	if err := c.call("Events", EventsIn{0, false}, &out); err != nil {
+		close(ch)
+		return ch
+	}
+	seq := out.LastSeq
+	go func() {
+		defer close(ch)
+		for {
+			out := EventsOut{}
+			if err := c.call("Events", EventsIn{seq, true}, &out); err != nil {
+				return
+			}
+			for _, ev := range out.Events {
+				ch <- ev
+				seq = ev.Seq
+				if ev.Kind == api.EventDetached {
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// Cancel interrupts the Command, Eval, Stacktrace and ListGoroutines
+// requests that this client has in progress, which will return an error.
+func (c *RPCClient) Cancel() (int, error) {
+	var out CancelOut
+	err := c.call("Cancel", CancelIn{}, &out)
+	return out.Cancelled, err
+}
+
+// GetOutput returns the captured output of the target starting at offset
+// and the offset at which the next call should start.
+func (c *RPCClient) GetOutput(offset uint64) ([]api.TargetOutput, uint64, error) {
+	var out GetOutputOut
+	err := c.call("GetOutput", GetOutputIn{offset}, &out)
+	return out.Output, out.Next, err
+}
+
 func (c *RPCClient) call(method string, args, reply interface{}) error {
 	return c.client.Call("RPCServer."+method, args, reply)
 }
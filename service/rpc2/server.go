@@ -84,7 +84,7 @@ type RestartOut struct {
 
 // Restart restarts program.
 func (s *RPCServer) Restart(arg RestartIn, cb service.RPCCallback) {
-	if s.config.Debugger.AttachPid != 0 {
+	if s.config.Debugger.AttachedToExistingProcess() {
 		cb.Return(nil, errors.New("cannot restart process Delve did not create"))
 		return
 	}
@@ -121,7 +121,7 @@ type CommandOut struct {
 
 // Command interrupts, continues and steps through the program.
 func (s *RPCServer) Command(command api.DebuggerCommand, cb service.RPCCallback) {
-	st, err := s.debugger.Command(&command)
+	st, err := s.debugger.Command(cb.Context(), &command)
 	if err != nil {
 		cb.Return(nil, err)
 		return
@@ -131,6 +131,86 @@ func (s *RPCServer) Command(command api.DebuggerCommand, cb service.RPCCallback)
 	cb.Return(out, nil)
 }
 
+type EventsIn struct {
+	// Since is the sequence number of the last event received by the
+	// client, only events published after it are returned.
+	Since uint64
+	// Wait, if true, makes the call wait for new events if there are none.
+	// The call returns with an empty list of events if no event is published
+	// within one minute.
+	Wait bool
+}
+
+type EventsOut struct {
+	Events []api.Event
+	// LastSeq is the sequence number of the last event published.
+	LastSeq uint64
+}
+
+// eventsWaitTimeout is the maximum time an Events call waits for new events.
+const eventsWaitTimeout = time.Minute
+
+// Events returns the events published by the debugger after the event with
+// sequence number arg.Since. It lets clients be notified of changes made by
+// other clients connected to a multiclient server: the target being
+// resumed and stopped, breakpoints being hit, created or removed, the target
+// exiting.
+func (s *RPCServer) Events(arg EventsIn, cb service.RPCCallback) {
+	var out EventsOut
+	var wait time.Duration
+	if arg.Wait {
+		wait = eventsWaitTimeout
+	}
+	out.Events, out.LastSeq = s.debugger.Events(arg.Since, wait)
+	cb.Return(out, nil)
+}
+
+type CancelIn struct {
+	// ID is the JSON-RPC id of the request to cancel, if it is null all
+	// the requests in progress on the connection are cancelled.
+	ID interface{}
+}
+
+type CancelOut struct {
+	// Cancelled is the number of requests that were cancelled.
+	Cancelled int
+}
+
+// Cancel interrupts requests in progress that were made on the same
+// connection, identified by their JSON-RPC id. Cancelled requests return
+// an error.
+// The requests that can be interrupted are Command, where cancelling has
+// the same effect as the halt command, Eval, Stacktrace and ListGoroutines.
+// Cancelling a Command calling a function stops the target while the
+// function is running, the call completes when the target is resumed, as
+// if the function had hit a breakpoint.
+// Since other requests are processed one at a time, in the order they are
+// received, Cancel will only be processed after them.
+// Closing the connection does not cancel the requests in progress.
+func (s *RPCServer) Cancel(arg CancelIn, cb service.RPCCallback) {
+	cb.Return(CancelOut{Cancelled: cb.CancelRequests(arg.ID)}, nil)
+}
+
+type GetOutputIn struct {
+	// Offset is the position in the captured output from which to start.
+	Offset uint64
+}
+
+type GetOutputOut struct {
+	Output []api.TargetOutput
+	// Next is the offset to pass to the next call to GetOutput.
+	Next uint64
+}
+
+// GetOutput returns the output written by the target to its standard
+// output and standard error starting at arg.Offset.
+// Output is only captured if the server was started with --capture-output,
+// new output is also delivered as events of kind "output", see Events.
+func (s *RPCServer) GetOutput(arg GetOutputIn, out *GetOutputOut) error {
+	out.Output, out.Next = s.debugger.Output(arg.Offset)
+	return nil
+}
+
 type GetBreakpointIn struct {
 	Id   int
 	Name string
@@ -175,7 +255,9 @@ type StacktraceOut struct {
 //
 // If Full is set it will also the variable of all local variables
 // and function arguments of all stack frames.
-func (s *RPCServer) Stacktrace(arg StacktraceIn, out *StacktraceOut) error {
+//
+// Stacktrace can be interrupted with Cancel.
+func (s *RPCServer) Stacktrace(arg StacktraceIn, cb service.RPCCallback) {
 	cfg := arg.Cfg
 	if cfg == nil && arg.Full {
 		cfg = &api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
@@ -183,9 +265,14 @@ func (s *RPCServer) Stacktrace(arg StacktraceIn, out *StacktraceOut) error {
 	if arg.Defers {
 		arg.Opts |= api.StacktraceReadDefers
 	}
+	var out StacktraceOut
 	var err error
-	out.Locations, err = s.debugger.Stacktrace(arg.Id, arg.Depth, arg.Opts, api.LoadConfigToProc(cfg))
-	return err
+	out.Locations, err = s.debugger.Stacktrace(cb.Context(), arg.Id, arg.Depth, arg.Opts, api.LoadConfigToProc(cfg))
+	if err != nil {
+		cb.Return(nil, err)
+		return
+	}
+	cb.Return(out, nil)
 }
 
 type AncestorsIn struct {
@@ -205,6 +292,22 @@ func (s *RPCServer) Ancestors(arg AncestorsIn, out *AncestorsOut) error {
 	return err
 }
 
+type StepInTargetsIn struct {
+}
+
+type StepInTargetsOut struct {
+	Targets []api.StepInTarget
+}
+
+// StepInTargets returns the function calls, on the current line of the
+// selected goroutine, that can be stepped into using the StepIntoCall
+// command.
+func (s *RPCServer) StepInTargets(arg StepInTargetsIn, out *StepInTargetsOut) error {
+	var err error
+	out.Targets, err = s.debugger.StepInTargets(-1)
+	return err
+}
+
 type ListBreakpointsIn struct {
 }
 
@@ -444,6 +547,11 @@ type EvalIn struct {
 	Scope api.EvalScope
 	Expr  string
 	Cfg   *api.LoadConfig
+	// If Start or Count are not zero only the children of the result in
+	// the range [Start, Start+Count) are loaded (key/value pairs for maps,
+	// bytes for strings), see ListVariableChildren.
+	Start int
+	Count int
 }
 
 type EvalOut struct {
@@ -454,12 +562,72 @@ type EvalOut struct {
 //
 // See https://github.com/go-delve/delve/wiki/Expressions for
 // a description of acceptable values of arg.Expr.
-func (s *RPCServer) Eval(arg EvalIn, out *EvalOut) error {
+//
+// Eval can be interrupted with Cancel.
+func (s *RPCServer) Eval(arg EvalIn, cb service.RPCCallback) {
 	cfg := arg.Cfg
 	if cfg == nil {
 		cfg = &api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
 	}
-	v, err := s.debugger.EvalVariableInScope(arg.Scope, arg.Expr, *api.LoadConfigToProc(cfg))
+	var v *api.Variable
+	var err error
+	if arg.Start != 0 || arg.Count != 0 {
+		count := arg.Count
+		if count == 0 {
+			count = -1
+		}
+		v, err = s.debugger.EvalVariableRangeInScope(cb.Context(), arg.Scope, arg.Expr, arg.Start, count, *api.LoadConfigToProc(cfg))
+	} else {
+		v, err = s.debugger.EvalVariableInScope(cb.Context(), arg.Scope, arg.Expr, *api.LoadConfigToProc(cfg))
+	}
+	if err != nil {
+		cb.Return(nil, err)
+		return
+	}
+	cb.Return(EvalOut{Variable: v}, nil)
+}
+
+type ListVariableChildrenIn struct {
+	Scope api.EvalScope
+	// Ref identifies the variable, as returned in the Ref field of
+	// api.Variable.
+	Ref api.VariableRef
+	// Start is the index of the first child to load, for strings the offset
+	// of the first byte.
+	Start int
+	// Count is the maximum number of children to load, if it is 0
+	// Cfg.MaxArrayValues is used for arrays, slices and maps,
+	// Cfg.MaxStringLen for strings and Cfg.MaxStructFields for structs.
+	Count int
+	Cfg   *api.LoadConfig
+}
+
+type ListVariableChildrenOut struct {
+	// Variable is the variable identified by Ref, its Children field
+	// contains the requested children (key/value pairs for maps), for
+	// strings Value contains the requested substring.
+	// The total number of children, or the length of the string, is in
+	// Variable.Len.
+	Variable *api.Variable
+}
+
+// ListVariableChildren loads the children of a variable identified by its
+// address and type, with paging for arrays, slices, maps, structs and
+// strings.
+// Every variable returned by the API that is stored in memory has a Ref
+// that can be passed to this method, this lets clients expand large
+// values one level at a time without evaluating an expression for each
+// node.
+func (s *RPCServer) ListVariableChildren(arg ListVariableChildrenIn, out *ListVariableChildrenOut) error {
+	cfg := arg.Cfg
+	if cfg == nil {
+		cfg = &api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 0, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+	}
+	count := arg.Count
+	if count == 0 {
+		count = -1
+	}
+	v, err := s.debugger.VariableChildren(arg.Scope, arg.Ref, arg.Start, count, *api.LoadConfigToProc(cfg))
 	if err != nil {
 		return err
 	}
@@ -552,14 +720,18 @@ type ListGoroutinesOut struct {
 // parameter, to get more goroutines from ListGoroutines.
 // Passing a value of Start that wasn't returned by ListGoroutines will skip
 // an undefined number of goroutines.
-func (s *RPCServer) ListGoroutines(arg ListGoroutinesIn, out *ListGoroutinesOut) error {
-	gs, nextg, err := s.debugger.Goroutines(arg.Start, arg.Count)
+//
+// ListGoroutines can be interrupted with Cancel.
+func (s *RPCServer) ListGoroutines(arg ListGoroutinesIn, cb service.RPCCallback) {
+	gs, nextg, err := s.debugger.Goroutines(cb.Context(), arg.Start, arg.Count)
 	if err != nil {
-		return err
+		cb.Return(nil, err)
+		return
 	}
+	var out ListGoroutinesOut
 	out.Goroutines = gs
 	out.Nextg = nextg
-	return nil
+	cb.Return(out, nil)
 }
 
 type AttachedToExistingProcessIn struct {
@@ -571,7 +743,7 @@ type AttachedToExistingProcessOut struct {
 
 // AttachedToExistingProcess returns whether we attached to a running process or not
 func (c *RPCServer) AttachedToExistingProcess(arg AttachedToExistingProcessIn, out *AttachedToExistingProcessOut) error {
-	if c.config.Debugger.AttachPid != 0 {
+	if c.config.Debugger.AttachedToExistingProcess() {
 		out.Answer = true
 	}
 	return nil
@@ -606,6 +778,32 @@ func (c *RPCServer) FindLocation(arg FindLocationIn, out *FindLocationOut) error
 	return err
 }
 
+type CompleteIn struct {
+	Scope api.EvalScope
+	Kind  api.CompletionKind
+	Text  string
+}
+
+type CompleteOut struct {
+	// Start is the offset in Text of the portion being completed, each
+	// candidate replaces Text[Start:].
+	Start       int
+	Completions []api.Completion
+}
+
+// Complete returns completion candidates for a partially typed location
+// specification (if Kind is api.CompleteLocation) or for the last operand
+// of a partially typed expression (if Kind is api.CompleteExpression).
+//
+// Locations are completed with function names and source files.
+// Expressions are completed with local variables, package variables,
+// struct fields (after '.') and map keys (after '[').
+func (c *RPCServer) Complete(arg CompleteIn, out *CompleteOut) error {
+	var err error
+	out.Start, out.Completions, err = c.debugger.Complete(arg.Scope, arg.Kind, arg.Text)
+	return err
+}
+
 type DisassembleIn struct {
 	Scope          api.EvalScope
 	StartPC, EndPC uint64
@@ -642,6 +840,21 @@ func (s *RPCServer) Recorded(arg RecordedIn, out *RecordedOut) error {
 	return nil
 }
 
+type WarningsIn struct {
+}
+
+type WarningsOut struct {
+	Warnings []string
+}
+
+// Warnings returns the problems found while loading the target that did
+// not prevent it from being loaded, for example memory of a core file that
+// can not be read.
+func (s *RPCServer) Warnings(arg WarningsIn, out *WarningsOut) error {
+	out.Warnings = s.debugger.Warnings()
+	return nil
+}
+
 type CheckpointIn struct {
 	Where string
 }
@@ -680,6 +893,88 @@ func (s *RPCServer) ClearCheckpoint(arg ClearCheckpointIn, out *ClearCheckpointO
 	return s.debugger.ClearCheckpoint(arg.ID)
 }
 
+type WhenIn struct {
+}
+
+type WhenOut struct {
+	Position api.RecordingPosition
+}
+
+// When returns the current rr event and the ticks of the current thread,
+// only supported when replaying a recording.
+func (s *RPCServer) When(arg WhenIn, out *WhenOut) error {
+	var err error
+	out.Position, err = s.debugger.When()
+	return err
+}
+
+type SeekIn struct {
+	// Event is the rr event to move to, ignored if UseTicks is set.
+	Event uint64
+	// Ticks is the number of ticks of the current thread to move to, only
+	// used if UseTicks is set.
+	Ticks uint64
+	// UseTicks selects Ticks instead of Event as the destination.
+	UseTicks bool
+}
+
+type SeekOut struct {
+	State api.DebuggerState
+}
+
+// Seek moves to the start of an rr event, or to a number of ticks of the
+// current thread, of the recording being replayed. Breakpoints are not
+// hit while moving.
+func (s *RPCServer) Seek(arg SeekIn, out *SeekOut) error {
+	var st *api.DebuggerState
+	var err error
+	if arg.UseTicks {
+		st, err = s.debugger.Seek(arg.Ticks, true)
+	} else {
+		st, err = s.debugger.Seek(arg.Event, false)
+	}
+	if err != nil {
+		return err
+	}
+	out.State = *st
+	return nil
+}
+
+type ListTraceProcessesIn struct {
+}
+
+type ListTraceProcessesOut struct {
+	Processes []api.TraceProcess
+}
+
+// ListTraceProcesses lists the processes recorded in the trace being
+// replayed. Use ReplayProcess to debug a different process.
+func (s *RPCServer) ListTraceProcesses(arg ListTraceProcessesIn, out *ListTraceProcessesOut) error {
+	var err error
+	out.Processes, err = s.debugger.TraceProcesses()
+	return err
+}
+
+type ReplayProcessIn struct {
+	// Pid of the process to debug, one of the processes returned by
+	// ListTraceProcesses.
+	Pid int
+}
+
+type ReplayProcessOut struct {
+	DiscardedBreakpoints []api.DiscardedBreakpoint
+}
+
+// ReplayProcess starts a new replay of the trace being replayed that
+// debugs the process with the given pid. Breakpoints are recreated in the
+// new replay.
+func (s *RPCServer) ReplayProcess(arg ReplayProcessIn, cb service.RPCCallback) {
+	var out ReplayProcessOut
+	var err error
+	out.DiscardedBreakpoints, err = s.debugger.ReplayProcess(arg.Pid)
+	cb.Return(out, err)
+}
+
 type IsMulticlientIn struct {
 }
 
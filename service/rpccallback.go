@@ -1,6 +1,17 @@
 package service
 
+import "context"
+
 // RPCCallback is used by RPC methods to return their result asynchronously.
 type RPCCallback interface {
 	Return(out interface{}, err error)
+
+	// Context returns a context that is done when the request is cancelled.
+	// Closing the connection does not cancel the requests in progress.
+	Context() context.Context
+
+	// CancelRequests cancels the requests in progress, made on the same
+	// connection, with the JSON-RPC id specified or all of them if id is
+	// nil. It returns the number of requests that were cancelled.
+	CancelRequests(id interface{}) int
 }
@@ -0,0 +1,135 @@
+package rpccommon
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/rpc"
+	"sync"
+)
+
+// serverCodec is a JSON-RPC 1.0 server codec, like the one returned by
+// jsonrpc.NewServerCodec, that also lets the server retrieve the id sent
+// by the client for each request, which is used to identify the requests
+// to cancel.
+type serverCodec struct {
+	dec *json.Decoder
+	enc *json.Encoder
+	c   io.Closer
+
+	req serverRequest
+
+	// the JSON-RPC ids of the requests in progress, by sequence number.
+	mutex   sync.Mutex
+	seq     uint64
+	pending map[uint64]*json.RawMessage
+}
+
+type serverRequest struct {
+	Method string           `json:"method"`
+	Params *json.RawMessage `json:"params"`
+	Id     *json.RawMessage `json:"id"`
+}
+
+type serverResponse struct {
+	Id     *json.RawMessage `json:"id"`
+	Result interface{}      `json:"result"`
+	Error  interface{}      `json:"error"`
+}
+
+var errMissingParams = errors.New("jsonrpc: request body missing params")
+
+var null = json.RawMessage([]byte("null"))
+
+func newServerCodec(conn io.ReadWriteCloser) *serverCodec {
+	return &serverCodec{
+		dec:     json.NewDecoder(conn),
+		enc:     json.NewEncoder(conn),
+		c:       conn,
+		pending: make(map[uint64]*json.RawMessage),
+	}
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	c.req = serverRequest{}
+	if err := c.dec.Decode(&c.req); err != nil {
+		return err
+	}
+	r.ServiceMethod = c.req.Method
+
+	c.mutex.Lock()
+	c.seq++
+	c.pending[c.seq] = c.req.Id
+	c.req.Id = nil
+	r.Seq = c.seq
+	c.mutex.Unlock()
+
+	return nil
+}
+
+func (c *serverCodec) ReadRequestBody(x interface{}) error {
+	if x == nil {
+		return nil
+	}
+	if c.req.Params == nil {
+		return errMissingParams
+	}
+	// JSON params is array value, RPC params is struct: unmarshal into
+	// array containing the struct.
+	var params [1]interface{}
+	params[0] = x
+	return json.Unmarshal(*c.req.Params, &params)
+}
+
+func (c *serverCodec) WriteResponse(r *rpc.Response, x interface{}) error {
+	c.mutex.Lock()
+	b, ok := c.pending[r.Seq]
+	if !ok {
+		c.mutex.Unlock()
+		return errors.New("invalid sequence number in response")
+	}
+	delete(c.pending, r.Seq)
+	c.mutex.Unlock()
+
+	if b == nil {
+		// Invalid request so no id. Use JSON null.
+		b = &null
+	}
+	resp := serverResponse{Id: b}
+	if r.Error == "" {
+		resp.Result = x
+	} else {
+		resp.Error = r.Error
+	}
+	return c.enc.Encode(resp)
+}
+
+func (c *serverCodec) Close() error {
+	return c.c.Close()
+}
+
+// requestID returns the id, sent by the client, of the request with
+// sequence number seq, normalized so that it can be compared with the ids
+// passed to Cancel.
+func (c *serverCodec) requestID(seq uint64) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	b := c.pending[seq]
+	if b == nil {
+		return "null"
+	}
+	var id interface{}
+	if err := json.Unmarshal(*b, &id); err != nil {
+		return string(*b)
+	}
+	return normalizeRequestID(id)
+}
+
+// normalizeRequestID returns the JSON encoding of id.
+func normalizeRequestID(id interface{}) string {
+	buf, err := json.Marshal(id)
+	if err != nil {
+		return ""
+	}
+	return string(buf)
+}
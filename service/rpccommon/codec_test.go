@@ -0,0 +1,43 @@
+package rpccommon
+
+import (
+	"context"
+	"net"
+	"net/rpc"
+	"testing"
+)
+
+func TestCancelRequests(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	codec := newServerCodec(server)
+	go client.Write([]byte(`{"method":"RPCServer.Stacktrace","params":[{}],"id":"a"}{"method":"RPCServer.ListGoroutines","params":[{}],"id":7}{"method":"RPCServer.Cancel","params":[{}],"id":8}`))
+
+	requests := &connRequests{requests: make(map[uint64]inProgressRequest)}
+	var cbs []*RPCCallback
+	for i := 0; i < 3; i++ {
+		var req rpc.Request
+		if err := codec.ReadRequestHeader(&req); err != nil {
+			t.Fatal(err)
+		}
+		if err := codec.ReadRequestBody(nil); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		requests.add(req.Seq, codec.requestID(req.Seq), cancel)
+		cbs = append(cbs, &RPCCallback{req: req, conn: requests, ctx: ctx})
+	}
+
+	if n := cbs[2].CancelRequests(float64(7)); n != 1 {
+		t.Errorf("cancelled %d requests with id 7", n)
+	}
+	if cbs[0].Context().Err() != nil || cbs[1].Context().Err() == nil {
+		t.Errorf("wrong request cancelled")
+	}
+	if n := cbs[2].CancelRequests(nil); n != 2 {
+		t.Errorf("cancelled %d requests with nil id", n)
+	}
+	if cbs[0].Context().Err() == nil || cbs[2].Context().Err() != nil {
+		t.Errorf("cancelling all requests should only exclude the Cancel request itself")
+	}
+}
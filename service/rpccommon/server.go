@@ -2,12 +2,13 @@ package rpccommon
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/rpc"
-	"net/rpc/jsonrpc"
 	"os"
 	"reflect"
 	"runtime"
@@ -50,6 +51,35 @@ type RPCCallback struct {
 	sending *sync.Mutex
 	codec   rpc.ServerCodec
 	req     rpc.Request
+	conn    *connRequests
+	ctx     context.Context
+}
+
+// connRequests keeps track of the asynchronous requests in progress on a
+// connection so that they can be cancelled.
+type connRequests struct {
+	mu       sync.Mutex
+	requests map[uint64]inProgressRequest
+}
+
+type inProgressRequest struct {
+	id     string // JSON-RPC id, see serverCodec.requestID
+	cancel context.CancelFunc
+}
+
+func (cr *connRequests) add(seq uint64, id string, cancel context.CancelFunc) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.requests[seq] = inProgressRequest{id: id, cancel: cancel}
+}
+
+func (cr *connRequests) remove(seq uint64) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if r, ok := cr.requests[seq]; ok {
+		r.cancel()
+		delete(cr.requests, seq)
+	}
 }
 
 // RPCServer implements the RPC method calls common to all versions of the API.
@@ -73,12 +103,16 @@ func NewServer(config *service.Config) *ServerImpl {
 	}
 	if config.Debugger.Foreground {
 		// Print listener address
-		logflags.WriteAPIListeningMessage(config.Listener.Addr().String())
+		logflags.WriteAPIListeningMessage(service.ListenerAddr(config.Listener))
 		logger.Debug("API server pid = ", os.Getpid())
 	}
+	listener := config.Listener
+	if config.TLSConfig != nil {
+		listener = service.NewTLSListener(listener, config.TLSConfig)
+	}
 	return &ServerImpl{
 		config:   config,
-		listener: config.Listener,
+		listener: listener,
 		stopChan: make(chan struct{}),
 		log:      logger,
 	}
@@ -90,7 +124,7 @@ func (s *ServerImpl) Stop() error {
 		close(s.stopChan)
 		s.listener.Close()
 	}
-	kill := s.config.Debugger.AttachPid == 0
+	kill := !s.config.Debugger.AttachedToExistingProcess()
 	return s.debugger.Detach(kill)
 }
 
@@ -148,15 +182,40 @@ func (s *ServerImpl) Run() error {
 				}
 			}
 
-			go s.serveJSONCodec(c)
-			if !s.config.AcceptMulti {
-				break
+			if s.config.AcceptMulti {
+				// Authenticate in the connection's goroutine so that a slow
+				// client can not stop others from connecting.
+				go func(c net.Conn) {
+					if s.authenticate(c) {
+						s.serveJSONCodec(c)
+					}
+				}(c)
+				continue
+			}
+			if !s.authenticate(c) {
+				continue
 			}
+			go s.serveJSONCodec(c)
+			break
 		}
 	}()
 	return nil
 }
 
+// authenticate checks the authentication token sent by the client
+// connected to c, if the server requires one, and closes c if it is wrong.
+func (s *ServerImpl) authenticate(c net.Conn) bool {
+	if s.config.AuthToken == "" {
+		return true
+	}
+	if err := service.CheckAuthToken(c, s.config.AuthToken); err != nil {
+		s.log.Errorf("closing connection from %v: %v", c.RemoteAddr(), err)
+		c.Close()
+		return false
+	}
+	return true
+}
+
 // Precompute the reflect type for error.  Can't use error directly
 // because Typeof takes an empty interface value.  This is annoying.
 var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
@@ -255,7 +314,8 @@ func (s *ServerImpl) serveJSONCodec(conn io.ReadWriteCloser) {
 	}()
 
 	sending := new(sync.Mutex)
-	codec := jsonrpc.NewServerCodec(conn)
+	codec := newServerCodec(conn)
+	requests := &connRequests{requests: make(map[uint64]inProgressRequest)}
 	var req rpc.Request
 	var resp rpc.Response
 	for {
@@ -328,7 +388,12 @@ func (s *ServerImpl) serveJSONCodec(conn io.ReadWriteCloser) {
 				s.log.Debugf("(async %d) <- %s(%T%s)", req.Seq, req.ServiceMethod, argv.Interface(), argvbytes)
 			}
 			function := mtype.method.Func
-			ctl := &RPCCallback{s, sending, codec, req}
+			// Requests are only cancelled by Cancel, not when the connection
+			// is closed: Disconnect(true) sends Continue and closes the
+			// connection immediately, the target must keep running.
+			ctx, cancel := context.WithCancel(context.Background())
+			requests.add(req.Seq, codec.requestID(req.Seq), cancel)
+			ctl := &RPCCallback{s, sending, codec, req, requests, ctx}
 			go func() {
 				defer func() {
 					if ierr := recover(); ierr != nil {
@@ -363,8 +428,12 @@ func (s *ServerImpl) sendResponse(sending *sync.Mutex, req *rpc.Request, resp *r
 }
 
 func (cb *RPCCallback) Return(out interface{}, err error) {
+	cb.conn.remove(cb.req.Seq)
 	errmsg := ""
 	if err != nil {
+		if err == context.Canceled {
+			err = errors.New("request cancelled")
+		}
 		errmsg = err.Error()
 	}
 	var resp rpc.Response
@@ -375,6 +444,34 @@ func (cb *RPCCallback) Return(out interface{}, err error) {
 	cb.s.sendResponse(cb.sending, &cb.req, &resp, out, cb.codec, errmsg)
 }
 
+// Context returns the context of the request, which is done when the
+// request is cancelled with Cancel.
+func (cb *RPCCallback) Context() context.Context {
+	return cb.ctx
+}
+
+// CancelRequests cancels the asynchronous requests in progress on the
+// connection with JSON-RPC id equal to id, or all of them if id is nil.
+func (cb *RPCCallback) CancelRequests(id interface{}) int {
+	var sid string
+	if id != nil {
+		sid = normalizeRequestID(id)
+	}
+	cb.conn.mu.Lock()
+	defer cb.conn.mu.Unlock()
+	n := 0
+	for seq, r := range cb.conn.requests {
+		if seq == cb.req.Seq {
+			continue
+		}
+		if id == nil || r.id == sid {
+			r.cancel()
+			n++
+		}
+	}
+	return n
+}
+
 // GetVersion returns the version of delve as well as the API version
 // currently served.
 func (s *RPCServer) GetVersion(args api.GetVersionIn, out *api.GetVersionOut) error {
@@ -1,8 +1,15 @@
 package service_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"flag"
 	"fmt"
+	"math/big"
 	"math/rand"
 	"net"
 	"net/rpc"
@@ -789,6 +796,70 @@ func TestClientServer_EvalVariable(t *testing.T) {
 	})
 }
 
+func TestClientServer_ListVariableChildren(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestClient2("testvariables2", t, func(c service.Client) {
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		scope := api.EvalScope{GoroutineID: -1}
+
+		s4, err := c.EvalVariable(scope, "s4", api.LoadConfig{})
+		assertNoError(err, t, "EvalVariable(s4)")
+		if s4.Ref == nil {
+			t.Fatal("no reference for s4")
+		}
+		v, err := c.ListVariableChildren(scope, *s4.Ref, 3, 4, normalLoadConfig)
+		assertNoError(err, t, "ListVariableChildren(s4)")
+		if v.Len != 10 || len(v.Children) != 4 {
+			t.Fatalf("wrong length or number of children: %d %d", v.Len, len(v.Children))
+		}
+		for i, child := range v.Children {
+			if child.Value != fmt.Sprintf("%d", i+4) {
+				t.Errorf("wrong value for child %d: %s", i, child.Value)
+			}
+		}
+		v, err = c.ListVariableChildren(scope, *s4.Ref, 8, 4, normalLoadConfig)
+		assertNoError(err, t, "ListVariableChildren(s4, 8)")
+		if len(v.Children) != 2 {
+			t.Fatalf("wrong number of children for last page: %d", len(v.Children))
+		}
+		_, err = c.ListVariableChildren(scope, *s4.Ref, 11, 4, normalLoadConfig)
+		if err == nil {
+			t.Fatal("no error for out of bounds start index")
+		}
+
+		longstr, err := c.EvalVariable(scope, "longstr", api.LoadConfig{})
+		assertNoError(err, t, "EvalVariable(longstr)")
+		v, err = c.ListVariableChildren(scope, *longstr.Ref, 5, 4, normalLoadConfig)
+		assertNoError(err, t, "ListVariableChildren(longstr)")
+		if v.Value != "long" || v.Len != longstr.Len {
+			t.Fatalf("wrong substring %q (len %d)", v.Value, v.Len)
+		}
+
+		// map with keys that can not be written in Go syntax, children are
+		// loaded from the reference of the keys.
+		m3, err := c.EvalVariable(scope, "m3", api.LoadConfig{})
+		assertNoError(err, t, "EvalVariable(m3)")
+		v, err = c.ListVariableChildren(scope, *m3.Ref, 1, 1, normalLoadConfig)
+		assertNoError(err, t, "ListVariableChildren(m3)")
+		if v.Len != 2 || len(v.Children) != 2 {
+			t.Fatalf("wrong length or number of children: %d %d", v.Len, len(v.Children))
+		}
+		key := v.Children[0]
+		if key.Ref == nil {
+			t.Fatal("no reference for map key")
+		}
+		kv, err := c.ListVariableChildren(scope, *key.Ref, 0, 0, normalLoadConfig)
+		assertNoError(err, t, "ListVariableChildren(key)")
+		if len(kv.Children) != 2 || kv.Children[0].Name != "A" || kv.Children[1].Name != "B" {
+			t.Fatalf("wrong fields for map key: %#v", kv.Children)
+		}
+		if a, val := kv.Children[0].Value, v.Children[1].Value; !(a == "1" && val == "42") && !(a == "2" && val == "43") {
+			t.Fatalf("wrong map entry: %s %s", a, val)
+		}
+	})
+}
+
 func TestClientServer_SetVariable(t *testing.T) {
 	withTestClient2("testvariables", t, func(c service.Client) {
 		state := <-c.Continue()
@@ -1595,6 +1666,53 @@ func TestAcceptMulticlient(t *testing.T) {
 	<-serverDone
 }
 
+func TestAcceptMulticlientDisconnectContinue(t *testing.T) {
+	// Disconnect(true) sends a Continue command and closes the connection
+	// right away, closing the connection must not halt the target.
+	if testBackend == "rr" {
+		t.Skip("recording not allowed for TestAcceptMulticlientDisconnectContinue")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("couldn't start listener: %s\n", err)
+	}
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		defer listener.Close()
+		disconnectChan := make(chan struct{})
+		server := rpccommon.NewServer(&service.Config{
+			Listener:       listener,
+			ProcessArgs:    []string{protest.BuildFixture("loopprog", 0).Path},
+			AcceptMulti:    true,
+			DisconnectChan: disconnectChan,
+			Debugger: debugger.Config{
+				Backend: testBackend,
+			},
+		})
+		if err := server.Run(); err != nil {
+			t.Error(err)
+			return
+		}
+		<-disconnectChan
+		server.Stop()
+	}()
+	client1 := rpc2.NewClient(listener.Addr().String())
+	client1.Disconnect(true)
+
+	client2 := rpc2.NewClient(listener.Addr().String())
+	time.Sleep(500 * time.Millisecond)
+	state, err := client2.GetStateNonBlocking()
+	assertNoError(err, t, "GetStateNonBlocking()")
+	if !state.Running {
+		t.Fatalf("target not running after Disconnect(true): %#v", state)
+	}
+	_, err = client2.Halt()
+	assertNoError(err, t, "Halt()")
+	client2.Detach(true)
+	<-serverDone
+}
+
 func mustHaveDebugCalls(t *testing.T, c service.Client) {
 	locs, err := c.FindLocation(api.EvalScope{GoroutineID: -1}, "runtime.debugCallV1", false)
 	if len(locs) == 0 || err != nil {
@@ -1911,6 +2029,33 @@ func TestStopRecording(t *testing.T) {
 	})
 }
 
+func TestClientServer_SeekTicksZero(t *testing.T) {
+	// Ticks equal to zero is a valid destination, it must not be confused
+	// with a seek to an rr event.
+	protest.AllowRecording(t)
+	if testBackend != "rr" {
+		t.Skip("only for rr backend")
+	}
+	withTestClient2("continuetestprog", t, func(c service.Client) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.main", Line: -1})
+		assertNoError(err, t, "CreateBreakpoint()")
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		before, err := c.When()
+		assertNoError(err, t, "When()")
+		if before.Ticks == 0 {
+			t.Fatalf("no ticks executed before main.main: %#v", before)
+		}
+		_, err = c.SeekTicks(0)
+		assertNoError(err, t, "SeekTicks(0)")
+		after, err := c.When()
+		assertNoError(err, t, "When()")
+		if after.Ticks >= before.Ticks {
+			t.Errorf("wrong position after SeekTicks(0): %#v (before %#v)", after, before)
+		}
+	})
+}
+
 func TestClearLogicalBreakpoint(t *testing.T) {
 	// Clearing a logical breakpoint should clear all associated physical
 	// breakpoints.
@@ -1934,3 +2079,385 @@ func TestClearLogicalBreakpoint(t *testing.T) {
 		}
 	})
 }
+
+func TestClientServer_Complete(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestClient2("testvariables2", t, func(c service.Client) {
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+
+		scope := api.EvalScope{GoroutineID: -1}
+
+		completionTexts := func(kind api.CompletionKind, text string) (int, []string) {
+			start, completions, err := c.Complete(scope, kind, text)
+			assertNoError(err, t, fmt.Sprintf("Complete(%q)", text))
+			r := make([]string, len(completions))
+			for i := range completions {
+				r[i] = completions[i].Text
+			}
+			return start, r
+		}
+
+		find := func(tgt string, completions []string) bool {
+			for _, s := range completions {
+				if s == tgt {
+					return true
+				}
+			}
+			return false
+		}
+
+		start, completions := completionTexts(api.CompleteExpression, "1 + as")
+		if start != len("1 + ") || !find("as1", completions) {
+			t.Errorf("local variable: %d %v", start, completions)
+		}
+
+		start, completions = completionTexts(api.CompleteExpression, "as1.")
+		if start != len("as1.") || !find("A", completions) || !find("B", completions) {
+			t.Errorf("fields: %d %v", start, completions)
+		}
+
+		start, completions = completionTexts(api.CompleteExpression, `m1["Mal`)
+		if start != len("m1[") || !find(`"Malone"]`, completions) {
+			t.Errorf("map keys: %d %v", start, completions)
+		}
+
+		start, completions = completionTexts(api.CompleteLocation, "main.mai")
+		if start != 0 || !find("main.main", completions) {
+			t.Errorf("functions: %d %v", start, completions)
+		}
+
+		_, completions = completionTexts(api.CompleteLocation, "testvariables2.g")
+		if !find("testvariables2.go:", completions) {
+			t.Errorf("files: %v", completions)
+		}
+	})
+}
+
+func TestClientServer_PanicBreakpoint(t *testing.T) {
+	protest.AllowRecording(t)
+	testCases := []struct {
+		filter  api.PanicFilter
+		tgtType string
+	}{
+		{api.PanicFilter{}, "string"},
+		{api.PanicFilter{Types: []string{"*main.MyErr"}}, "*main.MyErr"},
+		{api.PanicFilter{Types: []string{"runtime.Error"}}, "runtime.boundsError"},
+		{api.PanicFilter{Message: "errors\\.New"}, "*errors.errorString"},
+		{api.PanicFilter{Types: []string{"error"}, Message: "custom"}, "*main.MyErr"},
+	}
+	for _, tc := range testCases {
+		withTestClient2("panicfilter", t, func(c service.Client) {
+			bp, err := c.CreateBreakpoint(&api.Breakpoint{Panic: &tc.filter})
+			assertNoError(err, t, "CreateBreakpoint()")
+			if bp.FunctionName != "runtime.gopanic" {
+				t.Fatalf("panic breakpoint set on %s", bp.FunctionName)
+			}
+			state := <-c.Continue()
+			assertNoError(state.Err, t, "Continue()")
+			if state.CurrentThread.Breakpoint == nil || state.CurrentThread.Breakpoint.ID != bp.ID {
+				t.Fatalf("%#v: did not stop at panic breakpoint", tc.filter)
+			}
+			v, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, "e", normalLoadConfig)
+			assertNoError(err, t, "EvalVariable(e)")
+			if len(v.Children) != 1 || v.Children[0].Type != tc.tgtType {
+				t.Errorf("%#v: wrong panic value %s", tc.filter, v.SinglelineString())
+			}
+		})
+	}
+}
+
+func TestClientServer_MultiplePanicBreakpoints(t *testing.T) {
+	// Panic breakpoints with different filters share the physical breakpoint
+	// on runtime.gopanic but keep their own IDs.
+	protest.AllowRecording(t)
+	withTestClient2("panicfilter", t, func(c service.Client) {
+		bp1, err := c.CreateBreakpoint(&api.Breakpoint{Panic: &api.PanicFilter{Types: []string{"*main.MyErr"}}})
+		assertNoError(err, t, "CreateBreakpoint(bp1)")
+		bp2, err := c.CreateBreakpoint(&api.Breakpoint{Panic: &api.PanicFilter{Message: "errors\\.New"}})
+		assertNoError(err, t, "CreateBreakpoint(bp2)")
+		if bp1.ID == bp2.ID {
+			t.Fatalf("panic breakpoints have the same ID %d", bp1.ID)
+		}
+		bps, err := c.ListBreakpoints()
+		assertNoError(err, t, "ListBreakpoints()")
+		found := 0
+		for _, bp := range bps {
+			if bp.ID == bp1.ID || bp.ID == bp2.ID {
+				found++
+			}
+		}
+		if found != 2 {
+			t.Fatalf("panic breakpoints missing from %v", bps)
+		}
+
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		if state.CurrentThread.Breakpoint == nil || state.CurrentThread.Breakpoint.ID != bp2.ID {
+			t.Fatalf("did not stop at the errors.New panic breakpoint: %#v", state.CurrentThread.Breakpoint)
+		}
+
+		// Clearing the first breakpoint must leave the second one working.
+		_, err = c.ClearBreakpoint(bp1.ID)
+		assertNoError(err, t, "ClearBreakpoint(bp1)")
+		bp3, err := c.CreateBreakpoint(&api.Breakpoint{Panic: &api.PanicFilter{Types: []string{"runtime.Error"}}})
+		assertNoError(err, t, "CreateBreakpoint(bp3)")
+
+		state = <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		if state.CurrentThread.Breakpoint == nil || state.CurrentThread.Breakpoint.ID != bp3.ID {
+			t.Fatalf("did not stop at the runtime.Error panic breakpoint: %#v", state.CurrentThread.Breakpoint)
+		}
+		if bp, err := c.GetBreakpoint(bp2.ID); err != nil || bp.TotalHitCount != 1 {
+			t.Fatalf("wrong hit count for the errors.New panic breakpoint: %#v %v", bp, err)
+		}
+	})
+}
+
+func TestClientServer_Events(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestClient2("continuetestprog", t, func(c service.Client) {
+		events := c.Events()
+		bp, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.sayhi", Line: -1})
+		assertNoError(err, t, "CreateBreakpoint()")
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+
+		expected := []api.EventKind{api.EventBreakpointCreated, api.EventRunning, api.EventBreakpointHit, api.EventStopped}
+		for i, kind := range expected {
+			var ev api.Event
+			select {
+			case ev = <-events:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for event %d (%s)", i, kind)
+			}
+			if ev.Kind != kind {
+				t.Fatalf("event %d: got %s expected %s", i, ev.Kind, kind)
+			}
+			if ev.Breakpoint != nil && ev.Breakpoint.ID != bp.ID {
+				t.Errorf("event %d: wrong breakpoint %d", i, ev.Breakpoint.ID)
+			}
+		}
+	})
+}
+
+func TestClientServer_CaptureOutput(t *testing.T) {
+	protest.AllowRecording(t)
+	if testBackend == "rr" {
+		protest.MustHaveRecordingAllowed(t)
+	}
+	fixture := protest.BuildFixture("outputprog", 0)
+	listener, clientConn := service.ListenerPipe()
+	defer listener.Close()
+	server := rpccommon.NewServer(&service.Config{
+		Listener:    listener,
+		ProcessArgs: []string{fixture.Path},
+		Debugger: debugger.Config{
+			Backend:       testBackend,
+			CaptureOutput: true,
+		},
+	})
+	if err := server.Run(); err != nil {
+		t.Fatal(err)
+	}
+	c := rpc2.NewClientFromConn(clientConn)
+	defer c.Detach(true)
+
+	state := <-c.Continue()
+	if !state.Exited {
+		t.Fatalf("target did not exit: %#v", state)
+	}
+
+	// The output is copied from the target by other goroutines, it can be
+	// captured after the target exits.
+	output := map[string]string{}
+	var offset uint64
+	for i := 0; i < 50; i++ {
+		chunks, next, err := c.GetOutput(offset)
+		assertNoError(err, t, "GetOutput()")
+		for _, chunk := range chunks {
+			output[chunk.Stream] += string(chunk.Data)
+		}
+		offset = next
+		if output["stdout"] != "" && output["stderr"] != "" {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if output["stdout"] != "hello, stdout\n" || output["stderr"] != "hello, stderr\n" {
+		t.Fatalf("wrong output %#v", output)
+	}
+}
+
+// startAuthServer starts a server for the continuetestprog fixture that
+// requires the authentication token "secret" and, if tlscfg isn't nil, TLS
+// connections. Returns the address of the server.
+func startAuthServer(t *testing.T, tlscfg *tls.Config) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("couldn't start listener: %s\n", err)
+	}
+	server := rpccommon.NewServer(&service.Config{
+		Listener:    listener,
+		ProcessArgs: []string{protest.BuildFixture("continuetestprog", 0).Path},
+		AuthToken:   "secret",
+		TLSConfig:   tlscfg,
+		Debugger: debugger.Config{
+			Backend: testBackend,
+		},
+	})
+	if err := server.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return listener.Addr().String()
+}
+
+// mustFailToConnect checks that the server at addr closes the connection
+// made with cfg.
+func mustFailToConnect(t *testing.T, addr string, cfg service.DialConfig, descr string) {
+	t.Helper()
+	c, err := rpc2.NewClientWithConfig(addr, cfg)
+	if err != nil {
+		return
+	}
+	if _, err := c.GetStateNonBlocking(); err == nil {
+		t.Fatalf("connection %s accepted", descr)
+	}
+}
+
+func TestClientServer_AuthToken(t *testing.T) {
+	protest.AllowRecording(t)
+	addr := startAuthServer(t, nil)
+
+	mustFailToConnect(t, addr, service.DialConfig{}, "without token")
+	mustFailToConnect(t, addr, service.DialConfig{AuthToken: "wrong"}, "with the wrong token")
+
+	c, err := rpc2.NewClientWithConfig(addr, service.DialConfig{AuthToken: "secret"})
+	assertNoError(err, t, "NewClientWithConfig()")
+	_, err = c.GetStateNonBlocking()
+	assertNoError(err, t, "GetStateNonBlocking()")
+	c.Detach(true)
+}
+
+// selfSignedCertificate returns a certificate for 127.0.0.1 and a pool
+// containing it, to verify the certificate on the client side.
+func selfSignedCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	assertNoError(err, t, "GenerateKey()")
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(crand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assertNoError(err, t, "CreateCertificate()")
+	cert, err := x509.ParseCertificate(der)
+	assertNoError(err, t, "ParseCertificate()")
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestClientServer_TLS(t *testing.T) {
+	protest.AllowRecording(t)
+	cert, pool := selfSignedCertificate(t)
+	addr := startAuthServer(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+
+	mustFailToConnect(t, addr, service.DialConfig{AuthToken: "secret"}, "without TLS")
+	mustFailToConnect(t, addr, service.DialConfig{AuthToken: "secret", TLSConfig: &tls.Config{}}, "with an unknown certificate")
+	mustFailToConnect(t, addr, service.DialConfig{TLSConfig: &tls.Config{RootCAs: pool}}, "over TLS without token")
+
+	c, err := rpc2.NewClientWithConfig(addr, service.DialConfig{AuthToken: "secret", TLSConfig: &tls.Config{RootCAs: pool}})
+	assertNoError(err, t, "NewClientWithConfig()")
+	_, err = c.GetStateNonBlocking()
+	assertNoError(err, t, "GetStateNonBlocking()")
+	c.Detach(true)
+}
+
+func TestClientServer_PendingBreakpointRestart(t *testing.T) {
+	// Breakpoints created as pending breakpoints must become pending again
+	// after a restart, if the plugin containing them isn't loaded yet, and
+	// all breakpoints must keep their IDs.
+	if testBackend == "rr" {
+		t.Skip("plugins are loaded before the first stop of a recording")
+	}
+	pluginFixtures := protest.WithPlugins(t, protest.AllNonOptimized, "plugin1/", "plugin2/")
+	fixture := protest.BuildFixture("plugintest", protest.AllNonOptimized)
+	listener, clientConn := service.ListenerPipe()
+	defer listener.Close()
+	server := rpccommon.NewServer(&service.Config{
+		Listener:    listener,
+		ProcessArgs: []string{fixture.Path, pluginFixtures[0].Path, pluginFixtures[1].Path},
+		Debugger: debugger.Config{
+			Backend: testBackend,
+		},
+	})
+	if err := server.Run(); err != nil {
+		t.Fatal(err)
+	}
+	c := rpc2.NewClientFromConn(clientConn)
+	defer c.Detach(true)
+
+	mainBp, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.main", Line: -1})
+	assertNoError(err, t, "CreateBreakpoint(main.main)")
+	mustBp, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.must", Line: -1})
+	assertNoError(err, t, "CreateBreakpoint(main.must)")
+	_, err = c.ClearBreakpoint(mustBp.ID)
+	assertNoError(err, t, "ClearBreakpoint(main.must)")
+	pluginBp, err := c.CreateBreakpoint(&api.Breakpoint{File: "plugin1.go", Line: 10, Pending: true})
+	assertNoError(err, t, "CreateBreakpoint(plugin1.go:10)")
+	missingBp, err := c.CreateBreakpoint(&api.Breakpoint{File: "missing.go", Line: 1, Pending: true})
+	assertNoError(err, t, "CreateBreakpoint(missing.go:1)")
+
+	checkBreakpoints := func(pluginLoaded bool) {
+		t.Helper()
+		bps, err := c.ListBreakpoints()
+		assertNoError(err, t, "ListBreakpoints()")
+		found := map[int]*api.Breakpoint{}
+		for _, bp := range bps {
+			found[bp.ID] = bp
+		}
+		if bp := found[mainBp.ID]; bp == nil || bp.FunctionName != "main.main" {
+			t.Errorf("breakpoint %d not on main.main: %#v", mainBp.ID, bp)
+		}
+		if bp := found[pluginBp.ID]; bp == nil || bp.Pending == pluginLoaded || (bp.Addr != 0) != pluginLoaded {
+			t.Errorf("wrong breakpoint %d (plugin loaded %v): %#v", pluginBp.ID, pluginLoaded, bp)
+		}
+		if bp := found[missingBp.ID]; bp == nil || !bp.Pending || bp.File != "missing.go" {
+			t.Errorf("wrong breakpoint %d: %#v", missingBp.ID, bp)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		checkBreakpoints(false)
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		if state.CurrentThread.Breakpoint == nil || state.CurrentThread.Breakpoint.ID != mainBp.ID {
+			t.Fatalf("did not stop at main.main: %#v", state.CurrentThread)
+		}
+		state = <-c.Continue() // runtime.Breakpoint after plugin1 is loaded
+		assertNoError(state.Err, t, "Continue()")
+		checkBreakpoints(true)
+
+		if i == 0 {
+			discarded, err := c.Restart()
+			assertNoError(err, t, "Restart()")
+			if len(discarded) != 0 {
+				t.Fatalf("breakpoints discarded by Restart: %#v", discarded)
+			}
+		}
+	}
+
+	newBp, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.must", Line: -1})
+	assertNoError(err, t, "CreateBreakpoint(main.must)")
+	if newBp.ID <= missingBp.ID {
+		t.Errorf("new breakpoint reused ID %d", newBp.ID)
+	}
+}
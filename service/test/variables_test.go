@@ -131,9 +131,9 @@ func withTestProcessArgs(name string, t *testing.T, wd string, args []string, bu
 	var tracedir string
 	switch testBackend {
 	case "native":
-		p, err = native.Launch(append([]string{fixture.Path}, args...), wd, false, []string{}, "")
+		p, err = native.Launch(append([]string{fixture.Path}, args...), wd, false, []string{}, "", proc.OutputRedirect{})
 	case "lldb":
-		p, err = gdbserial.LLDBLaunch(append([]string{fixture.Path}, args...), wd, false, []string{}, "")
+		p, err = gdbserial.LLDBLaunch(append([]string{fixture.Path}, args...), wd, false, []string{}, "", proc.OutputRedirect{})
 	case "rr":
 		protest.MustHaveRecordingAllowed(t)
 		t.Log("recording")
@@ -1519,3 +1519,53 @@ func TestPluginVariables(t *testing.T) {
 		assertVariable(t, vb, varTest{"b", true, `github.com/go-delve/delve/_fixtures/internal/pluginsupport.SomethingElse(*github.com/go-delve/delve/_fixtures/plugin2.asomethingelse) *{x: 1, y: 4}`, ``, `github.com/go-delve/delve/_fixtures/internal/pluginsupport.SomethingElse`, nil})
 	})
 }
+
+func TestCgoCTypes(t *testing.T) {
+	// Test printing of C types and evaluation of expressions using C syntax
+	// while stopped in a C function.
+	protest.MustHaveCgo(t)
+	protest.AllowRecording(t)
+	withTestProcess("cgoctypes", t, func(p *proc.Target, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture, 48)
+		assertNoError(p.Continue(), t, "Continue()")
+
+		testcases := []varTest{
+			{"lbits", true, "struct bits {a: 5, b: -1, c: 1000, d: 120}", "", "struct bits", nil},
+			{"lbits.b", true, "-1", "", "int", nil},
+			{"c", true, "RED (-2)", "", "enum color", nil},
+			{"p->val", false, "1", "", "int", nil},
+			{"p->next->val", false, "2", "", "int", nil},
+			{"C.gnum", true, "union num {i: 1065353216, f: 1}", "", "union num", nil},
+			{"C.ganon", true, "struct anon {x: 1, {y: 2, z: 2}, {w: 3}}", "", "struct anon", nil},
+			{"C.ganon.w", true, "3", "", "int", nil},
+			{"C.gcolor", true, "GREEN (-1)", "", "enum color", nil},
+			{"((struct node *)C.gptr)->next->val", false, "2", "", "int", nil},
+			{"(unsigned char)lbits.d", false, "120", "", "unsigned char", nil},
+		}
+
+		scope, err := proc.ThreadScope(p.CurrentThread())
+		assertNoError(err, t, "ThreadScope()")
+		for _, tc := range testcases {
+			v, err := scope.EvalVariable(tc.name, pnormalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", tc.name))
+			assertVariable(t, v, tc)
+		}
+
+		assertNoError(scope.SetVariable("lbits.c", "7"), t, "SetVariable(lbits.c)")
+		for _, tc := range []struct{ name, value string }{
+			{"lbits.a", "8"},
+			{"lbits.b", "-17"},
+			{"lbits.b", "16"},
+			{"lbits.c", "-1"},
+			{"lbits.c", "1.5"},
+			{"lbits.c", "true"},
+		} {
+			if err := scope.SetVariable(tc.name, tc.value); err == nil {
+				t.Errorf("SetVariable(%s, %s) did not return an error", tc.name, tc.value)
+			}
+		}
+		v, err := scope.EvalVariable("lbits", pnormalLoadConfig)
+		assertNoError(err, t, "EvalVariable(lbits)")
+		assertVariable(t, v, varTest{"lbits", true, "struct bits {a: 5, b: -1, c: 7, d: 120}", "", "struct bits", nil})
+	})
+}
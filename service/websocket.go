@@ -0,0 +1,423 @@
+package service
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// wsAddrPrefix is the prefix of addresses on which the debugging server
+// accepts WebSocket connections.
+const wsAddrPrefix = "ws:"
+
+// wsGUID is the value used to compute Sec-WebSocket-Accept, see RFC 6455
+// section 1.3.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsHandshakeTimeout is the maximum time a client has to complete the
+// WebSocket handshake.
+const wsHandshakeTimeout = 10 * time.Second
+
+// wsMaxFrameLen is the maximum payload length of frames sent by clients.
+const wsMaxFrameLen = 64 << 20
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xa
+)
+
+var errWSClosed = errors.New("websocket closed")
+
+// wsListener accepts WebSocket connections on a TCP listener. The payload
+// of the messages exchanged over each connection forms a byte stream that
+// carries the same protocol, JSON-RPC or DAP, used on TCP connections.
+type wsListener struct {
+	net.Listener
+	allowedOrigins []string
+}
+
+// Accept waits for a new TCP connection, the WebSocket handshake happens
+// on the first Read or Write so that a slow client does not stop the
+// server from accepting other connections.
+func (l *wsListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &wsConn{Conn: conn, rd: bufio.NewReader(conn), allowedOrigins: l.allowedOrigins}, nil
+}
+
+// wsConn is a WebSocket connection seen as a byte stream.
+type wsConn struct {
+	net.Conn
+	rd *bufio.Reader
+
+	// client is true for connections created by Dial, which mask the
+	// frames they send.
+	client         bool
+	allowedOrigins []string
+
+	handshakeMu   sync.Mutex
+	handshakeDone bool
+	handshakeErr  error
+
+	readMu  sync.Mutex
+	pending int64 // bytes left in the payload of the current frame
+	mask    [4]byte
+	masked  bool
+	maskPos int
+	// fragmented is true if the last data frame read didn't have the FIN
+	// bit set, the message continues in the next data frame, which must be
+	// a continuation frame.
+	fragmented bool
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// Handshake performs the server side of the opening handshake, if it
+// wasn't already done.
+func (c *wsConn) Handshake() error {
+	c.handshakeMu.Lock()
+	defer c.handshakeMu.Unlock()
+	if c.handshakeDone {
+		return c.handshakeErr
+	}
+	c.handshakeDone = true
+	c.handshakeErr = c.serverHandshake()
+	if c.handshakeErr != nil {
+		c.Conn.Close()
+	}
+	return c.handshakeErr
+}
+
+func (c *wsConn) serverHandshake() error {
+	c.Conn.SetDeadline(time.Now().Add(wsHandshakeTimeout))
+	defer c.Conn.SetDeadline(time.Time{})
+	req, err := http.ReadRequest(c.rd)
+	if err != nil {
+		return err
+	}
+	fail := func(status int, reason string) error {
+		fmt.Fprintf(c.Conn, "HTTP/1.1 %d %s\r\nContent-Type: text/plain\r\nConnection: close\r\n\r\n%s\n", status, http.StatusText(status), reason)
+		return errors.New("websocket handshake failed: " + reason)
+	}
+	if req.Method != "GET" || !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") || !headerContainsToken(req.Header, "Connection", "upgrade") {
+		return fail(http.StatusBadRequest, "not a websocket handshake")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		return fail(http.StatusBadRequest, "unsupported websocket version")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return fail(http.StatusBadRequest, "missing Sec-WebSocket-Key")
+	}
+	_, port, _ := net.SplitHostPort(c.Conn.LocalAddr().String())
+	if !originAllowed(req, c.allowedOrigins, port) {
+		return fail(http.StatusForbidden, "origin not allowed")
+	}
+	_, err = fmt.Fprintf(c.Conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", wsAcceptKey(key))
+	return err
+}
+
+// originAllowed returns true if the Origin header of req, which browsers
+// always send, is either in allowedOrigins or is a loopback origin using
+// the port the server listens on. Without this check any web page could
+// connect to a server listening on localhost.
+// The Host header of requests coming from a loopback origin must be a
+// loopback address too: comparing it with the origin isn't enough because
+// a web page controls both when it resolves its own domain to 127.0.0.1
+// (DNS rebinding).
+func originAllowed(req *http.Request, allowedOrigins []string, port string) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		// Not a browser, for example 'dlv connect': the check protects the
+		// server from web pages, other clients can connect to the listening
+		// address anyway.
+		return true
+	}
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	if err != nil || !isLoopbackHost(u.Hostname()) || u.Port() != port {
+		return false
+	}
+	host, _, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		host = req.Host
+	}
+	return isLoopbackHost(host)
+}
+
+// isLoopbackHost returns true if host is localhost or a loopback IP address.
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func wsAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Read reads the payload of data frames, answering control frames as they
+// arrive.
+func (c *wsConn) Read(b []byte) (int, error) {
+	if err := c.Handshake(); err != nil {
+		return 0, err
+	}
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	for c.pending == 0 {
+		if err := c.readFrameHeader(); err != nil {
+			return 0, err
+		}
+	}
+	if int64(len(b)) > c.pending {
+		b = b[:c.pending]
+	}
+	n, err := c.rd.Read(b)
+	if c.masked {
+		for i := 0; i < n; i++ {
+			b[i] ^= c.mask[c.maskPos%4]
+			c.maskPos++
+		}
+	}
+	c.pending -= int64(n)
+	return n, err
+}
+
+// readFrameHeader reads frames until the header of a data frame is found,
+// setting up c.pending and the mask for its payload.
+func (c *wsConn) readFrameHeader() error {
+	var hdr [2]byte
+	if _, err := io.ReadFull(c.rd, hdr[:]); err != nil {
+		return err
+	}
+	fin := hdr[0]&0x80 != 0
+	if hdr[0]&0x70 != 0 {
+		// No extensions are negotiated, the reserved bits must be 0.
+		return errors.New("websocket frame with reserved bits set")
+	}
+	opcode := hdr[0] & 0xf
+	masked := hdr[1]&0x80 != 0
+	length := int64(hdr[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.rd, ext[:]); err != nil {
+			return err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.rd, ext[:]); err != nil {
+			return err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if length < 0 || length > wsMaxFrameLen {
+		return errors.New("websocket frame too large")
+	}
+	if masked == c.client {
+		// RFC 6455 section 5.1: clients must mask their frames, servers
+		// must not.
+		return errors.New("websocket frame with wrong masking")
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.rd, mask[:]); err != nil {
+			return err
+		}
+	}
+
+	switch opcode {
+	case wsOpContinuation, wsOpText, wsOpBinary:
+		// The payload of all data messages goes to the same byte stream,
+		// but fragmented messages must still be well formed (RFC 6455
+		// section 5.4).
+		if opcode == wsOpContinuation && !c.fragmented {
+			return errors.New("websocket continuation frame outside of a fragmented message")
+		}
+		if opcode != wsOpContinuation && c.fragmented {
+			return errors.New("websocket data frame inside of a fragmented message")
+		}
+		c.fragmented = !fin
+		c.pending = length
+		c.mask = mask
+		c.masked = masked
+		c.maskPos = 0
+		return nil
+	}
+
+	if length > 125 || !fin {
+		return errors.New("websocket control frame too large or fragmented")
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.rd, payload); err != nil {
+		return err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	switch opcode {
+	case wsOpPing:
+		return c.writeFrame(wsOpPong, true, payload)
+	case wsOpPong:
+		return nil
+	case wsOpClose:
+		c.writeFrame(wsOpClose, true, nil)
+		return io.EOF
+	default:
+		return fmt.Errorf("unknown websocket opcode %#x", opcode)
+	}
+}
+
+// Write sends b as a single message, a text message if b is valid UTF-8,
+// as the JSON-RPC and DAP messages are, a binary message otherwise.
+func (c *wsConn) Write(b []byte) (int, error) {
+	if err := c.Handshake(); err != nil {
+		return 0, err
+	}
+	opcode := byte(wsOpText)
+	if !utf8.Valid(b) {
+		opcode = wsOpBinary
+	}
+	if err := c.writeFrame(opcode, true, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// writeFrame sends a frame with the given opcode and payload, fin is false
+// if the frame is not the last one of its message.
+func (c *wsConn) writeFrame(opcode byte, fin bool, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return errWSClosed
+	}
+	if opcode == wsOpClose {
+		c.closed = true
+	}
+	frame := make([]byte, 0, len(payload)+14)
+	if fin {
+		opcode |= 0x80
+	}
+	frame = append(frame, opcode)
+	var maskbit byte
+	if c.client {
+		maskbit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskbit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskbit|126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, maskbit|127)
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		frame = append(frame, ext[:]...)
+	}
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	_, err := c.Conn.Write(frame)
+	return err
+}
+
+// Close sends a close frame, if the handshake was completed, and closes
+// the underlying connection.
+func (c *wsConn) Close() error {
+	c.handshakeMu.Lock()
+	done := c.handshakeDone && c.handshakeErr == nil
+	c.handshakeMu.Unlock()
+	if done {
+		c.writeFrame(wsOpClose, true, nil)
+	}
+	return c.Conn.Close()
+}
+
+// dialWebSocket connects to a server listening on a WebSocket address.
+func dialWebSocket(hostport string, tlsConfig *tls.Config) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if tlsConfig != nil {
+		conn, err = tls.Dial("tcp", hostport, tlsConfig)
+	} else {
+		conn, err = net.Dial("tcp", hostport)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rawkey [16]byte
+	if _, err := rand.Read(rawkey[:]); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(rawkey[:])
+	_, err = fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", hostport, key)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	rd := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(rd, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(key) {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: %s", resp.Status)
+	}
+	return &wsConn{Conn: conn, rd: rd, client: true, handshakeDone: true}, nil
+}
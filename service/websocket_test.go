@@ -0,0 +1,190 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWebSocket(t *testing.T) {
+	listener, err := Listen("ws:127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	addr := ListenerAddr(listener)
+	if !strings.HasPrefix(addr, "ws:127.0.0.1:") {
+		t.Fatalf("wrong listener address %q", addr)
+	}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// echo every line back, upper cased
+		rd := bufio.NewReader(conn)
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(strings.ToUpper(line)))
+		}
+	}()
+
+	conn, err := Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	long := strings.Repeat("x", 70000)
+	for _, msg := range []string{"hello\n", long + "\n"} {
+		// split the message across frames
+		conn.Write([]byte(msg[:3]))
+		conn.Write([]byte(msg[3:]))
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != strings.ToUpper(msg) {
+			t.Fatalf("wrong echo %q for %q", line[:10], msg[:10])
+		}
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	listener, err := ListenWithConfig("ws:127.0.0.1:0", ListenConfig{AllowedOrigins: []string{"http://allowed.example"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	hostport := listener.Addr().String()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.Read(make([]byte, 1))
+				conn.Close()
+			}()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(hostport)
+
+	for _, tc := range []struct {
+		host   string
+		origin string
+		status int
+	}{
+		{hostport, "", http.StatusSwitchingProtocols},
+		{hostport, "http://" + hostport, http.StatusSwitchingProtocols},
+		{"localhost:" + port, "http://localhost:" + port, http.StatusSwitchingProtocols},
+		{"[::1]:" + port, "http://[::1]:" + port, http.StatusSwitchingProtocols},
+		{hostport, "http://allowed.example", http.StatusSwitchingProtocols},
+		{hostport, "http://evil.example", http.StatusForbidden},
+		{hostport, "http://localhost:1", http.StatusForbidden},
+		// DNS rebinding: evil.example resolves to 127.0.0.1
+		{"evil.example:" + port, "http://evil.example:" + port, http.StatusForbidden},
+		{"evil.example:" + port, "http://127.0.0.1:" + port, http.StatusForbidden},
+	} {
+		conn, err := net.Dial("tcp", hostport)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := fmt.Sprintf("GET / HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n", tc.host)
+		if tc.origin != "" {
+			req += "Origin: " + tc.origin + "\r\n"
+		}
+		io.WriteString(conn, req+"\r\n")
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.status {
+			t.Errorf("host %q origin %q: got status %d, expected %d", tc.host, tc.origin, resp.StatusCode, tc.status)
+		}
+		if tc.status == http.StatusSwitchingProtocols && resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+			t.Errorf("wrong Sec-WebSocket-Accept %q", resp.Header.Get("Sec-WebSocket-Accept"))
+		}
+		conn.Close()
+	}
+}
+
+// wsPipe returns the server and client ends of a WebSocket connection
+// over net.Pipe, with the handshake already done.
+func wsPipe() (server, client *wsConn) {
+	s, c := net.Pipe()
+	server = &wsConn{Conn: s, rd: bufio.NewReader(s), handshakeDone: true}
+	client = &wsConn{Conn: c, rd: bufio.NewReader(c), client: true, handshakeDone: true}
+	return server, client
+}
+
+func TestWebSocketFragments(t *testing.T) {
+	server, client := wsPipe()
+	defer server.Conn.Close()
+	// discard the pong sent by the server
+	go io.Copy(ioutil.Discard, client.Conn)
+	go func() {
+		client.writeFrame(wsOpText, false, []byte("hel"))
+		client.writeFrame(wsOpPing, true, []byte("ping"))
+		client.writeFrame(wsOpContinuation, false, []byte("lo "))
+		client.writeFrame(wsOpContinuation, true, []byte("world\n"))
+		// continuation of a message that was already finished
+		client.writeFrame(wsOpContinuation, true, []byte("x"))
+	}()
+	line, err := bufio.NewReader(server).ReadString('\n')
+	if err != nil || line != "hello world\n" {
+		t.Fatalf("wrong fragmented message %q %v", line, err)
+	}
+	if _, err := server.Read(make([]byte, 10)); err == nil {
+		t.Fatal("continuation frame outside of a fragmented message accepted")
+	}
+
+	server, client = wsPipe()
+	defer server.Conn.Close()
+	go func() {
+		client.writeFrame(wsOpText, false, []byte("a"))
+		client.writeFrame(wsOpText, true, []byte("b"))
+	}()
+	buf := make([]byte, 10)
+	if n, err := server.Read(buf); err != nil || string(buf[:n]) != "a" {
+		t.Fatalf("wrong first fragment %q %v", buf[:n], err)
+	}
+	if _, err := server.Read(buf); err == nil {
+		t.Fatal("new message inside of a fragmented message accepted")
+	}
+}
+
+func TestWebSocketBinaryMessages(t *testing.T) {
+	for _, tc := range []struct {
+		payload string
+		opcode  byte
+	}{
+		{"{\"id\":1}\n", wsOpText},
+		{"\xff\xfe\n", wsOpBinary},
+	} {
+		server, client := wsPipe()
+		go server.Write([]byte(tc.payload))
+		var hdr [2]byte
+		if _, err := io.ReadFull(client.Conn, hdr[:]); err != nil {
+			t.Fatal(err)
+		}
+		if hdr[0] != 0x80|tc.opcode {
+			t.Errorf("%q: got frame header %#x, want %#x", tc.payload, hdr[0], 0x80|tc.opcode)
+		}
+		server.Conn.Close()
+		client.Conn.Close()
+	}
+}